@@ -0,0 +1,18 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/openshift/cluster-network-operator/pkg/cmd/egressrouterha"
+)
+
+func main() {
+	command := egressrouterha.NewEgressRouterHACommand()
+	if err := command.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
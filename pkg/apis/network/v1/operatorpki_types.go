@@ -25,13 +25,26 @@ import (
 //   - tls.crt - the certificate, signed by the CA
 //
 // The CA certificate will have a validity of 10 years, rotated after 9.
-// The target certificate will have a validity of 6 months, rotated after 3
+// The target certificate will have a validity of 6 months, rotated after 3.
 //
 // The CA certificate will have a CommonName of "<namespace>_<name>-ca@<timestamp>", where
 // <timestamp> is the last rotation time.
 //
+// These durations, the keys and the subject alternative names of the
+// certificates can be configured in the spec. Certificates are always rotated
+// once 80% of their validity is over. A shorter refresh applies to the current
+// certificates, which are rotated as soon as they are older than it.
+//
+// Each entry of additionalTargetCerts, with <certName>, is signed by the same
+// CA and stored in a Secret called <name>-<certName>-cert.
+//
+// The status of the OperatorPKI reports the serial number, validity and rotation
+// times of both certificates, so that their consumers can check their freshness
+// without reading the Secrets.
+//
 // +k8s:openapi-gen=true
 // +kubebuilder:resource:path=operatorpkis,scope=Namespaced
+// +kubebuilder:subresource:status
 // +kubebuilder:metadata:annotations=include.release.openshift.io/self-managed-high-availability=true
 // +kubebuilder:metadata:annotations=include.release.openshift.io/ibm-cloud-managed=true
 type OperatorPKI struct {
@@ -48,21 +61,185 @@ type OperatorPKI struct {
 // +k8s:openapi-gen=true
 // +kubebuilder:validation:Required
 type OperatorPKISpec struct {
+	// ca configures the CA. It defaults to a validity of 10 years, rotated
+	// after 9.
+	//
+	// +optional
+	CA *CASpec `json:"ca,omitempty"`
+
 	// targetCert configures the certificate signed by the CA. It will have
 	// both ClientAuth and ServerAuth enabled
 	TargetCert CertSpec `json:"targetCert"`
+
+	// additionalTargetCerts configures more certificates signed by the CA,
+	// with the same usages as targetCert
+	//
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	AdditionalTargetCerts []NamedCertSpec `json:"additionalTargetCerts,omitempty"`
+}
+
+// CASpec defines the configuration of the CA.
+type CASpec struct {
+	// validity is the lifetime of the CA certificate. It must be at least 24h.
+	//
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// refresh is the age at which the CA certificate is rotated. It must be
+	// between 10% and 100% of the validity.
+	//
+	// +optional
+	Refresh *metav1.Duration `json:"refresh,omitempty"`
+
+	// key configures the private key of the CA. Changes are applied at the next
+	// rotation.
+	//
+	// +optional
+	Key *KeySpec `json:"key,omitempty"`
 }
 
 // CertSpec defines common certificate configuration.
 type CertSpec struct {
-	// commonName is the value in the certificate's CN
+	// commonName is the value in the certificate's CN. It is also a DNS
+	// subject alternative name of the certificate.
 	//
 	// +kubebuilder:validation:MinLength=1
 	CommonName string `json:"commonName"`
+
+	// dnsNames are additional DNS subject alternative names of the certificate
+	//
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	DNSNames []string `json:"dnsNames,omitempty"`
+
+	// ipAddresses are the IP subject alternative names of the certificate
+	//
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	IPAddresses []string `json:"ipAddresses,omitempty"`
+
+	// validity is the lifetime of the certificate. It must be at least 1h, and
+	// no longer than the validity of the CA. It defaults to 6 months.
+	//
+	// +optional
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// refresh is the age at which the certificate is rotated. It must be between
+	// 10% and 100% of the validity, and defaults to half of it.
+	//
+	// +optional
+	Refresh *metav1.Duration `json:"refresh,omitempty"`
+
+	// key configures the private key of the certificate. Changes are applied at
+	// the next rotation.
+	//
+	// +optional
+	Key *KeySpec `json:"key,omitempty"`
+}
+
+// NamedCertSpec defines an additional certificate signed by the CA.
+type NamedCertSpec struct {
+	// name identifies the certificate, which is stored in the
+	// <pki name>-<name>-cert Secret
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	CertSpec `json:",inline"`
+}
+
+// KeyAlgorithm is the algorithm of a private key.
+//
+// +kubebuilder:validation:Enum=RSA;ECDSA
+type KeyAlgorithm string
+
+const (
+	KeyAlgorithmRSA   KeyAlgorithm = "RSA"
+	KeyAlgorithmECDSA KeyAlgorithm = "ECDSA"
+)
+
+// KeySpec defines the private key of a certificate.
+type KeySpec struct {
+	// algorithm is the algorithm of the key, RSA or ECDSA
+	Algorithm KeyAlgorithm `json:"algorithm"`
+
+	// rsaKeySize is the size of an RSA key in bits. It defaults to 2048.
+	//
+	// +kubebuilder:validation:Enum=2048;3072;4096
+	// +optional
+	RSAKeySize int32 `json:"rsaKeySize,omitempty"`
+
+	// ecdsaCurve is the curve of an ECDSA key. It defaults to P256.
+	//
+	// +kubebuilder:validation:Enum=P256;P384;P521
+	// +optional
+	ECDSACurve string `json:"ecdsaCurve,omitempty"`
 }
 
-// OperatorPKIStatus is not implemented.
+// OperatorPKIStatus is the state of the CA and of the certificate it signs.
 type OperatorPKIStatus struct {
+	// conditions are the Ready and RotationFailing conditions of the PKI
+	//
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ca is the CA certificate, stored in the <name>-ca Secret
+	//
+	// +optional
+	CA *CertificateStatus `json:"ca,omitempty"`
+
+	// targetCert is the certificate signed by the CA, stored in the <name>-cert
+	// Secret
+	//
+	// +optional
+	TargetCert *CertificateStatus `json:"targetCert,omitempty"`
+
+	// additionalTargetCerts are the additional certificates signed by the CA
+	//
+	// +listType=map
+	// +listMapKey=secretName
+	// +optional
+	AdditionalTargetCerts []CertificateStatus `json:"additionalTargetCerts,omitempty"`
+}
+
+const (
+	// OperatorPKIReady is true when the CA and the target certificates exist, are
+	// valid, and the target certificates are signed by a CA of the <name>-ca bundle
+	OperatorPKIReady = "Ready"
+
+	// OperatorPKIRotationFailing is true when the last attempt to create or rotate
+	// the certificates failed
+	OperatorPKIRotationFailing = "RotationFailing"
+)
+
+// CertificateStatus describes a certificate managed by the PKI.
+type CertificateStatus struct {
+	// secretName is the name of the Secret holding the certificate
+	SecretName string `json:"secretName"`
+
+	// serialNumber is the serial number of the certificate, in hexadecimal
+	SerialNumber string `json:"serialNumber"`
+
+	// notBefore is the start of the validity of the certificate
+	NotBefore metav1.Time `json:"notBefore"`
+
+	// notAfter is the end of the validity of the certificate
+	NotAfter metav1.Time `json:"notAfter"`
+
+	// lastRotationTime is when the operator observed the rotation to the current
+	// certificate, or its notBefore if the certificate predates the status
+	LastRotationTime metav1.Time `json:"lastRotationTime"`
+
+	// nextRotationTime is when the operator plans to rotate the certificate. The
+	// certificate is rotated earlier if its Secret is deleted or modified.
+	NextRotationTime metav1.Time `json:"nextRotationTime"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -89,6 +89,9 @@ type OVNBootstrapResult struct {
 	FlowsConfig               *FlowsConfig
 	DefaultV4MasqueradeSubnet string
 	DefaultV6MasqueradeSubnet string
+	// GatewayModes is the gateway mode of the nodes, under "default", and of each
+	// node that is switched to a different mode while migrating the gateway mode
+	GatewayModes map[string]string
 }
 
 // IPTablesAlerterBootstrapResult contains configuration for the iptables-alerter
@@ -97,12 +100,52 @@ type IPTablesAlerterBootstrapResult struct {
 	Enabled bool
 }
 
+// ResourceRequests are the cpu, in millicores, and memory, in MiB, requests of a container
+type ResourceRequests struct {
+	CPU    int64
+	Memory int64
+}
+
+// SizingBootstrapResult contains the sizing profile of the network control plane
+type SizingBootstrapResult struct {
+	// Profile is the name of the sizing profile in use
+	Profile string
+	// Selected is the name of the profile selected from the object counts, which
+	// the administrator may have overridden
+	Selected string
+	// Overridden is true if the administrator overrode the profile or the requests
+	Overridden bool
+	// Nodes, Pods, Services and NetworkPolicies are the object counts the profile
+	// was selected from
+	Nodes           int64
+	Pods            int64
+	Services        int64
+	NetworkPolicies int64
+	// OVNControlPlane, MultusAdmissionController and NetworkNodeIdentity are the
+	// resource requests of the main containers of each component
+	OVNControlPlane           ResourceRequests
+	MultusAdmissionController ResourceRequests
+	NetworkNodeIdentity       ResourceRequests
+}
+
+// AuxiliaryIPAMBootstrapResult contains the NetworkAttachmentDefinitions, by
+// namespace/name, that were not rendered from spec.additionalNetworks and use an
+// IPAM plugin needing an auxiliary component
+type AuxiliaryIPAMBootstrapResult struct {
+	// DHCP are the NetworkAttachmentDefinitions using the dhcp IPAM plugin
+	DHCP []string
+	// Whereabouts are the NetworkAttachmentDefinitions using the whereabouts IPAM plugin
+	Whereabouts []string
+}
+
 type BootstrapResult struct {
 	Infra InfraStatus
 
 	OVN             OVNBootstrapResult
 	IPTablesAlerter IPTablesAlerterBootstrapResult
 	TLSProfile      TLSProfile
+	Sizing          SizingBootstrapResult
+	AuxiliaryIPAM   AuxiliaryIPAMBootstrapResult
 }
 
 type InfraStatus struct {
@@ -145,6 +188,10 @@ type InfraStatus struct {
 
 	// ConsolePluginCRDExists set to true when the consoleplugins.console.openshift.io has been deployed.
 	ConsolePluginCRDExists bool
+
+	// MachineNetworks contains the CIDRs of the networks the cluster machines are connected to,
+	// gathered from the install-config and the platform status.
+	MachineNetworks []string
 }
 
 // APIServer is the hostname & port of a given APIServer. (This is the
@@ -30,6 +30,7 @@ import (
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
 	op_netopv1 "github.com/openshift/api/networkoperator/v1"
 	operv1 "github.com/openshift/api/operator/v1"
+	operatorcontrolplanev1alpha1 "github.com/openshift/api/operatorcontrolplane/v1alpha1"
 	netopv1 "github.com/openshift/cluster-network-operator/pkg/apis/network/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 
@@ -48,6 +49,7 @@ func init() {
 	utilruntime.Must(op_netopv1.Install(scheme.Scheme))
 	utilruntime.Must(mcfgv1.Install(scheme.Scheme))
 	utilruntime.Must(apiextensionsv1.AddToScheme(scheme.Scheme))
+	utilruntime.Must(operatorcontrolplanev1alpha1.Install(scheme.Scheme))
 }
 
 // OperatorClusterClient is a bag of holding for object clients & informers.
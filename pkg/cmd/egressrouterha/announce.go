@@ -0,0 +1,118 @@
+//go:build linux
+
+package egressrouterha
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ethernetHeaderLen = 14
+	arpLen            = 28
+
+	arpRequest = 1
+	arpReply   = 2
+
+	icmpv6NeighborAdvertisement = 136
+	// naOverride is the override flag of a neighbor advertisement
+	naOverride = 0x20000000
+	// optionTargetLinkLayerAddress is the type of the NDP target link-layer
+	// address option
+	optionTargetLinkLayerAddress = 2
+)
+
+// gratuitousARP returns the ethernet frame of a gratuitous ARP of the given
+// operation for ip: a broadcast ARP whose sender and target are both ip
+func gratuitousARP(op uint16, mac net.HardwareAddr, ip net.IP) []byte {
+	frame := make([]byte, ethernetHeaderLen+arpLen)
+	copy(frame[0:6], ethernetBroadcast)
+	copy(frame[6:12], mac)
+	binary.BigEndian.PutUint16(frame[12:14], unix.ETH_P_ARP)
+
+	arp := frame[ethernetHeaderLen:]
+	binary.BigEndian.PutUint16(arp[0:2], 1) // ethernet
+	binary.BigEndian.PutUint16(arp[2:4], unix.ETH_P_IP)
+	arp[4] = 6 // hardware address length
+	arp[5] = 4 // protocol address length
+	binary.BigEndian.PutUint16(arp[6:8], op)
+	copy(arp[8:14], mac)
+	copy(arp[14:18], ip.To4())
+	if op == arpReply {
+		copy(arp[18:24], ethernetBroadcast)
+	}
+	copy(arp[24:28], ip.To4())
+	return frame
+}
+
+var ethernetBroadcast = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// sendGratuitousARP broadcasts a gratuitous ARP request and reply for ip, as
+// neighbors may only update their caches on one of them
+func sendGratuitousARP(ifindex int, mac net.HardwareAddr, ip net.IP) error {
+	if len(mac) != 6 {
+		return fmt.Errorf("unsupported hardware address %s", mac)
+	}
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	addr := &unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_ARP),
+		Ifindex:  ifindex,
+		Halen:    6,
+	}
+	copy(addr.Addr[:], ethernetBroadcast)
+	for _, op := range []uint16{arpRequest, arpReply} {
+		if err := unix.Sendto(fd, gratuitousARP(op, mac, ip), 0, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unsolicitedNA returns the ICMPv6 message of an unsolicited neighbor
+// advertisement for ip. The checksum is left to the kernel.
+func unsolicitedNA(mac net.HardwareAddr, ip net.IP) []byte {
+	msg := make([]byte, 24+2+len(mac))
+	msg[0] = icmpv6NeighborAdvertisement
+	binary.BigEndian.PutUint32(msg[4:8], naOverride)
+	copy(msg[8:24], ip.To16())
+	msg[24] = optionTargetLinkLayerAddress
+	msg[25] = byte((2 + len(mac) + 7) / 8) // in units of 8 bytes
+	copy(msg[26:], mac)
+	return msg
+}
+
+// sendUnsolicitedNA sends an unsolicited neighbor advertisement for ip to all the
+// nodes of the link
+func sendUnsolicitedNA(ifindex int, mac net.HardwareAddr, ip net.IP) error {
+	if len(mac) != 6 {
+		return fmt.Errorf("unsupported hardware address %s", mac)
+	}
+	fd, err := unix.Socket(unix.AF_INET6, unix.SOCK_RAW, unix.IPPROTO_ICMPV6)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	// neighbor discovery messages are only accepted with a hop limit of 255
+	if err := unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_HOPS, 255); err != nil {
+		return err
+	}
+	if err := unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_IF, ifindex); err != nil {
+		return err
+	}
+	addr := &unix.SockaddrInet6{ZoneId: uint32(ifindex)}
+	copy(addr.Addr[:], net.IPv6linklocalallnodes)
+	return unix.Sendto(fd, unsolicitedNA(mac, ip), 0, addr)
+}
+
+func htons(v uint16) uint16 {
+	return (v << 8) | (v >> 8)
+}
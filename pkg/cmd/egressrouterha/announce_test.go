@@ -0,0 +1,79 @@
+//go:build linux
+
+package egressrouterha
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestGratuitousARP(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	mac, _ := net.ParseMAC("0a:58:c0:a8:0c:63")
+	ip := net.ParseIP("192.168.12.99")
+
+	request := gratuitousARP(arpRequest, mac, ip)
+	g.Expect(request).To(Equal([]byte{
+		// ethernet
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x0a, 0x58, 0xc0, 0xa8, 0x0c, 0x63,
+		0x08, 0x06,
+		// arp
+		0x00, 0x01, 0x08, 0x00, 6, 4, 0x00, 0x01,
+		0x0a, 0x58, 0xc0, 0xa8, 0x0c, 0x63, 192, 168, 12, 99,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 192, 168, 12, 99,
+	}))
+
+	reply := gratuitousARP(arpReply, mac, ip)
+	g.Expect(reply[20:22]).To(Equal([]byte{0x00, 0x02}))
+	g.Expect(reply[32:38]).To(Equal([]byte(ethernetBroadcast)))
+	g.Expect(reply[38:42]).To(Equal([]byte{192, 168, 12, 99}))
+}
+
+func TestUnsolicitedNA(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	mac, _ := net.ParseMAC("0a:58:c0:a8:0c:63")
+	ip := net.ParseIP("fd00:12::99")
+
+	g.Expect(unsolicitedNA(mac, ip)).To(Equal([]byte{
+		136, 0, 0x00, 0x00,
+		0x20, 0x00, 0x00, 0x00,
+		0xfd, 0x00, 0x00, 0x12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x00, 0x99,
+		2, 1, 0x0a, 0x58, 0xc0, 0xa8, 0x0c, 0x63,
+	}))
+}
+
+func TestParseAddresses(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	addresses, err := parseAddresses([]string{"192.168.12.99/24", "fd00:12::99/64"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(addresses[0].String()).To(Equal("192.168.12.99/24"))
+	g.Expect(addresses[1].String()).To(Equal("fd00:12::99/64"))
+
+	_, err = parseAddresses([]string{"192.168.12.99"})
+	g.Expect(err).To(HaveOccurred())
+	_, err = parseAddresses(nil)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestParseGateways(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	gateways, err := parseGateways([]string{"192.168.12.1", "fd00:12::1"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(gateways).To(HaveLen(2))
+
+	gateways, err = parseGateways(nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(gateways).To(BeEmpty())
+
+	_, err = parseGateways([]string{"192.168.12.1", "192.168.12.2"})
+	g.Expect(err).To(HaveOccurred())
+	_, err = parseGateways([]string{"192.168.12"})
+	g.Expect(err).To(HaveOccurred())
+}
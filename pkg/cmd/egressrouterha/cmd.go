@@ -0,0 +1,224 @@
+//go:build linux
+
+// Package egressrouterha implements the sidecar of the egress routers running in
+// high-availability mode. The egress router CNI plugin configures the addresses of
+// the router on every replica; an init container removes them before the replica
+// starts, and the sidecar only configures them on the replica holding the lease
+// of the router, which announces them when it takes over.
+package egressrouterha
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/component-base/logs"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// the lease of the active replica expires after leaseDuration; it gives up
+	// the addresses if it could not renew the lease within renewDeadline, so
+	// before a standby replica can take over
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+
+	// activeFile exists while the replica is the active one. The readiness probe
+	// of the sidecar checks it, so that only the active replica is ready and
+	// Services in front of the router never send traffic to a standby one.
+	activeFile = "/tmp/egress-router-ha-active"
+)
+
+func NewEgressRouterHACommand() *cobra.Command {
+	var (
+		iface       string
+		leaseName   string
+		addresses   []string
+		gateways    []string
+		releaseOnly bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "egress-router-ha",
+		Short: "Holds the addresses of an egress router on the active replica only.",
+		Run: func(cmd *cobra.Command, args []string) {
+			logs.InitLogs()
+			defer logs.FlushLogs()
+
+			r, err := newRouterInterface(iface, addresses, gateways)
+			if err != nil {
+				klog.Fatal(err)
+			}
+			if releaseOnly {
+				if err := r.release(); err != nil {
+					klog.Fatalf("Failed to remove the addresses of the router: %v", err)
+				}
+				return
+			}
+
+			ctx := ctrl.SetupSignalHandler()
+			podName := os.Getenv("POD_NAME")
+			namespace := os.Getenv("POD_NAMESPACE")
+			if err := run(ctx, r, leaseName, podName, namespace); err != nil {
+				klog.Fatal(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&iface, "interface", "net1", "The egress interface of the router.")
+	cmd.Flags().StringVar(&leaseName, "lease-name", "", "The name of the lease held by the active replica.")
+	cmd.Flags().StringSliceVar(&addresses, "address", nil, "The addresses, with their prefix length, of the router.")
+	cmd.Flags().StringSliceVar(&gateways, "gateway", nil, "The gateways of the router, at most one per IP family.")
+	cmd.Flags().BoolVar(&releaseOnly, "release-only", false, "Only remove the addresses of the router from the interface, and exit.")
+
+	return cmd
+}
+
+// run holds the addresses of the router while the replica holds its lease. It
+// returns when ctx is canceled, or with an error if the addresses could not be
+// configured. The addresses are always removed before the lease is released, so
+// that they are never held by two replicas.
+func run(ctx context.Context, r *routerInterface, leaseName, podName, namespace string) error {
+	if leaseName == "" || podName == "" || namespace == "" {
+		return fmt.Errorf("the lease name, POD_NAME and POD_NAMESPACE must be set")
+	}
+
+	// start as a standby replica
+	setActive(false)
+	if err := r.release(); err != nil {
+		return fmt.Errorf("failed to remove the addresses of the router: %w", err)
+	}
+
+	kubeClient := kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie())
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: leaseName},
+		Client:     kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: podName},
+	}
+
+	for {
+		// takeErr is set by OnStartedLeading before canceling the election
+		takeErr := make(chan error, 1)
+		electionCtx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-ctx.Done():
+				// give up the addresses before the lease
+				setActive(false)
+				if err := r.release(); err != nil {
+					klog.Errorf("Failed to remove the addresses of the router: %v", err)
+				}
+				cancel()
+			case <-electionCtx.Done():
+			}
+		}()
+
+		leaderelection.RunOrDie(electionCtx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   leaseDuration,
+			RenewDeadline:   renewDeadline,
+			RetryPeriod:     retryPeriod,
+			ReleaseOnCancel: true,
+			Name:            leaseName,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(context.Context) {
+					klog.Infof("Pod %s is the active replica of the egress router", podName)
+					if err := r.take(); err != nil {
+						// give up the lease, so that another replica takes over
+						takeErr <- fmt.Errorf("failed to configure the addresses of the router: %w", err)
+						if err := r.release(); err != nil {
+							klog.Errorf("Failed to remove the addresses of the router: %v", err)
+						}
+						cancel()
+						return
+					}
+					setActive(true)
+				},
+				OnStoppedLeading: func() {
+					klog.Infof("Pod %s is no longer the active replica of the egress router", podName)
+					setActive(false)
+					if err := r.release(); err != nil {
+						klog.Errorf("Failed to remove the addresses of the router: %v", err)
+					}
+				},
+				OnNewLeader: func(identity string) {
+					if identity != podName {
+						klog.Infof("Pod %s is the active replica of the egress router", identity)
+					}
+				},
+			},
+		})
+		cancel()
+
+		select {
+		case err := <-takeErr:
+			return err
+		default:
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		// the lease could not be renewed: wait to take over again as a standby
+		// replica
+	}
+}
+
+// setActive records whether the replica is the active one, for its readiness
+// probe
+func setActive(active bool) {
+	var err error
+	if active {
+		err = os.WriteFile(activeFile, nil, 0o644)
+	} else if err = os.Remove(activeFile); os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		klog.Errorf("Failed to update %s: %v", activeFile, err)
+	}
+}
+
+// parseAddresses parses the addresses of the router
+func parseAddresses(addresses []string) ([]*net.IPNet, error) {
+	out := []*net.IPNet{}
+	for _, address := range addresses {
+		ip, ipnet, err := net.ParseCIDR(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", address, err)
+		}
+		ipnet.IP = ip
+		out = append(out, ipnet)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("the router has no address")
+	}
+	return out, nil
+}
+
+// parseGateways parses the gateways of the router, at most one per family
+func parseGateways(gateways []string) ([]net.IP, error) {
+	out := []net.IP{}
+	families := map[bool]string{}
+	for _, gateway := range gateways {
+		ip := net.ParseIP(gateway)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid gateway %q", gateway)
+		}
+		ipv6 := utilnet.IsIPv6(ip)
+		if other, ok := families[ipv6]; ok {
+			return nil, fmt.Errorf("gateways %s and %s are of the same IP family", other, gateway)
+		}
+		families[ipv6] = gateway
+		out = append(out, ip)
+	}
+	return out, nil
+}
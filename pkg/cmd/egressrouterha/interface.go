@@ -0,0 +1,114 @@
+//go:build linux
+
+package egressrouterha
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+)
+
+const (
+	// announceCount is how many times the addresses are announced on take over
+	announceCount    = 3
+	announceInterval = 500 * time.Millisecond
+)
+
+// routerInterface is the egress interface of the router, configured by the egress
+// router CNI plugin
+type routerInterface struct {
+	name      string
+	addresses []*net.IPNet
+	// gateways are the default gateways of the router, at most one per family
+	gateways []net.IP
+}
+
+func newRouterInterface(name string, addresses []string, gateways []string) (*routerInterface, error) {
+	r := &routerInterface{name: name}
+	var err error
+	if r.addresses, err = parseAddresses(addresses); err != nil {
+		return nil, err
+	}
+	if r.gateways, err = parseGateways(gateways); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// release removes the addresses of the router from the interface, which also
+// removes the routes through them
+func (r *routerInterface) release() error {
+	link, err := netlink.LinkByName(r.name)
+	if err != nil {
+		return fmt.Errorf("failed to find interface %s: %w", r.name, err)
+	}
+	for _, address := range r.addresses {
+		err := netlink.AddrDel(link, &netlink.Addr{IPNet: address})
+		if err != nil && !errors.Is(err, unix.EADDRNOTAVAIL) {
+			return fmt.Errorf("failed to remove address %s from interface %s: %w", address, r.name, err)
+		}
+	}
+	return nil
+}
+
+// take configures the addresses of the router, and its default routes, on the
+// interface, and announces them so that the neighbors of the router update their
+// caches
+func (r *routerInterface) take() error {
+	link, err := netlink.LinkByName(r.name)
+	if err != nil {
+		return fmt.Errorf("failed to find interface %s: %w", r.name, err)
+	}
+	for _, address := range r.addresses {
+		addr := &netlink.Addr{IPNet: address}
+		if utilnet.IsIPv6(address.IP) {
+			// the address is already in use by the previous active replica, if
+			// it is still around
+			addr.Flags = unix.IFA_F_NODAD
+		}
+		if err := netlink.AddrReplace(link, addr); err != nil {
+			return fmt.Errorf("failed to add address %s to interface %s: %w", address, r.name, err)
+		}
+	}
+	for _, gateway := range r.gateways {
+		route := &netlink.Route{LinkIndex: link.Attrs().Index, Gw: gateway}
+		if utilnet.IsIPv6(gateway) {
+			route.Dst = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+		} else {
+			route.Dst = &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+		}
+		if err := netlink.RouteReplace(route); err != nil {
+			return fmt.Errorf("failed to add the default route through %s: %w", gateway, err)
+		}
+	}
+
+	go r.announce(link)
+	return nil
+}
+
+// announce sends gratuitous ARPs for the IPv4 addresses, and unsolicited neighbor
+// advertisements for the IPv6 ones
+func (r *routerInterface) announce(link netlink.Link) {
+	for i := 0; i < announceCount; i++ {
+		if i > 0 {
+			time.Sleep(announceInterval)
+		}
+		for _, address := range r.addresses {
+			var err error
+			if utilnet.IsIPv6(address.IP) {
+				err = sendUnsolicitedNA(link.Attrs().Index, link.Attrs().HardwareAddr, address.IP)
+			} else {
+				err = sendGratuitousARP(link.Attrs().Index, link.Attrs().HardwareAddr, address.IP)
+			}
+			if err != nil {
+				klog.Warningf("Failed to announce address %s on interface %s: %v", address.IP, r.name, err)
+			}
+		}
+	}
+}
@@ -2,17 +2,22 @@ package controller
 
 import (
 	"github.com/openshift/cluster-network-operator/pkg/controller/allowlist"
+	"github.com/openshift/cluster-network-operator/pkg/controller/certinventory"
 	"github.com/openshift/cluster-network-operator/pkg/controller/clusterconfig"
 	configmapcainjector "github.com/openshift/cluster-network-operator/pkg/controller/configmap_ca_injector"
 	"github.com/openshift/cluster-network-operator/pkg/controller/dashboards"
 	"github.com/openshift/cluster-network-operator/pkg/controller/egress_router"
+	"github.com/openshift/cluster-network-operator/pkg/controller/gatewaymode"
 	"github.com/openshift/cluster-network-operator/pkg/controller/infrastructureconfig"
 	"github.com/openshift/cluster-network-operator/pkg/controller/ingressconfig"
+	"github.com/openshift/cluster-network-operator/pkg/controller/multinetworkpolicy"
+	"github.com/openshift/cluster-network-operator/pkg/controller/nodesubnet"
 	"github.com/openshift/cluster-network-operator/pkg/controller/observability"
 	"github.com/openshift/cluster-network-operator/pkg/controller/operconfig"
 	pkictrl "github.com/openshift/cluster-network-operator/pkg/controller/pki"
 	"github.com/openshift/cluster-network-operator/pkg/controller/proxyconfig"
 	signer "github.com/openshift/cluster-network-operator/pkg/controller/signer"
+	"github.com/openshift/cluster-network-operator/pkg/controller/whereabouts"
 )
 
 func init() {
@@ -29,5 +34,10 @@ func init() {
 		dashboards.Add,
 		pkictrl.Add,
 		observability.Add,
+		nodesubnet.Add,
+		gatewaymode.Add,
+		whereabouts.Add,
+		multinetworkpolicy.Add,
+		certinventory.Add,
 	)
 }
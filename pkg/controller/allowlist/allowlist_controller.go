@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	v1coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
@@ -27,6 +29,7 @@ import (
 
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -58,7 +61,7 @@ func Add(mgr manager.Manager, status *statusmanager.StatusManager, client cnocli
 
 	r.client.Default().AddCustomInformer(cmInformer) // Tell the ClusterClient about this informer
 
-	return c.Watch(&source.Informer{
+	err = c.Watch(&source.Informer{
 		Informer: cmInformer,
 		Handler:  &handler.EnqueueRequestForObject{},
 		Predicates: []predicate.TypedPredicate[crclient.Object]{
@@ -71,6 +74,43 @@ func Add(mgr manager.Manager, status *statusmanager.StatusManager, client cnocli
 			}),
 		},
 	})
+	if err != nil {
+		return err
+	}
+
+	// Copy the allowlist to the nodes that join the cluster or did not get it once
+	// they are ready again, and stop reporting the nodes that are removed
+	nodePredicate := predicate.Funcs{
+		CreateFunc: func(_ event.CreateEvent) bool {
+			return true
+		},
+		UpdateFunc: func(ev event.UpdateEvent) bool {
+			old, ok := ev.ObjectOld.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			new, ok := ev.ObjectNew.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			return !nodeReady(old) && nodeReady(new)
+		},
+		DeleteFunc: func(_ event.DeleteEvent) bool {
+			return true
+		},
+		GenericFunc: func(_ event.GenericEvent) bool {
+			return false
+		},
+	}
+	return c.Watch(source.Kind[crclient.Object](mgr.GetCache(), &corev1.Node{}, handler.EnqueueRequestsFromMapFunc(reconcileAllowlist), nodePredicate))
+}
+
+// reconcileAllowlist maps node events to the allowlist ConfigMap
+func reconcileAllowlist(_ context.Context, _ crclient.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: names.MultusNamespace,
+		Name:      names.AllowlistConfigName,
+	}}}
 }
 
 var _ reconcile.Reconciler = &ReconcileAllowlist{}
@@ -88,7 +128,7 @@ func (r *ReconcileAllowlist) Reconcile(ctx context.Context, request reconcile.Re
 		return reconcile.Result{}, err
 	}
 	if !exists {
-		err = createObjectsFrom(ctx, r.client, defaultCMManifest)
+		err = createObjectsFrom(ctx, r.client, defaultCMManifest, "")
 		if err != nil {
 			klog.Errorf("Failed to create allowlist config map: %v", err)
 			return reconcile.Result{}, err
@@ -115,6 +155,28 @@ func (r *ReconcileAllowlist) Reconcile(ctx context.Context, request reconcile.Re
 		return reconcile.Result{}, nil
 	}
 
+	// The tuning plugin fails every pod setting a sysctl if an entry is not a valid
+	// regular expression, so keep the allowlist the nodes have until it is fixed
+	if err := validateAllowlist(configMap); err != nil {
+		klog.Errorf("Invalid sysctl allowlist, not copying it to the nodes: %v", err)
+		r.status.SetDegraded(statusmanager.AllowlistConfig, "InvalidSysctlAllowlist",
+			fmt.Sprintf("The %s/%s ConfigMap is invalid and was not copied to the nodes: %v", configMap.Namespace, configMap.Name, err))
+		return reconcile.Result{}, nil
+	}
+	r.status.SetNotDegraded(statusmanager.AllowlistConfig)
+
+	hash := allowlistHash(configMap)
+	nodes := &corev1.NodeList{}
+	if err := r.client.Default().CRClient().List(ctx, nodes); err != nil {
+		klog.Errorf("Failed to list nodes: %v", err)
+		return reconcile.Result{}, err
+	}
+	outdated := outdatedNodes(nodes.Items, hash)
+	if len(outdated) == 0 {
+		r.status.SetCondition(allowlistCondition(nil))
+		return reconcile.Result{}, nil
+	}
+
 	defer cleanupDaemonSet(ctx, r.client)
 
 	// If daemonset still exists, delete it and reconcile again
@@ -128,7 +190,7 @@ func (r *ReconcileAllowlist) Reconcile(ctx context.Context, request reconcile.Re
 		return reconcile.Result{}, fmt.Errorf("retrying")
 	}
 
-	err = createObjectsFrom(ctx, r.client, dsManifestDir)
+	err = createObjectsFrom(ctx, r.client, dsManifestDir, hash)
 	if err != nil {
 		klog.Errorf("Failed to create allowlist daemonset: %v", err)
 		return reconcile.Result{}, err
@@ -139,21 +201,45 @@ func (r *ReconcileAllowlist) Reconcile(ctx context.Context, request reconcile.Re
 	// This also prevents unwanted retries when one or more pods are not ready due to
 	// issues with the cluster.
 	// https://issues.redhat.com/browse/OCPBUGS-15818
-	err = checkDsPodsReady(ctx, r.client)
+	// Instead, the nodes that did not get the allowlist are reported, and updated
+	// once they are ready again.
+	updated, err := checkDsPodsReady(ctx, r.client, hash)
 	if err != nil {
 		klog.Errorf("Failed to verify ready status on allowlist daemonset pods: %v", err)
-		return reconcile.Result{}, nil
 	}
+	for _, node := range outdated {
+		if !updated.Has(node) {
+			continue
+		}
+		if err := setNodeAllowlistHash(ctx, r.client, node, hash); err != nil {
+			klog.Errorf("Failed to record the sysctl allowlist of node %s: %v", node, err)
+			updated.Delete(node)
+		}
+	}
+	outdated = slices.DeleteFunc(outdated, updated.Has)
+	r.status.SetCondition(allowlistCondition(outdated))
 
+	if len(outdated) > 0 {
+		klog.Warningf("Sysctl allowlist not updated on nodes %v", outdated)
+		return reconcile.Result{}, nil
+	}
 	klog.Infoln("Successfully updated sysctl allowlist")
 	return reconcile.Result{}, nil
 }
 
-func createObjectsFrom(ctx context.Context, client cnoclient.Client, manifestPath string) error {
+// setNodeAllowlistHash records on the node the hash of the allowlist copied to it
+func setNodeAllowlistHash(ctx context.Context, client cnoclient.Client, node, hash string) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, names.SysctlAllowlistHashAnnotation, hash)
+	_, err := client.Default().Kubernetes().CoreV1().Nodes().Patch(ctx, node, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+func createObjectsFrom(ctx context.Context, client cnoclient.Client, manifestPath, hash string) error {
 	data := render.MakeRenderData()
 	data.Data["MultusImage"] = os.Getenv("MULTUS_IMAGE")
 	data.Data["CniSysctlAllowlist"] = names.AllowlistConfigName
 	data.Data["ReleaseVersion"] = os.Getenv("RELEASE_VERSION")
+	data.Data["AllowlistHash"] = hash
 	manifests, err := render.RenderDir(manifestPath, &data)
 	if err != nil {
 		return err
@@ -178,8 +264,11 @@ func getConfigMap(ctx context.Context, client cnoclient.Client, namespacedName t
 	return configMap, nil
 }
 
-func checkDsPodsReady(ctx context.Context, client cnoclient.Client) error {
-	return wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, false, func(ctx context.Context) (done bool, err error) {
+// checkDsPodsReady waits for the pods of the daemon set, copying the allowlist with
+// the given hash, to be ready and returns the nodes they are ready on
+func checkDsPodsReady(ctx context.Context, client cnoclient.Client, hash string) (sets.Set[string], error) {
+	ready := sets.New[string]()
+	err := wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, false, func(ctx context.Context) (done bool, err error) {
 		ds, err := getDaemonSet(ctx, client)
 		if err != nil {
 			return false, err
@@ -194,22 +283,26 @@ func checkDsPodsReady(ctx context.Context, client cnoclient.Client) error {
 			return false, err
 		}
 
-		if len(podList.Items) == 0 {
-			return false, nil
-		}
-
+		ready = sets.New[string]()
 		for _, pod := range podList.Items {
 			// Ignore pods that are not owned by current daemon set.
 			if len(pod.GetOwnerReferences()) == 0 || pod.GetOwnerReferences()[0].UID != ds.GetUID() {
 				continue
 			}
+			if pod.Annotations[names.SysctlAllowlistHashAnnotation] != hash {
+				continue
+			}
 
-			if len(pod.Status.ContainerStatuses) == 0 || !pod.Status.ContainerStatuses[0].Ready {
-				return false, nil
+			if len(pod.Status.ContainerStatuses) > 0 && pod.Status.ContainerStatuses[0].Ready {
+				ready.Insert(pod.Spec.NodeName)
 			}
 		}
-		return true, nil
+		return ds.Status.DesiredNumberScheduled > 0 && int32(ready.Len()) >= ds.Status.DesiredNumberScheduled, nil
 	})
+	if err != nil && !wait.Interrupted(err) {
+		return ready, err
+	}
+	return ready, nil
 }
 
 func cleanupDaemonSet(ctx context.Context, client cnoclient.Client) {
@@ -0,0 +1,102 @@
+package allowlist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+)
+
+// allowlistKey is the key of the allowlist in the cni-sysctl-allowlist ConfigMap
+const allowlistKey = "allowlist.conf"
+
+// maxReportedNodes is the number of out of date nodes named in the condition
+const maxReportedNodes = 10
+
+// validateAllowlist checks that every entry of the allowlist is a valid regular
+// expression, as the tuning CNI plugin matches sysctls against them
+func validateAllowlist(configMap *corev1.ConfigMap) error {
+	allowlist, ok := configMap.Data[allowlistKey]
+	if !ok {
+		return fmt.Errorf("%s/%s has no %s key", configMap.Namespace, configMap.Name, allowlistKey)
+	}
+
+	errs := []error{}
+	for i, line := range strings.Split(allowlist, "\n") {
+		entry := strings.TrimSpace(line)
+		if entry == "" {
+			continue
+		}
+		if _, err := regexp.Compile(entry); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: invalid entry %q: %w", i+1, entry, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// allowlistHash returns the hash of the allowlist content, recorded on the nodes
+// it was copied to
+func allowlistHash(configMap *corev1.ConfigMap) string {
+	sum := sha256.Sum256([]byte(configMap.Data[allowlistKey]))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// outdatedNodes returns the names of the Linux nodes that did not get the allowlist
+// with the given hash
+func outdatedNodes(nodes []corev1.Node, hash string) []string {
+	outdated := []string{}
+	for _, node := range nodes {
+		if os, ok := node.Labels[corev1.LabelOSStable]; ok && os != "linux" {
+			continue
+		}
+		if node.Annotations[names.SysctlAllowlistHashAnnotation] != hash {
+			outdated = append(outdated, node.Name)
+		}
+	}
+	sort.Strings(outdated)
+	return outdated
+}
+
+// nodeReady returns whether the node has a true Ready condition
+func nodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// allowlistCondition reports the nodes that did not get the current allowlist
+func allowlistCondition(outdated []string) operv1.OperatorCondition {
+	if len(outdated) == 0 {
+		return operv1.OperatorCondition{
+			Type:    names.SysctlAllowlistNodesOutOfDateCondition,
+			Status:  operv1.ConditionFalse,
+			Reason:  "AllNodesUpToDate",
+			Message: "All nodes have the current CNI sysctl allowlist",
+		}
+	}
+
+	reported := outdated
+	more := ""
+	if len(reported) > maxReportedNodes {
+		reported = reported[:maxReportedNodes]
+		more = fmt.Sprintf(" and %d more", len(outdated)-maxReportedNodes)
+	}
+	return operv1.OperatorCondition{
+		Type:   names.SysctlAllowlistNodesOutOfDateCondition,
+		Status: operv1.ConditionTrue,
+		Reason: "NodesOutOfDate",
+		Message: fmt.Sprintf("%d node(s) did not get the current CNI sysctl allowlist, they will be updated when they are ready: %s%s",
+			len(outdated), strings.Join(reported, ", "), more),
+	}
+}
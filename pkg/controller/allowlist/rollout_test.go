@@ -0,0 +1,95 @@
+package allowlist
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+)
+
+func allowlistConfigMap(allowlist string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: names.MultusNamespace, Name: names.AllowlistConfigName},
+		Data:       map[string]string{allowlistKey: allowlist},
+	}
+}
+
+func TestValidateAllowlist(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(validateAllowlist(allowlistConfigMap("^net.ipv4.conf.IFNAME.accept_redirects$\n\n  ^net.ipv6.neigh.IFNAME.retrans_time_ms$  \n"))).To(Succeed())
+
+	err := validateAllowlist(allowlistConfigMap("^net.ipv4.conf.IFNAME.arp_accept$\n^net.ipv4.conf.(IFNAME.arp_notify$\n^net.ipv6.conf.IFNAME.[accept_ra$"))
+	g.Expect(err).To(MatchError(ContainSubstring(`line 2: invalid entry "^net.ipv4.conf.(IFNAME.arp_notify$"`)))
+	g.Expect(err).To(MatchError(ContainSubstring(`line 3: invalid entry "^net.ipv6.conf.IFNAME.[accept_ra$"`)))
+
+	err = validateAllowlist(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: names.MultusNamespace, Name: names.AllowlistConfigName}})
+	g.Expect(err).To(MatchError("openshift-multus/cni-sysctl-allowlist has no allowlist.conf key"))
+}
+
+func TestOutdatedNodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	hash := allowlistHash(allowlistConfigMap("^net.ipv4.conf.IFNAME.arp_accept$"))
+	g.Expect(hash).NotTo(Equal(allowlistHash(allowlistConfigMap("^net.ipv4.conf.IFNAME.arp_notify$"))))
+
+	node := func(name, os, nodeHash string) corev1.Node {
+		n := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}, Annotations: map[string]string{}}}
+		if os != "" {
+			n.Labels[corev1.LabelOSStable] = os
+		}
+		if nodeHash != "" {
+			n.Annotations[names.SysctlAllowlistHashAnnotation] = nodeHash
+		}
+		return n
+	}
+	nodes := []corev1.Node{
+		node("worker-2", "linux", "0123456789abcdef"),
+		node("worker-1", "linux", ""),
+		node("worker-0", "linux", hash),
+		node("windows-0", "windows", ""),
+		node("master-0", "", ""),
+	}
+	g.Expect(outdatedNodes(nodes, hash)).To(Equal([]string{"master-0", "worker-1", "worker-2"}))
+}
+
+func TestAllowlistCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(allowlistCondition(nil)).To(Equal(operv1.OperatorCondition{
+		Type:    names.SysctlAllowlistNodesOutOfDateCondition,
+		Status:  operv1.ConditionFalse,
+		Reason:  "AllNodesUpToDate",
+		Message: "All nodes have the current CNI sysctl allowlist",
+	}))
+
+	cond := allowlistCondition([]string{"worker-0", "worker-1"})
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal("NodesOutOfDate"))
+	g.Expect(cond.Message).To(HaveSuffix(": worker-0, worker-1"))
+
+	outdated := []string{}
+	for _, n := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+		outdated = append(outdated, "worker-"+n)
+	}
+	g.Expect(allowlistCondition(outdated).Message).To(HavePrefix("12 node(s)"))
+	g.Expect(allowlistCondition(outdated).Message).To(HaveSuffix("worker-j and 2 more"))
+}
+
+func TestNodeReady(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	node := &corev1.Node{}
+	g.Expect(nodeReady(node)).To(BeFalse())
+	node.Status.Conditions = []corev1.NodeCondition{
+		{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+	}
+	g.Expect(nodeReady(node)).To(BeTrue())
+	node.Status.Conditions[1].Status = corev1.ConditionUnknown
+	g.Expect(nodeReady(node)).To(BeFalse())
+}
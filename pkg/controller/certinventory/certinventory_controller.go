@@ -0,0 +1,347 @@
+// Package certinventory implements a controller that keeps an inventory of the
+// certificates managed by the network operator, and exports when they expire as
+// metrics, so that certificates that are not rotated in time raise alerts.
+package certinventory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	netopv1 "github.com/openshift/cluster-network-operator/pkg/apis/network/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+	"github.com/openshift/cluster-network-operator/pkg/util"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+
+	csrv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	v1coreinformers "k8s.io/client-go/informers/core/v1"
+	v1corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// resyncPeriod is how often the inventory is rebuilt when nothing changes, to
+// pick up the Secrets of the PKIs outside of the watched namespaces
+const resyncPeriod = time.Hour
+
+// pkiNamespaces are the namespaces of the OperatorPKIs of the network operator,
+// whose Secrets are watched
+var pkiNamespaces = []string{util.OVN_NAMESPACE, network.NetworkNodeIdentityNamespace}
+
+// Add attaches our control loop to the manager and watches for the objects
+// holding certificates
+func Add(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client, _ featuregates.FeatureGate) error {
+	r := newReconciler(mgr, status, c)
+	ctrl, err := controller.New("certificate-inventory-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	enqueue := handler.EnqueueRequestsFromMapFunc(reconcileInventory)
+	for _, inf := range r.informers {
+		if err := ctrl.Watch(&source.Informer{Informer: inf, Handler: enqueue}); err != nil {
+			return err
+		}
+	}
+
+	// The PKI controller publishes the Secrets of the certificates in the status
+	err = ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &netopv1.OperatorPKI{}, enqueue))
+	if err != nil {
+		return err
+	}
+	err = ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &configv1.Proxy{}, enqueue, predicate.GenerationChangedPredicate{}))
+	if err != nil {
+		return err
+	}
+	err = ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &csrv1.CertificateSigningRequest{}, enqueue))
+	if err != nil {
+		return err
+	}
+
+	// Only deleted nodes matter, to forget their certificates
+	nodePredicate := predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool { return false },
+		UpdateFunc: func(event.UpdateEvent) bool { return false },
+	}
+	return ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &corev1.Node{}, enqueue, nodePredicate))
+}
+
+func newReconciler(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client) *ReconcileCertInventory {
+	r := &ReconcileCertInventory{
+		client:           c,
+		cache:            mgr.GetClient(),
+		status:           status,
+		secretListers:    map[string]v1corelisters.SecretLister{},
+		configMapListers: map[string]v1corelisters.ConfigMapLister{},
+	}
+
+	for _, ns := range pkiNamespaces {
+		inf := v1coreinformers.NewSecretInformer(
+			c.Default().Kubernetes(),
+			ns,
+			0, // no resync
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		r.secretListers[ns] = v1corelisters.NewSecretLister(inf.GetIndexer())
+		r.informers = append(r.informers, inf)
+	}
+	// The CA bundle of the node identity webhook, and the trusted CA of the proxy
+	for _, ns := range []string{network.NetworkNodeIdentityNamespace, names.ADDL_TRUST_BUNDLE_CONFIGMAP_NS} {
+		inf := v1coreinformers.NewConfigMapInformer(
+			c.Default().Kubernetes(),
+			ns,
+			0, // no resync
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		r.configMapListers[ns] = v1corelisters.NewConfigMapLister(inf.GetIndexer())
+		r.informers = append(r.informers, inf)
+	}
+
+	for _, inf := range r.informers {
+		c.Default().AddCustomInformer(inf) // Tell the ClusterClient about this informer
+	}
+	return r
+}
+
+// reconcileInventory maps every event to a single request, since the inventory
+// is always built as a whole
+func reconcileInventory(_ context.Context, _ crclient.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name: names.OPERATOR_CONFIG,
+	}}}
+}
+
+var _ reconcile.Reconciler = &ReconcileCertInventory{}
+
+// ReconcileCertInventory exports the validity of the certificates of the
+// OperatorPKIs, of the node identity webhook CA, of the trusted CA of the proxy
+// and of the certificates issued to the nodes for IPsec and for their identity.
+type ReconcileCertInventory struct {
+	client cnoclient.Client
+	// cache reads the OperatorPKIs, CertificateSigningRequests, Proxy and Nodes
+	// from the cache of the manager
+	cache  crclient.Reader
+	status *statusmanager.StatusManager
+
+	informers        []cache.SharedIndexInformer
+	secretListers    map[string]v1corelisters.SecretLister
+	configMapListers map[string]v1corelisters.ConfigMapLister
+
+	// nodeCerts is only used by Reconcile, which never runs concurrently. It is
+	// nil until it is loaded from the certificate inventory ConfigMap.
+	nodeCerts nodeCertificates
+}
+
+// Reconcile rebuilds the inventory of the certificates and exports it as
+// metrics. Certificates that cannot be parsed are logged and left out.
+func (r *ReconcileCertInventory) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
+
+	certs := []certificate{}
+
+	pkis := &netopv1.OperatorPKIList{}
+	if err := r.cache.List(ctx, pkis); err != nil {
+		klog.Errorf("Failed to list OperatorPKIs: %v", err)
+		return reconcile.Result{}, err
+	}
+	for i := range pkis.Items {
+		obj := &pkis.Items[i]
+		owner := "OperatorPKI/" + obj.Name
+		caName, targetNames := pkiSecretNames(obj)
+		for _, name := range append([]string{caName}, targetNames...) {
+			if name == "" {
+				continue
+			}
+			purpose := purposePKICert
+			if name == caName {
+				purpose = purposePKICA
+			}
+			secret, err := r.getSecret(ctx, obj.Namespace, name)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			cert, err := secretCertificate(purpose, owner, secret)
+			if err != nil {
+				klog.Warningf("Certificate inventory: %v", err)
+				continue
+			}
+			if cert != nil {
+				certs = append(certs, *cert)
+			}
+		}
+	}
+
+	cm, err := r.getConfigMap(network.NetworkNodeIdentityNamespace, nodeIdentityCAConfigMap)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	webhookCAs, err := bundleCertificates(purposeNodeIdentityCA, nodeIdentityWebhookOwner, cm)
+	if err != nil {
+		klog.Warningf("Certificate inventory: %v", err)
+	}
+	certs = append(certs, latestCertificate(webhookCAs)...)
+
+	proxy := &configv1.Proxy{}
+	if err := r.cache.Get(ctx, names.Proxy(), proxy); err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("Failed to get proxy configuration: %v", err)
+		return reconcile.Result{}, err
+	}
+	if proxy.Spec.TrustedCA.Name != "" {
+		cm, err := r.getConfigMap(names.ADDL_TRUST_BUNDLE_CONFIGMAP_NS, proxy.Spec.TrustedCA.Name)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		// every CA of the bundle is trusted on its own
+		proxyCAs, err := bundleCertificates(purposeProxyTrustedCA, "Proxy/"+names.PROXY_CONFIG, cm)
+		if err != nil {
+			klog.Warningf("Certificate inventory: %v", err)
+		}
+		certs = append(certs, proxyCAs...)
+	}
+
+	csrs := &csrv1.CertificateSigningRequestList{}
+	if err := r.cache.List(ctx, csrs); err != nil {
+		klog.Errorf("Failed to list CertificateSigningRequests: %v", err)
+		return reconcile.Result{}, err
+	}
+	issued := []certificate{}
+	for i := range csrs.Items {
+		cert, ok, err := csrCertificate(&csrs.Items[i])
+		if err != nil {
+			klog.Warningf("Certificate inventory: %v", err)
+			continue
+		}
+		if ok {
+			issued = append(issued, cert)
+		}
+	}
+	nodes := &corev1.NodeList{}
+	if err := r.cache.List(ctx, nodes); err != nil {
+		klog.Errorf("Failed to list nodes: %v", err)
+		return reconcile.Result{}, err
+	}
+	nodeNames := sets.New[string]()
+	for _, node := range nodes.Items {
+		nodeNames.Insert(node.Name)
+	}
+	if r.nodeCerts == nil {
+		if r.nodeCerts, err = r.loadNodeCertificates(ctx); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	if r.nodeCerts.update(issued, nodeNames) {
+		if err := r.saveNodeCertificates(ctx); err != nil {
+			klog.Errorf("Failed to save node certificates: %v", err)
+			return reconcile.Result{}, err
+		}
+	}
+	certs = append(certs, r.nodeCerts.list()...)
+
+	updateMetrics(certs)
+
+	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// getSecret returns a Secret from the informers, or from the API server outside
+// of the watched namespaces. It returns nil if the Secret does not exist.
+func (r *ReconcileCertInventory) getSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	var secret *corev1.Secret
+	var err error
+	if lister, ok := r.secretListers[namespace]; ok {
+		secret, err = lister.Secrets(namespace).Get(name)
+	} else {
+		secret = &corev1.Secret{}
+		err = r.client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret)
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		klog.Errorf("Failed to get Secret %s/%s: %v", namespace, name, err)
+		return nil, err
+	}
+	return secret, nil
+}
+
+// getConfigMap returns a ConfigMap from the informers, or nil if it does not
+// exist.
+func (r *ReconcileCertInventory) getConfigMap(namespace, name string) (*corev1.ConfigMap, error) {
+	cm, err := r.configMapListers[namespace].ConfigMaps(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		klog.Errorf("Failed to get ConfigMap %s/%s: %v", namespace, name, err)
+		return nil, err
+	}
+	return cm, nil
+}
+
+// loadNodeCertificates returns the node certificates stored in the certificate
+// inventory ConfigMap. Certificates that cannot be read are reported again when
+// they are renewed.
+func (r *ReconcileCertInventory) loadNodeCertificates(ctx context.Context) (nodeCertificates, error) {
+	cm := &corev1.ConfigMap{}
+	err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: names.CertificateInventoryConfigMap}, cm)
+	if apierrors.IsNotFound(err) {
+		return nodeCertificates{}, nil
+	} else if err != nil {
+		klog.Errorf("Failed to get ConfigMap %s/%s: %v", names.APPLIED_NAMESPACE, names.CertificateInventoryConfigMap, err)
+		return nil, err
+	}
+	n, err := unmarshalNodeCertificates(cm.Data[nodeCertificatesKey])
+	if err != nil {
+		klog.Warningf("Certificate inventory: ignoring the node certificates of ConfigMap %s/%s: %v", names.APPLIED_NAMESPACE, names.CertificateInventoryConfigMap, err)
+		return nodeCertificates{}, nil
+	}
+	return n, nil
+}
+
+// saveNodeCertificates stores the node certificates in the certificate
+// inventory ConfigMap
+func (r *ReconcileCertInventory) saveNodeCertificates(ctx context.Context) error {
+	data, err := r.nodeCerts.marshal()
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = r.client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: names.CertificateInventoryConfigMap}, cm)
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: names.APPLIED_NAMESPACE,
+				Name:      names.CertificateInventoryConfigMap,
+			},
+			Data: map[string]string{nodeCertificatesKey: data},
+		}
+		if err := r.client.Default().CRClient().Create(ctx, cm); err != nil {
+			return fmt.Errorf("failed to create the certificate inventory: %w", err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get the certificate inventory: %w", err)
+	}
+
+	cm.Data = map[string]string{nodeCertificatesKey: data}
+	if err := r.client.Default().CRClient().Update(ctx, cm); err != nil {
+		return fmt.Errorf("failed to update the certificate inventory: %w", err)
+	}
+	return nil
+}
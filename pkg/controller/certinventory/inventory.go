@@ -0,0 +1,257 @@
+package certinventory
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	netopv1 "github.com/openshift/cluster-network-operator/pkg/apis/network/v1"
+	"github.com/openshift/cluster-network-operator/pkg/util/validation"
+
+	csrv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// The purposes of the certificates of the inventory
+const (
+	purposePKICA           = "operator-pki-ca"
+	purposePKICert         = "operator-pki-cert"
+	purposeNodeIdentityCA  = "node-identity-webhook-ca"
+	purposeProxyTrustedCA  = "proxy-trusted-ca"
+	purposeIPsec           = "ipsec"
+	purposeOVNNodeIdentity = "ovn-node-identity"
+)
+
+const (
+	caBundleKey = "ca-bundle.crt"
+
+	// nodeCertificatesKey is the key of the certificate inventory ConfigMap
+	// holding the node certificates
+	nodeCertificatesKey = "node-certificates.json"
+
+	// networkSignerName is the signer of the IPsec certificates
+	networkSignerName = "network.openshift.io/signer"
+	// ovnNodeUserPrefix is the prefix of the user names of the ovnkube-node
+	// pods, followed by the name of their node
+	ovnNodeUserPrefix = "system:ovn-node:"
+
+	// The CA bundle of the network-node-identity webhook
+	nodeIdentityCAConfigMap  = "network-node-identity-ca"
+	nodeIdentityWebhookOwner = "ValidatingWebhookConfiguration/network-node-identity.openshift.io"
+)
+
+// certificate is a certificate of the inventory
+type certificate struct {
+	// purpose is what the certificate is used for
+	purpose string
+	// owner is the object the certificate is issued for, as Kind/name
+	owner string
+	// resource is the object holding the certificate, as Kind/namespace/name
+	resource  string
+	subject   string
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+func newCertificate(purpose, owner, resource string, cert *x509.Certificate) certificate {
+	return certificate{
+		purpose:   purpose,
+		owner:     owner,
+		resource:  resource,
+		subject:   cert.Subject.CommonName,
+		notBefore: cert.NotBefore,
+		notAfter:  cert.NotAfter,
+	}
+}
+
+// pkiSecretNames returns the Secrets of the CA and of the target certificates
+// of an OperatorPKI, as the PKI controller publishes them in its status. They
+// are missing until the PKI controller has created the certificates.
+func pkiSecretNames(obj *netopv1.OperatorPKI) (string, []string) {
+	ca := ""
+	if obj.Status.CA != nil {
+		ca = obj.Status.CA.SecretName
+	}
+	targets := []string{}
+	if obj.Status.TargetCert != nil {
+		targets = append(targets, obj.Status.TargetCert.SecretName)
+	}
+	for _, cert := range obj.Status.AdditionalTargetCerts {
+		targets = append(targets, cert.SecretName)
+	}
+	return ca, targets
+}
+
+// secretCertificate returns the first certificate of the tls.crt key of secret,
+// or nil if the secret does not exist.
+func secretCertificate(purpose, owner string, secret *corev1.Secret) (*certificate, error) {
+	if secret == nil {
+		return nil, nil
+	}
+	certs, err := certutil.ParseCertsPEM(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the certificate of Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+	cert := newCertificate(purpose, owner, fmt.Sprintf("Secret/%s/%s", secret.Namespace, secret.Name), certs[0])
+	return &cert, nil
+}
+
+// bundleCertificates returns the certificates of the ca-bundle.crt key of cm,
+// or nil if the ConfigMap does not exist.
+func bundleCertificates(purpose, owner string, cm *corev1.ConfigMap) ([]certificate, error) {
+	if cm == nil {
+		return nil, nil
+	}
+	certs, _, err := validation.TrustBundleConfigMap(cm, caBundleKey)
+	if err != nil {
+		return nil, err
+	}
+	resource := fmt.Sprintf("ConfigMap/%s/%s", cm.Namespace, cm.Name)
+	out := make([]certificate, 0, len(certs))
+	for _, cert := range certs {
+		out = append(out, newCertificate(purpose, owner, resource, cert))
+	}
+	return out, nil
+}
+
+// latestCertificate returns the certificate of certs that expires last. A CA
+// bundle keeps the previous CAs until they expire, so only the current CA, the
+// last one to expire, tells when the bundle must be renewed.
+func latestCertificate(certs []certificate) []certificate {
+	if len(certs) == 0 {
+		return nil
+	}
+	latest := certs[0]
+	for _, cert := range certs[1:] {
+		if cert.notAfter.After(latest.notAfter) {
+			latest = cert
+		}
+	}
+	return []certificate{latest}
+}
+
+// csrCertificate returns the certificate issued to a node by csr. ok is false
+// if csr is not for a node certificate that the network operator cares about, or
+// the certificate was not issued yet.
+func csrCertificate(csr *csrv1.CertificateSigningRequest) (cert certificate, ok bool, err error) {
+	if len(csr.Status.Certificate) == 0 {
+		return certificate{}, false, nil
+	}
+
+	var purpose, nodeName string
+	switch csr.Spec.SignerName {
+	case networkSignerName:
+		purpose = purposeIPsec
+		nodeName, ok = strings.CutPrefix(csr.Spec.Username, ovnNodeUserPrefix)
+	case csrv1.KubeAPIServerClientSignerName:
+		purpose = purposeOVNNodeIdentity
+	default:
+		return certificate{}, false, nil
+	}
+
+	certs, err := certutil.ParseCertsPEM(csr.Status.Certificate)
+	if err != nil {
+		return certificate{}, false, fmt.Errorf("could not parse the certificate of CertificateSigningRequest %s: %w", csr.Name, err)
+	}
+	if purpose == purposeOVNNodeIdentity {
+		// the ovnkube-node pods authenticate as their node
+		nodeName, ok = strings.CutPrefix(certs[0].Subject.CommonName, ovnNodeUserPrefix)
+	}
+	if !ok || nodeName == "" {
+		return certificate{}, false, nil
+	}
+
+	return newCertificate(purpose, "Node/"+nodeName, "CertificateSigningRequest/"+csr.Name, certs[0]), true, nil
+}
+
+// nodeCertificates remembers the latest certificate of each purpose issued to
+// each node. Issued CertificateSigningRequests are garbage collected after an
+// hour, while their certificates are used much longer, so the certificates are
+// kept until their node is deleted, and stored in a ConfigMap to survive the
+// restarts of the operator.
+type nodeCertificates map[string]certificate
+
+// update records certs, issued to nodes, and forgets the certificates of the
+// nodes that no longer exist. It returns whether the certificates changed.
+func (n nodeCertificates) update(certs []certificate, nodes sets.Set[string]) bool {
+	changed := false
+	for _, cert := range certs {
+		key := cert.purpose + " " + cert.owner
+		if previous, ok := n[key]; !ok || cert.notAfter.After(previous.notAfter) {
+			n[key] = cert
+			changed = true
+		}
+	}
+	for key, cert := range n {
+		if !nodes.Has(strings.TrimPrefix(cert.owner, "Node/")) {
+			delete(n, key)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// list returns the remembered certificates
+func (n nodeCertificates) list() []certificate {
+	certs := make([]certificate, 0, len(n))
+	for _, cert := range n {
+		certs = append(certs, cert)
+	}
+	return certs
+}
+
+// storedCertificate is a node certificate, as stored in the certificate
+// inventory ConfigMap
+type storedCertificate struct {
+	Purpose   string    `json:"purpose"`
+	Owner     string    `json:"owner"`
+	Resource  string    `json:"resource"`
+	Subject   string    `json:"subject"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+}
+
+// marshal returns the certificates as JSON, in a stable order
+func (n nodeCertificates) marshal() (string, error) {
+	stored := make([]storedCertificate, 0, len(n))
+	for _, key := range sets.List(sets.KeySet(n)) {
+		cert := n[key]
+		stored = append(stored, storedCertificate{
+			Purpose:   cert.purpose,
+			Owner:     cert.owner,
+			Resource:  cert.resource,
+			Subject:   cert.subject,
+			NotBefore: cert.notBefore.UTC(),
+			NotAfter:  cert.notAfter.UTC(),
+		})
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// unmarshalNodeCertificates returns the certificates stored as JSON by marshal
+func unmarshalNodeCertificates(data string) (nodeCertificates, error) {
+	stored := []storedCertificate{}
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, err
+	}
+	n := nodeCertificates{}
+	for _, cert := range stored {
+		n[cert.Purpose+" "+cert.Owner] = certificate{
+			purpose:   cert.Purpose,
+			owner:     cert.Owner,
+			resource:  cert.Resource,
+			subject:   cert.Subject,
+			notBefore: cert.NotBefore,
+			notAfter:  cert.NotAfter,
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,208 @@
+package certinventory
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	netopv1 "github.com/openshift/cluster-network-operator/pkg/apis/network/v1"
+	"github.com/openshift/library-go/pkg/crypto"
+
+	csrv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func makeCA(t *testing.T, name string, validity time.Duration) *crypto.CA {
+	t.Helper()
+	config, err := crypto.MakeSelfSignedCAConfigForDuration(name, validity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &crypto.CA{Config: config, SerialGenerator: &crypto.RandomSerialGenerator{}}
+}
+
+func makeCertPEM(t *testing.T, ca *crypto.CA, commonName string, validity time.Duration) []byte {
+	t.Helper()
+	config, err := ca.MakeServerCertForDuration(sets.New(commonName), validity, func(cert *x509.Certificate) error {
+		cert.Subject = pkix.Name{CommonName: commonName}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, _, err := config.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certPEM
+}
+
+func TestPKISecretNames(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// the certificates are not created yet
+	obj := &netopv1.OperatorPKI{ObjectMeta: metav1.ObjectMeta{Name: "ovn"}}
+	ca, targets := pkiSecretNames(obj)
+	g.Expect(ca).To(BeEmpty())
+	g.Expect(targets).To(BeEmpty())
+
+	obj.Status = netopv1.OperatorPKIStatus{
+		CA:                    &netopv1.CertificateStatus{SecretName: "ovn-ca"},
+		TargetCert:            &netopv1.CertificateStatus{SecretName: "ovn-cert"},
+		AdditionalTargetCerts: []netopv1.CertificateStatus{{SecretName: "ovn-metrics-cert"}},
+	}
+	ca, targets = pkiSecretNames(obj)
+	g.Expect(ca).To(Equal("ovn-ca"))
+	g.Expect(targets).To(Equal([]string{"ovn-cert", "ovn-metrics-cert"}))
+}
+
+func TestSecretCertificate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cert, err := secretCertificate(purposePKICA, "OperatorPKI/ovn", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cert).To(BeNil())
+
+	ca := makeCA(t, "ovn-ca", 24*time.Hour)
+	certPEM, _, err := ca.Config.GetPEMBytes()
+	g.Expect(err).NotTo(HaveOccurred())
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-ovn-kubernetes", Name: "ovn-ca"},
+		Data:       map[string][]byte{corev1.TLSCertKey: certPEM},
+	}
+	cert, err = secretCertificate(purposePKICA, "OperatorPKI/ovn", secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*cert).To(Equal(certificate{
+		purpose:   purposePKICA,
+		owner:     "OperatorPKI/ovn",
+		resource:  "Secret/openshift-ovn-kubernetes/ovn-ca",
+		subject:   "ovn-ca",
+		notBefore: ca.Config.Certs[0].NotBefore,
+		notAfter:  ca.Config.Certs[0].NotAfter,
+	}))
+
+	secret.Data[corev1.TLSCertKey] = []byte("garbage")
+	_, err = secretCertificate(purposePKICA, "OperatorPKI/ovn", secret)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestBundleCertificates(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	certs, err := bundleCertificates(purposeNodeIdentityCA, nodeIdentityWebhookOwner, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(certs).To(BeEmpty())
+	g.Expect(latestCertificate(certs)).To(BeEmpty())
+
+	previous := makeCA(t, "network-node-identity-ca-1", 24*time.Hour)
+	current := makeCA(t, "network-node-identity-ca-2", 48*time.Hour)
+	previousPEM, _, err := previous.Config.GetPEMBytes()
+	g.Expect(err).NotTo(HaveOccurred())
+	currentPEM, _, err := current.Config.GetPEMBytes()
+	g.Expect(err).NotTo(HaveOccurred())
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-network-node-identity", Name: nodeIdentityCAConfigMap},
+		Data:       map[string]string{caBundleKey: string(currentPEM) + string(previousPEM)},
+	}
+
+	certs, err = bundleCertificates(purposeNodeIdentityCA, nodeIdentityWebhookOwner, cm)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(certs).To(HaveLen(2))
+	g.Expect(certs[0].resource).To(Equal("ConfigMap/openshift-network-node-identity/network-node-identity-ca"))
+
+	// only the current CA tells when the bundle must be renewed
+	latest := latestCertificate(certs)
+	g.Expect(latest).To(HaveLen(1))
+	g.Expect(latest[0].subject).To(Equal("network-node-identity-ca-2"))
+
+	cm.Data = map[string]string{}
+	_, err = bundleCertificates(purposeNodeIdentityCA, nodeIdentityWebhookOwner, cm)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestCSRCertificate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca := makeCA(t, "signer-ca", 24*time.Hour)
+	csr := &csrv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "ipsec-csr"},
+		Spec: csrv1.CertificateSigningRequestSpec{
+			SignerName: networkSignerName,
+			Username:   "system:ovn-node:node1",
+		},
+	}
+
+	// not issued yet
+	_, ok, err := csrCertificate(csr)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+
+	csr.Status.Certificate = makeCertPEM(t, ca, "chassis-id", time.Hour)
+	cert, ok, err := csrCertificate(csr)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(cert.purpose).To(Equal(purposeIPsec))
+	g.Expect(cert.owner).To(Equal("Node/node1"))
+	g.Expect(cert.resource).To(Equal("CertificateSigningRequest/ipsec-csr"))
+	g.Expect(cert.subject).To(Equal("chassis-id"))
+
+	// the node identity certificates are requested by ovnkube-node for its node
+	csr.Spec.SignerName = csrv1.KubeAPIServerClientSignerName
+	csr.Spec.Username = "system:serviceaccount:openshift-ovn-kubernetes:ovn-kubernetes-node"
+	csr.Status.Certificate = makeCertPEM(t, ca, "system:ovn-node:node2", time.Hour)
+	cert, ok, err = csrCertificate(csr)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(cert.purpose).To(Equal(purposeOVNNodeIdentity))
+	g.Expect(cert.owner).To(Equal("Node/node2"))
+
+	// other client certificates are not ours
+	csr.Status.Certificate = makeCertPEM(t, ca, "system:admin", time.Hour)
+	_, ok, err = csrCertificate(csr)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+
+	csr.Spec.SignerName = csrv1.KubeletServingSignerName
+	_, ok, err = csrCertificate(csr)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+
+	csr.Spec.SignerName = networkSignerName
+	csr.Status.Certificate = []byte("garbage")
+	_, _, err = csrCertificate(csr)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestNodeCertificates(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ipsec := func(node string, notAfter time.Time) certificate {
+		return certificate{purpose: purposeIPsec, owner: "Node/" + node, notAfter: notAfter}
+	}
+	nodes := sets.New("node1", "node2")
+
+	n := nodeCertificates{}
+	g.Expect(n.update([]certificate{ipsec("node1", now.Add(time.Hour)), ipsec("node2", now.Add(time.Hour))}, nodes)).To(BeTrue())
+	g.Expect(n.list()).To(HaveLen(2))
+
+	// the latest certificate of a node is kept, even after its request is gone
+	g.Expect(n.update([]certificate{ipsec("node1", now.Add(2*time.Hour))}, nodes)).To(BeTrue())
+	g.Expect(n.update([]certificate{ipsec("node1", now.Add(time.Hour))}, nodes)).To(BeFalse())
+	g.Expect(n.list()).To(ConsistOf(ipsec("node1", now.Add(2*time.Hour)), ipsec("node2", now.Add(time.Hour))))
+
+	// the certificates survive a restart of the operator
+	data, err := n.marshal()
+	g.Expect(err).NotTo(HaveOccurred())
+	loaded, err := unmarshalNodeCertificates(data)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(loaded).To(Equal(n))
+
+	// the certificates of a deleted node are forgotten
+	g.Expect(n.update(nil, sets.New("node1"))).To(BeTrue())
+	g.Expect(n.list()).To(ConsistOf(ipsec("node1", now.Add(2*time.Hour))))
+}
@@ -0,0 +1,40 @@
+package certinventory
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const cnoNamespace = "openshift_network_operator"
+
+var (
+	certificateNotBeforeGauge *metrics.GaugeVec
+	certificateNotAfterGauge  *metrics.GaugeVec
+)
+
+func init() {
+	labels := []string{"purpose", "owner", "resource", "subject"}
+	certificateNotBeforeGauge = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace: cnoNamespace,
+		Name:      "certificate_not_before_timestamp_seconds",
+		Help:      "The time, in seconds since the epoch, at which a certificate managed by the network operator becomes valid.",
+	}, labels)
+	certificateNotAfterGauge = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace: cnoNamespace,
+		Name:      "certificate_not_after_timestamp_seconds",
+		Help:      "The time, in seconds since the epoch, at which a certificate managed by the network operator expires.",
+	}, labels)
+	legacyregistry.MustRegister(certificateNotBeforeGauge)
+	legacyregistry.MustRegister(certificateNotAfterGauge)
+}
+
+// updateMetrics replaces the certificate gauges with certs, so that the
+// certificates that were rotated or deleted stop being reported.
+func updateMetrics(certs []certificate) {
+	certificateNotBeforeGauge.Reset()
+	certificateNotAfterGauge.Reset()
+	for _, cert := range certs {
+		certificateNotBeforeGauge.WithLabelValues(cert.purpose, cert.owner, cert.resource, cert.subject).Set(float64(cert.notBefore.Unix()))
+		certificateNotAfterGauge.WithLabelValues(cert.purpose, cert.owner, cert.resource, cert.subject).Set(float64(cert.notAfter.Unix()))
+	}
+}
@@ -438,6 +438,12 @@ func withTarget(label, target string) func(check *applyconfigv1alpha1.PodNetwork
 	return WithTarget(label + "-" + target)
 }
 
+// TargetNodeCheckSuffix returns the suffix of the names of the checks of the
+// network-check-target pod running on the given node
+func TargetNodeCheckSuffix(nodeName string) string {
+	return "-to-network-check-target-" + nodeNameForLabel(nodeName)
+}
+
 // nodeNameForLabel returns a string derived from a node name that is safe to embed
 // in a Kubernetes resource name. For bare IP addresses (IPv4 or IPv6), dots and
 // colons are replaced with dashes so the full address is preserved and collisions
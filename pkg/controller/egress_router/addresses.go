@@ -0,0 +1,113 @@
+package egress_router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+)
+
+const (
+	// objectNamePrefix is the prefix of the name of the objects rendered for an
+	// egress router
+	objectNamePrefix = "egress-router-cni-"
+
+	// routerLabel is set to the name of the router on its pods
+	routerLabel = "network.operator.openshift.io/egress-router"
+
+	// legacyNADName and legacyDeploymentName are the names of the objects
+	// rendered for a router before they were named after it
+	legacyNADName        = "egress-router-cni-nad"
+	legacyDeploymentName = "egress-router-cni-deployment"
+)
+
+// egressRouterObjectName returns the name of the NetworkAttachmentDefinition and
+// of the Deployment of an egress router
+func egressRouterObjectName(router string) string {
+	return objectNamePrefix + router
+}
+
+// isHighAvailability returns whether the router runs in high-availability mode
+func isHighAvailability(router *netopv1.EgressRouter) bool {
+	return router.Annotations[names.EgressRouterHighAvailabilityAnnotation] == "true"
+}
+
+// getAddressesConfigJSON generates the addresses json config
+func getAddressesConfigJSON(addresses []netopv1.EgressRouterAddress) (string, error) {
+	config := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		config = append(config, address.IP)
+	}
+	jsonByte, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonByte), nil
+}
+
+// getGateway returns the gateway of the addresses, if any. The egress router CNI
+// plugin has a single gateway, so validateEgressRouterSpec ensures that the
+// addresses agree on it, unless the router is in high-availability mode, whose
+// sidecar configures the gateways of both IP families.
+func getGateway(addresses []netopv1.EgressRouterAddress) string {
+	for _, address := range addresses {
+		if address.Gateway != "" {
+			return address.Gateway
+		}
+	}
+	return ""
+}
+
+// getGateways returns the distinct gateways of the addresses, which
+// validateEgressRouterSpec ensures are at most one per IP family
+func getGateways(addresses []netopv1.EgressRouterAddress) []string {
+	out := []string{}
+	for _, address := range addresses {
+		if address.Gateway != "" && !slices.Contains(out, address.Gateway) {
+			out = append(out, address.Gateway)
+		}
+	}
+	return out
+}
+
+// validateAddressConflicts checks that none of the addresses of the router is used
+// by another router of the namespace. When two routers use the same address, the
+// one created first keeps it.
+func validateAddressConflicts(router *netopv1.EgressRouter, routers []netopv1.EgressRouter) error {
+	ips := map[string]string{}
+	for _, address := range router.Spec.Addresses {
+		if ip, _, err := net.ParseCIDR(address.IP); err == nil {
+			ips[ip.String()] = address.IP
+		}
+	}
+
+	for i := range routers {
+		other := &routers[i]
+		if other.Name == router.Name || other.DeletionTimestamp != nil || !createdBefore(other, router) {
+			continue
+		}
+		for _, address := range other.Spec.Addresses {
+			ip, _, err := net.ParseCIDR(address.IP)
+			if err != nil {
+				continue
+			}
+			if mine, ok := ips[ip.String()]; ok {
+				return fmt.Errorf("address %s is already used by Egress Router %s/%s", mine, other.Namespace, other.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// createdBefore returns whether router a was created before router b, using the
+// name to order the routers created in the same second
+func createdBefore(a, b *netopv1.EgressRouter) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
@@ -5,8 +5,8 @@ package egress_router
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net"
 	"os"
 
 	"github.com/openshift/cluster-network-operator/pkg/apply"
@@ -19,6 +19,11 @@ import (
 	"strings"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
@@ -29,6 +34,7 @@ import (
 	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -50,8 +56,11 @@ func Add(mgr manager.Manager, status *statusmanager.StatusManager, cli cnoclient
 		return err
 	}
 
-	// Watch for changes to primary resource EgressRouter.network.operator.openshift.io/v1
-	err = c.Watch(source.Kind[crclient.Object](mgr.GetCache(), &netopv1.EgressRouter{}, &handler.EnqueueRequestForObject{}))
+	// Watch for changes to primary resource EgressRouter.network.operator.openshift.io/v1,
+	// ignoring the updates of its status. The high-availability mode is set through
+	// an annotation, which does not change the generation.
+	err = c.Watch(source.Kind[crclient.Object](mgr.GetCache(), &netopv1.EgressRouter{}, &handler.EnqueueRequestForObject{},
+		predicate.Or[crclient.Object](predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})))
 	if err != nil {
 		return err
 	}
@@ -62,8 +71,15 @@ func Add(mgr manager.Manager, status *statusmanager.StatusManager, cli cnoclient
 var _ reconcile.Reconciler = &EgressRouterReconciler{}
 var manifestDir = "bindata/"
 
+// egressrouter is what the objects of a router were last rendered from
 type egressrouter struct {
-	spec netopv1.EgressRouterSpec
+	spec             netopv1.EgressRouterSpec
+	highAvailability bool
+}
+
+// renderedFrom returns true if the objects of router were rendered from e
+func (e *egressrouter) renderedFrom(router *netopv1.EgressRouter) bool {
+	return reflect.DeepEqual(router.Spec, e.spec) && isHighAvailability(router) == e.highAvailability
 }
 
 type EgressRouterReconciler struct {
@@ -77,6 +93,10 @@ type EgressRouterReconciler struct {
 
 var ResyncPeriod = 5 * time.Minute
 
+// progressingResyncPeriod is how often the status of a router that is not yet
+// available is refreshed
+var progressingResyncPeriod = 15 * time.Second
+
 func newEgressRouterReconciler(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client) (reconcile.Reconciler, error) {
 
 	return &EgressRouterReconciler{
@@ -99,16 +119,22 @@ func (r EgressRouterReconciler) Reconcile(ctx context.Context, request reconcile
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			klog.Infof("Egress Router %s seems to have been deleted\n", request.NamespacedName)
+			delete(r.egressrouters, request.NamespacedName)
+			delete(r.egressrouterErrs, request.NamespacedName)
+			r.setStatus()
 			return reconcile.Result{}, nil
 		}
 		klog.Error(err)
 		return reconcile.Result{}, err
 	}
+	if obj.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
 
 	// Check to see if we already know this object
 	existing := r.egressrouters[request.NamespacedName]
 	if existing != nil {
-		if !reflect.DeepEqual(obj.Spec, existing.spec) {
+		if !existing.renderedFrom(obj) {
 			klog.Infof("Egress Router %s has changed, refreshing\n", request.NamespacedName)
 			delete(r.egressrouters, request.NamespacedName)
 			existing = nil
@@ -128,33 +154,102 @@ func (r EgressRouterReconciler) Reconcile(ctx context.Context, request reconcile
 				Controller: &boolTrue,
 			},
 		}
-		err := r.ensureEgressRouter(ctx, manifestDir, request.Namespace, obj, EgressRouterOwnerReferences)
-
-		if err != nil {
-			klog.Error(err)
-			r.egressrouterErrs[request.NamespacedName] =
-				fmt.Errorf("could not reconcile Egress Router %s: %w", request.NamespacedName, err)
-			r.setStatus()
-			return reconcile.Result{}, err
+		err = r.ensureEgressRouter(ctx, manifestDir, request.Namespace, obj, EgressRouterOwnerReferences)
+		if err == nil {
+			r.egressrouters[request.NamespacedName] = &egressrouter{spec: obj.Spec, highAvailability: isHighAvailability(obj)}
 		}
-
-		r.egressrouters[request.NamespacedName] = existing
 	}
 
-	if err != nil {
+	// An invalid router is only reported in its own status, as it is not a
+	// failure of the operator
+	specErr := &invalidSpecError{}
+	invalidSpec := errors.As(err, &specErr)
+	if err != nil && !invalidSpec {
 		klog.Error(err)
 		r.egressrouterErrs[request.NamespacedName] =
 			fmt.Errorf("could not reconcile Egress Router %s: %w", request.NamespacedName, err)
-		r.setStatus()
-		return reconcile.Result{}, err
+	} else {
+		delete(r.egressrouterErrs, request.NamespacedName)
 	}
+	r.setStatus()
 
+	available := netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterAvailable, Status: netopv1.ConditionFalse, Reason: "InvalidSpec"}
+	progressing := netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterProgressing, Status: netopv1.ConditionFalse, Reason: "InvalidSpec"}
+	if invalidSpec {
+		klog.Warningf("Egress Router %s is invalid: %v", request.NamespacedName, err)
+		available.Message = err.Error()
+	} else {
+		deployment, pods, holder, lookupErr := r.getRouterPods(ctx, obj)
+		if lookupErr != nil {
+			klog.Errorf("Failed to get the pods of Egress Router %s: %v", request.NamespacedName, lookupErr)
+			return reconcile.Result{}, lookupErr
+		}
+		available, progressing = availableCondition(obj, deployment, pods, holder)
+	}
+	if statusErr := r.updateStatus(ctx, obj, available, progressing, degradedCondition(err)); statusErr != nil {
+		klog.Errorf("Failed to update the status of Egress Router %s: %v", request.NamespacedName, statusErr)
+		return reconcile.Result{}, statusErr
+	}
+
+	if err != nil && !invalidSpec {
+		return reconcile.Result{}, err
+	}
 	klog.Infof("successful reconciliation")
-	delete(r.egressrouterErrs, request.NamespacedName)
-	r.setStatus()
+	if progressing.Status == netopv1.ConditionTrue {
+		return reconcile.Result{RequeueAfter: progressingResyncPeriod}, nil
+	}
 	return reconcile.Result{RequeueAfter: ResyncPeriod}, nil
 }
 
+// getRouterPods returns the deployment of the router, or nil if it does not exist,
+// its pods, and in high-availability mode the pod holding the lease of the router.
+// They are read from the API, rather than from the cache of the manager, to avoid
+// caching every deployment and pod of the cluster.
+func (r *EgressRouterReconciler) getRouterPods(ctx context.Context, router *netopv1.EgressRouter) (*appsv1.Deployment, []corev1.Pod, string, error) {
+	name := types.NamespacedName{Namespace: router.Namespace, Name: egressRouterObjectName(router.Name)}
+	deployment := &appsv1.Deployment{}
+	err := r.client.Default().CRClient().Get(ctx, name, deployment)
+	if apierrors.IsNotFound(err) {
+		return nil, nil, "", nil
+	} else if err != nil {
+		return nil, nil, "", err
+	}
+	pods := &corev1.PodList{}
+	if err := r.client.Default().CRClient().List(ctx, pods, crclient.InNamespace(router.Namespace), crclient.MatchingLabels{routerLabel: router.Name}); err != nil {
+		return nil, nil, "", err
+	}
+
+	holder := ""
+	if isHighAvailability(router) {
+		lease := &coordinationv1.Lease{}
+		err := r.client.Default().CRClient().Get(ctx, name, lease)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, nil, "", err
+		}
+		if err == nil && lease.Spec.HolderIdentity != nil && !leaseExpired(lease, time.Now()) {
+			holder = *lease.Spec.HolderIdentity
+		}
+	}
+	return deployment, pods.Items, holder, nil
+}
+
+// leaseExpired returns whether the holder of the lease failed to renew it
+func leaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second).Before(now)
+}
+
+// updateStatus sets the conditions of the router, if they changed
+func (r *EgressRouterReconciler) updateStatus(ctx context.Context, router *netopv1.EgressRouter, conds ...netopv1.EgressRouterStatusCondition) error {
+	router = router.DeepCopy()
+	if !setConditions(&router.Status, metav1.Now(), conds...) {
+		return nil
+	}
+	return r.mgr.GetClient().Status().Update(ctx, router)
+}
+
 // setStatus summarizes the status of all Egress Router objects and updates the statusmanager
 // as appropriate.
 func (r *EgressRouterReconciler) setStatus() {
@@ -188,40 +283,34 @@ func getAllowedDestinationsConfigJSON(RedirectRules []netopv1.L4RedirectRule) (s
 
 	jsonByte, err := json.Marshal(config)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
 	return string(jsonByte), nil
 }
 
 func (r *EgressRouterReconciler) ensureEgressRouter(ctx context.Context, manifestDir string, namespace string, router *netopv1.EgressRouter, EgressRouterOwnerReferences []metav1.OwnerReference) error {
-	var err error
-	if len(router.Spec.Addresses) == 0 {
-		return fmt.Errorf("router without addresses")
+	routers := &netopv1.EgressRouterList{}
+	if err := r.mgr.GetClient().List(ctx, routers, crclient.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list the egress routers of namespace %s: %w", namespace, err)
 	}
-	out := []*uns.Unstructured{}
-	data := render.MakeRenderData()
-	data.Data["ReleaseVersion"] = os.Getenv("RELEASE_VERSION")
-	data.Data["EgressRouterNamespace"] = namespace
-	if isItValidCidr(router.Spec.Addresses[0].IP) {
-		data.Data["Addresses"] = router.Spec.Addresses[0].IP
+	if err := validateEgressRouterSpec(router); err != nil {
+		return &invalidSpecError{err: err}
 	}
-	if isItValidIPAddress(router.Spec.Addresses[0].Gateway) {
-		data.Data["Gateway"] = router.Spec.Addresses[0].Gateway
+	if err := validateAddressConflicts(router, routers.Items); err != nil {
+		return &invalidSpecError{err: err}
 	}
-	data.Data["AllowedDestinations"], err = getAllowedDestinationsConfigJSON(router.Spec.Redirect.RedirectRules)
+
+	out, err := renderEgressRouter(manifestDir, namespace, router)
 	if err != nil {
-		return fmt.Errorf("failed to render AllowedDestinations config: %w", err)
+		return err
 	}
-	data.Data["FallbackIP"] = router.Spec.Redirect.FallbackIP
-	data.Data["mode"] = router.Spec.Mode
-	data.Data["network_interfaces"] = router.Spec.NetworkInterface
-	data.Data["EgressRouterPodImage"] = os.Getenv("EGRESS_ROUTER_CNI_IMAGE")
-	manifests, err := render.RenderDir(filepath.Join(manifestDir, "egress-router"), &data)
-	if err != nil {
+
+	// the objects rendered for the router before they were named after it hold
+	// the same addresses, so they are deleted before the renamed ones are applied
+	if err := r.deleteOwnedObjects(ctx, router, legacyObjects(namespace, router)); err != nil {
 		return err
 	}
-	out = append(out, manifests...)
 
 	for _, obj := range out {
 		klog.Infof("Assigning owner references")
@@ -233,18 +322,94 @@ func (r *EgressRouterReconciler) ensureEgressRouter(ctx context.Context, manifes
 		}
 	}
 
-	return nil
+	if isHighAvailability(router) {
+		return nil
+	}
+	return r.deleteOwnedObjects(ctx, router, []crclient.Object{
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: egressRouterObjectName(router.Name)}},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: egressRouterObjectName(router.Name)}},
+	})
 }
 
-func isItValidCidr(cidr string) bool {
-	_, _, err := net.ParseCIDR(cidr)
+// legacyObjects returns the NetworkAttachmentDefinition and the Deployment
+// rendered for the router before they were named after it. The ones whose name
+// is now the name of the objects of the router, as for a router named "nad" or
+// "deployment", are left out.
+func legacyObjects(namespace string, router *netopv1.EgressRouter) []crclient.Object {
+	out := []crclient.Object{}
+	if legacyDeploymentName != egressRouterObjectName(router.Name) {
+		out = append(out, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: legacyDeploymentName}})
+	}
+	if legacyNADName != egressRouterObjectName(router.Name) {
+		out = append(out, &uns.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "k8s.cni.cncf.io/v1",
+			"kind":       "NetworkAttachmentDefinition",
+			"metadata":   map[string]interface{}{"namespace": namespace, "name": legacyNADName},
+		}})
+	}
+	return out
+}
+
+// renderEgressRouter renders the NetworkAttachmentDefinition and the Deployment of
+// an egress router, named after the router, and the ServiceAccount of its
+// replicas in high-availability mode
+func renderEgressRouter(manifestDir string, namespace string, router *netopv1.EgressRouter) ([]*uns.Unstructured, error) {
+	var err error
+	if len(router.Spec.Addresses) == 0 {
+		return nil, fmt.Errorf("router without addresses")
+	}
+	data := render.MakeRenderData()
+	data.Data["ReleaseVersion"] = os.Getenv("RELEASE_VERSION")
+	data.Data["EgressRouterNamespace"] = namespace
+	data.Data["EgressRouterName"] = router.Name
+	data.Data["EgressRouterObjectName"] = egressRouterObjectName(router.Name)
+	data.Data["Addresses"], err = getAddressesConfigJSON(router.Spec.Addresses)
 	if err != nil {
-		klog.Error(err)
-		return false
+		return nil, fmt.Errorf("failed to render Addresses config: %w", err)
+	}
+	data.Data["Gateway"] = getGateway(router.Spec.Addresses)
+	var redirectRules []netopv1.L4RedirectRule
+	fallbackIP := ""
+	if router.Spec.Redirect != nil {
+		redirectRules = router.Spec.Redirect.RedirectRules
+		fallbackIP = router.Spec.Redirect.FallbackIP
 	}
-	return true
+	data.Data["AllowedDestinations"], err = getAllowedDestinationsConfigJSON(redirectRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render AllowedDestinations config: %w", err)
+	}
+	data.Data["FallbackIP"] = fallbackIP
+	data.Data["mode"] = router.Spec.Mode
+	data.Data["network_interfaces"] = router.Spec.NetworkInterface
+	data.Data["EgressRouterPodImage"] = os.Getenv("EGRESS_ROUTER_CNI_IMAGE")
+	data.Data["HighAvailability"] = isHighAvailability(router)
+	data.Data["EgressRouterHAImage"] = os.Getenv("NETWORK_OPERATOR_IMAGE")
+	addressList := []string{}
+	for _, address := range router.Spec.Addresses {
+		addressList = append(addressList, address.IP)
+	}
+	data.Data["AddressList"] = addressList
+	data.Data["GatewayList"] = getGateways(router.Spec.Addresses)
+	return render.RenderDir(filepath.Join(manifestDir, "egress-router"), &data)
 }
 
-func isItValidIPAddress(ip string) bool {
-	return net.ParseIP(ip) != nil
+// deleteOwnedObjects deletes the objects, if they exist and are controlled by
+// the router
+func (r *EgressRouterReconciler) deleteOwnedObjects(ctx context.Context, router *netopv1.EgressRouter, objs []crclient.Object) error {
+	for _, obj := range objs {
+		if err := r.client.Default().CRClient().Get(ctx, crclient.ObjectKeyFromObject(obj), obj); err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return err
+		}
+		if owner := metav1.GetControllerOf(obj); owner == nil || owner.UID != router.UID {
+			continue
+		}
+		klog.Infof("Deleting stale object %s/%s of Egress Router %s", obj.GetNamespace(), obj.GetName(), router.Name)
+		if err := r.client.Default().CRClient().Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
 }
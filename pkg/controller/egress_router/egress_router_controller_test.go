@@ -0,0 +1,257 @@
+package egress_router
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	. "github.com/onsi/gomega"
+)
+
+func egressRouter(name string, created time.Time, addresses ...netopv1.EgressRouterAddress) *netopv1.EgressRouter {
+	return &netopv1.EgressRouter{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "ns",
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: netopv1.EgressRouterSpec{
+			Mode: netopv1.EgressRouterModeRedirect,
+			Redirect: &netopv1.RedirectConfig{
+				RedirectRules: []netopv1.L4RedirectRule{{DestinationIP: "10.0.0.99", Port: 80, Protocol: netopv1.ProtocolTypeTCP}},
+			},
+			Addresses: addresses,
+		},
+	}
+}
+
+func TestRenderEgressRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter("app-a", time.Now(),
+		netopv1.EgressRouterAddress{IP: "192.168.12.99/24", Gateway: "192.168.12.1"},
+		netopv1.EgressRouterAddress{IP: "fd00:12::99/64"},
+	)
+	objs, err := renderEgressRouter("../../../bindata", "ns", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+
+	var nad, deployment *uns.Unstructured
+	for _, obj := range objs {
+		g.Expect(obj.GetName()).To(Equal("egress-router-cni-app-a"))
+		g.Expect(obj.GetNamespace()).To(Equal("ns"))
+		switch obj.GetKind() {
+		case "NetworkAttachmentDefinition":
+			nad = obj
+		case "Deployment":
+			deployment = obj
+		}
+	}
+	g.Expect(nad).NotTo(BeNil())
+	g.Expect(deployment).NotTo(BeNil())
+
+	config, _, _ := uns.NestedString(nad.Object, "spec", "config")
+	cniConfig := struct {
+		Name string `json:"name"`
+		IP   struct {
+			Addresses    []string `json:"addresses"`
+			Destinations []string `json:"destinations"`
+			Gateway      string   `json:"gateway"`
+		} `json:"ip"`
+	}{}
+	g.Expect(json.Unmarshal([]byte(config), &cniConfig)).To(Succeed())
+	g.Expect(cniConfig.Name).To(Equal("egress-router-cni-app-a"))
+	g.Expect(cniConfig.IP.Addresses).To(Equal([]string{"192.168.12.99/24", "fd00:12::99/64"}))
+	g.Expect(cniConfig.IP.Destinations).To(Equal([]string{"80 TCP 10.0.0.99"}))
+	g.Expect(cniConfig.IP.Gateway).To(Equal("192.168.12.1"))
+
+	selector, _, _ := uns.NestedStringMap(deployment.Object, "spec", "selector", "matchLabels")
+	g.Expect(selector).To(HaveKeyWithValue("network.operator.openshift.io/egress-router", "app-a"))
+	networks, _, _ := uns.NestedString(deployment.Object, "spec", "template", "metadata", "annotations", "k8s.v1.cni.cncf.io/networks")
+	g.Expect(networks).To(ContainSubstring(`"name":"egress-router-cni-app-a"`))
+	var selections []map[string]any
+	g.Expect(json.Unmarshal([]byte(networks), &selections)).To(Succeed())
+	g.Expect(selections).To(ConsistOf(HaveKeyWithValue("default-route", []any{"192.168.12.1"})))
+}
+
+func TestRenderEgressRouterWithoutGateway(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter("app-a", time.Now(), netopv1.EgressRouterAddress{IP: "192.168.12.99/24"})
+	router.Spec.Redirect.RedirectRules[0].DestinationIP = "192.168.12.5"
+	objs, err := renderEgressRouter("../../../bindata", "ns", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+	g.Expect(objs[0].GetKind()).To(Equal("NetworkAttachmentDefinition"))
+	g.Expect(objs[1].GetKind()).To(Equal("Deployment"))
+
+	config, _, _ := uns.NestedString(objs[0].Object, "spec", "config")
+	cniConfig := struct {
+		IP map[string]any `json:"ip"`
+	}{}
+	g.Expect(json.Unmarshal([]byte(config), &cniConfig)).To(Succeed())
+	g.Expect(cniConfig.IP).NotTo(HaveKey("gateway"))
+	g.Expect(cniConfig.IP).To(HaveKeyWithValue("destinations", []any{"80 TCP 192.168.12.5"}))
+
+	networks, _, _ := uns.NestedString(objs[1].Object, "spec", "template", "metadata", "annotations", "k8s.v1.cni.cncf.io/networks")
+	var selections []map[string]any
+	g.Expect(json.Unmarshal([]byte(networks), &selections)).To(Succeed())
+	g.Expect(selections).To(ConsistOf(Equal(map[string]any{"name": "egress-router-cni-app-a"})))
+}
+
+func TestRenderEgressRouterHighAvailability(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter("app-a", time.Now(),
+		netopv1.EgressRouterAddress{IP: "192.168.12.99/24", Gateway: "192.168.12.1"},
+		netopv1.EgressRouterAddress{IP: "fd00:12::99/64"},
+	)
+	objs, err := renderEgressRouter("../../../bindata", "ns", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+
+	router.Annotations = map[string]string{names.EgressRouterHighAvailabilityAnnotation: "true"}
+	objs, err = renderEgressRouter("../../../bindata", "ns", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	kinds := []string{}
+	var deployment *uns.Unstructured
+	for _, obj := range objs {
+		g.Expect(obj.GetName()).To(Equal("egress-router-cni-app-a"))
+		kinds = append(kinds, obj.GetKind())
+		if obj.GetKind() == "Deployment" {
+			deployment = obj
+		}
+	}
+	g.Expect(kinds).To(ConsistOf("NetworkAttachmentDefinition", "Deployment", "ServiceAccount", "RoleBinding"))
+
+	d := &appsv1.Deployment{}
+	g.Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(deployment.Object, d)).To(Succeed())
+	g.Expect(*d.Spec.Replicas).To(BeEquivalentTo(2))
+	g.Expect(d.Spec.Template.Spec.ServiceAccountName).To(Equal("egress-router-cni-app-a"))
+	g.Expect(d.Spec.Template.Annotations).To(HaveKeyWithValue("openshift.io/required-scc", "egress-router-ha"))
+	terms := d.Spec.Template.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	g.Expect(terms).To(HaveLen(1))
+	g.Expect(terms[0].TopologyKey).To(Equal("kubernetes.io/hostname"))
+	g.Expect(terms[0].LabelSelector.MatchLabels).To(Equal(map[string]string{routerLabel: "app-a"}))
+	// only the active replica is ready, which a rolling update would wait for
+	g.Expect(d.Spec.Strategy.Type).To(Equal(appsv1.RecreateDeploymentStrategyType))
+	g.Expect(d.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+	g.Expect(d.Spec.Template.Spec.InitContainers[0].Command).To(Equal([]string{
+		"/usr/bin/cluster-network-egress-router-ha",
+		"--release-only",
+		"--interface=net1",
+		"--address=192.168.12.99/24",
+		"--address=fd00:12::99/64",
+	}))
+	g.Expect(d.Spec.Template.Spec.Containers).To(HaveLen(2))
+	sidecar := d.Spec.Template.Spec.Containers[1]
+	g.Expect(sidecar.Command).To(Equal([]string{
+		"/usr/bin/cluster-network-egress-router-ha",
+		"--interface=net1",
+		"--lease-name=egress-router-cni-app-a",
+		"--address=192.168.12.99/24",
+		"--address=fd00:12::99/64",
+		"--gateway=192.168.12.1",
+	}))
+	g.Expect(sidecar.ReadinessProbe.Exec.Command).To(Equal([]string{"test", "-f", "/tmp/egress-router-ha-active"}))
+
+	// dual-stack routers get a default route per family
+	router.Spec.Addresses[1].Gateway = "fd00:12::1"
+	objs, err = renderEgressRouter("../../../bindata", "ns", router)
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, obj := range objs {
+		if obj.GetKind() == "Deployment" {
+			g.Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, d)).To(Succeed())
+		}
+	}
+	g.Expect(d.Spec.Template.Spec.Containers[1].Command).To(ContainElements("--gateway=192.168.12.1", "--gateway=fd00:12::1"))
+}
+
+func TestRenderedFrom(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	router := egressRouter("app-a", time.Now(), netopv1.EgressRouterAddress{IP: "192.168.12.99/24", Gateway: "192.168.12.1"})
+	rendered := &egressrouter{spec: *router.Spec.DeepCopy(), highAvailability: isHighAvailability(router)}
+	g.Expect(rendered.renderedFrom(router)).To(BeTrue())
+
+	// Toggling high availability only changes an annotation, but must re-render
+	router.Annotations = map[string]string{names.EgressRouterHighAvailabilityAnnotation: "true"}
+	g.Expect(rendered.renderedFrom(router)).To(BeFalse())
+
+	router.Annotations = nil
+	router.Spec.Addresses[0].IP = "192.168.12.98/24"
+	g.Expect(rendered.renderedFrom(router)).To(BeFalse())
+}
+
+func TestLegacyObjects(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	legacyNames := func(router string) []string {
+		out := []string{}
+		for _, obj := range legacyObjects("ns", egressRouter(router, time.Now())) {
+			out = append(out, obj.GetName())
+		}
+		return out
+	}
+	g.Expect(legacyNames("app-a")).To(ConsistOf(legacyDeploymentName, legacyNADName))
+	// the objects of routers named like the legacy ones are not stale
+	g.Expect(legacyNames("nad")).To(ConsistOf(legacyDeploymentName))
+	g.Expect(legacyNames("deployment")).To(ConsistOf(legacyNADName))
+}
+
+func TestValidateAddressConflicts(t *testing.T) {
+	now := time.Now()
+	first := egressRouter("first", now.Add(-time.Hour),
+		netopv1.EgressRouterAddress{IP: "192.168.12.99/24"},
+		netopv1.EgressRouterAddress{IP: "192.168.12.100/24"},
+	)
+	deleted := egressRouter("deleted", now.Add(-time.Hour), netopv1.EgressRouterAddress{IP: "192.168.12.101/24"})
+	deleted.DeletionTimestamp = &metav1.Time{Time: now}
+
+	for _, tc := range []struct {
+		name   string
+		router *netopv1.EgressRouter
+		err    string
+	}{
+		{
+			name:   "distinct addresses",
+			router: egressRouter("second", now, netopv1.EgressRouterAddress{IP: "192.168.12.102/24"}),
+		},
+		{
+			name:   "the first router keeps its addresses",
+			router: first,
+		},
+		{
+			name:   "address of an older router",
+			router: egressRouter("second", now, netopv1.EgressRouterAddress{IP: "192.168.12.100/32"}),
+			err:    "address 192.168.12.100/32 is already used by Egress Router ns/first",
+		},
+		{
+			name:   "address of a router being deleted",
+			router: egressRouter("second", now, netopv1.EgressRouterAddress{IP: "192.168.12.101/24"}),
+		},
+		{
+			name:   "routers created at the same time are ordered by name",
+			router: egressRouter("aaa", now.Add(-time.Hour), netopv1.EgressRouterAddress{IP: "192.168.12.99/24"}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			routers := []netopv1.EgressRouter{*first, *deleted, *tc.router}
+			err := validateAddressConflicts(tc.router, routers)
+			if tc.err == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.err)))
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package egress_router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// networkStatusAnnotation is set by multus on the pods with the addresses of
+// their interfaces
+const networkStatusAnnotation = "k8s.v1.cni.cncf.io/network-status"
+
+// networkStatus is an entry of the network-status annotation
+type networkStatus struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips,omitempty"`
+}
+
+// invalidSpecError is an error in the spec of an egress router, which is reported
+// on the router but does not degrade the operator
+type invalidSpecError struct {
+	err error
+}
+
+func (e *invalidSpecError) Error() string {
+	return e.err.Error()
+}
+
+func (e *invalidSpecError) Unwrap() error {
+	return e.err
+}
+
+// availableCondition returns the Available and Progressing conditions of the
+// router: it is available when a ready pod of its deployment has all the
+// addresses of the router on its egress interface. In high-availability mode,
+// that pod must hold the lease of the router, as holder reports: only that pod
+// is ready, as its sidecar holds the addresses, and the other running pods are
+// the standby ones.
+func availableCondition(router *netopv1.EgressRouter, deployment *appsv1.Deployment, pods []corev1.Pod, holder string) (netopv1.EgressRouterStatusCondition, netopv1.EgressRouterStatusCondition) {
+	available := netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterAvailable, Status: netopv1.ConditionFalse}
+	progressing := netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterProgressing, Status: netopv1.ConditionTrue}
+
+	switch {
+	case deployment == nil:
+		available.Reason = "DeploymentNotFound"
+		available.Message = fmt.Sprintf("Deployment %s has not been created yet", egressRouterObjectName(router.Name))
+	case deployment.Status.ReadyReplicas == 0 && !isHighAvailability(router):
+		available.Reason = "DeploymentNotReady"
+		available.Message = fmt.Sprintf("Deployment %s has no ready pod", deployment.Name)
+	default:
+		network := router.Namespace + "/" + egressRouterObjectName(router.Name)
+		notConfigured := []string{}
+		var active *corev1.Pod
+		standby := []string{}
+		for i := range pods {
+			pod := &pods[i]
+			if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+				continue
+			}
+			if isHighAvailability(router) {
+				if pod.Name == holder && podReady(pod) {
+					active = pod
+				} else {
+					standby = append(standby, fmt.Sprintf("%s on node %s", pod.Name, pod.Spec.NodeName))
+				}
+				continue
+			}
+			if !podReady(pod) {
+				continue
+			}
+			if missing := missingAddresses(router, pod, network); len(missing) > 0 {
+				notConfigured = append(notConfigured, fmt.Sprintf("pod %s is missing %s", pod.Name, strings.Join(missing, ", ")))
+				continue
+			}
+			if active == nil {
+				active = pod
+			}
+		}
+
+		if active != nil {
+			available.Status = netopv1.ConditionTrue
+			available.Reason = "RouterReady"
+			available.Message = fmt.Sprintf("Egress Router is served by pod %s on node %s", active.Name, active.Spec.NodeName)
+			if isHighAvailability(router) {
+				if len(standby) == 0 {
+					available.Message += "; no standby pod is running"
+				} else {
+					available.Message += "; standby pods: " + strings.Join(standby, ", ")
+				}
+			}
+			progressing.Status = netopv1.ConditionFalse
+			progressing.Reason = "AsExpected"
+			return available, progressing
+		}
+
+		switch {
+		case len(standby) > 0:
+			available.Reason = "NoActivePod"
+			available.Message = fmt.Sprintf("No pod holds the lease of the router, standby pods: %s", strings.Join(standby, ", "))
+		case len(notConfigured) > 0:
+			available.Reason = "AddressesNotConfigured"
+			available.Message = "The egress interface is not configured: " + strings.Join(notConfigured, "; ")
+		default:
+			available.Reason = "AddressesNotConfigured"
+			available.Message = fmt.Sprintf("Deployment %s has no ready pod", deployment.Name)
+		}
+	}
+	progressing.Reason = available.Reason
+	progressing.Message = available.Message
+	return available, progressing
+}
+
+// missingAddresses returns the addresses of the router which the pod does not
+// have on its interface on the egress router network
+func missingAddresses(router *netopv1.EgressRouter, pod *corev1.Pod, network string) []string {
+	statuses := []networkStatus{}
+	_ = json.Unmarshal([]byte(pod.Annotations[networkStatusAnnotation]), &statuses)
+	configured := map[string]bool{}
+	for _, status := range statuses {
+		if status.Name != network {
+			continue
+		}
+		for _, ip := range status.IPs {
+			if parsed := net.ParseIP(ip); parsed != nil {
+				configured[parsed.String()] = true
+			}
+		}
+	}
+
+	missing := []string{}
+	for _, address := range router.Spec.Addresses {
+		ip, _, err := net.ParseCIDR(address.IP)
+		if err != nil || !configured[ip.String()] {
+			missing = append(missing, address.IP)
+		}
+	}
+	return missing
+}
+
+func podReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// degradedCondition returns the Degraded condition for the error, if any, met
+// while rendering the router
+func degradedCondition(err error) netopv1.EgressRouterStatusCondition {
+	cond := netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterDegraded}
+	specErr := &invalidSpecError{}
+	switch {
+	case err == nil:
+		cond.Status = netopv1.ConditionFalse
+		cond.Reason = "AsExpected"
+	case errors.As(err, &specErr):
+		cond.Status = netopv1.ConditionTrue
+		cond.Reason = "InvalidSpec"
+		cond.Message = err.Error()
+	default:
+		cond.Status = netopv1.ConditionTrue
+		cond.Reason = "ApplyFailed"
+		cond.Message = err.Error()
+	}
+	return cond
+}
+
+// setConditions updates the conditions of the status, keeping the transition time
+// of the ones whose status did not change, and returns whether any changed
+func setConditions(status *netopv1.EgressRouterStatus, now metav1.Time, conds ...netopv1.EgressRouterStatusCondition) bool {
+	changed := false
+	for _, cond := range conds {
+		found := false
+		for i := range status.Conditions {
+			existing := &status.Conditions[i]
+			if existing.Type != cond.Type {
+				continue
+			}
+			found = true
+			if existing.Status != cond.Status {
+				existing.Status = cond.Status
+				existing.LastTransitionTime = now
+				changed = true
+			}
+			if existing.Reason != cond.Reason || existing.Message != cond.Message {
+				existing.Reason = cond.Reason
+				existing.Message = cond.Message
+				changed = true
+			}
+		}
+		if !found {
+			cond.LastTransitionTime = now
+			status.Conditions = append(status.Conditions, cond)
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,203 @@
+package egress_router
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	. "github.com/onsi/gomega"
+)
+
+func routerPod(name string, ready bool, ips ...string) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	quoted, _ := json.Marshal(ips)
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      name,
+			Annotations: map[string]string{
+				networkStatusAnnotation: fmt.Sprintf(`[{"name": "ovn-kubernetes", "ips": ["10.128.0.5"], "default": true}, {"name": "ns/egress-router-cni-app-a", "interface": "net1", "ips": %s}]`, quoted),
+			},
+		},
+		Spec: corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestAvailableCondition(t *testing.T) {
+	router := egressRouter("app-a", time.Now(),
+		netopv1.EgressRouterAddress{IP: "192.168.12.99/24"},
+		netopv1.EgressRouterAddress{IP: "192.168.12.100/24"},
+	)
+	ready := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "egress-router-cni-app-a"},
+		Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+	}
+	notReady := ready.DeepCopy()
+	notReady.Status.ReadyReplicas = 0
+
+	for _, tc := range []struct {
+		name       string
+		ha         bool
+		holder     string
+		deployment *appsv1.Deployment
+		pods       []corev1.Pod
+		available  netopv1.ConditionStatus
+		reason     string
+		message    string
+	}{
+		{
+			name:      "no deployment",
+			available: netopv1.ConditionFalse,
+			reason:    "DeploymentNotFound",
+		},
+		{
+			name:       "deployment not ready",
+			deployment: notReady,
+			pods:       []corev1.Pod{routerPod("pod-1", false, "192.168.12.99", "192.168.12.100")},
+			available:  netopv1.ConditionFalse,
+			reason:     "DeploymentNotReady",
+		},
+		{
+			name:       "missing address",
+			deployment: ready,
+			pods:       []corev1.Pod{routerPod("pod-1", true, "192.168.12.99")},
+			available:  netopv1.ConditionFalse,
+			reason:     "AddressesNotConfigured",
+			message:    "pod pod-1 is missing 192.168.12.100/24",
+		},
+		{
+			name:       "available",
+			deployment: ready,
+			pods: []corev1.Pod{
+				routerPod("pod-0", false, "192.168.12.99", "192.168.12.100"),
+				routerPod("pod-1", true, "192.168.12.99", "192.168.12.100"),
+			},
+			available: netopv1.ConditionTrue,
+			reason:    "RouterReady",
+			message:   "Egress Router is served by pod pod-1 on node node-1",
+		},
+		{
+			name:       "high availability",
+			ha:         true,
+			holder:     "pod-1",
+			deployment: ready,
+			pods: []corev1.Pod{
+				routerPod("pod-0", false),
+				routerPod("pod-1", true),
+			},
+			available: netopv1.ConditionTrue,
+			reason:    "RouterReady",
+			message:   "Egress Router is served by pod pod-1 on node node-1; standby pods: pod-0 on node node-1",
+		},
+		{
+			name:       "high availability without standby",
+			ha:         true,
+			holder:     "pod-1",
+			deployment: ready,
+			pods:       []corev1.Pod{routerPod("pod-1", true)},
+			available:  netopv1.ConditionTrue,
+			reason:     "RouterReady",
+			message:    "no standby pod is running",
+		},
+		{
+			name:       "high availability without active pod",
+			ha:         true,
+			holder:     "pod-2",
+			deployment: notReady,
+			pods: []corev1.Pod{
+				routerPod("pod-0", false),
+				routerPod("pod-1", false),
+			},
+			available: netopv1.ConditionFalse,
+			reason:    "NoActivePod",
+			message:   "No pod holds the lease of the router, standby pods: pod-0 on node node-1, pod-1 on node node-1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			router := router.DeepCopy()
+			if tc.ha {
+				router.Annotations = map[string]string{names.EgressRouterHighAvailabilityAnnotation: "true"}
+			}
+			available, progressing := availableCondition(router, tc.deployment, tc.pods, tc.holder)
+			g.Expect(available.Status).To(Equal(tc.available))
+			g.Expect(available.Reason).To(Equal(tc.reason))
+			g.Expect(available.Message).To(ContainSubstring(tc.message))
+			if tc.available == netopv1.ConditionTrue {
+				g.Expect(progressing.Status).To(Equal(netopv1.ConditionFalse))
+			} else {
+				g.Expect(progressing.Status).To(Equal(netopv1.ConditionTrue))
+			}
+		})
+	}
+}
+
+func TestDegradedCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(degradedCondition(nil).Status).To(Equal(netopv1.ConditionFalse))
+
+	cond := degradedCondition(&invalidSpecError{err: fmt.Errorf("router without addresses")})
+	g.Expect(cond.Status).To(Equal(netopv1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal("InvalidSpec"))
+	g.Expect(cond.Message).To(Equal("router without addresses"))
+
+	cond = degradedCondition(fmt.Errorf("wrapped: %w", fmt.Errorf("apply failed")))
+	g.Expect(cond.Reason).To(Equal("ApplyFailed"))
+}
+
+func TestSetConditions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	status := &netopv1.EgressRouterStatus{}
+	first := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	g.Expect(setConditions(status, first,
+		netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterAvailable, Status: netopv1.ConditionFalse, Reason: "DeploymentNotReady"},
+		netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterDegraded, Status: netopv1.ConditionFalse, Reason: "AsExpected"},
+	)).To(BeTrue())
+	g.Expect(status.Conditions).To(HaveLen(2))
+
+	// nothing changed
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	g.Expect(setConditions(status, now,
+		netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterDegraded, Status: netopv1.ConditionFalse, Reason: "AsExpected"},
+	)).To(BeFalse())
+
+	g.Expect(setConditions(status, now,
+		netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterAvailable, Status: netopv1.ConditionTrue, Reason: "RouterReady"},
+		netopv1.EgressRouterStatusCondition{Type: netopv1.EgressRouterDegraded, Status: netopv1.ConditionFalse, Reason: "AsExpected", Message: "changed"},
+	)).To(BeTrue())
+	g.Expect(status.Conditions[0].LastTransitionTime).To(Equal(now))
+	g.Expect(status.Conditions[1].LastTransitionTime).To(Equal(first))
+	g.Expect(status.Conditions[1].Message).To(Equal("changed"))
+}
+
+func TestLeaseExpired(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now()
+	duration := int32(15)
+	lease := &coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{
+		RenewTime:            &metav1.MicroTime{Time: now.Add(-10 * time.Second)},
+		LeaseDurationSeconds: &duration,
+	}}
+	g.Expect(leaseExpired(lease, now)).To(BeFalse())
+	g.Expect(leaseExpired(lease, now.Add(10*time.Second))).To(BeTrue())
+	g.Expect(leaseExpired(&coordinationv1.Lease{}, now)).To(BeTrue())
+}
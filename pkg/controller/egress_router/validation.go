@@ -0,0 +1,174 @@
+package egress_router
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilnet "k8s.io/utils/net"
+)
+
+var (
+	supportedModes        = []string{string(netopv1.EgressRouterModeRedirect)}
+	supportedMacvlanModes = []string{string(netopv1.MacvlanModeBridge), string(netopv1.MacvlanModePrivate), string(netopv1.MacvlanModeVEPA), string(netopv1.MacvlanModePassthru)}
+	supportedProtocols    = []string{string(netopv1.ProtocolTypeTCP), string(netopv1.ProtocolTypeUDP), string(netopv1.ProtocolTypeSCTP)}
+)
+
+// validateEgressRouterSpec checks the spec of the router, so that invalid values
+// are reported in its status rather than rendered into broken manifests.
+func validateEgressRouterSpec(router *netopv1.EgressRouter) error {
+	errs := field.ErrorList{}
+
+	// the name is used as a label value for the pods of the router
+	if msgs := validation.IsValidLabelValue(router.Name); len(msgs) > 0 {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), router.Name, strings.Join(msgs, ", ")))
+	}
+
+	specPath := field.NewPath("spec")
+	if router.Spec.Mode != netopv1.EgressRouterModeRedirect {
+		errs = append(errs, field.NotSupported(specPath.Child("mode"), router.Spec.Mode, supportedModes))
+	}
+	switch router.Spec.NetworkInterface.Macvlan.Mode {
+	// an empty mode is defaulted by the CRD
+	case "", netopv1.MacvlanModeBridge, netopv1.MacvlanModePrivate, netopv1.MacvlanModeVEPA, netopv1.MacvlanModePassthru:
+	default:
+		errs = append(errs, field.NotSupported(specPath.Child("networkInterface", "macvlan", "mode"), router.Spec.NetworkInterface.Macvlan.Mode, supportedMacvlanModes))
+	}
+
+	reach, addressErrs := validateAddresses(specPath.Child("addresses"), router.Spec.Addresses, isHighAvailability(router))
+	errs = append(errs, addressErrs...)
+	errs = append(errs, validateRedirect(specPath.Child("redirect"), router.Spec.Redirect, reach)...)
+
+	return errs.ToAggregate()
+}
+
+// reachability is what the router can reach from its addresses: the subnets of
+// the addresses, and beyond them, the networks behind the gateway of each IP
+// family, keyed by whether it is IPv6.
+type reachability struct {
+	subnets  []*net.IPNet
+	gateways map[bool]net.IP
+}
+
+// validateAddresses checks that the addresses are CIDRs, and that their optional
+// gateway is in their subnet. The addresses of an IP family must agree on their
+// gateway. The egress router CNI plugin has a single gateway, so only routers in
+// high-availability mode, whose sidecar configures the default routes, can have
+// a gateway for each IP family.
+func validateAddresses(fldPath *field.Path, addresses []netopv1.EgressRouterAddress, highAvailability bool) (*reachability, field.ErrorList) {
+	errs := field.ErrorList{}
+	reach := &reachability{gateways: map[bool]net.IP{}}
+	if len(addresses) == 0 {
+		return reach, append(errs, field.Required(fldPath, "router without addresses"))
+	}
+
+	ips := map[string]bool{}
+	for i, address := range addresses {
+		ip, ipnet, err := net.ParseCIDR(address.IP)
+		if err != nil {
+			errs = append(errs, field.Invalid(fldPath.Index(i).Child("ip"), address.IP, "must be an IP address with a prefix length"))
+			continue
+		}
+		if ips[ip.String()] {
+			errs = append(errs, field.Duplicate(fldPath.Index(i).Child("ip"), address.IP))
+		}
+		ips[ip.String()] = true
+		reach.subnets = append(reach.subnets, ipnet)
+
+		if address.Gateway == "" {
+			continue
+		}
+		gwPath := fldPath.Index(i).Child("gateway")
+		gw := net.ParseIP(address.Gateway)
+		switch {
+		case gw == nil:
+			errs = append(errs, field.Invalid(gwPath, address.Gateway, "must be an IP address"))
+			continue
+		case utilnet.IsIPv6(gw) != utilnet.IsIPv6(ip):
+			errs = append(errs, field.Invalid(gwPath, address.Gateway, fmt.Sprintf("must be of the IP family of %s", address.IP)))
+			continue
+		case !ipnet.Contains(gw):
+			errs = append(errs, field.Invalid(gwPath, address.Gateway, fmt.Sprintf("must be in the subnet of %s", address.IP)))
+		case gw.Equal(ip):
+			errs = append(errs, field.Invalid(gwPath, address.Gateway, "must not be the address of the router"))
+		}
+		family := utilnet.IsIPv6(gw)
+		switch {
+		case reach.gateways[family] != nil && !reach.gateways[family].Equal(gw):
+			errs = append(errs, field.Invalid(gwPath, address.Gateway, fmt.Sprintf("only one gateway per IP family is supported, and %s is already set", reach.gateways[family])))
+		case reach.gateways[!family] != nil && !highAvailability:
+			errs = append(errs, field.Invalid(gwPath, address.Gateway, fmt.Sprintf("only one gateway is supported outside of high-availability mode, and %s is already set", reach.gateways[!family])))
+		default:
+			reach.gateways[family] = gw
+		}
+	}
+	return reach, errs
+}
+
+// validateRedirect checks the redirect rules and the fallback IP, and that the
+// router can reach their destinations.
+func validateRedirect(fldPath *field.Path, redirect *netopv1.RedirectConfig, reach *reachability) field.ErrorList {
+	errs := field.ErrorList{}
+	if redirect == nil || (len(redirect.RedirectRules) == 0 && redirect.FallbackIP == "") {
+		return append(errs, field.Required(fldPath, "the Redirect mode requires redirectRules or a fallbackIP"))
+	}
+
+	rules := map[string]bool{}
+	for i, rule := range redirect.RedirectRules {
+		rulePath := fldPath.Child("redirectRules").Index(i)
+		errs = append(errs, validateDestination(rulePath.Child("destinationIP"), rule.DestinationIP, reach)...)
+		for _, msg := range validation.IsValidPortNum(int(rule.Port)) {
+			errs = append(errs, field.Invalid(rulePath.Child("port"), rule.Port, msg))
+		}
+		if rule.TargetPort != 0 {
+			for _, msg := range validation.IsValidPortNum(int(rule.TargetPort)) {
+				errs = append(errs, field.Invalid(rulePath.Child("targetPort"), rule.TargetPort, msg))
+			}
+		}
+		switch rule.Protocol {
+		case netopv1.ProtocolTypeTCP, netopv1.ProtocolTypeUDP, netopv1.ProtocolTypeSCTP:
+		default:
+			errs = append(errs, field.NotSupported(rulePath.Child("protocol"), rule.Protocol, supportedProtocols))
+		}
+
+		key := fmt.Sprintf("%d/%s", rule.Port, rule.Protocol)
+		if rules[key] {
+			errs = append(errs, field.Duplicate(rulePath, fmt.Sprintf("port %d %s", rule.Port, rule.Protocol)))
+		}
+		rules[key] = true
+	}
+	if redirect.FallbackIP != "" {
+		errs = append(errs, validateDestination(fldPath.Child("fallbackIP"), redirect.FallbackIP, reach)...)
+	}
+	return errs
+}
+
+// validateDestination checks that the destination is in the subnet of an address
+// of the router, or that the router has a gateway of its IP family
+func validateDestination(fldPath *field.Path, destination string, reach *reachability) field.ErrorList {
+	ip := net.ParseIP(destination)
+	if ip == nil {
+		return field.ErrorList{field.Invalid(fldPath, destination, "must be an IP address")}
+	}
+	// the addresses are invalid, and already reported
+	if len(reach.subnets) == 0 {
+		return nil
+	}
+	if reach.gateways[utilnet.IsIPv6(ip)] != nil {
+		return nil
+	}
+	sameFamily := false
+	for _, subnet := range reach.subnets {
+		if subnet.Contains(ip) {
+			return nil
+		}
+		sameFamily = sameFamily || utilnet.IsIPv6(subnet.IP) == utilnet.IsIPv6(ip)
+	}
+	if !sameFamily {
+		return field.ErrorList{field.Invalid(fldPath, destination, "the router has no address of the IP family of the destination")}
+	}
+	return field.ErrorList{field.Invalid(fldPath, destination, "must be in the subnet of an address of the router, which has no gateway of the IP family of the destination")}
+}
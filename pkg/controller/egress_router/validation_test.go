@@ -0,0 +1,170 @@
+package egress_router
+
+import (
+	"testing"
+	"time"
+
+	netopv1 "github.com/openshift/api/networkoperator/v1"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestValidateEgressRouterSpec(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*netopv1.EgressRouter)
+		errs   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(*netopv1.EgressRouter) {},
+		},
+		{
+			name: "dual-stack",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Addresses = append(r.Spec.Addresses, netopv1.EgressRouterAddress{IP: "fd00:12::99/64"})
+				r.Spec.Redirect.RedirectRules = append(r.Spec.Redirect.RedirectRules,
+					netopv1.L4RedirectRule{DestinationIP: "fd00:12::5", Port: 8080, Protocol: netopv1.ProtocolTypeTCP, TargetPort: 80})
+			},
+		},
+		{
+			name: "dual-stack without IPv6 gateway",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Addresses = append(r.Spec.Addresses, netopv1.EgressRouterAddress{IP: "fd00:12::99/64"})
+				r.Spec.Redirect.FallbackIP = "fd00:99::2"
+			},
+			errs: []string{`spec.redirect.fallbackIP: Invalid value: "fd00:99::2": must be in the subnet of an address of the router, which has no gateway of the IP family of the destination`},
+		},
+		{
+			name: "dual-stack gateways",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Addresses = append(r.Spec.Addresses, netopv1.EgressRouterAddress{IP: "fd00:12::99/64", Gateway: "fd00:12::1"})
+			},
+			errs: []string{`spec.addresses[1].gateway: Invalid value: "fd00:12::1": only one gateway is supported outside of high-availability mode, and 192.168.12.1 is already set`},
+		},
+		{
+			name: "dual-stack gateways in high-availability mode",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Annotations = map[string]string{names.EgressRouterHighAvailabilityAnnotation: "true"}
+				r.Spec.Addresses = append(r.Spec.Addresses, netopv1.EgressRouterAddress{IP: "fd00:12::99/64", Gateway: "fd00:12::1"})
+				r.Spec.Redirect.FallbackIP = "fd00:99::2"
+			},
+		},
+		{
+			name: "IPv6",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Addresses = []netopv1.EgressRouterAddress{{IP: "fd00:12::99/64", Gateway: "fd00:12::1"}}
+				r.Spec.Redirect.RedirectRules = nil
+				r.Spec.Redirect.FallbackIP = "fd00:99::2"
+			},
+		},
+		{
+			name: "invalid addresses",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Addresses = []netopv1.EgressRouterAddress{
+					{IP: "192.168.12.99", Gateway: "192.168.12.1"},
+					{IP: "192.168.12.100/24", Gateway: "192.168.13.1"},
+					{IP: "192.168.12.100/32"},
+					{IP: "fd00:12::99/64", Gateway: "192.168.12.1"},
+				}
+			},
+			errs: []string{
+				`spec.addresses[0].ip: Invalid value: "192.168.12.99": must be an IP address with a prefix length`,
+				`spec.addresses[1].gateway: Invalid value: "192.168.13.1": must be in the subnet of 192.168.12.100/24`,
+				`spec.addresses[2].ip: Duplicate value: "192.168.12.100/32"`,
+				`spec.addresses[3].gateway: Invalid value: "192.168.12.1": must be of the IP family of fd00:12::99/64`,
+			},
+		},
+		{
+			name: "different gateways",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Addresses = append(r.Spec.Addresses, netopv1.EgressRouterAddress{IP: "192.168.12.100/24", Gateway: "192.168.12.2"})
+			},
+			errs: []string{"only one gateway per IP family is supported"},
+		},
+		{
+			name: "no gateway",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Addresses[0].Gateway = ""
+				r.Spec.Redirect.RedirectRules = []netopv1.L4RedirectRule{{DestinationIP: "192.168.12.5", Port: 80, Protocol: netopv1.ProtocolTypeTCP}}
+			},
+		},
+		{
+			name: "no gateway for the destination",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Addresses[0].Gateway = ""
+			},
+			errs: []string{"must be in the subnet of an address of the router, which has no gateway of the IP family of the destination"},
+		},
+		{
+			name: "no address",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Addresses = nil
+			},
+			errs: []string{"spec.addresses: Required value: router without addresses"},
+		},
+		{
+			name: "invalid redirect rules",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Redirect.RedirectRules = []netopv1.L4RedirectRule{
+					{DestinationIP: "10.0.0.99", Port: 0, Protocol: netopv1.ProtocolTypeTCP},
+					{DestinationIP: "10.0.0.99", Port: 80, Protocol: "ICMP"},
+					{DestinationIP: "10.0.0.99", Port: 443, Protocol: netopv1.ProtocolTypeTCP, TargetPort: 70000},
+					{DestinationIP: "10.0.0.100", Port: 443, Protocol: netopv1.ProtocolTypeTCP},
+					{DestinationIP: "fd00:99::1", Port: 8080, Protocol: netopv1.ProtocolTypeUDP},
+					{DestinationIP: "10.0.0", Port: 53, Protocol: netopv1.ProtocolTypeUDP},
+				}
+			},
+			errs: []string{
+				"spec.redirect.redirectRules[0].port: Invalid value: 0",
+				`spec.redirect.redirectRules[1].protocol: Unsupported value: "ICMP"`,
+				"spec.redirect.redirectRules[2].targetPort: Invalid value: 70000",
+				`spec.redirect.redirectRules[3]: Duplicate value: "port 443 TCP"`,
+				`spec.redirect.redirectRules[4].destinationIP: Invalid value: "fd00:99::1": the router has no address of the IP family of the destination`,
+				`spec.redirect.redirectRules[5].destinationIP: Invalid value: "10.0.0": must be an IP address`,
+			},
+		},
+		{
+			name: "fallback IP of another family",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Redirect.FallbackIP = "fd00:99::2"
+			},
+			errs: []string{`spec.redirect.fallbackIP: Invalid value: "fd00:99::2"`},
+		},
+		{
+			name: "no redirection",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Redirect = nil
+			},
+			errs: []string{"spec.redirect: Required value"},
+		},
+		{
+			name: "unsupported modes",
+			modify: func(r *netopv1.EgressRouter) {
+				r.Spec.Mode = "Proxy"
+				r.Spec.NetworkInterface.Macvlan.Mode = "Source"
+			},
+			errs: []string{
+				`spec.mode: Unsupported value: "Proxy"`,
+				`spec.networkInterface.macvlan.mode: Unsupported value: "Source"`,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			router := egressRouter("app-a", time.Now(), netopv1.EgressRouterAddress{IP: "192.168.12.99/24", Gateway: "192.168.12.1"})
+			tc.modify(router)
+			err := validateEgressRouterSpec(router)
+			if len(tc.errs) == 0 {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			for _, e := range tc.errs {
+				g.Expect(err.Error()).To(ContainSubstring(e))
+			}
+		})
+	}
+}
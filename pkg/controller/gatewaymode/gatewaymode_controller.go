@@ -0,0 +1,314 @@
+// Package gatewaymode implements a controller that migrates the OVN-Kubernetes
+// gateway mode of the nodes a batch at a time when gatewayConfig.routingViaHost
+// changes, rather than restarting every ovnkube-node at once.
+package gatewaymode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	operatorcontrolplanev1alpha1 "github.com/openshift/api/operatorcontrolplane/v1alpha1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+	"github.com/openshift/cluster-network-operator/pkg/util"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// pollPeriod is how often a migration in progress is checked
+const pollPeriod = 30 * time.Second
+
+// Add attaches our control loop to the manager and watches for nodes and the
+// operator configuration
+func Add(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client, _ featuregates.FeatureGate) error {
+	r := &ReconcileGatewayMode{client: c, status: status}
+	ctrl, err := controller.New("gateway-mode-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	err = ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &operv1.Network{}, handler.EnqueueRequestsFromMapFunc(reconcileGatewayMode),
+		predicate.Or[crclient.Object](predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})))
+	if err != nil {
+		return err
+	}
+
+	// Only the gateway mode reported by ovnkube-node matters
+	nodePredicate := predicate.Funcs{
+		UpdateFunc: func(ev event.UpdateEvent) bool {
+			return ev.ObjectOld.GetAnnotations()[l3GatewayConfigAnnotation] != ev.ObjectNew.GetAnnotations()[l3GatewayConfigAnnotation]
+		},
+	}
+	return ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &corev1.Node{}, handler.EnqueueRequestsFromMapFunc(reconcileGatewayMode), nodePredicate))
+}
+
+// reconcileGatewayMode maps every event to a single request, since the migration
+// covers the whole cluster
+func reconcileGatewayMode(_ context.Context, _ crclient.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name: names.OPERATOR_CONFIG,
+	}}}
+}
+
+var _ reconcile.Reconciler = &ReconcileGatewayMode{}
+
+// ReconcileGatewayMode migrates the nodes to the gateway mode of the operator
+// configuration
+type ReconcileGatewayMode struct {
+	client cnoclient.Client
+	status *statusmanager.StatusManager
+}
+
+// Reconcile moves the gateway mode migration one step forward. The operconfig
+// controller renders the ovnkube-gateway-mode ConfigMap from the state of the
+// migration; once it did, this controller restarts the ovnkube-node pods of the
+// current batch, waits for them to report the new mode through their node's
+// l3-gateway-config annotation and checks that they are reachable, rolling the
+// batch back otherwise.
+func (r *ReconcileGatewayMode) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
+
+	operConfig := &operv1.Network{}
+	if err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		klog.Errorf("Failed to get operator configuration: %v", err)
+		return reconcile.Result{}, err
+	}
+	if operConfig.Spec.DefaultNetwork.Type != operv1.NetworkTypeOVNKubernetes {
+		return reconcile.Result{}, nil
+	}
+	desired := network.DesiredGatewayMode(&operConfig.Spec)
+
+	modes := &corev1.ConfigMap{}
+	err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: util.OVN_NAMESPACE, Name: util.OVN_GATEWAY_MODE_CM_NAME}, modes)
+	if apierrors.IsNotFound(err) {
+		// not rendered yet
+		return reconcile.Result{RequeueAfter: pollPeriod}, nil
+	} else if err != nil {
+		klog.Errorf("Failed to get %s ConfigMap: %v", util.OVN_GATEWAY_MODE_CM_NAME, err)
+		return reconcile.Result{}, err
+	}
+
+	m, err := network.GetGatewayModeMigration(ctx, r.client.Default().CRClient())
+	if err != nil {
+		klog.Errorf("Failed to get gateway mode migration: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := r.client.Default().CRClient().List(ctx, nodes); err != nil {
+		klog.Errorf("Failed to list nodes: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	result, err := r.step(ctx, operConfig, desired, modes.Data, m, nodes.Items)
+	if err != nil {
+		r.status.SetDegraded(statusmanager.GatewayModeMigration, "GatewayModeMigrationError",
+			fmt.Sprintf("Internal error while migrating the gateway mode: %v", err))
+		return reconcile.Result{}, err
+	}
+	return result, nil
+}
+
+// step moves the migration m one step forward and reports its state
+func (r *ReconcileGatewayMode) step(ctx context.Context, operConfig *operv1.Network, desired string, modes map[string]string, m *network.GatewayModeMigration, nodes []corev1.Node) (reconcile.Result, error) {
+	current := modes[network.GatewayModeDefaultKey]
+
+	switch {
+	case m == nil || (m.Completed && m.To != desired):
+		if current == desired {
+			r.report(nil, len(nodes))
+			return reconcile.Result{}, nil
+		}
+		m = newMigration(current, desired, nodes)
+		klog.Infof("Starting gateway mode migration from %s to %s", m.From, m.To)
+
+	case !m.Completed && m.To != desired:
+		// The migration was reverted: every node goes back at once, as most of them
+		// never left the mode
+		if modes[network.GatewayModeDefaultKey] != desired || len(modes) > 1 {
+			r.report(m, len(nodes))
+			return reconcile.Result{RequeueAfter: pollPeriod}, nil
+		}
+		klog.Infof("Gateway mode migration from %s to %s reverted, restarting the migrated nodes", m.From, m.To)
+		reverted := sets.New(m.Migrated...).Insert(m.Batch...).Insert(m.Stuck...).UnsortedList()
+		if err := r.restartNodes(ctx, reverted, time.Now()); err != nil {
+			return reconcile.Result{}, err
+		}
+		m = &network.GatewayModeMigration{From: m.To, To: desired, Completed: true}
+
+	case m.Completed:
+		r.report(m, len(nodes))
+		return reconcile.Result{}, nil
+
+	case len(m.Failed) > 0:
+		// Halted; make sure the failed nodes are back in the mode they came from
+		if rendered(modes, m) {
+			if err := r.restartNodes(ctx, m.Failed, m.BatchStarted.Time); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+		r.report(m, len(nodes))
+		return reconcile.Result{RequeueAfter: pollPeriod}, nil
+
+	case len(m.Batch) == 0:
+		size, err := batchSize(operConfig, len(nodes))
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		m.Batch = nextBatch(m, nodes, size)
+		if len(m.Batch) == 0 {
+			klog.Infof("Gateway mode migration from %s to %s completed", m.From, m.To)
+			m.Completed = true
+		} else {
+			klog.Infof("Switching nodes %v to %s gateway mode", m.Batch, m.To)
+			m.BatchStarted = metav1.Now()
+		}
+
+	default:
+		if !rendered(modes, m) {
+			r.report(m, len(nodes))
+			return reconcile.Result{RequeueAfter: pollPeriod}, nil
+		}
+		if err := r.restartNodes(ctx, m.Batch, m.BatchStarted.Time); err != nil {
+			return reconcile.Result{}, err
+		}
+		checks, err := r.connectivityChecks(ctx)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		batch := checkBatch(m, nodes, checks, time.Now())
+		switch {
+		case batch.failed:
+			klog.Warningf("Rolling back nodes %v to %s gateway mode: %s", m.Batch, m.From, batch.describe())
+			m.Failed = m.Batch
+			m.Message = batch.describe()
+			m.Batch = nil
+			m.BatchStarted = metav1.Now()
+		case batch.done():
+			if stuck := batch.stuck(); len(stuck) > 0 {
+				klog.Warningf("Skipping nodes %v in the gateway mode migration: %s", stuck, batch.describe())
+				m.Stuck = append(m.Stuck, stuck...)
+			}
+			m.Migrated = append(m.Migrated, sets.List(sets.New(m.Batch...).Delete(m.Stuck...))...)
+			m.Batch = nil
+		default:
+			r.report(m, len(nodes))
+			return reconcile.Result{RequeueAfter: pollPeriod}, nil
+		}
+	}
+
+	if err := r.saveMigration(ctx, m); err != nil {
+		return reconcile.Result{}, err
+	}
+	r.report(m, len(nodes))
+	if m.Completed {
+		return reconcile.Result{}, nil
+	}
+	return reconcile.Result{RequeueAfter: pollPeriod}, nil
+}
+
+// report reflects the state of the migration in the operator status
+func (r *ReconcileGatewayMode) report(m *network.GatewayModeMigration, nodes int) {
+	cond := migrationCondition(m, nodes)
+	r.status.SetCondition(cond)
+	switch cond.Reason {
+	case "MigrationFailed":
+		r.status.UnsetProgressing(statusmanager.GatewayModeMigration)
+		r.status.SetDegraded(statusmanager.GatewayModeMigration, "GatewayModeMigrationFailed", cond.Message)
+	case "Migrating":
+		r.status.SetNotDegraded(statusmanager.GatewayModeMigration)
+		r.status.SetProgressing(statusmanager.GatewayModeMigration, "GatewayModeMigrating", cond.Message)
+	default:
+		r.status.SetNotDegraded(statusmanager.GatewayModeMigration)
+		r.status.UnsetProgressing(statusmanager.GatewayModeMigration)
+	}
+}
+
+// restartNodes deletes the ovnkube-node pods, created before the given time, of the
+// given nodes so that they start with the rendered gateway mode
+func (r *ReconcileGatewayMode) restartNodes(ctx context.Context, nodeNames []string, before time.Time) error {
+	pods := &corev1.PodList{}
+	if err := r.client.Default().CRClient().List(ctx, pods, crclient.InNamespace(util.OVN_NAMESPACE), crclient.MatchingLabels{"app": util.OVN_NODE}); err != nil {
+		return fmt.Errorf("failed to list %s pods: %w", util.OVN_NODE, err)
+	}
+	restart := sets.New(nodeNames...)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !restart.Has(pod.Spec.NodeName) || !pod.CreationTimestamp.Time.Before(before) || pod.DeletionTimestamp != nil {
+			continue
+		}
+		klog.Infof("Restarting %s/%s to switch the gateway mode of node %s", pod.Namespace, pod.Name, pod.Spec.NodeName)
+		if err := r.client.Default().CRClient().Delete(ctx, pod); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// connectivityChecks returns the network diagnostics connectivity checks, if any
+func (r *ReconcileGatewayMode) connectivityChecks(ctx context.Context) ([]operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck, error) {
+	checks := &operatorcontrolplanev1alpha1.PodNetworkConnectivityCheckList{}
+	err := r.client.Default().CRClient().List(ctx, checks, crclient.InNamespace("openshift-network-diagnostics"))
+	if meta.IsNoMatchError(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list connectivity checks: %w", err)
+	}
+	return checks.Items, nil
+}
+
+// saveMigration stores the state of the migration, which triggers the operconfig
+// controller to render the gateway modes accordingly
+func (r *ReconcileGatewayMode) saveMigration(ctx context.Context, m *network.GatewayModeMigration) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = r.client.Default().CRClient().Get(ctx, types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: names.GatewayModeMigrationConfigMap}, cm)
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: names.APPLIED_NAMESPACE,
+				Name:      names.GatewayModeMigrationConfigMap,
+			},
+			Data: map[string]string{network.GatewayModeMigrationKey: string(data)},
+		}
+		if err := r.client.Default().CRClient().Create(ctx, cm); err != nil {
+			return fmt.Errorf("failed to create gateway mode migration state: %w", err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get gateway mode migration state: %w", err)
+	}
+
+	cm.Data = map[string]string{network.GatewayModeMigrationKey: string(data)}
+	if err := r.client.Default().CRClient().Update(ctx, cm); err != nil {
+		return fmt.Errorf("failed to update gateway mode migration state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package gatewaymode
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	operatorcontrolplanev1alpha1 "github.com/openshift/api/operatorcontrolplane/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/cluster-network-operator/pkg/controller/connectivitycheck"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+)
+
+const (
+	// l3GatewayConfigAnnotation is set by ovnkube-node on its node to the gateway
+	// configuration it runs with, e.g. {"default":{"mode":"shared",...}}
+	l3GatewayConfigAnnotation = "k8s.ovn.org/l3-gateway-config"
+
+	// defaultBatchFraction is the fraction of the nodes switched to the new gateway
+	// mode at a time, unless overridden by names.GatewayModeMigrationBatchSizeAnnotation
+	defaultBatchFraction = 0.1
+
+	// batchTimeout is how long the nodes of a batch have to report the new gateway
+	// mode and pass their connectivity checks before the batch is rolled back, or
+	// the nodes that did not report any gateway mode are skipped
+	batchTimeout = 10 * time.Minute
+
+	// unreachableGracePeriod is how long a connectivity check of a switched node may
+	// fail before its batch is rolled back
+	unreachableGracePeriod = 2 * time.Minute
+)
+
+// effectiveGatewayMode returns the gateway mode ovnkube-node reported it runs with
+// on node, or "" if it did not report any
+func effectiveGatewayMode(node *corev1.Node) string {
+	annotation, ok := node.Annotations[l3GatewayConfigAnnotation]
+	if !ok {
+		return ""
+	}
+	config := map[string]struct {
+		Mode string `json:"mode"`
+	}{}
+	if err := json.Unmarshal([]byte(annotation), &config); err != nil {
+		return ""
+	}
+	return config["default"].Mode
+}
+
+// batchSize returns the number of nodes to switch to the new gateway mode at a time
+func batchSize(operConfig *operv1.Network, nodes int) (int, error) {
+	if value, ok := operConfig.Annotations[names.GatewayModeMigrationBatchSizeAnnotation]; ok {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			return 0, fmt.Errorf("invalid %s annotation %q: must be a positive integer", names.GatewayModeMigrationBatchSizeAnnotation, value)
+		}
+		return size, nil
+	}
+	return max(int(math.Ceil(defaultBatchFraction*float64(nodes))), 1), nil
+}
+
+// newMigration returns the state of a migration from the given mode to the desired
+// one. Nodes that already run in the desired mode are considered migrated.
+func newMigration(from, desired string, nodes []corev1.Node) *network.GatewayModeMigration {
+	m := &network.GatewayModeMigration{From: from, To: desired}
+	for i := range nodes {
+		if effectiveGatewayMode(&nodes[i]) == desired {
+			m.Migrated = append(m.Migrated, nodes[i].Name)
+		}
+	}
+	sort.Strings(m.Migrated)
+	return m
+}
+
+// nextBatch returns, by name, up to size nodes that were neither migrated nor
+// skipped yet
+func nextBatch(m *network.GatewayModeMigration, nodes []corev1.Node, size int) []string {
+	switched := sets.New(m.Migrated...).Insert(m.Stuck...)
+	remaining := []string{}
+	for _, node := range nodes {
+		if !switched.Has(node.Name) {
+			remaining = append(remaining, node.Name)
+		}
+	}
+	sort.Strings(remaining)
+	if len(remaining) > size {
+		remaining = remaining[:size]
+	}
+	return remaining
+}
+
+// rendered returns true if the rendered gateway modes match the state of the
+// migration, so that restarted ovnkube-node pods pick up the right mode
+func rendered(modes map[string]string, m *network.GatewayModeMigration) bool {
+	return maps.Equal(modes, network.GatewayModes(m.To, "", m))
+}
+
+// batchResult is the outcome so far of switching a batch of nodes
+type batchResult struct {
+	// pending are the nodes of the batch that still report the old mode
+	pending []string
+	// unreported are the nodes of the batch that never reported any mode
+	unreported []string
+	// unreachable are the nodes of the batch with failing connectivity checks
+	unreachable []string
+	// timedOut is set once the batch ran for longer than batchTimeout
+	timedOut bool
+	// failed is set if the batch must be rolled back
+	failed bool
+}
+
+// checkBatch checks whether the nodes of the current batch of m switched to the new
+// gateway mode and are reachable. Nodes without connectivity checks, for example
+// because network diagnostics are disabled, are only required to switch.
+//
+// ovnkube-node keeps the l3-gateway-config annotation of its node across restarts,
+// so a node that fails to come back in the new mode still reports the old one and
+// gets its batch rolled back. A node that never reported any mode does not run
+// ovnkube-node at all; it is only waited for until the batch times out.
+func checkBatch(m *network.GatewayModeMigration, nodes []corev1.Node, checks []operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck, now time.Time) *batchResult {
+	result := &batchResult{}
+	byName := map[string]*corev1.Node{}
+	for i := range nodes {
+		byName[nodes[i].Name] = &nodes[i]
+	}
+
+	for _, name := range m.Batch {
+		node, ok := byName[name]
+		if !ok {
+			// the node was removed
+			continue
+		}
+		mode := effectiveGatewayMode(node)
+		if mode == "" {
+			result.unreported = append(result.unreported, name)
+			continue
+		}
+		if mode != m.To {
+			result.pending = append(result.pending, name)
+			continue
+		}
+
+		suffix := connectivitycheck.TargetNodeCheckSuffix(name)
+		for _, check := range checks {
+			if !strings.HasSuffix(check.Name, suffix) {
+				continue
+			}
+			reachable := reachableCondition(&check)
+			if reachable == nil || reachable.Status != metav1.ConditionFalse {
+				continue
+			}
+			result.unreachable = append(result.unreachable, name)
+			if now.Sub(reachable.LastTransitionTime.Time) > unreachableGracePeriod {
+				result.failed = true
+			}
+			break
+		}
+	}
+
+	result.timedOut = now.Sub(m.BatchStarted.Time) > batchTimeout
+	if (len(result.pending) > 0 || len(result.unreachable) > 0) && result.timedOut {
+		result.failed = true
+	}
+	return result
+}
+
+// done returns true if all the nodes of the batch switched and are reachable, but
+// for the ones that did not report any mode before the batch timed out
+func (b *batchResult) done() bool {
+	return len(b.pending) == 0 && len(b.unreachable) == 0 && (len(b.unreported) == 0 || b.timedOut)
+}
+
+// stuck returns the nodes to skip once the batch is done
+func (b *batchResult) stuck() []string {
+	if !b.done() {
+		return nil
+	}
+	return b.unreported
+}
+
+// describe returns why the batch failed, or what it is waiting for
+func (b *batchResult) describe() string {
+	messages := []string{}
+	if len(b.pending) > 0 {
+		messages = append(messages, fmt.Sprintf("nodes %s did not report the new gateway mode", strings.Join(b.pending, ", ")))
+	}
+	if len(b.unreported) > 0 {
+		messages = append(messages, fmt.Sprintf("nodes %s did not report any gateway mode", strings.Join(b.unreported, ", ")))
+	}
+	if len(b.unreachable) > 0 {
+		messages = append(messages, fmt.Sprintf("nodes %s failed their connectivity checks", strings.Join(b.unreachable, ", ")))
+	}
+	return strings.Join(messages, "; ")
+}
+
+func reachableCondition(check *operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck) *operatorcontrolplanev1alpha1.PodNetworkConnectivityCheckCondition {
+	for i := range check.Status.Conditions {
+		if check.Status.Conditions[i].Type == operatorcontrolplanev1alpha1.Reachable {
+			return &check.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// migrationCondition returns the GatewayModeMigrationProgressing condition for the
+// state of the migration
+func migrationCondition(m *network.GatewayModeMigration, nodes int) operv1.OperatorCondition {
+	switch {
+	case m == nil || m.Completed:
+		cond := operv1.OperatorCondition{
+			Type:   names.GatewayModeMigrationCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+		if m != nil && len(m.Stuck) > 0 {
+			cond.Message = fmt.Sprintf("Migrated from %s to %s gateway mode, nodes %s were skipped: %s",
+				m.From, m.To, strings.Join(m.Stuck, ", "), stuckMessage(m))
+		}
+		return cond
+	case len(m.Failed) > 0:
+		return operv1.OperatorCondition{
+			Type:   names.GatewayModeMigrationCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "MigrationFailed",
+			Message: fmt.Sprintf("Migration from %s to %s gateway mode halted after %d of %d nodes, nodes %s were rolled back: %s. "+
+				"Revert gatewayConfig.routingViaHost to go back to %s gateway mode, or delete ConfigMap %s/%s to retry.",
+				m.From, m.To, len(m.Migrated), nodes, strings.Join(m.Failed, ", "), m.Message,
+				m.From, names.APPLIED_NAMESPACE, names.GatewayModeMigrationConfigMap),
+		}
+	default:
+		msg := fmt.Sprintf("Migrating from %s to %s gateway mode: %d of %d nodes migrated", m.From, m.To, len(m.Migrated), nodes)
+		if len(m.Batch) > 0 {
+			msg += fmt.Sprintf(", switching nodes %s", strings.Join(m.Batch, ", "))
+		}
+		if len(m.Stuck) > 0 {
+			msg += fmt.Sprintf(", nodes %s were skipped: %s", strings.Join(m.Stuck, ", "), stuckMessage(m))
+		}
+		return operv1.OperatorCondition{
+			Type:    names.GatewayModeMigrationCondition,
+			Status:  operv1.ConditionTrue,
+			Reason:  "Migrating",
+			Message: msg,
+		}
+	}
+}
+
+// stuckMessage explains what happens to the nodes skipped by the migration m
+func stuckMessage(m *network.GatewayModeMigration) string {
+	return fmt.Sprintf("they did not report any gateway mode within %v and start in %s gateway mode once ovnkube-node runs on them",
+		batchTimeout, m.To)
+}
@@ -0,0 +1,230 @@
+package gatewaymode
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	operatorcontrolplanev1alpha1 "github.com/openshift/api/operatorcontrolplane/v1alpha1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var now = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+func gatewayNode(name, mode string) corev1.Node {
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if mode != "" {
+		node.Annotations = map[string]string{
+			l3GatewayConfigAnnotation: fmt.Sprintf(`{"default":{"mode":"%s","interface-id":"br-ex_%s"}}`, mode, name),
+		}
+	}
+	return node
+}
+
+func connectivityCheck(target string, reachable metav1.ConditionStatus, since time.Duration) operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck {
+	return operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "network-check-source-node-x-to-network-check-target-" + target},
+		Status: operatorcontrolplanev1alpha1.PodNetworkConnectivityCheckStatus{
+			Conditions: []operatorcontrolplanev1alpha1.PodNetworkConnectivityCheckCondition{{
+				Type:               operatorcontrolplanev1alpha1.Reachable,
+				Status:             reachable,
+				LastTransitionTime: metav1.NewTime(now.Add(-since)),
+			}},
+		},
+	}
+}
+
+func TestEffectiveGatewayMode(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	node := gatewayNode("a", "local")
+	g.Expect(effectiveGatewayMode(&node)).To(Equal("local"))
+	node = gatewayNode("a", "")
+	g.Expect(effectiveGatewayMode(&node)).To(Equal(""))
+	node.Annotations = map[string]string{l3GatewayConfigAnnotation: "garbage"}
+	g.Expect(effectiveGatewayMode(&node)).To(Equal(""))
+}
+
+func TestBatches(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	nodes := []corev1.Node{}
+	for i := 24; i >= 0; i-- {
+		nodes = append(nodes, gatewayNode(fmt.Sprintf("node-%02d", i), "shared"))
+	}
+	nodes[0] = gatewayNode("node-24", "local")
+
+	operConfig := &operv1.Network{}
+	size, err := batchSize(operConfig, len(nodes))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(size).To(Equal(3))
+
+	operConfig.Annotations = map[string]string{names.GatewayModeMigrationBatchSizeAnnotation: "2"}
+	size, err = batchSize(operConfig, len(nodes))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(size).To(Equal(2))
+
+	operConfig.Annotations[names.GatewayModeMigrationBatchSizeAnnotation] = "0"
+	_, err = batchSize(operConfig, len(nodes))
+	g.Expect(err).To(HaveOccurred())
+
+	m := newMigration("shared", "local", nodes)
+	g.Expect(m.Migrated).To(Equal([]string{"node-24"}))
+	g.Expect(nextBatch(m, nodes, 2)).To(Equal([]string{"node-00", "node-01"}))
+
+	m.Migrated = append(m.Migrated, "node-00")
+	m.Stuck = append(m.Stuck, "node-01")
+	g.Expect(nextBatch(m, nodes, 2)).To(Equal([]string{"node-02", "node-03"}))
+}
+
+func TestRendered(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	m := &network.GatewayModeMigration{From: "shared", To: "local", Migrated: []string{"a"}, Batch: []string{"b"}}
+	g.Expect(rendered(map[string]string{"default": "shared", "a": "local"}, m)).To(BeFalse())
+	g.Expect(rendered(map[string]string{"default": "shared", "a": "local", "b": "local"}, m)).To(BeTrue())
+
+	// a rolled back batch must no longer be rendered
+	m = &network.GatewayModeMigration{From: "shared", To: "local", Migrated: []string{"a"}, Failed: []string{"b"}}
+	g.Expect(rendered(map[string]string{"default": "shared", "a": "local", "b": "local"}, m)).To(BeFalse())
+	g.Expect(rendered(map[string]string{"default": "shared", "a": "local"}, m)).To(BeTrue())
+}
+
+func TestCheckBatch(t *testing.T) {
+	testcases := []struct {
+		name        string
+		started     time.Duration
+		nodes       []corev1.Node
+		checks      []operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck
+		done        bool
+		failed      bool
+		stuck       []string
+		description string
+	}{
+		{
+			name:    "switched and reachable",
+			started: time.Minute,
+			nodes:   []corev1.Node{gatewayNode("a", "local"), gatewayNode("b.example.com", "local")},
+			checks: []operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck{
+				connectivityCheck("a", metav1.ConditionTrue, time.Hour),
+				connectivityCheck("b", metav1.ConditionTrue, time.Hour),
+				connectivityCheck("c", metav1.ConditionFalse, time.Hour),
+			},
+			done: true,
+		},
+		{
+			name:        "still switching",
+			started:     time.Minute,
+			nodes:       []corev1.Node{gatewayNode("a", "local"), gatewayNode("b.example.com", "shared")},
+			description: "nodes b.example.com did not report the new gateway mode",
+		},
+		{
+			name:        "not switched in time",
+			started:     batchTimeout + time.Minute,
+			nodes:       []corev1.Node{gatewayNode("a", "local"), gatewayNode("b.example.com", "shared")},
+			failed:      true,
+			description: "nodes b.example.com did not report the new gateway mode",
+		},
+		{
+			name:        "waiting for a node without gateway mode",
+			started:     time.Minute,
+			nodes:       []corev1.Node{gatewayNode("a", "local"), gatewayNode("b.example.com", "")},
+			description: "nodes b.example.com did not report any gateway mode",
+		},
+		{
+			name:        "node without gateway mode skipped",
+			started:     batchTimeout + time.Minute,
+			nodes:       []corev1.Node{gatewayNode("a", "local"), gatewayNode("b.example.com", "")},
+			done:        true,
+			stuck:       []string{"b.example.com"},
+			description: "nodes b.example.com did not report any gateway mode",
+		},
+		{
+			name:        "node without gateway mode in a failed batch",
+			started:     batchTimeout + time.Minute,
+			nodes:       []corev1.Node{gatewayNode("a", "shared"), gatewayNode("b.example.com", "")},
+			failed:      true,
+			description: "nodes a did not report the new gateway mode; nodes b.example.com did not report any gateway mode",
+		},
+		{
+			name:    "briefly unreachable",
+			started: time.Minute,
+			nodes:   []corev1.Node{gatewayNode("a", "local"), gatewayNode("b.example.com", "local")},
+			checks: []operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck{
+				connectivityCheck("a", metav1.ConditionFalse, 10*time.Second),
+			},
+			description: "nodes a failed their connectivity checks",
+		},
+		{
+			name:    "unreachable",
+			started: 5 * time.Minute,
+			nodes:   []corev1.Node{gatewayNode("a", "local"), gatewayNode("b.example.com", "local")},
+			checks: []operatorcontrolplanev1alpha1.PodNetworkConnectivityCheck{
+				connectivityCheck("a", metav1.ConditionTrue, time.Hour),
+				connectivityCheck("b", metav1.ConditionFalse, 3*time.Minute),
+			},
+			failed:      true,
+			description: "nodes b.example.com failed their connectivity checks",
+		},
+		{
+			name:    "removed node",
+			started: time.Minute,
+			nodes:   []corev1.Node{gatewayNode("a", "local")},
+			done:    true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			m := &network.GatewayModeMigration{
+				From:         "shared",
+				To:           "local",
+				Batch:        []string{"a", "b.example.com"},
+				BatchStarted: metav1.NewTime(now.Add(-tc.started)),
+			}
+			result := checkBatch(m, tc.nodes, tc.checks, now)
+			g.Expect(result.done()).To(Equal(tc.done))
+			g.Expect(result.failed).To(Equal(tc.failed))
+			g.Expect(result.stuck()).To(Equal(tc.stuck))
+			g.Expect(result.describe()).To(Equal(tc.description))
+		})
+	}
+}
+
+func TestMigrationCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cond := migrationCondition(nil, 3)
+	g.Expect(cond.Type).To(Equal(names.GatewayModeMigrationCondition))
+	g.Expect(cond.Status).To(Equal(operv1.ConditionFalse))
+
+	m := &network.GatewayModeMigration{From: "shared", To: "local", Migrated: []string{"a"}, Batch: []string{"b"}}
+	cond = migrationCondition(m, 3)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Message).To(Equal("Migrating from shared to local gateway mode: 1 of 3 nodes migrated, switching nodes b"))
+
+	m = &network.GatewayModeMigration{From: "shared", To: "local", Migrated: []string{"a"}, Batch: []string{"c"}, Stuck: []string{"b"}}
+	cond = migrationCondition(m, 3)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Message).To(Equal("Migrating from shared to local gateway mode: 1 of 3 nodes migrated, switching nodes c, nodes b were skipped: " +
+		"they did not report any gateway mode within 10m0s and start in local gateway mode once ovnkube-node runs on them"))
+
+	m = &network.GatewayModeMigration{From: "shared", To: "local", Migrated: []string{"a", "c"}, Stuck: []string{"b"}, Completed: true}
+	cond = migrationCondition(m, 3)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionFalse))
+	g.Expect(cond.Reason).To(Equal("AsExpected"))
+	g.Expect(cond.Message).To(HavePrefix("Migrated from shared to local gateway mode, nodes b were skipped"))
+
+	m = &network.GatewayModeMigration{From: "shared", To: "local", Migrated: []string{"a"}, Failed: []string{"b"}, Message: "nodes b failed their connectivity checks"}
+	cond = migrationCondition(m, 3)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionFalse))
+	g.Expect(cond.Reason).To(Equal("MigrationFailed"))
+	g.Expect(cond.Message).To(HavePrefix("Migration from shared to local gateway mode halted after 1 of 3 nodes, nodes b were rolled back: nodes b failed their connectivity checks."))
+}
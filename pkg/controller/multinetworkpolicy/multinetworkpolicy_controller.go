@@ -0,0 +1,180 @@
+// Package multinetworkpolicy implements a controller that restricts where
+// MultiNetworkPolicies can be written, according to the scope set in the operator
+// configuration, and reports how many policies are enforced on each network.
+package multinetworkpolicy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/apply"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/render"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// resyncPeriod is how often the policies are summarized. The
+	// MultiNetworkPolicy CRD only exists when useMultiNetworkPolicy is set, so
+	// policies are polled rather than watched.
+	resyncPeriod = 5 * time.Minute
+
+	manifestDir = "bindata/network/multus-networkpolicy-scope"
+
+	admissionPolicyName        = "multi-networkpolicy-scope"
+	admissionPolicyBindingName = "multi-networkpolicy-scope-binding"
+)
+
+// multiNetworkPolicyGVK is the kind of the MultiNetworkPolicies
+var multiNetworkPolicyGVK = schema.GroupVersionKind{Group: "k8s.cni.cncf.io", Version: "v1beta1", Kind: "MultiNetworkPolicy"}
+
+// Add attaches our control loop to the manager and watches for the operator
+// configuration
+func Add(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client, _ featuregates.FeatureGate) error {
+	r := &ReconcileMultiNetworkPolicy{client: c, status: status}
+	ctrl, err := controller.New("multi-network-policy-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &operv1.Network{}, handler.EnqueueRequestsFromMapFunc(reconcileMultiNetworkPolicy),
+		predicate.GenerationChangedPredicate{}))
+}
+
+// reconcileMultiNetworkPolicy maps every event to a single request, since the
+// scope is set for the whole cluster
+func reconcileMultiNetworkPolicy(_ context.Context, _ crclient.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name: names.OPERATOR_CONFIG,
+	}}}
+}
+
+var _ reconcile.Reconciler = &ReconcileMultiNetworkPolicy{}
+
+// ReconcileMultiNetworkPolicy enforces the scope of MultiNetworkPolicies
+type ReconcileMultiNetworkPolicy struct {
+	client cnoclient.Client
+	status *statusmanager.StatusManager
+}
+
+// Reconcile renders the admission policy rejecting the MultiNetworkPolicies outside
+// of the configured scope, or removes it when no scope is set, and sets the
+// MultiNetworkPoliciesEnforced condition.
+func (r *ReconcileMultiNetworkPolicy) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
+
+	operConfig := &operv1.Network{}
+	if err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		klog.Errorf("Failed to get operator configuration: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	if (operConfig.Spec.DisableMultiNetwork != nil && *operConfig.Spec.DisableMultiNetwork) ||
+		operConfig.Spec.UseMultiNetworkPolicy == nil || !*operConfig.Spec.UseMultiNetworkPolicy {
+		if err := r.deleteAdmissionPolicy(ctx); err != nil {
+			klog.Errorf("Failed to delete the MultiNetworkPolicy admission policy: %v", err)
+			return reconcile.Result{}, err
+		}
+		r.status.SetNotDegraded(statusmanager.MultiNetworkPolicyConfig)
+		r.status.SetCondition(enforcementCondition(nil))
+		return reconcile.Result{}, nil
+	}
+
+	s, err := parseScope(&operConfig.Spec)
+	if err != nil {
+		klog.Errorf("Invalid MultiNetworkPolicy scope: %v", err)
+		r.status.SetDegraded(statusmanager.MultiNetworkPolicyConfig, "InvalidMultiNetworkPolicyScope", err.Error())
+		return reconcile.Result{}, nil
+	}
+	if s == nil {
+		err = r.deleteAdmissionPolicy(ctx)
+	} else {
+		err = r.applyAdmissionPolicy(ctx, s)
+	}
+	if err != nil {
+		klog.Errorf("Failed to update the MultiNetworkPolicy admission policy: %v", err)
+		r.status.SetDegraded(statusmanager.MultiNetworkPolicyConfig, "MultiNetworkPolicyScopeError",
+			fmt.Sprintf("Failed to update the MultiNetworkPolicy admission policy: %v", err))
+		return reconcile.Result{}, err
+	}
+	r.status.SetNotDegraded(statusmanager.MultiNetworkPolicyConfig)
+
+	policies := &uns.UnstructuredList{}
+	policies.SetGroupVersionKind(multiNetworkPolicyGVK.GroupVersion().WithKind(multiNetworkPolicyGVK.Kind + "List"))
+	if err := r.client.Default().CRClient().List(ctx, policies); err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		klog.Errorf("Failed to list MultiNetworkPolicies: %v", err)
+		return reconcile.Result{}, err
+	}
+	namespaceLabels := map[string]labels.Set{}
+	if s != nil {
+		namespaces := &corev1.NamespaceList{}
+		if err := r.client.Default().CRClient().List(ctx, namespaces); err != nil {
+			klog.Errorf("Failed to list namespaces: %v", err)
+			return reconcile.Result{}, err
+		}
+		for _, ns := range namespaces.Items {
+			namespaceLabels[ns.Name] = ns.Labels
+		}
+	}
+	r.status.SetCondition(enforcementCondition(summarize(s, policies.Items, namespaceLabels)))
+
+	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// applyAdmissionPolicy renders and applies the admission policy for the scope
+func (r *ReconcileMultiNetworkPolicy) applyAdmissionPolicy(ctx context.Context, s *scope) error {
+	data := render.MakeRenderData()
+	data.Data["ReleaseVersion"] = os.Getenv("RELEASE_VERSION")
+	data.Data["NamespaceSelectedExpression"] = s.namespaceSelectedExpression()
+	data.Data["SelectedNetworksExpression"] = s.selectedNetworksExpression()
+	manifests, err := render.RenderDir(manifestDir, &data)
+	if err != nil {
+		return err
+	}
+	for _, obj := range manifests {
+		if err := apply.ApplyObject(ctx, r.client, obj, "multi-network-policy"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteAdmissionPolicy removes the admission policy, if any
+func (r *ReconcileMultiNetworkPolicy) deleteAdmissionPolicy(ctx context.Context) error {
+	for kind, name := range map[string]string{
+		"ValidatingAdmissionPolicyBinding": admissionPolicyBindingName,
+		"ValidatingAdmissionPolicy":        admissionPolicyName,
+	} {
+		obj := &uns.Unstructured{}
+		obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "admissionregistration.k8s.io", Version: "v1", Kind: kind})
+		obj.SetName(name)
+		if err := r.client.Default().CRClient().Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s %s: %w", kind, name, err)
+		}
+	}
+	return nil
+}
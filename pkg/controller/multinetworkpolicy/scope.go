@@ -0,0 +1,203 @@
+package multinetworkpolicy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+)
+
+// policyForAnnotation lists the networks a MultiNetworkPolicy applies to
+const policyForAnnotation = "k8s.v1.cni.cncf.io/policy-for"
+
+// maxReported is the number of networks named in the condition
+const maxReported = 10
+
+// scope is where MultiNetworkPolicies can be written: in the namespaces matching
+// namespaces, or for the NetworkAttachmentDefinitions in networks
+type scope struct {
+	namespaces labels.Selector
+	networks   sets.Set[string]
+}
+
+// parseScope returns the scope set in the unsupportedConfigOverrides of the
+// operator configuration, or nil if MultiNetworkPolicies are not scoped
+func parseScope(conf *operv1.NetworkSpec) (*scope, error) {
+	config, err := network.GetMultiNetworkPolicyScope(conf)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	selector, err := config.Namespaces()
+	if err != nil {
+		return nil, fmt.Errorf("invalid multiNetworkPolicyScope namespaceSelector: %w", err)
+	}
+	return &scope{namespaces: selector, networks: sets.New(config.Networks...)}, nil
+}
+
+// namespaceSelectedExpression returns the CEL expression of the admission policy
+// that is true when the namespace of the policy is selected
+func (s *scope) namespaceSelectedExpression() string {
+	reqs, selectable := s.namespaces.Requirements()
+	if !selectable {
+		return "false"
+	}
+	if len(reqs) == 0 {
+		return "true"
+	}
+
+	terms := []string{}
+	for _, r := range reqs {
+		key := strconv.Quote(r.Key())
+		hasKey := fmt.Sprintf("(has(namespaceObject.metadata.labels) && %s in namespaceObject.metadata.labels)", key)
+		values := celList(r.Values().List())
+		switch r.Operator() {
+		case selection.Exists:
+			terms = append(terms, hasKey)
+		case selection.DoesNotExist:
+			terms = append(terms, "!"+hasKey)
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			terms = append(terms, fmt.Sprintf("(%s && namespaceObject.metadata.labels[%s] in %s)", hasKey, key, values))
+		case selection.NotEquals, selection.NotIn:
+			terms = append(terms, fmt.Sprintf("(!%s || !(namespaceObject.metadata.labels[%s] in %s))", hasKey, key, values))
+		}
+	}
+	return strings.Join(terms, " && ")
+}
+
+// selectedNetworksExpression returns the CEL list of the selected networks
+func (s *scope) selectedNetworksExpression() string {
+	return celList(sets.List(s.networks))
+}
+
+func celList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// allows returns whether a MultiNetworkPolicy, for the given networks in a
+// namespace with the given labels, can be written in the scope
+func (s *scope) allows(namespaceLabels labels.Set, networks []string) bool {
+	if s == nil || s.namespaces.Matches(namespaceLabels) {
+		return true
+	}
+	return len(networks) > 0 && s.networks.HasAll(networks...)
+}
+
+// policyNetworks returns the namespace/name of the networks a MultiNetworkPolicy
+// applies to. Networks without a namespace are in the namespace of the policy.
+func policyNetworks(policy *uns.Unstructured) []string {
+	value, ok := policy.GetAnnotations()[policyForAnnotation]
+	if !ok {
+		return nil
+	}
+	networks := []string{}
+	for _, network := range strings.Split(value, ",") {
+		network = strings.TrimSpace(network)
+		if !strings.Contains(network, "/") {
+			network = policy.GetNamespace() + "/" + network
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// enforcement is the MultiNetworkPolicies enforced on each network
+type enforcement struct {
+	// policies is the namespace/name of the policies enforced on each network
+	policies map[string][]string
+	// outOfScope is the policies written before the scope was set, that would
+	// not be allowed now but are still enforced
+	outOfScope []string
+	// unbound is the policies without a network to apply to, that are ignored
+	unbound []string
+}
+
+// summarize returns which MultiNetworkPolicies are enforced on which networks
+func summarize(s *scope, policies []uns.Unstructured, namespaceLabels map[string]labels.Set) *enforcement {
+	e := &enforcement{policies: map[string][]string{}, outOfScope: []string{}, unbound: []string{}}
+	for i := range policies {
+		policy := &policies[i]
+		name := policy.GetNamespace() + "/" + policy.GetName()
+		networks := policyNetworks(policy)
+		if len(networks) == 0 {
+			e.unbound = append(e.unbound, name)
+			continue
+		}
+		if !s.allows(namespaceLabels[policy.GetNamespace()], networks) {
+			e.outOfScope = append(e.outOfScope, name)
+		}
+		for _, network := range networks {
+			e.policies[network] = append(e.policies[network], name)
+		}
+	}
+	for _, p := range e.policies {
+		sort.Strings(p)
+	}
+	sort.Strings(e.outOfScope)
+	sort.Strings(e.unbound)
+	return e
+}
+
+// enforcementCondition reports how many MultiNetworkPolicies are enforced on each
+// network. The names of the policies are not reported: they belong to the tenants
+// of the cluster, while the ClusterOperator can be read much more widely.
+func enforcementCondition(e *enforcement) operv1.OperatorCondition {
+	if e == nil {
+		return operv1.OperatorCondition{
+			Type:   names.MultiNetworkPoliciesEnforcedCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "Disabled",
+		}
+	}
+
+	messages := []string{}
+	if len(e.policies) > 0 {
+		networks := sets.List(sets.KeySet(e.policies))
+		enforced := []string{}
+		for _, network := range networks {
+			enforced = append(enforced, fmt.Sprintf("%s (%d)", network, len(e.policies[network])))
+		}
+		messages = append(messages, fmt.Sprintf("MultiNetworkPolicies are enforced on %d network(s): %s", len(networks), reported(enforced, ", ")))
+	}
+	if len(e.outOfScope) > 0 {
+		messages = append(messages, fmt.Sprintf("%d MultiNetworkPolicies outside of the configured scope are still enforced", len(e.outOfScope)))
+	}
+	if len(e.unbound) > 0 {
+		messages = append(messages, fmt.Sprintf("%d MultiNetworkPolicies have no %s annotation and are ignored", len(e.unbound), policyForAnnotation))
+	}
+
+	if len(e.policies) == 0 {
+		return operv1.OperatorCondition{
+			Type:    names.MultiNetworkPoliciesEnforcedCondition,
+			Status:  operv1.ConditionFalse,
+			Reason:  "NoPolicies",
+			Message: strings.Join(messages, ". "),
+		}
+	}
+	return operv1.OperatorCondition{
+		Type:    names.MultiNetworkPoliciesEnforcedCondition,
+		Status:  operv1.ConditionTrue,
+		Reason:  "PoliciesEnforced",
+		Message: strings.Join(messages, ". "),
+	}
+}
+
+// reported joins at most maxReported items
+func reported(items []string, sep string) string {
+	if len(items) > maxReported {
+		items = append(items[:maxReported:maxReported], fmt.Sprintf("and %d more", len(items)-maxReported))
+	}
+	return strings.Join(items, sep)
+}
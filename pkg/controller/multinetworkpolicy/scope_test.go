@@ -0,0 +1,145 @@
+package multinetworkpolicy
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/render"
+)
+
+func policy(namespace, name, policyFor string) uns.Unstructured {
+	obj := uns.Unstructured{}
+	obj.SetGroupVersionKind(multiNetworkPolicyGVK)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	if policyFor != "" {
+		obj.SetAnnotations(map[string]string{policyForAnnotation: policyFor})
+	}
+	return obj
+}
+
+// scopeConfig returns an operator configuration with the given
+// unsupportedConfigOverrides
+func scopeConfig(overrides string) *operv1.NetworkSpec {
+	conf := &operv1.NetworkSpec{}
+	conf.UnsupportedConfigOverrides.Raw = []byte(overrides)
+	return conf
+}
+
+func TestParseScope(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, err := parseScope(&operv1.NetworkSpec{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s).To(BeNil())
+
+	s, err = parseScope(scopeConfig(`{"controlPlaneSizing": {"profile": "small"}}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s).To(BeNil())
+
+	s, err = parseScope(scopeConfig(`{"multiNetworkPolicyScope": {
+		"namespaceSelector": {"matchExpressions": [{"key": "team", "operator": "In", "values": ["a", "b"]}, {"key": "legacy", "operator": "DoesNotExist"}]},
+		"networks": ["shared/net1", "shared/net2"]}}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.namespaces.Matches(labels.Set{"team": "a"})).To(BeTrue())
+	g.Expect(s.namespaces.Matches(labels.Set{"team": "a", "legacy": ""})).To(BeFalse())
+	g.Expect(s.selectedNetworksExpression()).To(Equal(`["shared/net1", "shared/net2"]`))
+	g.Expect(s.namespaceSelectedExpression()).To(Equal(
+		`!(has(namespaceObject.metadata.labels) && "legacy" in namespaceObject.metadata.labels) && ` +
+			`((has(namespaceObject.metadata.labels) && "team" in namespaceObject.metadata.labels) && namespaceObject.metadata.labels["team"] in ["a", "b"])`))
+
+	// only networks: no namespace is selected
+	s, err = parseScope(scopeConfig(`{"multiNetworkPolicyScope": {"networks": ["shared/net1"]}}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(s.namespaceSelectedExpression()).To(Equal("false"))
+	g.Expect(s.allows(labels.Set{}, []string{"shared/net1"})).To(BeTrue())
+	g.Expect(s.allows(labels.Set{}, []string{"shared/net1", "ns/net3"})).To(BeFalse())
+
+	for _, overrides := range []string{
+		`{"multiNetworkPolicyScope": {"namespaceSelector": {"matchExpressions": [{"key": "team", "operator": "In"}]}}}`,
+		`{"multiNetworkPolicyScope": {"networks": "shared/net1"}}`,
+	} {
+		_, err = parseScope(scopeConfig(overrides))
+		g.Expect(err).To(HaveOccurred(), overrides)
+	}
+}
+
+func TestAdmissionPolicyRender(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, err := parseScope(scopeConfig(`{"multiNetworkPolicyScope": {"namespaceSelector": {"matchExpressions": [{"key": "team", "operator": "Exists"}]}}}`))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	data := render.MakeRenderData()
+	data.Data["ReleaseVersion"] = "4.99"
+	data.Data["NamespaceSelectedExpression"] = s.namespaceSelectedExpression()
+	data.Data["SelectedNetworksExpression"] = s.selectedNetworksExpression()
+	objs, err := render.RenderDir("../../../"+manifestDir, &data)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(2))
+	g.Expect(objs[0].GetName()).To(Equal(admissionPolicyName))
+	g.Expect(objs[1].GetName()).To(Equal(admissionPolicyBindingName))
+
+	variables, _, _ := uns.NestedSlice(objs[0].Object, "spec", "variables")
+	g.Expect(variables).To(ConsistOf(
+		map[string]any{"name": "namespaceSelected", "expression": `(has(namespaceObject.metadata.labels) && "team" in namespaceObject.metadata.labels)`},
+		map[string]any{"name": "selectedNetworks", "expression": "[]"},
+	))
+}
+
+func TestEnforcementCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, err := parseScope(scopeConfig(`{"multiNetworkPolicyScope": {
+		"namespaceSelector": {"matchLabels": {"multi-network-policy": "enabled"}},
+		"networks": ["shared/net1"]}}`))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	policies := []uns.Unstructured{
+		policy("team-a", "deny-all", "net1"),
+		policy("team-a", "allow-db", "net1, shared/net1"),
+		policy("team-b", "allow-web", "shared/net1"),
+		policy("team-c", "old", "net1"),
+		policy("team-c", "unbound", ""),
+	}
+	namespaceLabels := map[string]labels.Set{
+		"team-a": {"multi-network-policy": "enabled"},
+	}
+
+	// the policies outside of the scope are still enforced
+	e := summarize(s, policies, namespaceLabels)
+	g.Expect(e.policies).To(Equal(map[string][]string{
+		"team-a/net1": {"team-a/allow-db", "team-a/deny-all"},
+		"shared/net1": {"team-a/allow-db", "team-b/allow-web"},
+		"team-c/net1": {"team-c/old"},
+	}))
+	g.Expect(e.outOfScope).To(Equal([]string{"team-c/old"}))
+	g.Expect(enforcementCondition(e)).To(Equal(operv1.OperatorCondition{
+		Type:   names.MultiNetworkPoliciesEnforcedCondition,
+		Status: operv1.ConditionTrue,
+		Reason: "PoliciesEnforced",
+		Message: "MultiNetworkPolicies are enforced on 3 network(s): shared/net1 (2), team-a/net1 (2), team-c/net1 (1). " +
+			"1 MultiNetworkPolicies outside of the configured scope are still enforced. " +
+			"1 MultiNetworkPolicies have no k8s.v1.cni.cncf.io/policy-for annotation and are ignored",
+	}))
+
+	// without a scope, all the policies are allowed
+	e = summarize(nil, policies, nil)
+	g.Expect(e.outOfScope).To(BeEmpty())
+
+	// at most maxReported networks are named
+	many := []uns.Unstructured{}
+	for i := 0; i < maxReported+2; i++ {
+		many = append(many, policy("team-a", "deny-all", fmt.Sprintf("net%02d", i)))
+	}
+	g.Expect(enforcementCondition(summarize(nil, many, nil)).Message).To(HaveSuffix("team-a/net09 (1), and 2 more"))
+
+	g.Expect(enforcementCondition(summarize(s, nil, nil)).Reason).To(Equal("NoPolicies"))
+	g.Expect(enforcementCondition(nil).Reason).To(Equal("Disabled"))
+}
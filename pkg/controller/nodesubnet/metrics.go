@@ -0,0 +1,44 @@
+package nodesubnet
+
+import (
+	"strconv"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const cnoNamespace = "openshift_network_operator"
+
+var (
+	nodeSubnetsUsedGauge      *metrics.GaugeVec
+	nodeSubnetsRemainingGauge *metrics.GaugeVec
+)
+
+func init() {
+	nodeSubnetsUsedGauge = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace: cnoNamespace,
+		Name:      "cluster_network_node_subnets_used",
+		Help:      "The number of per-node subnets of a clusterNetwork entry that are assigned to a node.",
+	}, []string{"cidr", "host_prefix"})
+	nodeSubnetsRemainingGauge = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace: cnoNamespace,
+		Name:      "cluster_network_node_subnets_remaining",
+		Help:      "The number of per-node subnets of a clusterNetwork entry that are still free.",
+	}, []string{"cidr", "host_prefix"})
+	legacyregistry.MustRegister(nodeSubnetsUsedGauge)
+	legacyregistry.MustRegister(nodeSubnetsRemainingGauge)
+}
+
+// updateMetrics replaces the node subnet gauges with the given usage, so that
+// removed clusterNetwork entries stop being reported.
+func updateMetrics(u *clusterNetworkUsage) {
+	nodeSubnetsUsedGauge.Reset()
+	nodeSubnetsRemainingGauge.Reset()
+	for _, f := range u.families {
+		for _, e := range f.entries {
+			hostPrefix := strconv.FormatUint(uint64(e.entry.HostPrefix), 10)
+			nodeSubnetsUsedGauge.WithLabelValues(e.entry.CIDR, hostPrefix).Set(float64(e.used))
+			nodeSubnetsRemainingGauge.WithLabelValues(e.entry.CIDR, hostPrefix).Set(e.remaining())
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package nodesubnet implements a controller that monitors how many of the
+// per-node subnets of each clusterNetwork entry are in use, so that the cluster
+// network can be expanded before new nodes fail to get a subnet.
+package nodesubnet
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/platform"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// resyncPeriod is how often usage is recomputed when nothing changes, so that
+// nodes leaving the growth window are accounted for
+const resyncPeriod = 30 * time.Minute
+
+// Add attaches our control loop to the manager and watches for nodes and the
+// operator configuration
+func Add(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client, _ featuregates.FeatureGate) error {
+	r := &ReconcileNodeSubnets{client: c, status: status}
+	ctrl, err := controller.New("node-subnet-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	err = ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &operv1.Network{}, handler.EnqueueRequestsFromMapFunc(reconcileNodeSubnets), predicate.GenerationChangedPredicate{}))
+	if err != nil {
+		return err
+	}
+
+	// Only the subnets assigned to the node matter, and node status changes a lot
+	nodePredicate := predicate.Funcs{
+		UpdateFunc: func(ev event.UpdateEvent) bool {
+			old, ok := ev.ObjectOld.(*corev1.Node)
+			if !ok {
+				return true
+			}
+			new, ok := ev.ObjectNew.(*corev1.Node)
+			if !ok {
+				return true
+			}
+			return old.Annotations[ovnNodeSubnetsAnnotation] != new.Annotations[ovnNodeSubnetsAnnotation] ||
+				!reflect.DeepEqual(old.Spec.PodCIDRs, new.Spec.PodCIDRs)
+		},
+	}
+	return ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &corev1.Node{}, handler.EnqueueRequestsFromMapFunc(reconcileNodeSubnets), nodePredicate))
+}
+
+// reconcileNodeSubnets maps every event to a single request, since usage is always
+// computed for the whole cluster
+func reconcileNodeSubnets(_ context.Context, _ crclient.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name: names.OPERATOR_CONFIG,
+	}}}
+}
+
+var _ reconcile.Reconciler = &ReconcileNodeSubnets{}
+
+// ReconcileNodeSubnets tracks the per-node subnet usage of the cluster network
+type ReconcileNodeSubnets struct {
+	client cnoclient.Client
+	status *statusmanager.StatusManager
+}
+
+// Reconcile computes how many per-node subnets of each clusterNetwork entry are in
+// use, exports the result as metrics and sets the ClusterNetworkNodeSubnetsLow
+// condition, with a suggestion on how to grow the cluster network, when they are
+// running out.
+func (r *ReconcileNodeSubnets) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
+
+	operConfig := &operv1.Network{}
+	if err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		klog.Errorf("Failed to get operator configuration: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := r.client.Default().CRClient().List(ctx, nodes); err != nil {
+		klog.Errorf("Failed to list nodes: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	infraStatus, err := platform.InfraStatus(r.client)
+	if err != nil {
+		klog.Errorf("Failed to get infrastructure status: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	usage := computeUsage(operConfig.Spec.ClusterNetwork, nodes.Items, time.Now())
+	updateMetrics(usage)
+	r.status.SetCondition(nodeSubnetsCondition(&operConfig.Spec, infraStatus.MachineNetworks, usage))
+
+	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// nodeSubnetsCondition returns the ClusterNetworkNodeSubnetsLow condition for the
+// given usage
+func nodeSubnetsCondition(conf *operv1.NetworkSpec, machineNetworks []string, u *clusterNetworkUsage) operv1.OperatorCondition {
+	messages := []string{}
+	for _, f := range u.families {
+		if !f.isLow(u.growth) {
+			continue
+		}
+		msg := fmt.Sprintf("The cluster network is running out of node subnets: %s; %d nodes were added in the last %d days",
+			f.describe(), u.growth, int(growthWindow.Hours()/24))
+		if suggestion := suggestClusterNetwork(conf, machineNetworks, f); suggestion != "" {
+			msg += fmt.Sprintf(". Suggestion: %s", suggestion)
+		}
+		messages = append(messages, msg)
+	}
+
+	if len(messages) == 0 {
+		return operv1.OperatorCondition{
+			Type:   names.ClusterNetworkNodeSubnetsLowCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+	return operv1.OperatorCondition{
+		Type:    names.ClusterNetworkNodeSubnetsLowCondition,
+		Status:  operv1.ConditionTrue,
+		Reason:  "NodeSubnetsLow",
+		Message: strings.Join(messages, "\n"),
+	}
+}
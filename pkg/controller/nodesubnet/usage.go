@@ -0,0 +1,239 @@
+package nodesubnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	utilnet "k8s.io/utils/net"
+
+	"github.com/openshift/cluster-network-operator/pkg/network"
+	iputil "github.com/openshift/cluster-network-operator/pkg/util/ip"
+)
+
+const (
+	// ovnNodeSubnetsAnnotation is set by ovn-kubernetes on every node to the subnets
+	// it allocated to the node, e.g. {"default":["10.128.0.0/23","fd01:0:0:1::/64"]}
+	ovnNodeSubnetsAnnotation = "k8s.ovn.org/node-subnets"
+
+	// lowThreshold is the fraction of the node subnets of an IP family that may be
+	// in use before the cluster network is reported as running low
+	lowThreshold = 0.8
+
+	// growthWindow is the period over which node growth is measured. The cluster
+	// network is also reported as running low if fewer node subnets are left than
+	// nodes were added during the last growthWindow.
+	growthWindow = 7 * 24 * time.Hour
+)
+
+var (
+	// v4SuggestionRanges and v6SuggestionRanges are searched for an additional
+	// clusterNetwork entry when no existing entry can be expanded
+	v4SuggestionRanges = []string{"10.0.0.0/8", "172.16.0.0/12"}
+	v6SuggestionRanges = []string{"fd00::/8"}
+)
+
+// entryUsage is the per-node subnet usage of a single clusterNetwork entry
+type entryUsage struct {
+	entry operv1.ClusterNetworkEntry
+	cidr  *net.IPNet
+	// capacity is the number of node subnets the entry can hold
+	capacity float64
+	// used is the number of node subnets of the entry assigned to a node
+	used int
+}
+
+// remaining returns the number of node subnets of the entry that are still free
+func (e *entryUsage) remaining() float64 {
+	return math.Max(e.capacity-float64(e.used), 0)
+}
+
+// familyUsage is the per-node subnet usage of all the clusterNetwork entries of one
+// IP family
+type familyUsage struct {
+	ipv6    bool
+	entries []*entryUsage
+	// unassigned is the number of nodes that have no subnet of this family yet
+	unassigned int
+}
+
+// clusterNetworkUsage is the per-node subnet usage of the cluster network
+type clusterNetworkUsage struct {
+	families []*familyUsage
+	// growth is the number of nodes created during the last growthWindow
+	growth int
+}
+
+// computeUsage attributes the subnets assigned to each node to the clusterNetwork
+// entry they were allocated from.
+func computeUsage(cns []operv1.ClusterNetworkEntry, nodes []corev1.Node, now time.Time) *clusterNetworkUsage {
+	u := &clusterNetworkUsage{}
+	var v4, v6 *familyUsage
+	for _, cn := range cns {
+		_, cidr, err := net.ParseCIDR(cn.CIDR)
+		if err != nil {
+			continue
+		}
+		ones, _ := cidr.Mask.Size()
+		e := &entryUsage{
+			entry:    cn,
+			cidr:     cidr,
+			capacity: math.Ldexp(1, int(cn.HostPrefix)-ones),
+		}
+		if utilnet.IsIPv6CIDR(cidr) {
+			if v6 == nil {
+				v6 = &familyUsage{ipv6: true}
+				u.families = append(u.families, v6)
+			}
+			v6.entries = append(v6.entries, e)
+		} else {
+			if v4 == nil {
+				v4 = &familyUsage{}
+				u.families = append(u.families, v4)
+			}
+			v4.entries = append(v4.entries, e)
+		}
+	}
+
+	for i := range nodes {
+		node := &nodes[i]
+		if now.Sub(node.CreationTimestamp.Time) < growthWindow {
+			u.growth++
+		}
+		subnets := nodeSubnets(node)
+		for _, f := range u.families {
+			assigned := false
+			for _, subnet := range subnets {
+				if utilnet.IsIPv6CIDR(subnet) != f.ipv6 {
+					continue
+				}
+				for _, e := range f.entries {
+					if e.cidr.Contains(subnet.IP) {
+						e.used++
+						assigned = true
+						break
+					}
+				}
+			}
+			if !assigned {
+				f.unassigned++
+			}
+		}
+	}
+	return u
+}
+
+// nodeSubnets returns the pod subnets assigned to node. The subnets allocated by
+// ovn-kubernetes take precedence over the node's podCIDRs, which ovn-kubernetes
+// does not use.
+func nodeSubnets(node *corev1.Node) []*net.IPNet {
+	cidrs := node.Spec.PodCIDRs
+	if annotation, ok := node.Annotations[ovnNodeSubnetsAnnotation]; ok {
+		subnets := map[string]json.RawMessage{}
+		if err := json.Unmarshal([]byte(annotation), &subnets); err == nil && subnets["default"] != nil {
+			// Note: older releases of ovn-kubernetes annotate a single subnet as a string
+			var list []string
+			if err := json.Unmarshal(subnets["default"], &list); err != nil {
+				var single string
+				if err := json.Unmarshal(subnets["default"], &single); err == nil {
+					list = []string{single}
+				}
+			}
+			cidrs = list
+		}
+	}
+
+	out := []*net.IPNet{}
+	for _, c := range cidrs {
+		if _, cidr, err := net.ParseCIDR(c); err == nil {
+			out = append(out, cidr)
+		}
+	}
+	return out
+}
+
+// isLow returns true if the node subnets of the family are running out, either
+// because more than lowThreshold of them are in use or because fewer are left than
+// nodes were added during the last growthWindow.
+func (f *familyUsage) isLow(growth int) bool {
+	var capacity, remaining float64
+	for _, e := range f.entries {
+		capacity += e.capacity
+		remaining += e.remaining()
+	}
+	remaining -= float64(f.unassigned)
+	return capacity-remaining >= lowThreshold*capacity || remaining < float64(growth)
+}
+
+// describe returns a human readable summary of the usage of the family
+func (f *familyUsage) describe() string {
+	entries := []string{}
+	for _, e := range f.entries {
+		entries = append(entries, fmt.Sprintf("%s (hostPrefix %d): %d of %.0f node subnets in use", e.entry.CIDR, e.entry.HostPrefix, e.used, e.capacity))
+	}
+	if f.unassigned > 0 {
+		entries = append(entries, fmt.Sprintf("%d nodes waiting for a subnet", f.unassigned))
+	}
+	return strings.Join(entries, ", ")
+}
+
+// suggestClusterNetwork returns a suggestion to grow the cluster network of the
+// given family: expanding one of its entries if the larger CIDR keeps the same
+// network address and is free, or adding an entry of the same size otherwise. The
+// other clusterNetwork entries, the machine networks and the networks returned by
+// network.ClusterNetworkReservedSubnets are not free.
+func suggestClusterNetwork(conf *operv1.NetworkSpec, machineNetworks []string, f *familyUsage) string {
+	reserved := network.ClusterNetworkReservedSubnets(conf, machineNetworks)
+	reserveAllExcept := func(except string) *iputil.SubnetPlanner {
+		planner := &iputil.SubnetPlanner{}
+		for _, cn := range conf.ClusterNetwork {
+			if cn.CIDR != except {
+				_ = planner.ReserveString(cn.CIDR)
+			}
+		}
+		for _, r := range reserved {
+			_ = planner.ReserveString(r)
+		}
+		return planner
+	}
+
+	for _, e := range f.entries {
+		ones, bits := e.cidr.Mask.Size()
+		if ones <= 1 {
+			continue
+		}
+		expanded := net.IPNet{IP: e.cidr.IP, Mask: net.CIDRMask(ones-1, bits)}
+		if !e.cidr.IP.Mask(expanded.Mask).Equal(e.cidr.IP) {
+			// expanding would change the network address, which is unsupported
+			continue
+		}
+		if reserveAllExcept(e.entry.CIDR).Available(expanded) {
+			return fmt.Sprintf("expand clusterNetwork entry %s to %s", e.entry.CIDR, expanded.String())
+		}
+	}
+
+	if len(f.entries) == 0 {
+		return ""
+	}
+	ranges := v4SuggestionRanges
+	if f.ipv6 {
+		ranges = v6SuggestionRanges
+	}
+	candidates := []net.IPNet{}
+	for _, r := range ranges {
+		_, cidr, _ := net.ParseCIDR(r)
+		candidates = append(candidates, *cidr)
+	}
+	first := f.entries[0]
+	ones, _ := first.cidr.Mask.Size()
+	subnet, err := reserveAllExcept("").Allocate(ones, candidates...)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("add a clusterNetwork entry with cidr %s and hostPrefix %d", subnet.String(), first.entry.HostPrefix)
+}
@@ -0,0 +1,163 @@
+package nodesubnet
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var now = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+func ovnNode(name string, age time.Duration, annotation string) corev1.Node {
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(now.Add(-age)),
+		},
+	}
+	if annotation != "" {
+		node.Annotations = map[string]string{ovnNodeSubnetsAnnotation: annotation}
+	}
+	return node
+}
+
+// ovnNodes returns count nodes, created a month ago, with consecutive /hostPrefix
+// subnets of the given IPv4 network
+func ovnNodes(count int, network string, hostPrefix int) []corev1.Node {
+	nodes := []corev1.Node{}
+	for i := 0; i < count; i++ {
+		subnet := fmt.Sprintf("%s.%d.0/%d", network, i*(1<<(24-hostPrefix)), hostPrefix)
+		nodes = append(nodes, ovnNode(fmt.Sprintf("node%d", i), 30*24*time.Hour, fmt.Sprintf(`{"default":["%s"]}`, subnet)))
+	}
+	return nodes
+}
+
+func TestComputeUsage(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cns := []operv1.ClusterNetworkEntry{
+		{CIDR: "10.128.0.0/20", HostPrefix: 23},
+		{CIDR: "10.132.0.0/22", HostPrefix: 24},
+		{CIDR: "fd01::/48", HostPrefix: 64},
+	}
+	nodes := []corev1.Node{
+		ovnNode("a", time.Hour, `{"default":["10.128.0.0/23","fd01::/64"]}`),
+		ovnNode("b", 30*24*time.Hour, `{"default":"10.128.2.0/23"}`),
+		ovnNode("c", 30*24*time.Hour, `{"default":["10.132.1.0/24"]}`),
+		ovnNode("d", 2*24*time.Hour, ""),
+	}
+	nodes[3].Spec.PodCIDRs = []string{"10.132.2.0/24"}
+	nodes = append(nodes, ovnNode("e", time.Minute, ""))
+
+	u := computeUsage(cns, nodes, now)
+	g.Expect(u.growth).To(Equal(3))
+	g.Expect(u.families).To(HaveLen(2))
+
+	v4 := u.families[0]
+	g.Expect(v4.ipv6).To(BeFalse())
+	g.Expect(v4.entries[0].capacity).To(Equal(8.0))
+	g.Expect(v4.entries[0].used).To(Equal(2))
+	g.Expect(v4.entries[0].remaining()).To(Equal(6.0))
+	g.Expect(v4.entries[1].capacity).To(Equal(4.0))
+	g.Expect(v4.entries[1].used).To(Equal(2))
+	g.Expect(v4.unassigned).To(Equal(1))
+
+	v6 := u.families[1]
+	g.Expect(v6.ipv6).To(BeTrue())
+	g.Expect(v6.entries[0].capacity).To(Equal(65536.0))
+	g.Expect(v6.entries[0].used).To(Equal(1))
+	g.Expect(v6.unassigned).To(Equal(4))
+}
+
+func TestNodeSubnetsCondition(t *testing.T) {
+	testcases := []struct {
+		name            string
+		clusterNetwork  []operv1.ClusterNetworkEntry
+		defaultNetwork  operv1.DefaultNetworkDefinition
+		machineNetworks []string
+		nodes           []corev1.Node
+		low             bool
+		suggestion      string
+	}{
+		{
+			name:           "plenty left",
+			clusterNetwork: []operv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/20", HostPrefix: 24}},
+			nodes:          ovnNodes(4, "10.128", 24),
+		},
+		{
+			name:           "above threshold, expand",
+			clusterNetwork: []operv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/21", HostPrefix: 24}},
+			nodes:          ovnNodes(7, "10.128", 24),
+			low:            true,
+			suggestion:     "expand clusterNetwork entry 10.128.0.0/21 to 10.128.0.0/20",
+		},
+		{
+			name:           "growth exceeds what is left",
+			clusterNetwork: []operv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/20", HostPrefix: 24}},
+			nodes: func() []corev1.Node {
+				// 12 of 16 subnets in use, 5 of them by nodes added this week
+				nodes := ovnNodes(12, "10.128", 24)
+				for i := 7; i < 12; i++ {
+					nodes[i].CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+				}
+				return nodes
+			}(),
+			low:        true,
+			suggestion: "expand clusterNetwork entry 10.128.0.0/20 to 10.128.0.0/19",
+		},
+		{
+			name: "expansion collides, add an entry",
+			clusterNetwork: []operv1.ClusterNetworkEntry{
+				{CIDR: "10.0.0.0/21", HostPrefix: 24},
+				{CIDR: "10.0.8.0/21", HostPrefix: 24},
+			},
+			nodes:      ovnNodes(16, "10.0", 24),
+			low:        true,
+			suggestion: "add a clusterNetwork entry with cidr 10.0.16.0/21 and hostPrefix 24",
+		},
+		{
+			name:           "machine, hybrid overlay and internal subnets are not free",
+			clusterNetwork: []operv1.ClusterNetworkEntry{{CIDR: "10.0.0.0/21", HostPrefix: 24}},
+			defaultNetwork: operv1.DefaultNetworkDefinition{
+				Type: operv1.NetworkTypeOVNKubernetes,
+				OVNKubernetesConfig: &operv1.OVNKubernetesConfig{
+					HybridOverlayConfig: &operv1.HybridOverlayConfig{
+						HybridClusterNetwork: []operv1.ClusterNetworkEntry{{CIDR: "10.0.16.0/21", HostPrefix: 24}},
+					},
+					IPv4: &operv1.IPv4OVNKubernetesConfig{InternalTransitSwitchSubnet: "10.0.24.0/21"},
+				},
+			},
+			machineNetworks: []string{"10.0.8.0/24"},
+			nodes:           ovnNodes(8, "10.0", 24),
+			low:             true,
+			suggestion:      "add a clusterNetwork entry with cidr 10.0.32.0/21 and hostPrefix 24",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			conf := &operv1.NetworkSpec{
+				ClusterNetwork: tc.clusterNetwork,
+				ServiceNetwork: []string{"172.30.0.0/16"},
+				DefaultNetwork: tc.defaultNetwork,
+			}
+
+			cond := nodeSubnetsCondition(conf, tc.machineNetworks, computeUsage(conf.ClusterNetwork, tc.nodes, now))
+			g.Expect(cond.Type).To(Equal(names.ClusterNetworkNodeSubnetsLowCondition))
+			if !tc.low {
+				g.Expect(cond.Status).To(Equal(operv1.ConditionFalse))
+				return
+			}
+			g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+			g.Expect(cond.Message).To(HaveSuffix("Suggestion: " + tc.suggestion))
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package operconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+)
+
+// fieldManager is the field manager the operconfig controller applies objects with
+const fieldManager = "cluster-network-operator/" + ControllerName
+
+// watchNetworkAttachmentDefinitions starts watching the NetworkAttachmentDefinitions of
+// all namespaces, so that the DHCP daemon and the whereabouts reconciler are rendered
+// as soon as one of them needs it, and the ones rendered from spec.additionalNetworks
+// are restored as soon as they are changed or deleted. The CRD is rendered along with
+// Multus, so the watch is started once the rendered manifests were applied, rather
+// than with the controller.
+func (r *ReconcileOperConfig) watchNetworkAttachmentDefinitions(conf *operv1.NetworkSpec) {
+	if r.nadWatchStarted || conf.DisableMultiNetwork == nil || *conf.DisableMultiNetwork {
+		return
+	}
+	r.nadWatchStarted = true
+
+	gvr := network.NetworkAttachmentDefinitionGVK.GroupVersion().WithResource("network-attachment-definitions")
+	inf := dynamicinformer.NewFilteredDynamicInformer(
+		r.client.Default().Dynamic(),
+		gvr,
+		metav1.NamespaceAll,
+		0, // don't resync
+		cache.Indexers{},
+		nil).Informer()
+
+	_, err := inf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if dhcp, whereabouts := auxiliaryIPAM(obj); dhcp || whereabouts {
+				r.enqueueNetworkAttachmentDefinition(obj)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			oldDHCP, oldWhereabouts := auxiliaryIPAM(oldObj)
+			newDHCP, newWhereabouts := auxiliaryIPAM(newObj)
+			if oldDHCP != newDHCP || oldWhereabouts != newWhereabouts {
+				r.enqueueNetworkAttachmentDefinition(newObj)
+				return
+			}
+			oldNAD, ok := oldObj.(*uns.Unstructured)
+			if !ok {
+				return
+			}
+			newNAD, ok := newObj.(*uns.Unstructured)
+			if !ok {
+				return
+			}
+			r.networkAttachmentDefinitionUpdated(oldNAD, newNAD)
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			nad, ok := obj.(*uns.Unstructured)
+			if !ok {
+				return
+			}
+			if r.networkAttachmentDefinitionDeleted(nad) {
+				return
+			}
+			if dhcp, whereabouts := network.UsesAuxiliaryIPAM(nad); dhcp || whereabouts {
+				r.enqueueNetworkAttachmentDefinition(obj)
+			}
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to watch NetworkAttachmentDefinitions: %v", err)
+		r.nadWatchStarted = false
+		return
+	}
+
+	r.nadInformer = inf
+	r.client.Default().AddCustomInformer(inf) // starts the informer, the client already runs
+}
+
+// networkAttachmentDefinitions returns the NetworkAttachmentDefinitions of all
+// namespaces, from the cache of the informer once it has synced. Until then, they
+// are listed from the API server.
+func (r *ReconcileOperConfig) networkAttachmentDefinitions(ctx context.Context, conf *operv1.NetworkSpec) ([]*uns.Unstructured, error) {
+	if conf.DisableMultiNetwork != nil && *conf.DisableMultiNetwork {
+		return nil, nil
+	}
+
+	nads := []*uns.Unstructured{}
+	if r.nadInformer != nil && r.nadInformer.HasSynced() {
+		for _, obj := range r.nadInformer.GetStore().List() {
+			if nad, ok := obj.(*uns.Unstructured); ok {
+				nads = append(nads, nad)
+			}
+		}
+		return nads, nil
+	}
+
+	list := &uns.UnstructuredList{}
+	list.SetGroupVersionKind(network.NetworkAttachmentDefinitionGVK.GroupVersion().WithKind("NetworkAttachmentDefinitionList"))
+	err := r.client.Default().CRClient().List(ctx, list)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		// The CRD is rendered along with Multus, there is nothing to find yet
+		return nads, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list NetworkAttachmentDefinitions: %w", err)
+	}
+	for i := range list.Items {
+		nads = append(nads, &list.Items[i])
+	}
+	return nads, nil
+}
+
+// auxiliaryIPAM returns whether the NetworkAttachmentDefinition obj uses the dhcp and
+// the whereabouts IPAM plugins
+func auxiliaryIPAM(obj any) (bool, bool) {
+	nad, ok := obj.(*uns.Unstructured)
+	if !ok {
+		return false, false
+	}
+	return network.UsesAuxiliaryIPAM(nad)
+}
+
+func (r *ReconcileOperConfig) enqueueNetworkAttachmentDefinition(obj any) {
+	if o, ok := obj.(crclient.Object); ok {
+		r.nadEvents <- event.GenericEvent{Object: o}
+	}
+}
+
+// setManagedNetworkAttachmentDefinitions records the spec.config of the
+// NetworkAttachmentDefinitions rendered from spec.additionalNetworks, which the
+// NetworkAttachmentDefinition handlers compare the cluster against
+func (r *ReconcileOperConfig) setManagedNetworkAttachmentDefinitions(objs []*uns.Unstructured) {
+	managed := map[string]string{}
+	for _, obj := range objs {
+		if obj.GroupVersionKind() != network.NetworkAttachmentDefinitionGVK {
+			continue
+		}
+		config, _, _ := uns.NestedString(obj.Object, "spec", "config")
+		managed[obj.GetNamespace()+"/"+obj.GetName()] = config
+	}
+
+	r.nadLock.Lock()
+	defer r.nadLock.Unlock()
+	r.managedNADs = managed
+}
+
+// managedNetworkAttachmentDefinition returns the rendered spec.config of nad, and
+// whether nad is rendered from spec.additionalNetworks
+func (r *ReconcileOperConfig) managedNetworkAttachmentDefinition(nad *uns.Unstructured) (string, bool) {
+	r.nadLock.Lock()
+	defer r.nadLock.Unlock()
+	config, ok := r.managedNADs[nad.GetNamespace()+"/"+nad.GetName()]
+	return config, ok
+}
+
+// networkAttachmentDefinitionUpdated reports the changes made to the spec.config of
+// a NetworkAttachmentDefinition rendered from spec.additionalNetworks, and reverts
+// them unless the NetworkAttachmentDefinition asks to preserve them
+func (r *ReconcileOperConfig) networkAttachmentDefinitionUpdated(oldNAD, newNAD *uns.Unstructured) {
+	rendered, ok := r.managedNetworkAttachmentDefinition(newNAD)
+	if !ok {
+		return
+	}
+	oldConfig, _, _ := uns.NestedString(oldNAD.Object, "spec", "config")
+	newConfig, _, _ := uns.NestedString(newNAD.Object, "spec", "config")
+	if newConfig == rendered {
+		return
+	}
+	// Only report a given drift once, but revert it as soon as the
+	// annotation preserving it is removed
+	if oldConfig == newConfig && preservesManualChanges(oldNAD) == preservesManualChanges(newNAD) {
+		return
+	}
+
+	manager := configManager(newNAD)
+	if preservesManualChanges(newNAD) {
+		log.Printf("NetworkAttachmentDefinition %s/%s was changed by %s, preserving the change", newNAD.GetNamespace(), newNAD.GetName(), manager)
+		r.recordEvent(newNAD, "ConfigDrift",
+			fmt.Sprintf("spec.config was changed by %s and differs from the one rendered from spec.additionalNetworks; the change is preserved because of the %s annotation",
+				manager, names.PreserveManualChangesAnnotation))
+		return
+	}
+
+	log.Printf("NetworkAttachmentDefinition %s/%s was changed by %s, reverting the change", newNAD.GetNamespace(), newNAD.GetName(), manager)
+	r.recordEvent(newNAD, "ConfigReverted",
+		fmt.Sprintf("spec.config was changed by %s; reverting it to the one rendered from spec.additionalNetworks. Set the %s annotation to \"true\" to preserve manual changes",
+			manager, names.PreserveManualChangesAnnotation))
+	r.enqueueNetworkAttachmentDefinition(newNAD)
+}
+
+// networkAttachmentDefinitionDeleted recreates a NetworkAttachmentDefinition rendered
+// from spec.additionalNetworks that was deleted. It returns whether nad was one.
+func (r *ReconcileOperConfig) networkAttachmentDefinitionDeleted(nad *uns.Unstructured) bool {
+	if _, ok := r.managedNetworkAttachmentDefinition(nad); !ok {
+		return false
+	}
+
+	log.Printf("NetworkAttachmentDefinition %s/%s was deleted, recreating it", nad.GetNamespace(), nad.GetName())
+	r.recordEvent(nad, "Recreated",
+		"NetworkAttachmentDefinition was deleted; recreating it from spec.additionalNetworks")
+	r.enqueueNetworkAttachmentDefinition(nad)
+	return true
+}
+
+func (r *ReconcileOperConfig) recordEvent(nad *uns.Unstructured, reason, message string) {
+	if r.recorder == nil {
+		return
+	}
+	r.recorder.Event(nad, corev1.EventTypeWarning, reason, message)
+}
+
+// preservesManualChanges returns whether the changes made to nad must not be reverted
+func preservesManualChanges(nad *uns.Unstructured) bool {
+	return nad.GetAnnotations()[names.PreserveManualChangesAnnotation] == "true"
+}
+
+// configManager returns the field manager that last changed the spec.config of nad,
+// other than the operator, as recorded in its managedFields
+func configManager(nad *uns.Unstructured) string {
+	var last *metav1.ManagedFieldsEntry
+	managedFields := nad.GetManagedFields()
+	for i := range managedFields {
+		entry := &managedFields[i]
+		if entry.Manager == fieldManager || !ownsConfig(entry) {
+			continue
+		}
+		if last == nil || (entry.Time != nil && (last.Time == nil || !entry.Time.Before(last.Time))) {
+			last = entry
+		}
+	}
+	if last == nil {
+		return "an unknown user"
+	}
+	return fmt.Sprintf("%q", last.Manager)
+}
+
+// ownsConfig returns whether the managedFields entry owns spec.config
+func ownsConfig(entry *metav1.ManagedFieldsEntry) bool {
+	if entry.FieldsV1 == nil {
+		return false
+	}
+	fields := map[string]map[string]any{}
+	if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+		return false
+	}
+	_, ok := fields["f:spec"]["f:config"]
+	return ok
+}
+
+// preservedManualChanges returns whether the NetworkAttachmentDefinition obj,
+// rendered from spec.additionalNetworks, must not be applied because its manual
+// changes are preserved
+func (r *ReconcileOperConfig) preservedManualChanges(ctx context.Context, obj *uns.Unstructured) (bool, error) {
+	if obj.GroupVersionKind() != network.NetworkAttachmentDefinitionGVK {
+		return false, nil
+	}
+
+	current := &uns.Unstructured{}
+	current.SetGroupVersionKind(network.NetworkAttachmentDefinitionGVK)
+	err := r.client.Default().CRClient().Get(ctx, crclient.ObjectKeyFromObject(obj), current)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !preservesManualChanges(current) {
+		return false, nil
+	}
+
+	rendered, _, _ := uns.NestedString(obj.Object, "spec", "config")
+	config, _, _ := uns.NestedString(current.Object, "spec", "config")
+	if config == rendered {
+		return false, nil
+	}
+	log.Printf("NetworkAttachmentDefinition %s/%s has the %s annotation and drifted from spec.additionalNetworks, not applying it",
+		obj.GetNamespace(), obj.GetName(), names.PreserveManualChangesAnnotation)
+	return true, nil
+}
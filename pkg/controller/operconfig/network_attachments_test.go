@@ -0,0 +1,154 @@
+package operconfig
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+)
+
+const renderedConfig = `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"dhcp"}}`
+
+func managedNAD(config string, annotations map[string]string, managedFields ...metav1.ManagedFieldsEntry) *uns.Unstructured {
+	nad := &uns.Unstructured{}
+	nad.SetGroupVersionKind(network.NetworkAttachmentDefinitionGVK)
+	nad.SetNamespace("foo")
+	nad.SetName("bar")
+	nad.SetAnnotations(annotations)
+	nad.SetManagedFields(managedFields)
+	_ = uns.SetNestedField(nad.Object, config, "spec", "config")
+	return nad
+}
+
+func configEntry(manager string, t time.Time) metav1.ManagedFieldsEntry {
+	return metav1.ManagedFieldsEntry{
+		Manager:   manager,
+		Operation: metav1.ManagedFieldsOperationUpdate,
+		Time:      &metav1.Time{Time: t},
+		FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:config":{}}}`)},
+	}
+}
+
+func newNADTestReconciler(objs ...crclient.Object) (*ReconcileOperConfig, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileOperConfig{
+		client: &fakeCNOClient{
+			clusterClient: &fakeClusterClient{
+				crclient: fake.NewClientBuilder().WithObjects(objs...).Build(),
+			},
+		},
+		recorder:  recorder,
+		nadEvents: make(chan event.GenericEvent, 10),
+	}
+	r.setManagedNetworkAttachmentDefinitions([]*uns.Unstructured{managedNAD(renderedConfig, nil)})
+	return r, recorder
+}
+
+func TestConfigManager(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now()
+	nad := managedNAD("{}", nil,
+		configEntry(fieldManager, now.Add(time.Minute)),
+		configEntry("kubectl-edit", now),
+		configEntry("kubectl-client-side-apply", now.Add(-time.Minute)),
+		metav1.ManagedFieldsEntry{Manager: "labeler", Time: &metav1.Time{Time: now.Add(time.Hour)}, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{}}`)}},
+	)
+	g.Expect(configManager(nad)).To(Equal(`"kubectl-edit"`))
+
+	nad.SetManagedFields([]metav1.ManagedFieldsEntry{configEntry(fieldManager, now)})
+	g.Expect(configManager(nad)).To(Equal("an unknown user"))
+}
+
+func TestNetworkAttachmentDefinitionUpdated(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r, recorder := newNADTestReconciler()
+
+	edited := `{"cniVersion":"0.3.1","type":"macvlan","mtu":1400,"ipam":{"type":"dhcp"}}`
+	old := managedNAD(renderedConfig, nil)
+
+	// Our own apply is not drift
+	r.networkAttachmentDefinitionUpdated(old, managedNAD(renderedConfig, map[string]string{"foo": "bar"}))
+	g.Expect(recorder.Events).To(BeEmpty())
+	g.Expect(r.nadEvents).To(BeEmpty())
+
+	// Manual changes are reverted
+	r.networkAttachmentDefinitionUpdated(old, managedNAD(edited, nil, configEntry("kubectl-edit", time.Now())))
+	g.Expect(recorder.Events).To(Receive(Equal(`Warning ConfigReverted spec.config was changed by "kubectl-edit"; reverting it to the one rendered from spec.additionalNetworks. Set the networkoperator.openshift.io/preserve-manual-changes annotation to "true" to preserve manual changes`)))
+	g.Expect(r.nadEvents).To(HaveLen(1))
+	<-r.nadEvents
+
+	// Unless they are preserved
+	preserve := map[string]string{names.PreserveManualChangesAnnotation: "true"}
+	preserved := managedNAD(edited, preserve, configEntry("kubectl-edit", time.Now()))
+	r.networkAttachmentDefinitionUpdated(old, preserved)
+	g.Expect(recorder.Events).To(Receive(HavePrefix(`Warning ConfigDrift spec.config was changed by "kubectl-edit"`)))
+	g.Expect(r.nadEvents).To(BeEmpty())
+
+	// and reported only once
+	r.networkAttachmentDefinitionUpdated(preserved, managedNAD(edited, map[string]string{names.PreserveManualChangesAnnotation: "true", "foo": "bar"}))
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	// Removing the annotation reverts them
+	r.networkAttachmentDefinitionUpdated(preserved, managedNAD(edited, nil))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning ConfigReverted")))
+	g.Expect(r.nadEvents).To(HaveLen(1))
+	<-r.nadEvents
+
+	// NetworkAttachmentDefinitions not rendered from spec.additionalNetworks are left alone
+	other := managedNAD(edited, nil)
+	other.SetName("other")
+	r.networkAttachmentDefinitionUpdated(managedNAD(renderedConfig, nil), other)
+	g.Expect(recorder.Events).To(BeEmpty())
+	g.Expect(r.nadEvents).To(BeEmpty())
+}
+
+func TestNetworkAttachmentDefinitionDeleted(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r, recorder := newNADTestReconciler()
+
+	g.Expect(r.networkAttachmentDefinitionDeleted(managedNAD(renderedConfig, nil))).To(BeTrue())
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning Recreated")))
+	g.Expect(r.nadEvents).To(HaveLen(1))
+
+	// Removed from spec.additionalNetworks
+	r.setManagedNetworkAttachmentDefinitions(nil)
+	g.Expect(r.networkAttachmentDefinitionDeleted(managedNAD(renderedConfig, nil))).To(BeFalse())
+	g.Expect(recorder.Events).To(BeEmpty())
+}
+
+func TestPreservedManualChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	preserve := map[string]string{names.PreserveManualChangesAnnotation: "true"}
+	edited := `{"cniVersion":"0.3.1","type":"macvlan","mtu":1400,"ipam":{"type":"dhcp"}}`
+
+	for _, tc := range []struct {
+		name     string
+		current  *uns.Unstructured
+		expected bool
+	}{
+		{name: "missing"},
+		{name: "edited", current: managedNAD(edited, nil)},
+		{name: "not edited", current: managedNAD(renderedConfig, preserve)},
+		{name: "preserved", current: managedNAD(edited, preserve), expected: true},
+	} {
+		objs := []crclient.Object{}
+		if tc.current != nil {
+			objs = append(objs, tc.current)
+		}
+		r, _ := newNADTestReconciler(objs...)
+		preserved, err := r.preservedManualChanges(t.Context(), managedNAD(renderedConfig, nil))
+		g.Expect(err).NotTo(HaveOccurred(), tc.name)
+		g.Expect(preserved).To(Equal(tc.expected), tc.name)
+	}
+}
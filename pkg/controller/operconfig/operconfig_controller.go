@@ -6,6 +6,7 @@ import (
 	"log"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/openshift/cluster-network-operator/pkg/hypershift"
@@ -30,9 +31,11 @@ import (
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	v1coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -73,6 +76,8 @@ func newReconciler(mgr manager.Manager, status *statusmanager.StatusManager, c c
 		status:       status,
 		mapper:       mgr.GetRESTMapper(),
 		featureGates: featureGates,
+		recorder:     mgr.GetEventRecorderFor("cluster-network-operator"),
+		nadEvents:    make(chan event.GenericEvent, 100),
 	}, nil
 }
 
@@ -176,6 +181,33 @@ func add(mgr manager.Manager, r *ReconcileOperConfig) error {
 		return err
 	}
 
+	// Watch when OpenShift component namespaces are created and deleted, so that
+	// the multus admission controller ignores them.
+	namespacePredicate := predicate.Funcs{
+		CreateFunc: func(ev event.CreateEvent) bool {
+			return isMultusAdmissionIgnoredNamespace(ev.Object)
+		},
+		UpdateFunc: func(ev event.UpdateEvent) bool {
+			return isMultusAdmissionIgnoredNamespace(ev.ObjectOld) != isMultusAdmissionIgnoredNamespace(ev.ObjectNew)
+		},
+		DeleteFunc: func(ev event.DeleteEvent) bool {
+			return isMultusAdmissionIgnoredNamespace(ev.Object)
+		},
+		GenericFunc: func(_ event.GenericEvent) bool {
+			return false
+		},
+	}
+	if err := c.Watch(source.Kind[crclient.Object](mgr.GetCache(), &corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(reconcileOperConfig), namespacePredicate)); err != nil {
+		return err
+	}
+
+	// Watch when NetworkAttachmentDefinitions start or stop needing an auxiliary
+	// IPAM component, and when the ones we render are changed or deleted. The
+	// informer is only started by the reconciler, see watchNetworkAttachmentDefinitions.
+	if err := c.Watch(source.Channel(r.nadEvents, handler.EnqueueRequestsFromMapFunc(reconcileOperConfig))); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -189,6 +221,17 @@ type ReconcileOperConfig struct {
 
 	// If we can skip cleaning up the MTU prober job.
 	mtuProberCleanedUp bool
+	// nadEvents receives the relevant NetworkAttachmentDefinition changes once
+	// nadWatchStarted
+	nadEvents       chan event.GenericEvent
+	nadWatchStarted bool
+	// nadInformer caches the NetworkAttachmentDefinitions once nadWatchStarted
+	nadInformer cache.SharedIndexInformer
+	// managedNADs maps the namespace/name of the NetworkAttachmentDefinitions
+	// rendered from spec.additionalNetworks to their spec.config
+	nadLock     sync.Mutex
+	managedNADs map[string]string
+	recorder    record.EventRecorder
 	// maintain the copy of feature gates in the cluster
 	featureGates featuregates.FeatureGate
 }
@@ -251,13 +294,22 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 	// Convert certain fields to canonicalized form for backward compatibility
 	network.DeprecatedCanonicalize(&operConfig.Spec)
 
+	// Gather the Infra status, we'll need it a few places
+	infraStatus, err := platform.InfraStatus(r.client)
+	if err != nil {
+		log.Printf("Failed to retrieve infrastructure status: %v", err)
+		return reconcile.Result{}, err
+	}
+
 	// Validate the configuration
-	if err := network.Validate(&operConfig.Spec); err != nil {
+	if err := network.Validate(&operConfig.Spec, infraStatus); err != nil {
 		log.Printf("Failed to validate Network.operator.openshift.io.Spec: %v", err)
 		r.status.SetDegraded(statusmanager.OperatorConfig, "InvalidOperatorConfig",
 			fmt.Sprintf("The operator configuration is invalid (%v). Use 'oc edit network.operator.openshift.io cluster' to fix.", err))
 		return reconcile.Result{}, err
 	}
+	r.status.SetCondition(network.MachineNetworkOverlapCondition(&operConfig.Spec, infraStatus))
+	r.status.SetCondition(network.AddressOverlapCondition(&operConfig.Spec))
 
 	// Retrieve the previously applied operator configuration
 	prev, err := GetAppliedConfiguration(ctx, r.client.Default().CRClient(), operConfig.Name)
@@ -267,12 +319,19 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, err
 	}
 
-	// Gather the Infra status, we'll need it a few places
-	infraStatus, err := platform.InfraStatus(r.client)
-	if err != nil {
-		log.Printf("Failed to retrieve infrastructure status: %v", err)
+	// Unknown default network types, and invalid Raw CNI configurations, are
+	// only tolerated on the clusters that already run with them
+	if err := utilerrors.NewAggregate([]error{
+		network.ValidateDefaultNetworkType(&operConfig.Spec, prev),
+		network.ValidateRawCNIConfigs(&operConfig.Spec, prev),
+	}); err != nil {
+		log.Printf("Failed to validate Network.operator.openshift.io.Spec: %v", err)
+		r.status.SetDegraded(statusmanager.OperatorConfig, "InvalidOperatorConfig",
+			fmt.Sprintf("The operator configuration is invalid (%v). Use 'oc edit network.operator.openshift.io cluster' to fix.", err))
 		return reconcile.Result{}, err
 	}
+	r.status.SetCondition(network.DefaultNetworkTypeCondition(&operConfig.Spec))
+	r.status.SetCondition(network.RawCNIConfigCondition(&operConfig.Spec, prev))
 
 	// If we need to, probe the host's MTU via a Job.
 	// Note that running clusters have no need of this but we want the configmap
@@ -293,12 +352,30 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 
 	// up-convert Prev by filling defaults
 	if prev != nil {
-		network.FillDefaults(prev, prev, mtu)
+		network.FillDefaults(prev, prev, mtu, infraStatus)
 	}
 	// Reserve operConfig for the DeepEqual check before UpdateOperConfig
 	newOperConfig := operConfig.DeepCopy()
 	// Fill all defaults explicitly
-	network.FillDefaults(&newOperConfig.Spec, prev, mtu)
+	network.FillDefaults(&newOperConfig.Spec, prev, mtu, infraStatus)
+
+	// At install time, record any internal subnet that had to be chosen because
+	// the default one was already in use, and report it for as long as it differs
+	// from the default.
+	if prev == nil {
+		network.SetSelectedOVNKubernetesSubnetsAnnotation(newOperConfig,
+			network.SelectedOVNKubernetesSubnets(&operConfig.Spec, &newOperConfig.Spec))
+	}
+	if selected := network.ReportedOVNKubernetesSubnets(newOperConfig); len(selected) > 0 {
+		r.status.SetCondition(operv1.OperatorCondition{
+			Type:    names.OVNKubernetesSubnetsSelectedCondition,
+			Status:  operv1.ConditionTrue,
+			Reason:  "DefaultSubnetsInUse",
+			Message: fmt.Sprintf("The default OVN-Kubernetes internal subnets overlap networks in use, selected: %s", strings.Join(selected, ", ")),
+		})
+	} else {
+		r.status.RemoveCondition(names.OVNKubernetesSubnetsSelectedCondition)
+	}
 
 	// Validate MTU for no-overlay mode
 	if err := network.ValidateMTUForNoOverlay(&newOperConfig.Spec, mtu); err != nil {
@@ -330,6 +407,17 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 			fmt.Sprintf("Internal error while reconciling platform networking resources: %v", err))
 		return reconcile.Result{}, err
 	}
+	nads, err := r.networkAttachmentDefinitions(ctx, &newOperConfig.Spec)
+	if err != nil {
+		log.Printf("Failed to reconcile platform networking resources: %v", err)
+		r.status.MaybeSetDegraded(statusmanager.OperatorConfig, "BootstrapError",
+			fmt.Sprintf("Internal error while reconciling platform networking resources: %v", err))
+		return reconcile.Result{}, err
+	}
+	bootstrapResult.AuxiliaryIPAM = network.BootstrapAuxiliaryIPAM(&newOperConfig.Spec, nads)
+	r.status.SetCondition(network.SizingCondition(&bootstrapResult.Sizing))
+	network.SetSizingProfileAnnotation(newOperConfig, &bootstrapResult.Sizing)
+	r.status.SetCondition(network.AuxiliaryIPAMCondition(&newOperConfig.Spec, &bootstrapResult.AuxiliaryIPAM))
 
 	if !reflect.DeepEqual(operConfig, newOperConfig) {
 		if err := r.UpdateOperConfig(ctx, newOperConfig); err != nil {
@@ -382,6 +470,7 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, err
 	}
 	objs = append([]*uns.Unstructured{app}, objs...)
+	r.setManagedNetworkAttachmentDefinitions(objs)
 
 	relatedObjects := []configv1.ObjectReference{}
 	relatedClusterObjects := []hypershift.RelatedObject{}
@@ -487,6 +576,12 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 			}
 		}
 
+		if preserved, err := r.preservedManualChanges(ctx, obj); err != nil {
+			log.Printf("Could not check NetworkAttachmentDefinition %s/%s for manual changes: %v", obj.GetNamespace(), obj.GetName(), err)
+		} else if preserved {
+			continue
+		}
+
 		// Open question: should an error here indicate we will never retry?
 		if err := apply.ApplyObject(ctx, r.client, obj, ControllerName); err != nil {
 			err = fmt.Errorf("could not apply (%s) %s/%s: %w", obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName(), err)
@@ -539,6 +634,8 @@ func (r *ReconcileOperConfig) Reconcile(ctx context.Context, request reconcile.R
 
 	r.status.SetNotDegraded(statusmanager.OperatorConfig)
 
+	r.watchNetworkAttachmentDefinitions(&operConfig.Spec)
+
 	// All was successful. Request that this be re-triggered after ResyncPeriod,
 	// so we can reconcile state again.
 	log.Printf("Operconfig Controller complete")
@@ -570,3 +667,8 @@ func reconcileOperConfig(ctx context.Context, obj crclient.Object) []reconcile.R
 		Name: names.OPERATOR_CONFIG,
 	}}}
 }
+
+func isMultusAdmissionIgnoredNamespace(obj crclient.Object) bool {
+	ns, ok := obj.(*corev1.Namespace)
+	return ok && network.IsMultusAdmissionIgnoredNamespace(ns)
+}
@@ -0,0 +1,259 @@
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net"
+	"time"
+
+	configv1alpha1 "github.com/openshift/api/config/v1alpha1"
+	netopv1 "github.com/openshift/cluster-network-operator/pkg/apis/network/v1"
+
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/certrotation"
+	"github.com/openshift/library-go/pkg/pki"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+const (
+	// The default validity and refresh of the CA
+	defaultCAValidity = 10 * OneYear
+	defaultCARefresh  = 9 * OneYear
+
+	// The shortest validities we accept: anything shorter would rotate faster
+	// than the consumers can reload the certificates
+	minCAValidity   = 24 * time.Hour
+	minCertValidity = time.Hour
+)
+
+// certConfig is the resolved configuration of a certificate of the PKI
+type certConfig struct {
+	// secretName is the Secret holding the certificate and its key
+	secretName string
+	commonName string
+	// hostnames are the DNS and IP subject alternative names, including the
+	// common name
+	hostnames []string
+
+	validity time.Duration
+	refresh  time.Duration
+
+	// key is the key configuration, or nil to use the cluster PKI profile
+	key *netopv1.KeySpec
+}
+
+// pkiConfig is the resolved configuration of a PKI
+type pkiConfig struct {
+	ca      certConfig
+	targets []certConfig
+}
+
+// resolveConfig validates the spec of the PKI and applies its defaults.
+// certDuration is the default validity of the target certificates.
+func resolveConfig(obj *netopv1.OperatorPKI, certDuration time.Duration) (*pkiConfig, error) {
+	spec := &obj.Spec
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+
+	config := &pkiConfig{
+		ca: certConfig{
+			secretName: obj.Name + "-ca",
+			validity:   defaultCAValidity,
+			refresh:    defaultCARefresh,
+		},
+	}
+	if spec.CA != nil {
+		caPath := specPath.Child("ca")
+		config.ca.key = spec.CA.Key
+		if spec.CA.Validity != nil {
+			config.ca.validity = spec.CA.Validity.Duration
+			// keep the default ratio of the refresh to the validity
+			config.ca.refresh = config.ca.validity * 9 / 10
+		}
+		if spec.CA.Refresh != nil {
+			config.ca.refresh = spec.CA.Refresh.Duration
+		}
+		allErrs = append(allErrs, validateRotation(caPath, config.ca.validity, config.ca.refresh, minCAValidity, config.ca.validity)...)
+		allErrs = append(allErrs, validateKey(caPath.Child("key"), config.ca.key)...)
+	}
+
+	target, errs := resolveCertConfig(specPath.Child("targetCert"), obj.Name+"-cert", &spec.TargetCert, certDuration, config.ca.validity)
+	allErrs = append(allErrs, errs...)
+	config.targets = append(config.targets, target)
+
+	names := sets.New[string]()
+	for i := range spec.AdditionalTargetCerts {
+		cert := &spec.AdditionalTargetCerts[i]
+		certPath := specPath.Child("additionalTargetCerts").Index(i)
+		for _, msg := range utilvalidation.IsDNS1123Label(cert.Name) {
+			allErrs = append(allErrs, field.Invalid(certPath.Child("name"), cert.Name, msg))
+		}
+		if names.Has(cert.Name) {
+			allErrs = append(allErrs, field.Duplicate(certPath.Child("name"), cert.Name))
+		}
+		names.Insert(cert.Name)
+
+		target, errs := resolveCertConfig(certPath, fmt.Sprintf("%s-%s-cert", obj.Name, cert.Name), &cert.CertSpec, certDuration, config.ca.validity)
+		allErrs = append(allErrs, errs...)
+		config.targets = append(config.targets, target)
+	}
+
+	if len(allErrs) > 0 {
+		return nil, allErrs.ToAggregate()
+	}
+	return config, nil
+}
+
+// resolveCertConfig validates the spec of a target certificate, stored in
+// secretName, and applies its defaults.
+func resolveCertConfig(path *field.Path, secretName string, spec *netopv1.CertSpec, certDuration, caValidity time.Duration) (certConfig, field.ErrorList) {
+	allErrs := field.ErrorList{}
+	config := certConfig{
+		secretName: secretName,
+		commonName: spec.CommonName,
+		hostnames:  []string{spec.CommonName},
+		validity:   certDuration,
+		key:        spec.Key,
+	}
+
+	for i, name := range spec.DNSNames {
+		for _, msg := range utilvalidation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(path.Child("dnsNames").Index(i), name, msg))
+		}
+		config.hostnames = append(config.hostnames, name)
+	}
+	for i, address := range spec.IPAddresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("ipAddresses").Index(i), address, "must be a valid IP address"))
+			continue
+		}
+		// the certificate annotations use the canonical form
+		config.hostnames = append(config.hostnames, ip.String())
+	}
+
+	if spec.Validity != nil {
+		config.validity = spec.Validity.Duration
+	}
+	config.refresh = config.validity / 2
+	if spec.Refresh != nil {
+		config.refresh = spec.Refresh.Duration
+	}
+	allErrs = append(allErrs, validateRotation(path, config.validity, config.refresh, minCertValidity, caValidity)...)
+	allErrs = append(allErrs, validateKey(path.Child("key"), config.key)...)
+
+	return config, allErrs
+}
+
+// validateRotation checks that validity is between minValidity and maxValidity,
+// and that refresh is between 10% and 100% of the validity.
+func validateRotation(path *field.Path, validity, refresh, minValidity, maxValidity time.Duration) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch {
+	case validity < minValidity:
+		allErrs = append(allErrs, field.Invalid(path.Child("validity"), validity.String(), fmt.Sprintf("must be at least %s", minValidity)))
+	case validity > maxValidity:
+		allErrs = append(allErrs, field.Invalid(path.Child("validity"), validity.String(), fmt.Sprintf("must not be longer than the validity of the CA, %s", maxValidity)))
+	}
+	if refresh < validity/10 || refresh > validity {
+		allErrs = append(allErrs, field.Invalid(path.Child("refresh"), refresh.String(), "must be between 10% and 100% of the validity"))
+	}
+	return allErrs
+}
+
+// validateKey checks the key configuration, if any
+func validateKey(path *field.Path, key *netopv1.KeySpec) field.ErrorList {
+	if key == nil {
+		return nil
+	}
+	allErrs := field.ErrorList{}
+	switch key.Algorithm {
+	case netopv1.KeyAlgorithmRSA:
+		switch key.RSAKeySize {
+		case 0, 2048, 3072, 4096:
+		default:
+			allErrs = append(allErrs, field.NotSupported(path.Child("rsaKeySize"), key.RSAKeySize, []string{"2048", "3072", "4096"}))
+		}
+		if key.ECDSACurve != "" {
+			allErrs = append(allErrs, field.Forbidden(path.Child("ecdsaCurve"), "not allowed with RSA keys"))
+		}
+	case netopv1.KeyAlgorithmECDSA:
+		switch key.ECDSACurve {
+		case "", "P256", "P384", "P521":
+		default:
+			allErrs = append(allErrs, field.NotSupported(path.Child("ecdsaCurve"), key.ECDSACurve, []string{"P256", "P384", "P521"}))
+		}
+		if key.RSAKeySize != 0 {
+			allErrs = append(allErrs, field.Forbidden(path.Child("rsaKeySize"), "not allowed with ECDSA keys"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("algorithm"), key.Algorithm, []netopv1.KeyAlgorithm{netopv1.KeyAlgorithmRSA, netopv1.KeyAlgorithmECDSA}))
+	}
+	return allErrs
+}
+
+// keyProfileProvider returns a PKI profile provider that generates keys as
+// configured in key, or clusterProvider when key is not set.
+func keyProfileProvider(key *netopv1.KeySpec, clusterProvider pki.PKIProfileProvider) pki.PKIProfileProvider {
+	if key == nil {
+		return clusterProvider
+	}
+
+	apiKey := configv1alpha1.KeyConfig{Algorithm: configv1alpha1.KeyAlgorithm(key.Algorithm)}
+	switch key.Algorithm {
+	case netopv1.KeyAlgorithmRSA:
+		apiKey.RSA.KeySize = key.RSAKeySize
+		if apiKey.RSA.KeySize == 0 {
+			apiKey.RSA.KeySize = 2048
+		}
+	case netopv1.KeyAlgorithmECDSA:
+		apiKey.ECDSA.Curve = configv1alpha1.ECDSACurve(key.ECDSACurve)
+		if apiKey.ECDSA.Curve == "" {
+			apiKey.ECDSA.Curve = configv1alpha1.ECDSACurveP256
+		}
+	}
+
+	// The certificate type picks the key in the profile, so use it for all of them
+	return pki.NewStaticPKIProfileProvider(&configv1alpha1.PKIProfile{
+		Defaults:            configv1alpha1.DefaultCertificateConfig{Key: apiKey},
+		SignerCertificates:  configv1alpha1.CertificateConfig{Key: apiKey},
+		ServingCertificates: configv1alpha1.CertificateConfig{Key: apiKey},
+		ClientCertificates:  configv1alpha1.CertificateConfig{Key: apiKey},
+	})
+}
+
+// peerRotation creates the target certificates of the PKI. Without a key
+// configuration, certrotation.PeerRotation requires the common name to be the
+// first of the sorted hostnames, which does not hold with additional subject
+// alternative names: in that case, the certificate is created here the same way.
+type peerRotation struct {
+	*certrotation.PeerRotation
+	commonName string
+}
+
+func newPeerRotation(config *certConfig) *peerRotation {
+	hostnames := config.hostnames
+	return &peerRotation{
+		PeerRotation: &certrotation.PeerRotation{
+			Hostnames: func() []string { return hostnames },
+			UserInfo:  &user.DefaultInfo{Name: config.commonName},
+		},
+		commonName: config.commonName,
+	}
+}
+
+func (r *peerRotation) NewCertificate(signer *crypto.CA, validity time.Duration, keyGen crypto.KeyPairGenerator) (*crypto.TLSCertificateConfig, error) {
+	if keyGen != nil {
+		return r.PeerRotation.NewCertificate(signer, validity, keyGen)
+	}
+	return signer.MakeServerCertForDuration(sets.New(r.Hostnames()...), validity, func(cert *x509.Certificate) error {
+		cert.Subject = pkix.Name{CommonName: r.commonName}
+		cert.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+		return nil
+	})
+}
@@ -0,0 +1,196 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	netopv1 "github.com/openshift/cluster-network-operator/pkg/apis/network/v1"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/pki"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newOperatorPKI(spec netopv1.OperatorPKISpec) *netopv1.OperatorPKI {
+	return &netopv1.OperatorPKI{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-ovn-kubernetes", Name: "ovn"},
+		Spec:       spec,
+	}
+}
+
+func duration(d time.Duration) *metav1.Duration {
+	return &metav1.Duration{Duration: d}
+}
+
+func TestResolveConfigDefaults(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	config, err := resolveConfig(newOperatorPKI(netopv1.OperatorPKISpec{
+		TargetCert: netopv1.CertSpec{CommonName: "ovn"},
+	}), OneYear/2)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(config.ca).To(Equal(certConfig{secretName: "ovn-ca", validity: 10 * OneYear, refresh: 9 * OneYear}))
+	g.Expect(config.targets).To(Equal([]certConfig{{
+		secretName: "ovn-cert",
+		commonName: "ovn",
+		hostnames:  []string{"ovn"},
+		validity:   OneYear / 2,
+		refresh:    OneYear / 4,
+	}}))
+}
+
+func TestResolveConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key := &netopv1.KeySpec{Algorithm: netopv1.KeyAlgorithmECDSA, ECDSACurve: "P384"}
+	config, err := resolveConfig(newOperatorPKI(netopv1.OperatorPKISpec{
+		CA: &netopv1.CASpec{Validity: duration(2 * OneYear), Key: key},
+		TargetCert: netopv1.CertSpec{
+			CommonName:  "ovn",
+			DNSNames:    []string{"ovn.openshift-ovn-kubernetes.svc"},
+			IPAddresses: []string{"fd00:0::1", "10.0.0.1"},
+			Validity:    duration(30 * 24 * time.Hour),
+			Refresh:     duration(20 * 24 * time.Hour),
+		},
+		AdditionalTargetCerts: []netopv1.NamedCertSpec{{
+			Name:     "metrics",
+			CertSpec: netopv1.CertSpec{CommonName: "ovn-metrics"},
+		}},
+	}), OneYear/2)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(config.ca.validity).To(Equal(2 * OneYear))
+	g.Expect(config.ca.refresh).To(Equal(2 * OneYear * 9 / 10))
+	g.Expect(config.ca.key).To(Equal(key))
+	g.Expect(config.targets).To(HaveLen(2))
+	g.Expect(config.targets[0].hostnames).To(Equal([]string{"ovn", "ovn.openshift-ovn-kubernetes.svc", "fd00::1", "10.0.0.1"}))
+	g.Expect(config.targets[0].validity).To(Equal(30 * 24 * time.Hour))
+	g.Expect(config.targets[0].refresh).To(Equal(20 * 24 * time.Hour))
+	g.Expect(config.targets[1].secretName).To(Equal("ovn-metrics-cert"))
+	g.Expect(config.targets[1].commonName).To(Equal("ovn-metrics"))
+	g.Expect(config.targets[1].validity).To(Equal(OneYear / 2))
+}
+
+func TestResolveConfigErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec netopv1.OperatorPKISpec
+		err  string
+	}{
+		{
+			name: "CA validity too short",
+			spec: netopv1.OperatorPKISpec{
+				CA:         &netopv1.CASpec{Validity: duration(time.Hour)},
+				TargetCert: netopv1.CertSpec{CommonName: "ovn", Validity: duration(time.Hour)},
+			},
+			err: "spec.ca.validity: Invalid value: \"1h0m0s\": must be at least 24h0m0s",
+		},
+		{
+			name: "CA refresh too short",
+			spec: netopv1.OperatorPKISpec{
+				CA:         &netopv1.CASpec{Refresh: duration(OneYear / 2)},
+				TargetCert: netopv1.CertSpec{CommonName: "ovn"},
+			},
+			err: "spec.ca.refresh",
+		},
+		{
+			name: "target validity longer than the CA",
+			spec: netopv1.OperatorPKISpec{
+				CA:         &netopv1.CASpec{Validity: duration(OneYear)},
+				TargetCert: netopv1.CertSpec{CommonName: "ovn", Validity: duration(2 * OneYear)},
+			},
+			err: "spec.targetCert.validity",
+		},
+		{
+			name: "target refresh longer than the validity",
+			spec: netopv1.OperatorPKISpec{
+				TargetCert: netopv1.CertSpec{CommonName: "ovn", Refresh: duration(OneYear)},
+			},
+			err: "spec.targetCert.refresh",
+		},
+		{
+			name: "invalid SANs",
+			spec: netopv1.OperatorPKISpec{
+				TargetCert: netopv1.CertSpec{CommonName: "ovn", DNSNames: []string{"Not_A_Name"}, IPAddresses: []string{"10.0.0.256"}},
+			},
+			err: "[spec.targetCert.dnsNames[0]: Invalid value: \"Not_A_Name\"",
+		},
+		{
+			name: "invalid key",
+			spec: netopv1.OperatorPKISpec{
+				TargetCert: netopv1.CertSpec{CommonName: "ovn", Key: &netopv1.KeySpec{Algorithm: netopv1.KeyAlgorithmRSA, RSAKeySize: 1024}},
+			},
+			err: "spec.targetCert.key.rsaKeySize: Unsupported value: 1024",
+		},
+		{
+			name: "mismatched key",
+			spec: netopv1.OperatorPKISpec{
+				TargetCert: netopv1.CertSpec{CommonName: "ovn", Key: &netopv1.KeySpec{Algorithm: netopv1.KeyAlgorithmECDSA, RSAKeySize: 2048}},
+			},
+			err: "spec.targetCert.key.rsaKeySize: Forbidden",
+		},
+		{
+			name: "duplicate additional certificates",
+			spec: netopv1.OperatorPKISpec{
+				TargetCert: netopv1.CertSpec{CommonName: "ovn"},
+				AdditionalTargetCerts: []netopv1.NamedCertSpec{
+					{Name: "metrics", CertSpec: netopv1.CertSpec{CommonName: "ovn-metrics"}},
+					{Name: "metrics", CertSpec: netopv1.CertSpec{CommonName: "ovn-metrics"}},
+				},
+			},
+			err: "spec.additionalTargetCerts[1].name: Duplicate value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			_, err := resolveConfig(newOperatorPKI(tc.spec), OneYear/2)
+			g.Expect(err).To(MatchError(ContainSubstring(tc.err)))
+		})
+	}
+}
+
+func TestKeyProfileProvider(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(keyProfileProvider(nil, nil)).To(BeNil())
+
+	provider := keyProfileProvider(&netopv1.KeySpec{Algorithm: netopv1.KeyAlgorithmRSA}, nil)
+	for _, certType := range []pki.CertificateType{pki.CertificateTypeSigner, pki.CertificateTypePeer} {
+		keyConfig, err := pki.ResolveCertificateConfig(provider, certType, "network")
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(keyConfig.Key).To(Equal(crypto.RSAKeyPairGenerator{Bits: 2048}))
+	}
+}
+
+func TestPeerRotation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca := makeCA(t, "ovn-ca")
+	config := &certConfig{
+		commonName: "ovn",
+		hostnames:  []string{"ovn", "a.ovn", "10.0.0.1"},
+	}
+	r := newPeerRotation(config)
+
+	// without a key, the common name is kept even if it doesn't sort first
+	cert, err := r.NewCertificate(ca, time.Hour, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cert.Certs[0].Subject.CommonName).To(Equal("ovn"))
+	g.Expect(cert.Certs[0].DNSNames).To(ContainElements("ovn", "a.ovn"))
+	g.Expect(cert.Certs[0].IPAddresses).To(ContainElement(net.ParseIP("10.0.0.1").To4()))
+	g.Expect(cert.Certs[0].PublicKey).To(BeAssignableToTypeOf(&rsa.PublicKey{}))
+
+	provider := keyProfileProvider(&netopv1.KeySpec{Algorithm: netopv1.KeyAlgorithmECDSA, ECDSACurve: "P384"}, nil)
+	keyConfig, err := pki.ResolveCertificateConfig(provider, pki.CertificateTypePeer, "network.peer")
+	g.Expect(err).NotTo(HaveOccurred())
+	cert, err = r.NewCertificate(ca, time.Hour, keyConfig.Key)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cert.Certs[0].Subject.CommonName).To(Equal("ovn"))
+	g.Expect(cert.Certs[0].PublicKey.(*ecdsa.PublicKey).Curve).To(Equal(elliptic.P384()))
+}
@@ -8,6 +8,7 @@ package pki
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"reflect"
@@ -26,16 +27,19 @@ import (
 	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
 	"github.com/openshift/library-go/pkg/pki"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	crclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -64,7 +68,9 @@ func Add(mgr manager.Manager, status *statusmanager.StatusManager, client cnocli
 	}
 
 	// Watch for changes to primary resource PKI.network.operator.openshift.io/v1
-	err = c.Watch(source.Kind[crclient.Object](mgr.GetCache(), &netopv1.OperatorPKI{}, &handler.EnqueueRequestForObject{}))
+	// Status updates do not bump the generation, so they don't requeue the PKI
+	err = c.Watch(source.Kind[crclient.Object](mgr.GetCache(), &netopv1.OperatorPKI{}, &handler.EnqueueRequestForObject{},
+		predicate.GenerationChangedPredicate{}))
 	if err != nil {
 		return err
 	}
@@ -132,6 +138,10 @@ func (r *PKIReconciler) Reconcile(ctx context.Context, request reconcile.Request
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			log.Printf("PKI %s seems to have been deleted\n", request.NamespacedName)
+			if existing := r.pkis[request.NamespacedName]; existing != nil {
+				existing.stop()
+				delete(r.pkis, request.NamespacedName)
+			}
 			return reconcile.Result{}, nil
 		}
 		log.Println(err)
@@ -144,6 +154,7 @@ func (r *PKIReconciler) Reconcile(ctx context.Context, request reconcile.Request
 		// If the spec has changed, refresh
 		if !reflect.DeepEqual(obj.Spec, existing.spec) {
 			log.Printf("PKI %s has changed, refreshing\n", request.NamespacedName)
+			existing.stop()
 			delete(r.pkis, request.NamespacedName)
 			existing = nil
 		}
@@ -152,6 +163,9 @@ func (r *PKIReconciler) Reconcile(ctx context.Context, request reconcile.Request
 		existing, err = newPKI(obj, r.clientset, r.mgr, r.certDuration, r.pkiProfileProvider)
 		if err != nil {
 			log.Println(err)
+			if statusErr := r.updateStatus(ctx, obj, nil, err); statusErr != nil {
+				log.Printf("Could not update the status of PKI %s: %v", request.NamespacedName, statusErr)
+			}
 			r.pkiErrs[request.NamespacedName] =
 				fmt.Errorf("could not parse PKI.Spec %s: %w", request.NamespacedName, err)
 			r.setStatus()
@@ -161,6 +175,9 @@ func (r *PKIReconciler) Reconcile(ctx context.Context, request reconcile.Request
 	}
 
 	err = existing.sync()
+	if statusErr := r.updateStatus(ctx, obj, existing.config, err); statusErr != nil {
+		log.Printf("Could not update the status of PKI %s: %v", request.NamespacedName, statusErr)
+	}
 	if err != nil {
 		log.Println(err)
 		r.pkiErrs[request.NamespacedName] =
@@ -192,13 +209,21 @@ func (r *PKIReconciler) setStatus() {
 // operatorPKI is the internal type that represents a single PKI CRD. It manages the
 // business of reconciling the certificate objects
 type operatorPKI struct {
-	spec       netopv1.OperatorPKISpec
-	controller factory.Controller
+	spec   netopv1.OperatorPKISpec
+	config *pkiConfig
+	// one controller per target certificate, all sharing the CA
+	controllers []factory.Controller
+	// stopCh stops the informers of the controllers
+	stopCh chan struct{}
 }
 
-// newPKI creates a CertRotationController for the supplied configuration
-func newPKI(config *netopv1.OperatorPKI, clientset *kubernetes.Clientset, mgr manager.Manager, certDuration time.Duration, pkiProfileProvider pki.PKIProfileProvider) (*operatorPKI, error) {
-	spec := config.Spec
+// newPKI creates a CertRotationController for each target certificate of the
+// supplied configuration
+func newPKI(obj *netopv1.OperatorPKI, clientset *kubernetes.Clientset, mgr manager.Manager, certDuration time.Duration, pkiProfileProvider pki.PKIProfileProvider) (*operatorPKI, error) {
+	config, err := resolveConfig(obj, certDuration)
+	if err != nil {
+		return nil, err
+	}
 
 	// Ugly: the existing cache + informers used as part of the controller-manager
 	// can't be used, because they're untyped. So, we need to create our own.
@@ -209,73 +234,186 @@ func newPKI(config *netopv1.OperatorPKI, clientset *kubernetes.Clientset, mgr ma
 	inf := informers.NewSharedInformerFactoryWithOptions(
 		clientset,
 		24*time.Hour,
-		informers.WithNamespace(config.Namespace))
-
-	cont := certrotation.NewCertRotationController(
-		fmt.Sprintf("%s/%s", config.Namespace, config.Name), // name, not really used
-		certrotation.RotatedSigningCASecret{
-			Namespace: config.Namespace,
-			Name:      config.Name + "-ca",
-			AdditionalAnnotations: certrotation.AdditionalAnnotations{
-				JiraComponent: names.ClusterNetworkOperatorJiraComponent,
-			},
-			CertificateName:    "network.signer",
-			PKIProfileProvider: pkiProfileProvider,
-			Validity:           10 * OneYear,
-			Refresh:            9 * OneYear,
-			Informer:           inf.Core().V1().Secrets(),
-			Lister:             inf.Core().V1().Secrets().Lister(),
-			Client:             clientset.CoreV1(),
-			EventRecorder:      &eventrecorder.LoggingRecorder{},
-		},
-		certrotation.CABundleConfigMap{
-			Namespace: config.Namespace,
-			Name:      config.Name + "-ca",
-			AdditionalAnnotations: certrotation.AdditionalAnnotations{
-				JiraComponent: names.ClusterNetworkOperatorJiraComponent,
+		informers.WithNamespace(obj.Namespace))
+
+	out := &operatorPKI{
+		config: config,
+		stopCh: make(chan struct{}),
+	}
+	obj.Spec.DeepCopyInto(&out.spec)
+
+	for i := range config.targets {
+		target := &config.targets[i]
+		out.controllers = append(out.controllers, certrotation.NewCertRotationController(
+			fmt.Sprintf("%s/%s", obj.Namespace, target.secretName), // name, not really used
+			certrotation.RotatedSigningCASecret{
+				Namespace: obj.Namespace,
+				Name:      config.ca.secretName,
+				AdditionalAnnotations: certrotation.AdditionalAnnotations{
+					JiraComponent: names.ClusterNetworkOperatorJiraComponent,
+				},
+				CertificateName:    "network.signer",
+				PKIProfileProvider: keyProfileProvider(config.ca.key, pkiProfileProvider),
+				Validity:           config.ca.validity,
+				Refresh:            config.ca.refresh,
+				Informer:           inf.Core().V1().Secrets(),
+				Lister:             inf.Core().V1().Secrets().Lister(),
+				Client:             clientset.CoreV1(),
+				EventRecorder:      &eventrecorder.LoggingRecorder{},
 			},
-			Lister:        inf.Core().V1().ConfigMaps().Lister(),
-			Informer:      inf.Core().V1().ConfigMaps(),
-			Client:        clientset.CoreV1(),
-			EventRecorder: &eventrecorder.LoggingRecorder{},
-		},
-		certrotation.RotatedSelfSignedCertKeySecret{
-			Namespace: config.Namespace,
-			Name:      config.Name + "-cert",
-			AdditionalAnnotations: certrotation.AdditionalAnnotations{
-				JiraComponent: names.ClusterNetworkOperatorJiraComponent,
+			certrotation.CABundleConfigMap{
+				Namespace: obj.Namespace,
+				Name:      obj.Name + "-ca",
+				AdditionalAnnotations: certrotation.AdditionalAnnotations{
+					JiraComponent: names.ClusterNetworkOperatorJiraComponent,
+				},
+				Lister:        inf.Core().V1().ConfigMaps().Lister(),
+				Informer:      inf.Core().V1().ConfigMaps(),
+				Client:        clientset.CoreV1(),
+				EventRecorder: &eventrecorder.LoggingRecorder{},
 			},
-			CertificateName:    "network.peer",
-			PKIProfileProvider: pkiProfileProvider,
-			Validity:           certDuration,
-			Refresh:            certDuration / 2,
-			CertCreator: &certrotation.PeerRotation{
-				Hostnames: func() []string { return []string{spec.TargetCert.CommonName} },
-				UserInfo:  &user.DefaultInfo{Name: spec.TargetCert.CommonName},
+			certrotation.RotatedSelfSignedCertKeySecret{
+				Namespace: obj.Namespace,
+				Name:      target.secretName,
+				AdditionalAnnotations: certrotation.AdditionalAnnotations{
+					JiraComponent: names.ClusterNetworkOperatorJiraComponent,
+				},
+				CertificateName:    "network.peer",
+				PKIProfileProvider: keyProfileProvider(target.key, pkiProfileProvider),
+				Validity:           target.validity,
+				Refresh:            target.refresh,
+				CertCreator:        newPeerRotation(target),
+				Lister:             inf.Core().V1().Secrets().Lister(),
+				Informer:           inf.Core().V1().Secrets(),
+				Client:             clientset.CoreV1(),
+				EventRecorder:      &eventrecorder.LoggingRecorder{},
 			},
-			Lister:        inf.Core().V1().Secrets().Lister(),
-			Informer:      inf.Core().V1().Secrets(),
-			Client:        clientset.CoreV1(),
-			EventRecorder: &eventrecorder.LoggingRecorder{},
-		},
-		&eventrecorder.LoggingRecorder{},
-		nil,
-	)
-
-	out := &operatorPKI{
-		controller: cont,
+			&eventrecorder.LoggingRecorder{},
+			nil,
+		))
 	}
-	config.Spec.DeepCopyInto(&out.spec)
 
-	ch := make(chan struct{})
-	inf.Start(ch)
-	inf.WaitForCacheSync(ch)
+	inf.Start(out.stopCh)
+	inf.WaitForCacheSync(out.stopCh)
 
 	return out, nil
 }
 
-// sync causes the underlying cert controller to try and reconcile
+// stop shuts down the informers of the PKI, once it is replaced or deleted
+func (p *operatorPKI) stop() {
+	close(p.stopCh)
+}
+
+// sync causes the underlying cert controllers to try and reconcile. The first
+// one creates or rotates the CA, which the others then reuse.
 func (p *operatorPKI) sync() error {
 	runOnceCtx := context.WithValue(context.Background(), certrotation.RunOnceContextKey, true) //nolint:staticcheck
-	return p.controller.Sync(runOnceCtx, nil)
+	for _, controller := range p.controllers {
+		if err := controller.Sync(runOnceCtx, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateStatus reports the certificates of the PKI, and the result of its last
+// sync in syncErr, in the status of obj. When config is nil, the spec of obj is
+// invalid and only the conditions are updated. The Secrets are read directly from
+// the apiserver, as the informers of the cert controllers are not exposed.
+func (r *PKIReconciler) updateStatus(ctx context.Context, obj *netopv1.OperatorPKI, config *pkiConfig, syncErr error) error {
+	now := time.Now()
+	status := obj.Status.DeepCopy()
+	if config == nil {
+		cond := rotationFailingCondition(syncErr)
+		cond.Reason = "InvalidSpec"
+		cond.ObservedGeneration = obj.Generation
+		meta.SetStatusCondition(&status.Conditions, cond)
+		return r.writeStatus(ctx, obj, status)
+	}
+
+	ca, err := r.getCertificate(ctx, obj.Namespace, config.ca.secretName)
+	if err != nil {
+		return err
+	}
+	var bundle []*x509.Certificate
+	cm, err := r.clientset.CoreV1().ConfigMaps(obj.Namespace).Get(ctx, obj.Name+"-ca", metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if bundle, err = parseCABundle(cm); err != nil {
+			return err
+		}
+	case !apierrors.IsNotFound(err):
+		return err
+	}
+
+	status.CA = nil
+	if ca != nil {
+		status.CA = certificateStatus(config.ca.secretName, ca, nextRotationTime(ca, config.ca.refresh, time.Time{}), obj.Status.CA, now)
+	}
+
+	previous := map[string]*netopv1.CertificateStatus{}
+	if obj.Status.TargetCert != nil {
+		previous[obj.Status.TargetCert.SecretName] = obj.Status.TargetCert
+	}
+	for i := range obj.Status.AdditionalTargetCerts {
+		previous[obj.Status.AdditionalTargetCerts[i].SecretName] = &obj.Status.AdditionalTargetCerts[i]
+	}
+	status.TargetCert = nil
+	status.AdditionalTargetCerts = nil
+	targets := []*x509.Certificate{}
+	for i := range config.targets {
+		target := &config.targets[i]
+		cert, err := r.getCertificate(ctx, obj.Namespace, target.secretName)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, cert)
+		if cert == nil {
+			continue
+		}
+
+		// The target certificates are not refreshed until the consumers had time
+		// to trust the CA that will sign the next ones
+		var notRefreshedBefore time.Time
+		if ca != nil {
+			notRefreshedBefore = ca.NotBefore.Add(target.refresh / 10)
+		}
+		certStatus := certificateStatus(target.secretName, cert, nextRotationTime(cert, target.refresh, notRefreshedBefore), previous[target.secretName], now)
+		if i == 0 {
+			status.TargetCert = certStatus
+		} else {
+			status.AdditionalTargetCerts = append(status.AdditionalTargetCerts, *certStatus)
+		}
+	}
+
+	for _, cond := range []metav1.Condition{
+		readyCondition(obj.Name, ca, config.targets, targets, bundle, now),
+		rotationFailingCondition(syncErr),
+	} {
+		cond.ObservedGeneration = obj.Generation
+		meta.SetStatusCondition(&status.Conditions, cond)
+	}
+	return r.writeStatus(ctx, obj, status)
+}
+
+// writeStatus sets the status of obj, if it changed
+func (r *PKIReconciler) writeStatus(ctx context.Context, obj *netopv1.OperatorPKI, status *netopv1.OperatorPKIStatus) error {
+	if equality.Semantic.DeepEqual(&obj.Status, status) {
+		return nil
+	}
+	obj.Status = *status
+	return r.mgr.GetClient().Status().Update(ctx, obj)
+}
+
+// getCertificate returns the certificate of the Secret namespace/name, or nil
+// if it doesn't exist
+func (r *PKIReconciler) getCertificate(ctx context.Context, namespace, name string) (*x509.Certificate, error) {
+	secret, err := r.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseCertificate(secret)
 }
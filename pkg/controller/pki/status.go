@@ -0,0 +1,157 @@
+package pki
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	netopv1 "github.com/openshift/cluster-network-operator/pkg/apis/network/v1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// parseCertificate returns the first certificate of the tls.crt key of secret,
+// or nil if the secret does not exist.
+func parseCertificate(secret *corev1.Secret) (*x509.Certificate, error) {
+	if secret == nil {
+		return nil, nil
+	}
+	certs, err := certutil.ParseCertsPEM(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the certificate of Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+	return certs[0], nil
+}
+
+// parseCABundle returns the certificates of the ca-bundle.crt key of cm, or nil
+// if the ConfigMap does not exist.
+func parseCABundle(cm *corev1.ConfigMap) ([]*x509.Certificate, error) {
+	if cm == nil {
+		return nil, nil
+	}
+	certs, err := certutil.ParseCertsPEM([]byte(cm.Data["ca-bundle.crt"]))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the CA bundle of ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+	return certs, nil
+}
+
+// nextRotationTime returns when the cert rotation controller will rotate cert:
+// after refresh, or when 80% of its validity is over, whichever comes first.
+// The refresh is not done before notRefreshedBefore, which the controller uses
+// to give the consumers time to trust a new CA before it signs certificates.
+func nextRotationTime(cert *x509.Certificate, refresh time.Duration, notRefreshedBefore time.Time) time.Time {
+	at80Percent := cert.NotAfter.Add(-cert.NotAfter.Sub(cert.NotBefore) / 5)
+	refreshTime := cert.NotBefore.Add(refresh)
+	if refreshTime.Before(notRefreshedBefore) {
+		refreshTime = notRefreshedBefore
+	}
+	if at80Percent.Before(refreshTime) {
+		return at80Percent
+	}
+	return refreshTime
+}
+
+// certificateStatus returns the status of cert, stored in secretName. The last
+// rotation time is kept from previous as long as the certificate has not changed;
+// when there is no previous status, the start of the validity of the certificate
+// is the best guess we have.
+func certificateStatus(secretName string, cert *x509.Certificate, nextRotation time.Time, previous *netopv1.CertificateStatus, now time.Time) *netopv1.CertificateStatus {
+	status := &netopv1.CertificateStatus{
+		SecretName:       secretName,
+		SerialNumber:     cert.SerialNumber.Text(16),
+		NotBefore:        metav1.NewTime(cert.NotBefore),
+		NotAfter:         metav1.NewTime(cert.NotAfter),
+		LastRotationTime: metav1.NewTime(cert.NotBefore),
+		NextRotationTime: metav1.NewTime(nextRotation),
+	}
+	switch {
+	case previous == nil:
+	case previous.SerialNumber == status.SerialNumber:
+		status.LastRotationTime = previous.LastRotationTime
+	default:
+		status.LastRotationTime = metav1.NewTime(now)
+	}
+	return status
+}
+
+// readyCondition returns the Ready condition of the PKI: the CA and the target
+// certificates must all exist and be valid, and the target certificates must be
+// signed by one of the CAs of the bundle, which keeps the previous CAs until the
+// certificates they signed are rotated. certs are the certificates of targets,
+// nil when their Secret does not exist.
+func readyCondition(name string, ca *x509.Certificate, targets []certConfig, certs []*x509.Certificate, bundle []*x509.Certificate, now time.Time) metav1.Condition {
+	cond := metav1.Condition{
+		Type:   netopv1.OperatorPKIReady,
+		Status: metav1.ConditionFalse,
+	}
+
+	switch {
+	case ca == nil:
+		cond.Reason = "CANotFound"
+		cond.Message = fmt.Sprintf("Secret %s-ca has not been created yet", name)
+		return cond
+	case now.After(ca.NotAfter):
+		cond.Reason = "CAExpired"
+		cond.Message = fmt.Sprintf("The CA expired at %s", ca.NotAfter.UTC().Format(time.RFC3339))
+		return cond
+	}
+
+	var notAfter time.Time
+	for i, cert := range certs {
+		secretName := targets[i].secretName
+		switch {
+		case cert == nil:
+			cond.Reason = "CertificateNotFound"
+			cond.Message = fmt.Sprintf("Secret %s has not been created yet", secretName)
+			return cond
+		case now.After(cert.NotAfter):
+			cond.Reason = "CertificateExpired"
+			cond.Message = fmt.Sprintf("The certificate of Secret %s expired at %s", secretName, cert.NotAfter.UTC().Format(time.RFC3339))
+			return cond
+		case !signedByBundle(cert, bundle):
+			cond.Reason = "CertificateNotTrusted"
+			cond.Message = fmt.Sprintf("The certificate of Secret %s is not signed by a CA of ConfigMap %s-ca", secretName, name)
+			return cond
+		}
+		if notAfter.IsZero() || cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+
+	cond.Status = metav1.ConditionTrue
+	cond.Reason = "CertificatesValid"
+	cond.Message = fmt.Sprintf("The certificates are valid until %s", notAfter.UTC().Format(time.RFC3339))
+	return cond
+}
+
+// signedByBundle returns true if cert is signed by one of the certificates of bundle
+func signedByBundle(cert *x509.Certificate, bundle []*x509.Certificate) bool {
+	for _, ca := range bundle {
+		if cert.CheckSignatureFrom(ca) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// rotationFailingCondition returns the RotationFailing condition of the PKI
+// from the result of its last sync.
+func rotationFailingCondition(err error) metav1.Condition {
+	if err != nil {
+		return metav1.Condition{
+			Type:    netopv1.OperatorPKIRotationFailing,
+			Status:  metav1.ConditionTrue,
+			Reason:  "SyncFailed",
+			Message: err.Error(),
+		}
+	}
+	return metav1.Condition{
+		Type:    netopv1.OperatorPKIRotationFailing,
+		Status:  metav1.ConditionFalse,
+		Reason:  "AsExpected",
+		Message: "The certificates are up to date",
+	}
+}
@@ -0,0 +1,156 @@
+package pki
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	netopv1 "github.com/openshift/cluster-network-operator/pkg/apis/network/v1"
+	"github.com/openshift/library-go/pkg/crypto"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func makeCA(t *testing.T, name string) *crypto.CA {
+	t.Helper()
+	config, err := crypto.MakeSelfSignedCAConfigForDuration(name, 10*OneYear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &crypto.CA{Config: config, SerialGenerator: &crypto.RandomSerialGenerator{}}
+}
+
+func makeSecret(t *testing.T, config *crypto.TLSCertificateConfig) *corev1.Secret {
+	t.Helper()
+	certPEM, keyPEM, err := config.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-ovn-kubernetes", Name: "ovn-cert"},
+		Data:       map[string][]byte{corev1.TLSCertKey: certPEM, corev1.TLSPrivateKeyKey: keyPEM},
+	}
+}
+
+func TestParseCertificate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cert, err := parseCertificate(nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cert).To(BeNil())
+
+	ca := makeCA(t, "ovn-ca")
+	cert, err = parseCertificate(makeSecret(t, ca.Config))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cert.Subject.CommonName).To(Equal("ovn-ca"))
+
+	_, err = parseCertificate(&corev1.Secret{Data: map[string][]byte{corev1.TLSCertKey: []byte("garbage")}})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestNextRotationTime(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca := makeCA(t, "ovn-ca")
+	cert := ca.Config.Certs[0]
+
+	// refresh comes before 80% of the validity
+	g.Expect(nextRotationTime(cert, 5*OneYear, time.Time{})).To(Equal(cert.NotBefore.Add(5 * OneYear)))
+
+	// 80% of the validity comes before refresh
+	at80Percent := cert.NotAfter.Add(-cert.NotAfter.Sub(cert.NotBefore) / 5)
+	g.Expect(nextRotationTime(cert, 9*OneYear, time.Time{})).To(Equal(at80Percent))
+
+	// the refresh is delayed until the signer is old enough
+	notRefreshedBefore := cert.NotBefore.Add(OneYear)
+	g.Expect(nextRotationTime(cert, OneYear/2, notRefreshedBefore)).To(Equal(notRefreshedBefore))
+}
+
+func TestCertificateStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca := makeCA(t, "ovn-ca")
+	cert := ca.Config.Certs[0]
+	now := time.Now().Add(time.Hour)
+	next := cert.NotBefore.Add(9 * OneYear)
+
+	// without a previous status, the certificate was rotated when it was issued
+	status := certificateStatus("ovn-ca", cert, next, nil, now)
+	g.Expect(status.SecretName).To(Equal("ovn-ca"))
+	g.Expect(status.SerialNumber).To(Equal(cert.SerialNumber.Text(16)))
+	g.Expect(status.NotBefore.Time).To(BeTemporally("==", cert.NotBefore))
+	g.Expect(status.NotAfter.Time).To(BeTemporally("==", cert.NotAfter))
+	g.Expect(status.LastRotationTime.Time).To(BeTemporally("==", cert.NotBefore))
+	g.Expect(status.NextRotationTime.Time).To(BeTemporally("==", next))
+
+	// the same certificate keeps its rotation time
+	previous := status.DeepCopy()
+	previous.LastRotationTime = metav1.NewTime(now.Add(-time.Minute))
+	status = certificateStatus("ovn-ca", cert, next, previous, now)
+	g.Expect(status.LastRotationTime).To(Equal(previous.LastRotationTime))
+
+	// a new certificate was rotated when we noticed it
+	previous.SerialNumber = "1"
+	status = certificateStatus("ovn-ca", cert, next, previous, now)
+	g.Expect(status.LastRotationTime.Time).To(BeTemporally("==", now))
+}
+
+func TestReadyCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca := makeCA(t, "ovn-ca")
+	serverConfig, err := ca.MakeServerCert(sets.New("ovn"), OneYear/2)
+	g.Expect(err).NotTo(HaveOccurred())
+	target := serverConfig.Certs[0]
+	otherConfig, err := ca.MakeServerCert(sets.New("ovn-other"), OneYear/4)
+	g.Expect(err).NotTo(HaveOccurred())
+	other := otherConfig.Certs[0]
+	targets := []certConfig{{secretName: "ovn-cert"}, {secretName: "ovn-other-cert"}}
+	bundle := ca.Config.Certs
+	now := time.Now()
+
+	cond := readyCondition("ovn", nil, targets, []*x509.Certificate{nil, nil}, nil, now)
+	g.Expect(cond.Type).To(Equal(netopv1.OperatorPKIReady))
+	g.Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(cond.Reason).To(Equal("CANotFound"))
+
+	cond = readyCondition("ovn", ca.Config.Certs[0], targets, []*x509.Certificate{target, nil}, bundle, now)
+	g.Expect(cond.Reason).To(Equal("CertificateNotFound"))
+	g.Expect(cond.Message).To(ContainSubstring("ovn-other-cert"))
+
+	cond = readyCondition("ovn", ca.Config.Certs[0], targets, []*x509.Certificate{target, other}, bundle, now.Add(OneYear/3))
+	g.Expect(cond.Reason).To(Equal("CertificateExpired"))
+	g.Expect(cond.Message).To(ContainSubstring("ovn-other-cert"))
+
+	cond = readyCondition("ovn", ca.Config.Certs[0], targets, []*x509.Certificate{target, other}, bundle, now)
+	g.Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(cond.Message).To(ContainSubstring(other.NotAfter.UTC().Format(time.RFC3339)))
+
+	// a certificate signed by a previous CA is still trusted while that CA is in
+	// the bundle
+	newCA := makeCA(t, "ovn-ca-2")
+	cond = readyCondition("ovn", newCA.Config.Certs[0], targets[:1], []*x509.Certificate{target}, newCA.Config.Certs, now)
+	g.Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(cond.Reason).To(Equal("CertificateNotTrusted"))
+
+	cond = readyCondition("ovn", newCA.Config.Certs[0], targets[:1], []*x509.Certificate{target}, append(newCA.Config.Certs, bundle...), now)
+	g.Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal("CertificatesValid"))
+}
+
+func TestRotationFailingCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cond := rotationFailingCondition(nil)
+	g.Expect(cond.Type).To(Equal(netopv1.OperatorPKIRotationFailing))
+	g.Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+
+	cond = rotationFailingCondition(errors.New("secrets is forbidden"))
+	g.Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal("SyncFailed"))
+	g.Expect(cond.Message).To(Equal("secrets is forbidden"))
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
 	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
 	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/util/validation"
 	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
 
 	corev1 "k8s.io/api/core/v1"
@@ -34,7 +36,10 @@ func Add(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.C
 		return fmt.Errorf("failed to create reconciler")
 	}
 
-	return add(mgr, reconciler)
+	if err := add(mgr, reconciler); err != nil {
+		return err
+	}
+	return addHealth(mgr, status, reconciler)
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -111,6 +116,8 @@ func (r *ReconcileProxyConfig) Reconcile(ctx context.Context, request reconcile.
 	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
 	validate := true
 	trustBundle := &corev1.ConfigMap{}
+	// trustedCASummary summarizes the certificates of the trustedCA of the proxy, if any
+	var trustedCASummary []validation.TrustBundleCertificate
 
 	switch {
 	case request.Namespace != names.ADDL_TRUST_BUNDLE_CONFIGMAP_NS:
@@ -178,7 +185,7 @@ func (r *ReconcileProxyConfig) Reconcile(ctx context.Context, request reconcile.
 			}
 
 			// Create a configmap containing the merged proxy.trustedCA/system bundles.
-			trustBundle, err = r.mergeTrustBundlesToConfigMap(proxyData, systemData)
+			trustBundle, trustedCASummary, err = r.mergeTrustBundlesToConfigMap(proxyData, systemData)
 			if err != nil {
 				log.Printf("Failed to merge trustedCA and system bundles for proxy '%s': %v", proxyConfig.Name, err)
 				r.status.SetDegraded(statusmanager.ProxyConfig, "ProxyCAMergeFailure",
@@ -251,7 +258,7 @@ func (r *ReconcileProxyConfig) Reconcile(ctx context.Context, request reconcile.
 		}
 
 		// Create a configmap containing the merged proxy.trustedCA/system bundles.
-		_, err = r.mergeTrustBundlesToConfigMap(proxyData, systemData)
+		_, trustedCASummary, err = r.mergeTrustBundlesToConfigMap(proxyData, systemData)
 		if err != nil {
 			log.Printf("Failed to merge trustedCA and system bundles for proxy '%s': %v", names.PROXY_CONFIG, err)
 			r.status.SetDegraded(statusmanager.ProxyConfig, "EnsureProxyConfigFailure",
@@ -315,7 +322,7 @@ func (r *ReconcileProxyConfig) Reconcile(ctx context.Context, request reconcile.
 					proxyConfig.Name, err)
 			}
 			// Create a configmap containing the merged proxy.trustedCA/system bundles.
-			trustBundle, err = r.mergeTrustBundlesToConfigMap(proxyData, systemData)
+			trustBundle, trustedCASummary, err = r.mergeTrustBundlesToConfigMap(proxyData, systemData)
 			if err != nil {
 				log.Printf("Failed to merge trustedCA and system bundles for proxy '%s': %v", proxyConfig.Name, err)
 				r.status.SetDegraded(statusmanager.ProxyConfig, "ProxyCAMergeFailure",
@@ -340,9 +347,22 @@ func (r *ReconcileProxyConfig) Reconcile(ctx context.Context, request reconcile.
 			trustBundle.Namespace, trustBundle.Name, err)
 	}
 
+	// Report the certificates of the trustedCA, warning about the expired ones.
+	if err := r.syncTrustedCASummary(trustedCASummary); err != nil {
+		log.Printf("Failed to sync trust bundle summary configmap %s/%s: %v", names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS,
+			names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP, err)
+		r.status.MaybeSetDegraded(statusmanager.ProxyConfig, "TrustBundleSummarySyncFailure",
+			fmt.Sprintf("Trust bundle summary configmap '%s/%s' not synced (%v)", names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS,
+				names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP, err))
+		return reconcile.Result{}, fmt.Errorf("failed to sync trust bundle summary configmap %s/%s: %v",
+			names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS, names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP, err)
+	}
+
 	r.status.SetNotDegraded(statusmanager.ProxyConfig)
 
-	return reconcile.Result{}, nil
+	// Inspect the trustedCA again when one of its certificates is about to
+	// expire, as no event is received then.
+	return reconcile.Result{RequeueAfter: trustedCARequeueAfter(trustedCASummary, time.Now())}, nil
 }
 
 // isSpecHTTPProxySet returns true if spec.httpProxy of
@@ -377,18 +397,25 @@ func isSpecTrustedCASet(proxyConfig *configv1.ProxySpec) bool {
 // into a single byte slice, ensures the merged byte slice contains valid
 // PEM encoded certificates, embeds the merged byte slice into a ConfigMap
 // named "trusted-ca-bundle" in namespace "openshift-config-managed" and
-// returns the ConfigMap. It's the caller's responsibility to create the
-// ConfigMap in the api server.
-func (r *ReconcileProxyConfig) mergeTrustBundlesToConfigMap(additionalData, systemData []byte) (*corev1.ConfigMap, error) {
+// returns the ConfigMap, along with the summary of the certificates of
+// additionalData. The certificates of additionalData that are expired or
+// already in systemData are left out. It's the caller's responsibility to
+// create the ConfigMap in the api server.
+func (r *ReconcileProxyConfig) mergeTrustBundlesToConfigMap(additionalData, systemData []byte) (*corev1.ConfigMap, []validation.TrustBundleCertificate, error) {
 	if len(additionalData) == 0 {
-		return nil, fmt.Errorf("failed to merge ca bundles, additional trust bundle is empty")
+		return nil, nil, fmt.Errorf("failed to merge ca bundles, additional trust bundle is empty")
 	}
 	if len(systemData) == 0 {
-		return nil, fmt.Errorf("failed to merge ca bundles, system trust bundle is empty")
+		return nil, nil, fmt.Errorf("failed to merge ca bundles, system trust bundle is empty")
+	}
+
+	mergedData, summary, err := validation.InspectTrustBundle(additionalData, systemData, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to inspect additional trust bundle: %v", err)
 	}
 
 	combinedTrustData := []byte{}
-	combinedTrustData = append(combinedTrustData, additionalData...)
+	combinedTrustData = append(combinedTrustData, mergedData...)
 	// add a newline here so that CVO can digest user and system certs correctly
 	combinedTrustData = append(combinedTrustData, []byte("\n")...)
 	combinedTrustData = append(combinedTrustData, systemData...)
@@ -406,11 +433,11 @@ func (r *ReconcileProxyConfig) mergeTrustBundlesToConfigMap(additionalData, syst
 		},
 	}
 	if _, _, err := r.validateTrustBundle(mergedCfgMap); err != nil {
-		return nil, fmt.Errorf("failed to validate merged configmap '%s/%s': %v", mergedCfgMap.Namespace,
+		return nil, nil, fmt.Errorf("failed to validate merged configmap '%s/%s': %v", mergedCfgMap.Namespace,
 			mergedCfgMap.Name, err)
 	}
 
-	return mergedCfgMap, nil
+	return mergedCfgMap, summary, nil
 }
 
 // syncTrustedCABundle checks if ConfigMap named "trusted-ca-bundle"
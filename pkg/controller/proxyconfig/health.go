@@ -0,0 +1,292 @@
+package proxyconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/util/validation"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/events"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// proxyHealthInterval is how often the proxies and readiness endpoints are probed
+	proxyHealthInterval = time.Minute
+	// proxyHealthFailureThreshold is the number of consecutive failed rounds of
+	// probes before the proxy is reported as failing, so that a single lost probe
+	// does not flap the condition
+	proxyHealthFailureThreshold = 3
+
+	// The kinds of probes
+	probeKindProxy             = "proxy"
+	probeKindReadinessEndpoint = "readiness_endpoint"
+)
+
+// probeResult is the result of a single probe
+type probeResult struct {
+	kind string
+	// url is the probed URL, without its credentials
+	url      string
+	duration time.Duration
+	err      error
+}
+
+// addHealth adds a controller that keeps probing the proxies and readiness
+// endpoints of the cluster-wide proxy after they were validated by r. It runs
+// apart from r so that slow probes never hold back the proxy configuration.
+func addHealth(mgr manager.Manager, status *statusmanager.StatusManager, r *ReconcileProxyConfig) error {
+	h := &ReconcileProxyHealth{
+		config:   r,
+		status:   status,
+		recorder: mgr.GetEventRecorder("proxy-health"),
+	}
+	c, err := controller.New("proxy-health-controller", mgr, controller.Options{Reconciler: h})
+	if err != nil {
+		return err
+	}
+
+	// Status updates do not change what is probed
+	return c.Watch(source.Kind[crclient.Object](mgr.GetCache(), &configv1.Proxy{}, &handler.EnqueueRequestForObject{},
+		predicate.GenerationChangedPredicate{}))
+}
+
+var _ reconcile.Reconciler = &ReconcileProxyHealth{}
+
+// ReconcileProxyHealth periodically probes the cluster-wide proxy. The results
+// are exported as metrics; when the probes keep failing, the non-blocking
+// ProxyProbesFailing condition is set on the operator and a Warning Event is
+// recorded on the Proxy, whose status has no conditions.
+type ReconcileProxyHealth struct {
+	// config reads the Proxy and validates its trust bundle
+	config   *ReconcileProxyConfig
+	status   *statusmanager.StatusManager
+	recorder events.EventRecorder
+
+	// failures is the number of consecutive failed rounds of probes. It is only
+	// used by Reconcile, which never runs concurrently.
+	failures int
+}
+
+// Reconcile probes the proxies and readiness endpoints of the cluster-wide
+// proxy, and requeues itself for the next round.
+func (r *ReconcileProxyHealth) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
+
+	if request.Name != names.PROXY_CONFIG {
+		return reconcile.Result{}, nil
+	}
+
+	proxyConfig := &configv1.Proxy{}
+	if err := r.config.client.Get(ctx, names.Proxy(), proxyConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.reset()
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("failed to get proxy '%s': %v", names.PROXY_CONFIG, err)
+	}
+	if !isSpecHTTPProxySet(&proxyConfig.Spec) && !isSpecHTTPSProxySet(&proxyConfig.Spec) {
+		// Nothing to probe until a proxy is configured
+		r.reset()
+		return reconcile.Result{}, nil
+	}
+
+	results := r.probe(&proxyConfig.Spec)
+	updateProbeMetrics(results)
+
+	failed := failedProbes(results)
+	wasFailing := r.failures >= proxyHealthFailureThreshold
+	if len(failed) > 0 {
+		r.failures++
+	} else {
+		r.failures = 0
+	}
+	cond := proxyHealthCondition(failed, r.failures)
+	r.status.SetCondition(cond)
+
+	isFailing := cond.Status == operv1.ConditionTrue
+	switch {
+	case isFailing && !wasFailing:
+		log.Printf("Proxy '%s' is failing its probes: %s", proxyConfig.Name, cond.Message)
+		r.recorder.Eventf(proxyConfig, nil, corev1.EventTypeWarning, "ProxyProbesFailing", "Probe", "%s", cond.Message)
+	case !isFailing && wasFailing:
+		log.Printf("Proxy '%s' is passing its probes again", proxyConfig.Name)
+		r.recorder.Eventf(proxyConfig, nil, corev1.EventTypeNormal, "ProxyProbesSucceeded", "Probe", "The proxy and its readiness endpoints are reachable again")
+	}
+
+	return reconcile.Result{RequeueAfter: proxyHealthInterval}, nil
+}
+
+// reset forgets the previous probes, when there is no proxy to probe
+func (r *ReconcileProxyHealth) reset() {
+	r.failures = 0
+	updateProbeMetrics(nil)
+	r.status.SetCondition(proxyHealthCondition(nil, 0))
+}
+
+// probe runs a round of probes against the proxies of proxyConfig and its
+// readiness endpoints. The readiness endpoints use the same trust bundle, and
+// the same proxy for each scheme, as the validation of the proxy configuration.
+func (r *ReconcileProxyHealth) probe(proxyConfig *configv1.ProxySpec) []probeResult {
+	trustBundle, bundleErr := r.config.readinessTrustBundle(proxyConfig)
+
+	results := []probeResult{}
+	proxies := []string{}
+	for _, proxy := range []string{proxyConfig.HTTPProxy, proxyConfig.HTTPSProxy} {
+		if proxy != "" && (len(proxies) == 0 || proxies[0] != proxy) {
+			proxies = append(proxies, proxy)
+		}
+	}
+	for _, proxy := range proxies {
+		results = append(results, timeProbe(probeKindProxy, proxy, func() error {
+			proxyURL, err := url.Parse(proxy)
+			if err != nil {
+				return fmt.Errorf("failed to parse proxy url: %v", err)
+			}
+			if proxyURL.Scheme == schemeHTTPS && bundleErr != nil {
+				return bundleErr
+			}
+			return probeProxy(trustBundle, proxyURL)
+		}))
+	}
+
+	for _, endpoint := range proxyConfig.ReadinessEndpoints {
+		results = append(results, timeProbe(probeKindReadinessEndpoint, endpoint, func() error {
+			if bundleErr != nil {
+				return bundleErr
+			}
+			scheme, err := validation.URI(endpoint)
+			if err != nil {
+				return fmt.Errorf("invalid URI for readinessEndpoint: %v", err)
+			}
+			proxyURL, err := url.Parse(proxyForScheme(proxyConfig, scheme))
+			if err != nil {
+				return fmt.Errorf("failed to parse proxy url: %v", err)
+			}
+			endpointURL, err := url.Parse(endpoint)
+			if err != nil {
+				return fmt.Errorf("failed to parse endpoint url: %v", err)
+			}
+			return runReadinessProbe(trustBundle, proxyURL, endpointURL)
+		}))
+	}
+
+	return results
+}
+
+// timeProbe runs probe, timing how long it takes
+func timeProbe(kind, target string, probe func() error) probeResult {
+	start := time.Now()
+	err := probe()
+	return probeResult{
+		kind:     kind,
+		url:      redactURL(target),
+		duration: time.Since(start),
+		err:      err,
+	}
+}
+
+// probeProxy checks that proxy accepts connections, and for an https proxy that
+// it presents a certificate signed by caBundle.
+func probeProxy(caBundle []*x509.Certificate, proxy *url.URL) error {
+	port := proxy.Port()
+	if port == "" {
+		port = "80"
+		if proxy.Scheme == schemeHTTPS {
+			port = "443"
+		}
+	}
+
+	dialer, err := probeDialer()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), proxyProbeTimeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(proxy.Hostname(), port))
+	if err != nil {
+		return fmt.Errorf("failed to connect to proxy '%s': %v", redactURL(proxy.String()), err)
+	}
+	defer conn.Close()
+
+	if proxy.Scheme != schemeHTTPS {
+		return nil
+	}
+	caPool := x509.NewCertPool()
+	for _, cert := range caBundle {
+		caPool.AddCert(cert)
+	}
+	tlsConn := tls.Client(conn, &tls.Config{RootCAs: caPool, ServerName: proxy.Hostname()})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("TLS handshake with proxy '%s' failed: %v", redactURL(proxy.String()), err)
+	}
+	return nil
+}
+
+// redactURL returns u without its password, which proxy URLs may contain
+func redactURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		// do not risk leaking credentials in what cannot be parsed
+		return "<invalid URL>"
+	}
+	return parsed.Redacted()
+}
+
+// failedProbes returns the results of the probes that failed
+func failedProbes(results []probeResult) []probeResult {
+	failed := []probeResult{}
+	for _, result := range results {
+		if result.err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// proxyHealthCondition returns the ProxyProbesFailing condition for the failed
+// probes of the last round, after failures consecutive failed rounds. The
+// message does not change while the same probes keep failing. The condition is
+// informational: the proxy is not under the control of the
+// operator, so it never degrades it.
+func proxyHealthCondition(failed []probeResult, failures int) operv1.OperatorCondition {
+	if len(failed) == 0 || failures < proxyHealthFailureThreshold {
+		return operv1.OperatorCondition{
+			Type:   names.ProxyProbesFailingCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+
+	messages := []string{}
+	for _, result := range failed {
+		messages = append(messages, fmt.Sprintf("%s %s: %v", strings.ReplaceAll(result.kind, "_", " "), result.url, result.err))
+	}
+	return operv1.OperatorCondition{
+		Type:   names.ProxyProbesFailingCondition,
+		Status: operv1.ConditionTrue,
+		Reason: "ProbesFailing",
+		Message: fmt.Sprintf("The cluster-wide proxy failed its probes at least %d times in a row: %s",
+			proxyHealthFailureThreshold, strings.Join(messages, "; ")),
+	}
+}
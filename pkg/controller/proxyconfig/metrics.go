@@ -0,0 +1,43 @@
+package proxyconfig
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const cnoNamespace = "openshift_network_operator"
+
+var (
+	proxyProbeSuccessGauge  *metrics.GaugeVec
+	proxyProbeDurationGauge *metrics.GaugeVec
+)
+
+func init() {
+	proxyProbeSuccessGauge = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace: cnoNamespace,
+		Name:      "proxy_probe_success",
+		Help:      "Whether the last probe of a cluster-wide proxy or of one of its readiness endpoints succeeded (1) or failed (0).",
+	}, []string{"kind", "url"})
+	proxyProbeDurationGauge = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace: cnoNamespace,
+		Name:      "proxy_probe_duration_seconds",
+		Help:      "How long the last probe of a cluster-wide proxy or of one of its readiness endpoints took.",
+	}, []string{"kind", "url"})
+	legacyregistry.MustRegister(proxyProbeSuccessGauge)
+	legacyregistry.MustRegister(proxyProbeDurationGauge)
+}
+
+// updateProbeMetrics replaces the probe gauges with results, so that the
+// proxies and endpoints that were removed stop being reported.
+func updateProbeMetrics(results []probeResult) {
+	proxyProbeSuccessGauge.Reset()
+	proxyProbeDurationGauge.Reset()
+	for _, result := range results {
+		success := 1.0
+		if result.err != nil {
+			success = 0
+		}
+		proxyProbeSuccessGauge.WithLabelValues(result.kind, result.url).Set(success)
+		proxyProbeDurationGauge.WithLabelValues(result.kind, result.url).Set(result.duration.Seconds())
+	}
+}
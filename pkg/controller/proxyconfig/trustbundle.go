@@ -0,0 +1,151 @@
+package proxyconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/util/validation"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// trustedCAMaxRequeueInterval is the longest time the certificates of the
+// trustedCA of the proxy go without being inspected, while any of them can
+// still change status.
+const trustedCAMaxRequeueInterval = time.Hour
+
+// syncTrustedCASummary publishes the summary of the certificates of the
+// trustedCA of the proxy in the ConfigMap "trusted-ca-bundle-summary", next to
+// "trusted-ca-bundle", and warns about the certificates that are expired or
+// about to expire with the ProxyTrustedCAExpiring condition.
+func (r *ReconcileProxyConfig) syncTrustedCASummary(summary []validation.TrustBundleCertificate) error {
+	cond := trustedCAExpiringCondition(summary)
+	if cond.Status == operv1.ConditionTrue {
+		log.Printf("Warning: %s", cond.Message)
+	}
+	r.status.SetCondition(cond)
+
+	summaryCfgMap, err := trustedCASummaryConfigMap(summary)
+	if err != nil {
+		return err
+	}
+
+	currentCfgMap := &corev1.ConfigMap{}
+	if err := r.client.Get(context.TODO(), names.TrustedCABundleSummaryConfigMap(), currentCfgMap); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get trust bundle summary configmap '%s/%s': %v",
+				summaryCfgMap.Namespace, summaryCfgMap.Name, err)
+		}
+		if err := r.client.Create(context.TODO(), summaryCfgMap); err != nil {
+			return fmt.Errorf("failed to create trust bundle summary configmap '%s/%s': %v",
+				summaryCfgMap.Namespace, summaryCfgMap.Name, err)
+		}
+		return nil
+	}
+
+	if !configMapsEqual(names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP_KEY, currentCfgMap, summaryCfgMap) {
+		if err := r.client.Update(context.TODO(), summaryCfgMap); err != nil {
+			return fmt.Errorf("failed to update trust bundle summary configmap '%s/%s': %v",
+				summaryCfgMap.Namespace, summaryCfgMap.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// trustedCARequeueAfter returns when the certificates of summary should be
+// inspected again: at the earliest time one of them starts expiring soon or
+// expires, and at least every trustedCAMaxRequeueInterval. It returns 0 when
+// no certificate can change status.
+func trustedCARequeueAfter(summary []validation.TrustBundleCertificate, now time.Time) time.Duration {
+	var next time.Duration
+	for _, cert := range summary {
+		var transition time.Time
+		switch cert.Status {
+		case validation.TrustBundleCertificateValid:
+			transition = cert.NotAfter.Add(-validation.TrustBundleExpiryWarningPeriod)
+		case validation.TrustBundleCertificateExpiringSoon, validation.TrustBundleCertificateDuplicate:
+			transition = cert.NotAfter
+		default:
+			continue
+		}
+		// the status changes right after the transition
+		after := transition.Sub(now) + time.Second
+		if after <= 0 {
+			after = time.Second
+		}
+		if next == 0 || after < next {
+			next = after
+		}
+	}
+	if next > trustedCAMaxRequeueInterval {
+		next = trustedCAMaxRequeueInterval
+	}
+	return next
+}
+
+// trustedCASummaryConfigMap returns the ConfigMap "trusted-ca-bundle-summary"
+// in namespace "openshift-config-managed" holding summary as JSON.
+func trustedCASummaryConfigMap(summary []validation.TrustBundleCertificate) (*corev1.ConfigMap, error) {
+	if summary == nil {
+		summary = []validation.TrustBundleCertificate{}
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode trust bundle summary: %v", err)
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP,
+			Namespace: names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS,
+			Annotations: map[string]string{
+				names.OpenShiftComponent: names.ClusterNetworkOperatorJiraComponent,
+			},
+		},
+		Data: map[string]string{
+			names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP_KEY: string(data),
+		},
+	}, nil
+}
+
+// trustedCAExpiringCondition returns the ProxyTrustedCAExpiring condition for
+// the certificates of summary. It is only a warning: expired certificates are
+// already left out of the merged trust bundle.
+func trustedCAExpiringCondition(summary []validation.TrustBundleCertificate) operv1.OperatorCondition {
+	messages := []string{}
+	for _, cert := range summary {
+		switch cert.Status {
+		case validation.TrustBundleCertificateExpired:
+			messages = append(messages, fmt.Sprintf("%q expired at %s and is not trusted anymore",
+				cert.Subject, cert.NotAfter.Format(time.RFC3339)))
+		case validation.TrustBundleCertificateExpiringSoon:
+			messages = append(messages, fmt.Sprintf("%q expires at %s",
+				cert.Subject, cert.NotAfter.Format(time.RFC3339)))
+		}
+	}
+
+	if len(messages) == 0 {
+		return operv1.OperatorCondition{
+			Type:   names.ProxyTrustedCAExpiringCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+	return operv1.OperatorCondition{
+		Type:   names.ProxyTrustedCAExpiringCondition,
+		Status: operv1.ConditionTrue,
+		Reason: "CertificatesExpiring",
+		Message: fmt.Sprintf("Certificates of the trustedCA of proxy '%s' need to be renewed: %s. "+
+			"See ConfigMap '%s/%s' for all its certificates.", names.PROXY_CONFIG, strings.Join(messages, "; "),
+			names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS, names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP),
+	}
+}
@@ -0,0 +1,122 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/util/validation"
+)
+
+func TestTrustedCAExpiringCondition(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	summary := []validation.TrustBundleCertificate{
+		{Subject: "CN=valid", NotAfter: notAfter, Status: validation.TrustBundleCertificateValid},
+		{Subject: "CN=duplicate", NotAfter: notAfter, Status: validation.TrustBundleCertificateDuplicate},
+	}
+
+	cond := trustedCAExpiringCondition(summary)
+	if cond.Type != names.ProxyTrustedCAExpiringCondition || cond.Status != operv1.ConditionFalse {
+		t.Errorf("expected condition to be false without expiring certificates, got: %v", cond)
+	}
+
+	summary = append(summary,
+		validation.TrustBundleCertificate{Subject: "CN=expired", NotAfter: notAfter, Status: validation.TrustBundleCertificateExpired},
+		validation.TrustBundleCertificate{Subject: "CN=expiring", NotAfter: notAfter, Status: validation.TrustBundleCertificateExpiringSoon},
+	)
+	cond = trustedCAExpiringCondition(summary)
+	if cond.Status != operv1.ConditionTrue || cond.Reason != "CertificatesExpiring" {
+		t.Errorf("expected condition to be true with expiring certificates, got: %v", cond)
+	}
+	for _, expected := range []string{
+		`"CN=expired" expired at 2030-01-02T03:04:05Z`,
+		`"CN=expiring" expires at 2030-01-02T03:04:05Z`,
+		names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP,
+	} {
+		if !strings.Contains(cond.Message, expected) {
+			t.Errorf("expected condition message to contain %q, got: %s", expected, cond.Message)
+		}
+	}
+	if strings.Contains(cond.Message, "CN=valid") {
+		t.Errorf("expected condition message to leave out valid certificates, got: %s", cond.Message)
+	}
+}
+
+func TestTrustedCASummaryConfigMap(t *testing.T) {
+	cfgMap, err := trustedCASummaryConfigMap(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfgMap.Namespace != names.TRUSTED_CA_BUNDLE_CONFIGMAP_NS || cfgMap.Name != names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP {
+		t.Errorf("unexpected configmap %s/%s", cfgMap.Namespace, cfgMap.Name)
+	}
+	if got := cfgMap.Data[names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP_KEY]; got != "[]" {
+		t.Errorf("expected an empty summary without trustedCA, got: %s", got)
+	}
+
+	summary := []validation.TrustBundleCertificate{{
+		Subject:      "CN=proxy-ca",
+		Issuer:       "CN=proxy-ca",
+		SerialNumber: "1f",
+		NotAfter:     time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		Status:       validation.TrustBundleCertificateValid,
+	}}
+	cfgMap, err = trustedCASummaryConfigMap(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := []validation.TrustBundleCertificate{}
+	if err := json.Unmarshal([]byte(cfgMap.Data[names.TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP_KEY]), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[0] != summary[0] {
+		t.Errorf("expected summary %v, got: %v", summary, decoded)
+	}
+}
+
+func TestTrustedCARequeueAfter(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		summary  []validation.TrustBundleCertificate
+		expected time.Duration
+	}{
+		{
+			name:     "no certificates",
+			expected: 0,
+		},
+		{
+			name: "expired certificates only",
+			summary: []validation.TrustBundleCertificate{
+				{NotAfter: now.Add(-time.Hour), Status: validation.TrustBundleCertificateExpired},
+			},
+			expected: 0,
+		},
+		{
+			name: "expiring soon",
+			summary: []validation.TrustBundleCertificate{
+				{NotAfter: now.Add(10 * time.Minute), Status: validation.TrustBundleCertificateExpiringSoon},
+				{NotAfter: now.Add(validation.TrustBundleExpiryWarningPeriod + 5*time.Minute), Status: validation.TrustBundleCertificateValid},
+			},
+			expected: 5*time.Minute + time.Second,
+		},
+		{
+			name: "far from expiry",
+			summary: []validation.TrustBundleCertificate{
+				{NotAfter: now.Add(365 * 24 * time.Hour), Status: validation.TrustBundleCertificateValid},
+				{NotAfter: now.Add(2 * time.Hour), Status: validation.TrustBundleCertificateDuplicate},
+			},
+			expected: trustedCAMaxRequeueInterval,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := trustedCARequeueAfter(tc.summary, now); got != tc.expected {
+				t.Errorf("expected requeue after %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -33,6 +34,8 @@ const (
 	proxyProbeMaxRetries = 3
 	// proxyProbeWaitTime is the time to wait before retrying a failed proxy probe.
 	proxyProbeWaitTime = 1 * time.Second
+	// proxyProbeTimeout is how long a proxy probe waits for an answer.
+	proxyProbeTimeout = 10 * time.Second
 )
 
 // ValidateProxyConfig ensures that httpProxy, httpsProxy and
@@ -76,42 +79,17 @@ func (r *ReconcileProxyConfig) ValidateProxyConfig(proxyConfig *configv1.ProxySp
 	}
 
 	if isSpecReadinessEndpointsSet(proxyConfig) {
+		trustBundle, err := r.readinessTrustBundle(proxyConfig)
+		if err != nil {
+			return err
+		}
 		for _, endpoint := range proxyConfig.ReadinessEndpoints {
 			scheme, err := validation.URI(endpoint)
 			if err != nil {
 				return fmt.Errorf("invalid URI for readinessEndpoint '%s': %v", endpoint, err)
 			}
-			var systemData []byte
-			var proxyData []byte
-			if isSpecTrustedCASet(proxyConfig) {
-				// TrustedCA is set, so create a combined trustedCA/system trust bundle for readinessEndpoints.
-				proxyData, systemData, err = r.validateTrustedCA(proxyConfig.TrustedCA.Name)
-				if err != nil {
-					return fmt.Errorf("failed to get certificate data for trustedCA '%s': %v",
-						proxyConfig.TrustedCA.Name, err)
-				}
-			} else {
-				// No trustedCA is set, so use the system trust bundle for readinessEndpoints.
-				systemData, err = os.ReadFile(names.SYSTEM_TRUST_BUNDLE)
-				if err != nil {
-					return fmt.Errorf("failed to read system trust bundle '%s': %v",
-						names.SYSTEM_TRUST_BUNDLE, err)
-				}
-			}
-			var trustBundle []*x509.Certificate
-			// Merge the proxy trustedCA (if it exists) and system trust bundle data.
-			trustBundle, err = validation.MergeCertificateData(systemData, proxyData)
-			if err != nil {
-				return fmt.Errorf("failed to merge system and trustedCA trust bundles: %v", err)
-			}
-			if scheme == schemeHTTPS && isSpecHTTPSProxySet(proxyConfig) {
-				if err := validateReadinessEndpoint(trustBundle, proxyConfig.HTTPSProxy, endpoint); err != nil {
-					return fmt.Errorf("readinessEndpoint probe failed for endpoint '%s': %v", endpoint, err)
-				}
-			} else {
-				if err := validateReadinessEndpoint(trustBundle, proxyConfig.HTTPProxy, endpoint); err != nil {
-					return fmt.Errorf("readinessEndpoint probe failed for endpoint '%s': %v", endpoint, err)
-				}
+			if err := validateReadinessEndpoint(trustBundle, proxyForScheme(proxyConfig, scheme), endpoint); err != nil {
+				return fmt.Errorf("readinessEndpoint probe failed for endpoint '%s': %v", endpoint, err)
 			}
 		}
 	}
@@ -119,6 +97,46 @@ func (r *ReconcileProxyConfig) ValidateProxyConfig(proxyConfig *configv1.ProxySp
 	return nil
 }
 
+// readinessTrustBundle returns the CAs used to probe the readinessEndpoints of
+// proxyConfig: the trustedCA of the proxy, if set, merged with the system trust
+// bundle.
+func (r *ReconcileProxyConfig) readinessTrustBundle(proxyConfig *configv1.ProxySpec) ([]*x509.Certificate, error) {
+	var systemData []byte
+	var proxyData []byte
+	var err error
+	if isSpecTrustedCASet(proxyConfig) {
+		// TrustedCA is set, so create a combined trustedCA/system trust bundle for readinessEndpoints.
+		proxyData, systemData, err = r.validateTrustedCA(proxyConfig.TrustedCA.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get certificate data for trustedCA '%s': %v",
+				proxyConfig.TrustedCA.Name, err)
+		}
+	} else {
+		// No trustedCA is set, so use the system trust bundle for readinessEndpoints.
+		systemData, err = os.ReadFile(names.SYSTEM_TRUST_BUNDLE)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read system trust bundle '%s': %v",
+				names.SYSTEM_TRUST_BUNDLE, err)
+		}
+	}
+	// Merge the proxy trustedCA (if it exists) and system trust bundle data.
+	trustBundle, err := validation.MergeCertificateData(systemData, proxyData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge system and trustedCA trust bundles: %v", err)
+	}
+	return trustBundle, nil
+}
+
+// proxyForScheme returns the proxy of proxyConfig used for endpoints with the
+// given scheme: httpsProxy for https endpoints when it is set, httpProxy
+// otherwise.
+func proxyForScheme(proxyConfig *configv1.ProxySpec, scheme string) string {
+	if scheme == schemeHTTPS && isSpecHTTPSProxySet(proxyConfig) {
+		return proxyConfig.HTTPSProxy
+	}
+	return proxyConfig.HTTPProxy
+}
+
 // validateTrustedCA validates that trustedCA is a valid ConfigMap
 // reference and that the ConfigMap contains a valid trust bundle,
 // returning the byte slices of the certificate data from the
@@ -235,25 +253,13 @@ func validateReadinessEndpointWithRetries(caBundle []*x509.Certificate, proxy, e
 // caBundle is used to authenticate endpoint if endpoint contains an
 // https scheme.
 func runReadinessProbe(caBundle []*x509.Certificate, proxyURL, endpoint *url.URL) error {
-	transport := &http.Transport{
-		Proxy: http.ProxyURL(proxyURL),
+	dialer, err := probeDialer()
+	if err != nil {
+		return err
 	}
-
-	if hypershift.NewHyperShiftConfig().Enabled {
-		hostedProxyURL, err := url.Parse(hypershift.HostedClusterLocalProxy)
-		if err != nil {
-			return fmt.Errorf("failed to parse hosted cluster proxy: %w", err)
-		}
-		hcpProxy, err := netproxy.FromURL(hostedProxyURL, nil)
-		if err != nil {
-			return fmt.Errorf("failed to create hosted cluster proxy: %w", err)
-		}
-
-		hcpProxyCtx, ok := hcpProxy.(netproxy.ContextDialer)
-		if !ok {
-			return fmt.Errorf("proxy from %q does not implement proxy.ContextDialer", hypershift.HostedClusterLocalProxy)
-		}
-		transport.DialContext = hcpProxyCtx.DialContext
+	transport := &http.Transport{
+		Proxy:       http.ProxyURL(proxyURL),
+		DialContext: dialer.DialContext,
 	}
 
 	if endpoint.Scheme == schemeHTTPS {
@@ -266,18 +272,19 @@ func runReadinessProbe(caBundle []*x509.Certificate, proxyURL, endpoint *url.URL
 
 	client := &http.Client{
 		Transport: transport,
+		Timeout:   proxyProbeTimeout,
 	}
 
 	request, err := http.NewRequest("GET", endpoint.String(), nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request for '%s' using proxy '%s': %v", endpoint.String(),
-			proxyURL.String(), err)
+			proxyURL.Redacted(), err)
 	}
 
 	resp, err := client.Do(request)
 	if err != nil {
 		return fmt.Errorf("endpoint probe failed for endpoint '%s' using proxy '%s': %v",
-			endpoint.String(), proxyURL.String(), err)
+			endpoint.String(), proxyURL.Redacted(), err)
 	}
 	defer resp.Body.Close()
 
@@ -286,5 +293,28 @@ func runReadinessProbe(caBundle []*x509.Certificate, proxyURL, endpoint *url.URL
 	}
 
 	return fmt.Errorf("endpoint probe failed with statuscode '%d' for endpoint '%s' using proxy '%s' ",
-		resp.StatusCode, endpoint.String(), proxyURL.String())
+		resp.StatusCode, endpoint.String(), proxyURL.Redacted())
+}
+
+// probeDialer returns the dialer of the proxy probes: on HyperShift, the
+// control plane reaches the proxy through the local proxy of the hosted cluster.
+func probeDialer() (netproxy.ContextDialer, error) {
+	if !hypershift.NewHyperShiftConfig().Enabled {
+		return &net.Dialer{Timeout: proxyProbeTimeout}, nil
+	}
+
+	hostedProxyURL, err := url.Parse(hypershift.HostedClusterLocalProxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse hosted cluster proxy: %w", err)
+	}
+	hcpProxy, err := netproxy.FromURL(hostedProxyURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hosted cluster proxy: %w", err)
+	}
+
+	hcpProxyCtx, ok := hcpProxy.(netproxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("proxy from %q does not implement proxy.ContextDialer", hypershift.HostedClusterLocalProxy)
+	}
+	return hcpProxyCtx, nil
 }
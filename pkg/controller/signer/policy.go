@@ -0,0 +1,106 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	csrv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// nodeUserPrefix is the prefix of the user names of the ovnkube-node pods,
+	// followed by the name of their node
+	nodeUserPrefix = "system:ovn-node:"
+
+	// chassisIDAnnotation is set by ovnkube-node on its node with the OVS
+	// system-id, which the IPsec pods use as the common name of their certificate
+	chassisIDAnnotation = "k8s.ovn.org/node-chassis-id"
+
+	// ipsecOrganization is the organization of the IPsec certificates
+	ipsecOrganization = "ovnkubernetes"
+
+	// The weakest keys we sign
+	minRSAKeySize   = 2048
+	minECDSAKeySize = 256
+)
+
+// allowedUsages are the usages that the IPsec pods may request
+var allowedUsages = sets.New(csrv1.UsageIPsecTunnel, csrv1.UsageDigitalSignature, csrv1.UsageKeyEncipherment)
+
+// policyError is the reason why the signing policy rejects a request
+type policyError struct {
+	reason  string
+	message string
+}
+
+func (e *policyError) Error() string {
+	return e.message
+}
+
+// nodeNameFromUser returns the node of an ovnkube-node user name, or false if
+// the user is not an ovnkube-node
+func nodeNameFromUser(username string) (string, bool) {
+	nodeName, ok := strings.CutPrefix(username, nodeUserPrefix)
+	return nodeName, ok && nodeName != ""
+}
+
+// validateRequest checks the request of csr, made by node, against the signing
+// policy: the certificate must be an IPsec certificate for the chassis of the
+// node, with no other name, and a strong enough key.
+func validateRequest(csr *csrv1.CertificateSigningRequest, req *x509.CertificateRequest, node *corev1.Node) *policyError {
+	for _, usage := range csr.Spec.Usages {
+		if !allowedUsages.Has(usage) {
+			return &policyError{"CSRInvalidUsage", fmt.Sprintf("Usage %q is not allowed, only %q are", usage, sets.List(allowedUsages))}
+		}
+	}
+
+	if err := req.CheckSignature(); err != nil {
+		return &policyError{"CSRInvalidSignature", fmt.Sprintf("Certificate Request signature is invalid: %v", err)}
+	}
+	if err := validateKeyStrength(req); err != nil {
+		return &policyError{"CSRWeakKey", err.Error()}
+	}
+
+	chassisID := node.Annotations[chassisIDAnnotation]
+	if req.Subject.CommonName != chassisID {
+		return &policyError{"CSRInvalidSubject", fmt.Sprintf("Common name %q is not the chassis ID %q of node %s", req.Subject.CommonName, chassisID, node.Name)}
+	}
+	if len(req.Subject.Organization) != 1 || req.Subject.Organization[0] != ipsecOrganization {
+		return &policyError{"CSRInvalidSubject", fmt.Sprintf("Organization %q must be %q", req.Subject.Organization, ipsecOrganization)}
+	}
+
+	if len(req.IPAddresses) > 0 || len(req.EmailAddresses) > 0 || len(req.URIs) > 0 {
+		return &policyError{"CSRInvalidSAN", "Only the common name is allowed as subject alternative name"}
+	}
+	for _, name := range req.DNSNames {
+		if name != chassisID {
+			return &policyError{"CSRInvalidSAN", fmt.Sprintf("Subject alternative name %q is not the chassis ID %q of node %s", name, chassisID, node.Name)}
+		}
+	}
+
+	return nil
+}
+
+// validateKeyStrength checks that the public key of req is strong enough
+func validateKeyStrength(req *x509.CertificateRequest) error {
+	switch key := req.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeySize {
+			return fmt.Errorf("RSA key size %d is below the minimum of %d", key.N.BitLen(), minRSAKeySize)
+		}
+	case *ecdsa.PublicKey:
+		if key.Curve.Params().BitSize < minECDSAKeySize {
+			return fmt.Errorf("ECDSA curve %s is below the minimum of %d bits", key.Curve.Params().Name, minECDSAKeySize)
+		}
+	case ed25519.PublicKey:
+	default:
+		return fmt.Errorf("public key algorithm %s is not allowed", req.PublicKeyAlgorithm)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	certificatev1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeNameFromUser(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	name, ok := nodeNameFromUser("system:ovn-node:worker-0")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(name).To(Equal("worker-0"))
+
+	_, ok = nodeNameFromUser("system:ovn-node:")
+	g.Expect(ok).To(BeFalse())
+	_, ok = nodeNameFromUser("system:node:worker-0")
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestValidateRequest(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        nodeName,
+			Annotations: map[string]string{chassisIDAnnotation: chassisID},
+		},
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		template func(*x509.CertificateRequest)
+		key      func(t *testing.T) crypto.Signer
+		usages   []certificatev1.KeyUsage
+		reason   string
+	}{
+		{
+			name: "valid request",
+		},
+		{
+			name:   "valid request with key usages",
+			usages: []certificatev1.KeyUsage{certificatev1.UsageIPsecTunnel, certificatev1.UsageDigitalSignature, certificatev1.UsageKeyEncipherment},
+		},
+		{
+			name: "valid request with an ECDSA key",
+			key: func(t *testing.T) crypto.Signer {
+				key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return key
+			},
+		},
+		{
+			name:   "client auth usage",
+			usages: []certificatev1.KeyUsage{certificatev1.UsageIPsecTunnel, certificatev1.UsageClientAuth},
+			reason: "CSRInvalidUsage",
+		},
+		{
+			name: "weak RSA key",
+			key: func(t *testing.T) crypto.Signer {
+				key, err := rsa.GenerateKey(rand.Reader, 1024)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return key
+			},
+			reason: "CSRWeakKey",
+		},
+		{
+			name: "weak ECDSA key",
+			key: func(t *testing.T) crypto.Signer {
+				key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return key
+			},
+			reason: "CSRWeakKey",
+		},
+		{
+			name:     "common name of another chassis",
+			template: func(req *x509.CertificateRequest) { req.Subject.CommonName = "other-chassis" },
+			reason:   "CSRInvalidSubject",
+		},
+		{
+			name:     "other organization",
+			template: func(req *x509.CertificateRequest) { req.Subject.Organization = []string{"system:masters"} },
+			reason:   "CSRInvalidSubject",
+		},
+		{
+			name: "additional organization",
+			template: func(req *x509.CertificateRequest) {
+				req.Subject.Organization = append(req.Subject.Organization, "system:masters")
+			},
+			reason: "CSRInvalidSubject",
+		},
+		{
+			name:     "additional DNS name",
+			template: func(req *x509.CertificateRequest) { req.DNSNames = append(req.DNSNames, "kubernetes.default.svc") },
+			reason:   "CSRInvalidSAN",
+		},
+		{
+			name:     "IP address",
+			template: func(req *x509.CertificateRequest) { req.IPAddresses = []net.IP{net.ParseIP("10.0.0.1")} },
+			reason:   "CSRInvalidSAN",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			template := ipsecCSRTemplate()
+			if tc.template != nil {
+				tc.template(template)
+			}
+			var key crypto.Signer = rsaKey
+			if tc.key != nil {
+				key = tc.key(t)
+			}
+			der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+			g.Expect(err).NotTo(HaveOccurred())
+			req, err := x509.ParseCertificateRequest(der)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			csr := &certificatev1.CertificateSigningRequest{}
+			csr.Spec.Usages = tc.usages
+			if csr.Spec.Usages == nil {
+				csr.Spec.Usages = []certificatev1.KeyUsage{certificatev1.UsageIPsecTunnel}
+			}
+
+			perr := validateRequest(csr, req, node)
+			if tc.reason == "" {
+				g.Expect(perr).To(BeNil())
+			} else {
+				g.Expect(perr).NotTo(BeNil())
+				g.Expect(perr.reason).To(Equal(tc.reason))
+			}
+		})
+	}
+}
+
+func TestValidateKeyStrengthEd25519(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	der, err := x509.CreateCertificateRequest(rand.Reader, ipsecCSRTemplate(), key)
+	g.Expect(err).NotTo(HaveOccurred())
+	req, err := x509.ParseCertificateRequest(der)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(validateKeyStrength(req)).To(Succeed())
+}
@@ -14,6 +14,7 @@ import (
 	csrv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/events"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -30,6 +31,10 @@ import (
 
 const signerName = "network.openshift.io/signer"
 
+// chassisIDRetryPeriod is how long we wait for the chassis ID of the node of a
+// request
+var chassisIDRetryPeriod = 10 * time.Second
+
 // Add controller and start it when the Manager is started.
 func Add(mgr manager.Manager, status *statusmanager.StatusManager, client cnoclient.Client, featureGates featuregates.FeatureGate) error {
 	reconciler, err := newReconciler(client, mgr, status, featureGates)
@@ -45,7 +50,14 @@ func newReconciler(client cnoclient.Client, mgr manager.Manager, status *statusm
 	if featureGates.Enabled(features.FeatureShortCertRotation) {
 		certDuration = 3 * time.Hour
 	}
-	return &ReconcileCSR{client: client, scheme: mgr.GetScheme(), status: status, certDuration: certDuration}, nil
+	return &ReconcileCSR{
+		client:       client,
+		nodeClient:   mgr.GetClient(),
+		recorder:     mgr.GetEventRecorder("network-signer"),
+		scheme:       mgr.GetScheme(),
+		status:       status,
+		certDuration: certDuration,
+	}, nil
 
 }
 
@@ -71,9 +83,9 @@ var _ reconcile.Reconciler = &ReconcileCSR{}
 // ReconcileCSR reconciles a cluster CertificateSigningRequest object. This
 // will watch for changes to CertificateSigningRequest resources with
 // SignerName == signerName. It will automatically approve these requests for
-// signing. This assumes that the cluster has been configured in a way that
-// no bad actors can make certificate signing requests. In future, we may decide
-// to implement a scheme that would use a one-time token to validate a request.
+// signing if they satisfy the signing policy of validateRequest: they must come
+// from an ovnkube-node, and ask for an IPsec certificate for the chassis of its
+// node. Rejected requests are marked as Failed, with an Event giving the reason.
 //
 // All requests will be signed using a CA, that is currently generated by
 // the OperatorPKI, and the signed certificate will be returned in the status.
@@ -83,7 +95,10 @@ var _ reconcile.Reconciler = &ReconcileCSR{}
 type ReconcileCSR struct {
 	// This client, initialized using mgr.GetClient() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client       cnoclient.Client
+	client cnoclient.Client
+	// nodeClient reads the Nodes from the cache of the manager
+	nodeClient   crclient.Reader
+	recorder     events.EventRecorder
 	scheme       *runtime.Scheme
 	status       *statusmanager.StatusManager
 	certDuration time.Duration
@@ -113,14 +128,14 @@ func (r *ReconcileCSR) Reconcile(ctx context.Context, request reconcile.Request)
 		return reconcile.Result{}, nil
 	}
 
-	isValid, err := r.isValidUserName(ctx, csr.Spec.Username)
+	node, err := r.getRequestingNode(ctx, csr.Spec.Username)
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("error occurred while validating CSR %s: %w", csr.Name, err)
 	}
-	if !isValid {
+	if node == nil {
 		// Update CSR status condition with Failed condition.
-		updateCSRStatusConditions(r, csr, "CSRInvalidUser",
-			"Certificate Signing Request is set with invalid user name, can't sign it")
+		rejectRequest(r, csr, &policyError{"CSRInvalidUser",
+			"Certificate Signing Request is set with invalid user name, can't sign it"})
 		return reconcile.Result{}, nil
 	}
 
@@ -131,10 +146,31 @@ func (r *ReconcileCSR) Reconcile(ctx context.Context, request reconcile.Request)
 		return reconcile.Result{}, nil
 	}
 
-	// We will make the assumption that anyone with permission to issue a
-	// certificate signing request to this signer is automatically approved. This
-	// is somewhat protected by permissions on the CSR resource.
-	// TODO: We may need a more robust way to do this later
+	// Decode the certificate request from PEM format.
+	certReq, err := decodeCertificateRequest(csr.Spec.Request)
+	if err != nil {
+		// We dont degrade the status of the controller as this is due to a
+		// malformed CSR rather than an issue with the controller.
+		rejectRequest(r, csr, &policyError{"CSRDecodeFailure",
+			fmt.Sprintf("Could not decode Certificate Request: %v", err)})
+		return reconcile.Result{}, nil
+	}
+
+	if _, ok := node.Annotations[chassisIDAnnotation]; !ok {
+		// ovnkube-node has not annotated its node yet, wait for it rather than
+		// rejecting the request
+		log.Printf("Node %s of CSR %s has no chassis ID yet, retrying", node.Name, csr.Name)
+		return reconcile.Result{RequeueAfter: chassisIDRetryPeriod}, nil
+	}
+
+	// The policy is checked before signing too, as the request may have been
+	// approved by someone else
+	if perr := validateRequest(csr, certReq, node); perr != nil {
+		rejectRequest(r, csr, perr)
+		return reconcile.Result{}, nil
+	}
+
+	// Requests that satisfy the signing policy are automatically approved.
 	if !isCertificateRequestApproved(csr) {
 		csr.Status.Conditions = append(csr.Status.Conditions, csrv1.CertificateSigningRequestCondition{
 			Type:    csrv1.CertificateApproved,
@@ -164,16 +200,6 @@ func (r *ReconcileCSR) Reconcile(ctx context.Context, request reconcile.Request)
 		return reconcile.Result{}, err
 	}
 
-	// Decode the certificate request from PEM format.
-	certReq, err := decodeCertificateRequest(csr.Spec.Request)
-	if err != nil {
-		// We dont degrade the status of the controller as this is due to a
-		// malformed CSR rather than an issue with the controller.
-		updateCSRStatusConditions(r, csr, "CSRDecodeFailure",
-			fmt.Sprintf("Could not decode Certificate Request: %v", err))
-		return reconcile.Result{}, nil
-	}
-
 	// Decode the CA certificate from PEM format.
 	caCert, err := decodeCertificate(caSecret.Data["tls.crt"])
 	if err != nil {
@@ -192,7 +218,13 @@ func (r *ReconcileCSR) Reconcile(ctx context.Context, request reconcile.Request)
 
 	// Create a new certificate using the certificate template and certificate.
 	// We can then sign this using the CA.
-	signedCert, err := signCSR(newCertificateTemplate(certReq, r.certDuration), certReq.PublicKey, caCert, caKey)
+	template, err := newCertificateTemplate(certReq, r.certDuration)
+	if err != nil {
+		signerFailure(r, csr, "SigningFailure",
+			fmt.Sprintf("Unable to create certificate for %v and signer %v: %v", request.Name, signerName, err))
+		return reconcile.Result{}, nil
+	}
+	signedCert, err := signCSR(template, certReq.PublicKey, caCert, caKey)
 	if err != nil {
 		signerFailure(r, csr, "SigningFailure",
 			fmt.Sprintf("Unable to sign certificate for %v and signer %v: %v", request.Name, signerName, err))
@@ -218,17 +250,21 @@ func (r *ReconcileCSR) Reconcile(ctx context.Context, request reconcile.Request)
 	return reconcile.Result{}, nil
 }
 
-func (r *ReconcileCSR) isValidUserName(ctx context.Context, csrUserName string) (bool, error) {
-	nodeList, err := r.client.Default().Kubernetes().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return false, fmt.Errorf("failed to list nodes: %v", err)
+// getRequestingNode returns the node of the ovnkube-node that made a request
+// as csrUserName, or nil if that is not an ovnkube-node of an existing node.
+func (r *ReconcileCSR) getRequestingNode(ctx context.Context, csrUserName string) (*corev1.Node, error) {
+	nodeName, ok := nodeNameFromUser(csrUserName)
+	if !ok {
+		return nil, nil
 	}
-	for _, node := range nodeList.Items {
-		if fmt.Sprintf("system:ovn-node:%s", node.Name) == csrUserName {
-			return true, nil
+	node := &corev1.Node{}
+	if err := r.nodeClient.Get(ctx, types.NamespacedName{Name: nodeName}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
 		}
+		return nil, fmt.Errorf("failed to get node %s: %v", nodeName, err)
 	}
-	return false, nil
+	return node, nil
 }
 
 // isCertificateRequestApproved returns true if a certificate request has the
@@ -258,6 +294,20 @@ func signerFailure(r *ReconcileCSR, csr *csrv1.CertificateSigningRequest, reason
 	r.status.MaybeSetDegraded(statusmanager.CertificateSigner, reason, message)
 }
 
+// The request does not satisfy the signing policy, so we mark the csr as Failed and
+// record an Event with the reason, without degrading the operator.
+func rejectRequest(r *ReconcileCSR, csr *csrv1.CertificateSigningRequest, perr *policyError) {
+	for _, c := range csr.Status.Conditions {
+		if c.Type == csrv1.CertificateFailed && c.Reason == perr.reason && c.Message == perr.message {
+			// Already rejected, don't record it again
+			return
+		}
+	}
+	log.Printf("Rejecting CSR %s: %s: %s", csr.Name, perr.reason, perr.message)
+	r.recorder.Eventf(csr, nil, corev1.EventTypeWarning, perr.reason, "Sign", "%s", perr.message)
+	updateCSRStatusConditions(r, csr, perr.reason, perr.message)
+}
+
 // Update the status conditions on the CSR object
 func updateCSRStatusConditions(r *ReconcileCSR, csr *csrv1.CertificateSigningRequest, reason string, message string) {
 	setCertificateSigningRequestCondition(&csr.Status.Conditions, csrv1.CertificateSigningRequestCondition{
@@ -4,33 +4,40 @@ import (
 	c "crypto"
 	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"math/big"
-	mathrand "math/rand"
 	"time"
 
 	"k8s.io/client-go/util/keyutil"
 )
 
-func newCertificateTemplate(certReq *x509.CertificateRequest, certDuration time.Duration) *x509.Certificate {
+func newCertificateTemplate(certReq *x509.CertificateRequest, certDuration time.Duration) (*x509.Certificate, error) {
 	// Like in openshift/library-go/pkg/crypto/crypto.go, we will generate a random
-	// serial number
-	serialNumber := mathrand.New(mathrand.NewSource(time.Now().UTC().UnixNano())).Int63()
+	// serial number, of 128 bits as recommended by RFC 5280
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("could not generate a serial number: %w", err)
+	}
 
+	// Only the names checked by validateRequest are copied from the request
 	template := &x509.Certificate{
-		Subject: certReq.Subject,
+		Subject: pkix.Name{
+			CommonName:   certReq.Subject.CommonName,
+			Organization: certReq.Subject.Organization,
+		},
 
 		NotBefore:    time.Now().Add(-1 * time.Second),
 		NotAfter:     time.Now().Add(certDuration),
-		SerialNumber: big.NewInt(serialNumber),
+		SerialNumber: serialNumber,
 
 		DNSNames:              certReq.DNSNames,
 		BasicConstraintsValid: true,
 	}
 
-	return template
+	return template, nil
 }
 
 func signCSR(template *x509.Certificate, requestKey c.PublicKey, issuer *x509.Certificate, issuerKey c.PrivateKey) (*x509.Certificate, error) {
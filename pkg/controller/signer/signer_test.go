@@ -8,6 +8,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
 	"testing"
@@ -27,13 +28,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 var (
-	csrName  = "ipsec-csr"
-	nodeName = "testnode"
-	coName   = "testing"
+	csrName   = "ipsec-csr"
+	nodeName  = "testnode"
+	chassisID = "0b6c9e4a-1f7d-4c2e-9a55-3c1f1e2d7b10"
+	coName    = "testing"
 )
 
 //nolint:errcheck
@@ -46,14 +49,15 @@ func TestSigner_reconciler(t *testing.T) {
 	g := NewGomegaWithT(t)
 	client := fake.NewFakeClient()
 	status := statusmanager.New(client, coName, names.StandAloneClusterName)
-	signer := ReconcileCSR{client: client, status: status}
+	recorder := events.NewFakeRecorder(10)
+	signer := ReconcileCSR{client: client, nodeClient: client.Default().CRClient(), recorder: recorder, status: status}
 
 	co := &configv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: coName}}
 	setCO(t, client, co)
 	no := &operv1.Network{ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG}}
 	setOC(t, client, no)
 
-	csr, err := generateCSR()
+	csr, err := generateCSR(ipsecCSRTemplate())
 	g.Expect(err).NotTo(HaveOccurred())
 	csrObj := &certificatev1.CertificateSigningRequest{}
 	csrObj.Name = csrName
@@ -74,7 +78,8 @@ func TestSigner_reconciler(t *testing.T) {
 
 	node := &corev1.Node{}
 	node.Name = nodeName
-	_, err = client.Default().Kubernetes().CoreV1().Nodes().Create(t.Context(), node, metav1.CreateOptions{})
+	node.Annotations = map[string]string{chassisIDAnnotation: chassisID}
+	err = client.Default().CRClient().Create(t.Context(), node)
 	g.Expect(err).NotTo(HaveOccurred())
 
 	ca, err := crypto.MakeSelfSignedCAConfigForDuration(signerName, 10*time.Minute)
@@ -99,6 +104,13 @@ func TestSigner_reconciler(t *testing.T) {
 	err = client.Default().CRClient().Get(t.Context(), types.NamespacedName{Name: csrName}, csrObj)
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(csrObj.Status.Certificate).ShouldNot(BeEmpty())
+	cert, err := decodeCertificate(csrObj.Status.Certificate)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cert.Subject.CommonName).To(Equal(chassisID))
+	g.Expect(cert.Subject.Organization).To(Equal([]string{ipsecOrganization}))
+	g.Expect(cert.Subject.OrganizationalUnit).To(BeEmpty())
+	g.Expect(cert.DNSNames).To(Equal([]string{chassisID}))
+	g.Expect(recorder.Events).To(BeEmpty())
 
 	co, _, err = getStatuses(client, "testing")
 	if err != nil {
@@ -123,14 +135,15 @@ func TestSigner_reconciler_withInvalidUserName(t *testing.T) {
 	g := NewGomegaWithT(t)
 	client := fake.NewFakeClient()
 	status := statusmanager.New(client, coName, names.StandAloneClusterName)
-	signer := ReconcileCSR{client: client, status: status}
+	recorder := events.NewFakeRecorder(10)
+	signer := ReconcileCSR{client: client, nodeClient: client.Default().CRClient(), recorder: recorder, status: status}
 
 	co := &configv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: coName}}
 	setCO(t, client, co)
 	no := &operv1.Network{ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG}}
 	setOC(t, client, no)
 
-	csr, err := generateCSR()
+	csr, err := generateCSR(ipsecCSRTemplate())
 	g.Expect(err).NotTo(HaveOccurred())
 	csrObj := &certificatev1.CertificateSigningRequest{}
 	csrObj.Name = csrName
@@ -146,7 +159,8 @@ func TestSigner_reconciler_withInvalidUserName(t *testing.T) {
 
 	node := &corev1.Node{}
 	node.Name = nodeName
-	_, err = client.Default().Kubernetes().CoreV1().Nodes().Create(t.Context(), node, metav1.CreateOptions{})
+	node.Annotations = map[string]string{chassisIDAnnotation: chassisID}
+	err = client.Default().CRClient().Create(t.Context(), node)
 	g.Expect(err).NotTo(HaveOccurred())
 
 	_, err = signer.Reconcile(t.Context(),
@@ -160,6 +174,13 @@ func TestSigner_reconciler_withInvalidUserName(t *testing.T) {
 	g.Expect(len(csrConditions)).To(Equal(1))
 	g.Expect(csrConditions[0].Reason).To(Equal("CSRInvalidUser"))
 	g.Expect(csrConditions[0].Type).To(Equal(certificatev1.CertificateFailed))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning CSRInvalidUser")))
+
+	// A second reconciliation doesn't record the rejection again
+	_, err = signer.Reconcile(t.Context(),
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: csrName}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recorder.Events).To(BeEmpty())
 
 	co, _, err = getStatuses(client, "testing")
 	if err != nil {
@@ -169,6 +190,58 @@ func TestSigner_reconciler_withInvalidUserName(t *testing.T) {
 	g.Expect(len(co.Status.Conditions)).To(BeZero())
 }
 
+func TestSigner_reconciler_waitsForChassisID(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := fake.NewFakeClient()
+	status := statusmanager.New(client, coName, names.StandAloneClusterName)
+	recorder := events.NewFakeRecorder(10)
+	signer := ReconcileCSR{client: client, nodeClient: client.Default().CRClient(), recorder: recorder, status: status}
+
+	csr, err := generateCSR(ipsecCSRTemplate())
+	g.Expect(err).NotTo(HaveOccurred())
+	csrObj := &certificatev1.CertificateSigningRequest{}
+	csrObj.Name = csrName
+	csrObj.Spec.Request = []byte(csr)
+	csrObj.Spec.SignerName = signerName
+	csrObj.Spec.Usages = []certificatev1.KeyUsage{"ipsec tunnel"}
+	csrObj.Spec.Username = fmt.Sprintf("system:ovn-node:%s", nodeName)
+	err = client.Default().CRClient().Create(t.Context(), csrObj)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	node := &corev1.Node{}
+	node.Name = nodeName
+	err = client.Default().CRClient().Create(t.Context(), node)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	result, err := signer.Reconcile(t.Context(),
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: csrName}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(chassisIDRetryPeriod))
+
+	err = client.Default().CRClient().Get(t.Context(), types.NamespacedName{Name: csrName}, csrObj)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(csrObj.Status.Conditions).To(BeEmpty())
+	g.Expect(recorder.Events).To(BeEmpty())
+}
+
+func TestNewCertificateTemplate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	csr, err := generateCSR(ipsecCSRTemplate())
+	g.Expect(err).NotTo(HaveOccurred())
+	req, err := decodeCertificateRequest([]byte(csr))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	first, err := newCertificateTemplate(req, time.Hour)
+	g.Expect(err).NotTo(HaveOccurred())
+	second, err := newCertificateTemplate(req, time.Hour)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(first.SerialNumber.Cmp(second.SerialNumber)).NotTo(BeZero())
+	g.Expect(first.SerialNumber.BitLen()).To(BeNumerically("<=", 128))
+	g.Expect(first.Subject.CommonName).To(Equal(chassisID))
+	g.Expect(first.Subject.Country).To(BeEmpty())
+}
+
 func TestDecodePrivateKey(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -299,14 +372,27 @@ func TestDecodePrivateKey(t *testing.T) {
 	}
 }
 
-func generateCSR() (string, error) {
+// ipsecCSRTemplate returns a request like the ones of the IPsec pods
+func ipsecCSRTemplate() *x509.CertificateRequest {
+	return &x509.CertificateRequest{
+		Subject: pkix.Name{
+			Country:            []string{"US"},
+			Organization:       []string{ipsecOrganization},
+			OrganizationalUnit: []string{"kind"},
+			CommonName:         chassisID,
+		},
+		DNSNames: []string{chassisID},
+	}
+}
+
+func generateCSR(template *x509.CertificateRequest) (string, error) {
 	// Create private key.
 	csrKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate private key: %v", err)
 	}
 	// Create CSR with private key.
-	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, csrKey)
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, template, csrKey)
 	if err != nil {
 		return "", err
 	}
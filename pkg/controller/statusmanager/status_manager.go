@@ -57,6 +57,10 @@ const (
 	CertificateSigner
 	InfrastructureConfig
 	DashboardConfig
+	GatewayModeMigration
+	AllowlistConfig
+	WhereaboutsConfig
+	MultiNetworkPolicyConfig
 	maxStatusLevel
 )
 
@@ -354,6 +358,12 @@ func (status *StatusManager) writeHypershiftStatus(operStatus *operv1.NetworkSta
 
 // Set updates the operator and clusteroperator statuses with the provided conditions.
 func (status *StatusManager) set(reachedAvailableLevel bool, conditions ...operv1.OperatorCondition) {
+	status.update(reachedAvailableLevel, nil, conditions...)
+}
+
+// update sets the provided conditions and removes the conditions of the removed
+// types from the operator and clusteroperator statuses.
+func (status *StatusManager) update(reachedAvailableLevel bool, removed []string, conditions ...operv1.OperatorCondition) {
 	var operStatus *operv1.NetworkStatus
 
 	// Set status on the network.operator object
@@ -393,6 +403,9 @@ func (status *StatusManager) set(reachedAvailableLevel bool, conditions ...operv
 		for _, condition := range conditions {
 			v1helpers.SetOperatorCondition(&oc.Status.Conditions, condition)
 		}
+		for _, conditionType := range removed {
+			v1helpers.RemoveOperatorCondition(&oc.Status.Conditions, conditionType)
+		}
 
 		progressingCondition := v1helpers.FindOperatorCondition(oc.Status.Conditions, operv1.OperatorStatusTypeProgressing)
 		availableCondition := v1helpers.FindOperatorCondition(oc.Status.Conditions, operv1.OperatorStatusTypeAvailable)
@@ -489,6 +502,9 @@ func (status *StatusManager) set(reachedAvailableLevel bool, conditions ...operv
 			for _, cond := range operStatus.Conditions {
 				cohelpers.SetStatusCondition(&co.Status.Conditions, operstatus.OperatorConditionToClusterOperatorCondition(cond), clock.RealClock{})
 			}
+			for _, conditionType := range removed {
+				cohelpers.RemoveStatusCondition(&co.Status.Conditions, configv1.ClusterStatusConditionType(conditionType))
+			}
 		}
 
 		if reflect.DeepEqual(*oldStatus, co.Status) {
@@ -642,6 +658,23 @@ func (status *StatusManager) UnsetProgressing(statusLevel StatusLevel) {
 	status.unsetProgressing(statusLevel)
 }
 
+// SetCondition sets an informational condition on the operator status. Unlike
+// Degraded and Progressing, such conditions are not tracked per StatusLevel: the
+// caller owns the condition type and is responsible for clearing it.
+func (status *StatusManager) SetCondition(condition operv1.OperatorCondition) {
+	status.Lock()
+	defer status.Unlock()
+	status.set(false, condition)
+}
+
+// RemoveCondition removes an informational condition set with SetCondition from
+// the operator status.
+func (status *StatusManager) RemoveCondition(conditionType string) {
+	status.Lock()
+	defer status.Unlock()
+	status.update(false, []string{conditionType})
+}
+
 func (status *StatusManager) SetRelatedObjects(relatedObjects []configv1.ObjectReference) {
 	status.Lock()
 	defer status.Unlock()
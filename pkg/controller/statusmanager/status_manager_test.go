@@ -13,6 +13,7 @@ import (
 	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
 	"github.com/openshift/cluster-network-operator/pkg/client/fake"
 	"github.com/openshift/cluster-network-operator/pkg/names"
+	cohelpers "github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
@@ -375,6 +376,47 @@ func TestStatusManagerSetDegraded(t *testing.T) {
 	}
 }
 
+func TestStatusManagerRemoveCondition(t *testing.T) {
+	client := fake.NewFakeClient()
+	status := New(client, "testing", names.StandAloneClusterName)
+	setCO(t, client, "testing")
+	setOC(t, client, &operv1.Network{ObjectMeta: metav1.ObjectMeta{Name: names.OPERATOR_CONFIG}})
+
+	condSelected := operv1.OperatorCondition{
+		Type:   "SubnetsSelected",
+		Status: operv1.ConditionTrue,
+		Reason: "DefaultSubnetsInUse",
+	}
+	condUpdate := operv1.OperatorCondition{
+		Type:   operv1.OperatorStatusTypeUpgradeable,
+		Status: operv1.ConditionTrue,
+	}
+
+	status.SetCondition(condSelected)
+	co, oc, err := getStatuses(client, "testing")
+	if err != nil {
+		t.Fatalf("error getting network config: %v", err)
+	}
+	if !conditionsEqual(oc.Status.Conditions, []operv1.OperatorCondition{condSelected, condUpdate}) {
+		t.Fatalf("unexpected Status.Conditions: %#v", oc.Status.Conditions)
+	}
+	if cohelpers.FindStatusCondition(co.Status.Conditions, "SubnetsSelected") == nil {
+		t.Fatalf("expected the SubnetsSelected condition on the ClusterOperator: %#v", co.Status.Conditions)
+	}
+
+	status.RemoveCondition("SubnetsSelected")
+	co, oc, err = getStatuses(client, "testing")
+	if err != nil {
+		t.Fatalf("error getting network config: %v", err)
+	}
+	if !conditionsEqual(oc.Status.Conditions, []operv1.OperatorCondition{condUpdate}) {
+		t.Fatalf("unexpected Status.Conditions: %#v", oc.Status.Conditions)
+	}
+	if cohelpers.FindStatusCondition(co.Status.Conditions, "SubnetsSelected") != nil {
+		t.Fatalf("unexpected SubnetsSelected condition on the ClusterOperator: %#v", co.Status.Conditions)
+	}
+}
+
 func TestStatusManagerMaybeSetDegradedDebouncing(t *testing.T) {
 	client := fake.NewFakeClient()
 	status := New(client, "testing", names.StandAloneClusterName)
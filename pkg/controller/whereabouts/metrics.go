@@ -0,0 +1,39 @@
+package whereabouts
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const cnoNamespace = "openshift_network_operator"
+
+var (
+	ipPoolAllocatedGauge *metrics.GaugeVec
+	ipPoolSizeGauge      *metrics.GaugeVec
+)
+
+func init() {
+	ipPoolAllocatedGauge = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace: cnoNamespace,
+		Name:      "whereabouts_ippool_allocated_addresses",
+		Help:      "The number of addresses of a whereabouts IP pool that are assigned to a pod.",
+	}, []string{"namespace", "pool", "range"})
+	ipPoolSizeGauge = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace: cnoNamespace,
+		Name:      "whereabouts_ippool_size_addresses",
+		Help:      "The number of addresses whereabouts can assign from an IP pool.",
+	}, []string{"namespace", "pool", "range"})
+	legacyregistry.MustRegister(ipPoolAllocatedGauge)
+	legacyregistry.MustRegister(ipPoolSizeGauge)
+}
+
+// updateMetrics replaces the IP pool gauges with the given usage, so that removed
+// pools stop being reported.
+func updateMetrics(usage []poolUsage) {
+	ipPoolAllocatedGauge.Reset()
+	ipPoolSizeGauge.Reset()
+	for _, u := range usage {
+		ipPoolAllocatedGauge.WithLabelValues(u.namespace, u.name, u.cidr).Set(u.allocated)
+		ipPoolSizeGauge.WithLabelValues(u.namespace, u.name, u.cidr).Set(u.size)
+	}
+}
@@ -0,0 +1,127 @@
+package whereabouts
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+)
+
+// exhaustionThreshold is the fraction of the addresses of an IP pool above which
+// it is reported as nearly exhausted
+const exhaustionThreshold = 0.9
+
+// maxReportedPools is the number of nearly exhausted IP pools named in the condition
+const maxReportedPools = 10
+
+// rangeOverlaps describes the whereabouts ranges that assign the same addresses
+// within the same network_name. Ranges of the same subnet share an IP pool, but
+// the others each have their own and so may assign the same addresses.
+func rangeOverlaps(ranges []network.WhereaboutsRange) []string {
+	out := []string{}
+	for i := range ranges {
+		for j := i + 1; j < len(ranges); j++ {
+			a, b := &ranges[i], &ranges[j]
+			if a.NetworkName != b.NetworkName || a.CIDR.String() == b.CIDR.String() || !a.Overlaps(b) {
+				continue
+			}
+			msg := fmt.Sprintf("%s (%s) overlaps %s (%s)", a.Owner, a, b.Owner, b)
+			if a.NetworkName != "" {
+				msg += fmt.Sprintf(" in network_name %q", a.NetworkName)
+			}
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// poolUsage is the number of addresses allocated from a whereabouts IP pool
+type poolUsage struct {
+	namespace string
+	name      string
+	cidr      string
+	allocated float64
+	size      float64
+}
+
+func (u *poolUsage) String() string {
+	return fmt.Sprintf("%s/%s (%s) %d/%d", u.namespace, u.name, u.cidr, int64(u.allocated), int64(u.size))
+}
+
+// ipPoolUsage returns the usage of the whereabouts IPPool pool, and false if its
+// range is invalid. Its size is the number of addresses that ranges assign from
+// it, or all the addresses of its subnet if none of them uses it.
+func ipPoolUsage(pool *uns.Unstructured, ranges []network.WhereaboutsRange) (poolUsage, bool) {
+	r, _, _ := uns.NestedString(pool.Object, "spec", "range")
+	_, cidr, err := net.ParseCIDR(r)
+	if err != nil {
+		return poolUsage{}, false
+	}
+	size, ok := network.WhereaboutsPoolSize(ranges, pool.GetName(), cidr)
+	if !ok {
+		size = rangeSize(cidr)
+	}
+	allocations, _, _ := uns.NestedMap(pool.Object, "spec", "allocations")
+	return poolUsage{
+		namespace: pool.GetNamespace(),
+		name:      pool.GetName(),
+		cidr:      cidr.String(),
+		allocated: float64(len(allocations)),
+		size:      size,
+	}, true
+}
+
+// rangeSize returns the number of addresses whereabouts can assign from cidr, which
+// are all of them but the network and broadcast addresses of IPv4 subnets
+func rangeSize(cidr *net.IPNet) float64 {
+	ones, bits := cidr.Mask.Size()
+	size := math.Pow(2, float64(bits-ones))
+	if bits == 32 && bits-ones >= 2 {
+		size -= 2
+	}
+	return size
+}
+
+// nearlyExhausted returns the IP pools that have allocated most of their addresses
+func nearlyExhausted(usage []poolUsage) []poolUsage {
+	out := []poolUsage{}
+	for _, u := range usage {
+		if u.allocated >= u.size*exhaustionThreshold {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// exhaustionCondition reports the IP pools that have allocated most of their addresses
+func exhaustionCondition(exhausted []poolUsage) operv1.OperatorCondition {
+	if len(exhausted) == 0 {
+		return operv1.OperatorCondition{
+			Type:   names.WhereaboutsIPPoolsNearlyExhaustedCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+
+	pools := []string{}
+	for i := range exhausted {
+		if i == maxReportedPools {
+			pools = append(pools, fmt.Sprintf("and %d more", len(exhausted)-maxReportedPools))
+			break
+		}
+		pools = append(pools, exhausted[i].String())
+	}
+	return operv1.OperatorCondition{
+		Type:   names.WhereaboutsIPPoolsNearlyExhaustedCondition,
+		Status: operv1.ConditionTrue,
+		Reason: "IPPoolsNearlyExhausted",
+		Message: fmt.Sprintf("%d whereabouts IP pool(s) allocated more than %d%% of their addresses: %s",
+			len(exhausted), int(exhaustionThreshold*100), strings.Join(pools, ", ")),
+	}
+}
@@ -0,0 +1,159 @@
+package whereabouts
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+)
+
+func nad(namespace, name, config string) uns.Unstructured {
+	obj := uns.Unstructured{}
+	obj.SetGroupVersionKind(network.NetworkAttachmentDefinitionGVK)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	_ = uns.SetNestedField(obj.Object, config, "spec", "config")
+	return obj
+}
+
+func ipPool(name, cidr string, allocated int) *uns.Unstructured {
+	allocations := map[string]any{}
+	for i := 0; i < allocated; i++ {
+		allocations[string(rune('a'+i%26))+string(rune('a'+i/26))] = map[string]any{"id": "x"}
+	}
+	obj := &uns.Unstructured{Object: map[string]any{"spec": map[string]any{"range": cidr, "allocations": allocations}}}
+	obj.SetGroupVersionKind(ipPoolGVK)
+	obj.SetNamespace("openshift-multus")
+	obj.SetName(name)
+	return obj
+}
+
+func TestRangeOverlaps(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	conf := &operv1.NetworkSpec{
+		AdditionalNetworks: []operv1.AdditionalNetworkDefinition{
+			{
+				Type:         operv1.NetworkTypeRaw,
+				Name:         "raw",
+				Namespace:    "ns1",
+				RawCNIConfig: `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","range":"192.0.2.0/24"}}`,
+			},
+			{
+				Type:         network.NetworkTypeSimpleBridge,
+				Name:         "bridge",
+				RawCNIConfig: `{"ipamConfig":{"type":"Whereabouts","whereaboutsIPAMConfig":{"range":"198.51.100.0/24"}}}`,
+			},
+		},
+	}
+	nads := []uns.Unstructured{
+		// rendered from spec.additionalNetworks
+		nad("ns1", "raw", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","range":"192.0.2.0/24"}}`),
+		// same range, same pool
+		nad("ns2", "same", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","range":"192.0.2.0/24"}}`),
+		// overlapping range in a conflist
+		nad("ns2", "overlapping", `{"cniVersion":"0.3.1","name":"n","plugins":[{"type":"macvlan","ipam":{"type":"whereabouts","range":"192.0.2.10-192.0.2.20/25"}}]}`),
+		// overlapping range in another network_name
+		nad("ns3", "scoped", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","network_name":"blue","range":"198.51.100.0/25"}}`),
+		// overlapping ipRanges in the same network_name
+		nad("ns3", "scoped-overlapping", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","network_name":"blue","ipRanges":[{"range":"198.51.100.64/26"},{"range":"2001:db8::/64"}]}}`),
+		// disjoint slices of overlapping ranges
+		nad("ns5", "low", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","network_name":"green","range":"10.0.0.0/16","range_end":"10.0.0.99"}}`),
+		nad("ns5", "high", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","network_name":"green","range":"10.0.0.0/24","range_start":"10.0.0.100"}}`),
+		// overlapping range whose common addresses are excluded
+		nad("ns5", "excluded", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","network_name":"green","range":"10.0.0.0/25","exclude":["10.0.0.0/26","10.0.0.64/26"]}}`),
+		// not whereabouts
+		nad("ns4", "host-local", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"host-local","subnet":"192.0.2.0/24"}}`),
+	}
+
+	ranges := network.WhereaboutsRanges(conf, nads)
+	g.Expect(ranges).To(HaveLen(10))
+	g.Expect(rangeOverlaps(ranges)).To(Equal([]string{
+		"additional network raw (192.0.2.0/24) overlaps NetworkAttachmentDefinition ns2/overlapping (192.0.2.0/25 [192.0.2.10-192.0.2.20])",
+		"NetworkAttachmentDefinition ns2/same (192.0.2.0/24) overlaps NetworkAttachmentDefinition ns2/overlapping (192.0.2.0/25 [192.0.2.10-192.0.2.20])",
+		`NetworkAttachmentDefinition ns3/scoped (198.51.100.0/25) overlaps NetworkAttachmentDefinition ns3/scoped-overlapping (198.51.100.64/26) in network_name "blue"`,
+	}))
+}
+
+func TestIPPoolUsage(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	u, ok := ipPoolUsage(ipPool("192.0.2.0-24", "192.0.2.0/24", 230), nil)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(u).To(Equal(poolUsage{namespace: "openshift-multus", name: "192.0.2.0-24", cidr: "192.0.2.0/24", allocated: 230, size: 254}))
+
+	u, ok = ipPoolUsage(ipPool("2001-db8---120", "2001:db8::/120", 1), nil)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(u.size).To(Equal(float64(256)))
+
+	_, ok = ipPoolUsage(ipPool("invalid", "192.0.2.0", 0), nil)
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestIPPoolUsageRestrictedRanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	conf := &operv1.NetworkSpec{
+		AdditionalNetworks: []operv1.AdditionalNetworkDefinition{
+			{
+				Type:         network.NetworkTypeSimpleBridge,
+				Name:         "bridge",
+				RawCNIConfig: `{"ipamConfig":{"type":"Whereabouts","whereaboutsIPAMConfig":{"range":"198.51.100.0/24","exclude":["198.51.100.0/25"]}}}`,
+			},
+		},
+	}
+	nads := []uns.Unstructured{
+		nad("ns1", "narrow", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","range":"10.1.0.0/16","range_start":"10.1.0.1","range_end":"10.1.0.20"}}`),
+		// same pool, partly the same addresses
+		nad("ns2", "narrow", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","range":"10.1.0.11-10.1.0.30/16"}}`),
+		// another network_name, another pool
+		nad("ns3", "scoped", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","network_name":"blue","range":"10.1.0.0/16","range_start":"10.1.0.1","range_end":"10.1.0.20"}}`),
+	}
+	ranges := network.WhereaboutsRanges(conf, nads)
+
+	u, ok := ipPoolUsage(ipPool("10.1.0.0-16", "10.1.0.0/16", 28), ranges)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(u.size).To(Equal(float64(30)))
+
+	u, ok = ipPoolUsage(ipPool("blue-10.1.0.0-16", "10.1.0.0/16", 19), ranges)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(u.size).To(Equal(float64(20)))
+	g.Expect(nearlyExhausted([]poolUsage{u})).To(HaveLen(1))
+
+	u, ok = ipPoolUsage(ipPool("198.51.100.0-24", "198.51.100.0/24", 1), ranges)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(u.size).To(Equal(float64(127)))
+
+	// not used by any range
+	u, ok = ipPoolUsage(ipPool("red-10.1.0.0-16", "10.1.0.0/16", 1), ranges)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(u.size).To(Equal(float64(65534)))
+}
+
+func TestExhaustionCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	usage := []poolUsage{}
+	for _, pool := range []*uns.Unstructured{
+		ipPool("192.0.2.0-24", "192.0.2.0/24", 230),
+		ipPool("198.51.100.0-24", "198.51.100.0/24", 228),
+		ipPool("blue-203.0.113.0-30", "203.0.113.0/30", 2),
+	} {
+		u, _ := ipPoolUsage(pool, nil)
+		usage = append(usage, u)
+	}
+
+	exhausted := nearlyExhausted(usage)
+	g.Expect(exhausted).To(HaveLen(2))
+	g.Expect(exhaustionCondition(exhausted)).To(Equal(operv1.OperatorCondition{
+		Type:    names.WhereaboutsIPPoolsNearlyExhaustedCondition,
+		Status:  operv1.ConditionTrue,
+		Reason:  "IPPoolsNearlyExhausted",
+		Message: "2 whereabouts IP pool(s) allocated more than 90% of their addresses: openshift-multus/192.0.2.0-24 (192.0.2.0/24) 230/254, openshift-multus/blue-203.0.113.0-30 (203.0.113.0/30) 2/2",
+	}))
+	g.Expect(exhaustionCondition(nil).Status).To(Equal(operv1.ConditionFalse))
+}
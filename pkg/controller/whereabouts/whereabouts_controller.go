@@ -0,0 +1,140 @@
+// Package whereabouts implements a controller that checks the address ranges of
+// the whereabouts IPAM plugin: it reports the ranges that overlap, which lead to
+// addresses being assigned twice, and how many addresses each IP pool assigned.
+package whereabouts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/controller/statusmanager"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/network"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// resyncPeriod is how often the ranges and the IP pools are checked. Neither the
+// NetworkAttachmentDefinition nor the IPPool CRD may exist, so they are polled
+// rather than watched.
+const resyncPeriod = 5 * time.Minute
+
+// ipPoolGVK is the kind of the whereabouts IP pools
+var ipPoolGVK = schema.GroupVersionKind{Group: "whereabouts.cni.cncf.io", Version: "v1alpha1", Kind: "IPPool"}
+
+// Add attaches our control loop to the manager and watches for the operator
+// configuration
+func Add(mgr manager.Manager, status *statusmanager.StatusManager, c cnoclient.Client, _ featuregates.FeatureGate) error {
+	r := &ReconcileWhereabouts{client: c, status: status}
+	ctrl, err := controller.New("whereabouts-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.Watch(source.Kind[crclient.Object](mgr.GetCache(), &operv1.Network{}, handler.EnqueueRequestsFromMapFunc(reconcileWhereabouts), predicate.GenerationChangedPredicate{}))
+}
+
+// reconcileWhereabouts maps every event to a single request, since the ranges are
+// always checked for the whole cluster
+func reconcileWhereabouts(_ context.Context, _ crclient.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name: names.OPERATOR_CONFIG,
+	}}}
+}
+
+var _ reconcile.Reconciler = &ReconcileWhereabouts{}
+
+// ReconcileWhereabouts checks the whereabouts ranges and IP pools
+type ReconcileWhereabouts struct {
+	client cnoclient.Client
+	status *statusmanager.StatusManager
+}
+
+// Reconcile reports Degraded when whereabouts ranges overlap, exports how many
+// addresses each IP pool assigned as metrics and sets the
+// WhereaboutsIPPoolsNearlyExhausted condition when pools are running out.
+func (r *ReconcileWhereabouts) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	defer utilruntime.HandleCrash(r.status.SetDegradedOnPanicAndCrash)
+
+	operConfig := &operv1.Network{}
+	if err := r.client.Default().CRClient().Get(ctx, types.NamespacedName{Name: names.OPERATOR_CONFIG}, operConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		klog.Errorf("Failed to get operator configuration: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	if operConfig.Spec.DisableMultiNetwork != nil && *operConfig.Spec.DisableMultiNetwork {
+		updateMetrics(nil)
+		r.status.SetNotDegraded(statusmanager.WhereaboutsConfig)
+		r.status.SetCondition(exhaustionCondition(nil))
+		return reconcile.Result{}, nil
+	}
+
+	nads, err := r.list(ctx, network.NetworkAttachmentDefinitionGVK)
+	if err != nil {
+		klog.Errorf("Failed to list NetworkAttachmentDefinitions: %v", err)
+		return reconcile.Result{}, err
+	}
+	pools, err := r.list(ctx, ipPoolGVK)
+	if err != nil {
+		klog.Errorf("Failed to list whereabouts IP pools: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	ranges := network.WhereaboutsRanges(&operConfig.Spec, nads)
+	if overlaps := rangeOverlaps(ranges); len(overlaps) > 0 {
+		klog.Warningf("Whereabouts ranges overlap: %s", strings.Join(overlaps, "; "))
+		r.status.SetDegraded(statusmanager.WhereaboutsConfig, "OverlappingWhereaboutsRanges",
+			fmt.Sprintf("Whereabouts ranges overlap, the same addresses may be assigned to several pods: %s", strings.Join(overlaps, "; ")))
+	} else {
+		r.status.SetNotDegraded(statusmanager.WhereaboutsConfig)
+	}
+
+	usage := []poolUsage{}
+	for i := range pools {
+		if u, ok := ipPoolUsage(&pools[i], ranges); ok {
+			usage = append(usage, u)
+		}
+	}
+	updateMetrics(usage)
+	exhausted := nearlyExhausted(usage)
+	for i := range exhausted {
+		klog.Warningf("Whereabouts IP pool nearly exhausted: %s addresses allocated", exhausted[i].String())
+	}
+	r.status.SetCondition(exhaustionCondition(exhausted))
+
+	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// list returns the objects of the given kind, in all namespaces. There are none if
+// the kind's CRD does not exist.
+func (r *ReconcileWhereabouts) list(ctx context.Context, gvk schema.GroupVersionKind) ([]uns.Unstructured, error) {
+	objs := &uns.UnstructuredList{}
+	objs.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	err := r.client.Default().CRClient().List(ctx, objs)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return objs.Items, nil
+}
@@ -54,6 +54,20 @@ const CreateWaitAnnotation = "networkoperator.openshift.io/create-wait"
 // rollingUpdate when switching a Deployment strategy to Recreate).
 const PrePatchAnnotation = "networkoperator.openshift.io/pre-patch"
 
+// PreserveManualChangesAnnotation is an annotation users can set to "true" on a
+// NetworkAttachmentDefinition rendered from spec.additionalNetworks, so that the
+// changes they made to its spec.config are reported as drift instead of reverted.
+const PreserveManualChangesAnnotation = "networkoperator.openshift.io/preserve-manual-changes"
+
+// MultusAdmissionIgnoreLabel is a label admins can set on namespaces, with any
+// value, so that the multus admission controller does not validate the
+// NetworkAttachmentDefinitions in them
+const MultusAdmissionIgnoreLabel = "networkoperator.openshift.io/ignore-multus-admission"
+
+// MultusNodePoolLabel is set on the multus pods of a node pool, declared in
+// spec.unsupportedConfigOverrides.multusNodePools, to the name of the pool
+const MultusNodePoolLabel = "networkoperator.openshift.io/multus-node-pool"
+
 // NonCriticalAnnotation is an annotation on Deployments/DaemonSets to indicate
 // that they are not critical to the functioning of the pod network
 const NonCriticalAnnotation = "networkoperator.openshift.io/non-critical"
@@ -123,6 +137,14 @@ const TRUSTED_CA_BUNDLE_CONFIGMAP_KEY = "ca-bundle.crt"
 // containing the combined user/system trust bundle.
 const TRUSTED_CA_BUNDLE_CONFIGMAP = "trusted-ca-bundle"
 
+// TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP is the name of the ConfigMap
+// summarizing the certificates of the user provided trust bundle.
+const TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP = "trusted-ca-bundle-summary"
+
+// TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP_KEY is the name of the data key
+// containing the JSON summary of the certificates.
+const TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP_KEY = "summary.json"
+
 // TRUSTED_CA_BUNDLE_CONFIGMAP_NS is the namespace that hosts the
 // ADDL_TRUST_BUNDLE_CONFIGMAP and TRUST_BUNDLE_CONFIGMAP
 // ConfigMaps.
@@ -193,6 +215,15 @@ func TrustedCABundleConfigMap() types.NamespacedName {
 	}
 }
 
+// TrustedCABundleSummaryConfigMap returns the namespaced name of the ConfigMap
+// openshift-config-managed/trusted-ca-bundle-summary.
+func TrustedCABundleSummaryConfigMap() types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: TRUSTED_CA_BUNDLE_CONFIGMAP_NS,
+		Name:      TRUSTED_CA_BUNDLE_SUMMARY_CONFIGMAP,
+	}
+}
+
 // constants for namespace and custom resource names
 // namespace in which ingress controller objects are created
 const IngressControllerNamespace = "openshift-ingress-operator"
@@ -241,3 +272,91 @@ const DefaultFieldManagerPrefix = "network-operator"
 
 // NetworkDiagnosticsAvailableCondition is the condition type for network diagnostics availability
 const NetworkDiagnosticsAvailableCondition string = "NetworkDiagnosticsAvailable"
+
+// OVNKubernetesSubnetsSelectedCondition is the condition type reporting the OVN-Kubernetes
+// internal subnets that were chosen at install time because the defaults were in use.
+// It is removed once none of them differs from its default anymore.
+const OVNKubernetesSubnetsSelectedCondition string = "OVNKubernetesInternalSubnetsSelected"
+
+// OVNKubernetesSelectedSubnetsAnnotation is set on the operator configuration to the
+// names of the OVN-Kubernetes internal subnets that were chosen at install time
+const OVNKubernetesSelectedSubnetsAnnotation = "networkoperator.openshift.io/selected-internal-subnets"
+
+// AdditionalNetworksInMachineNetworkCondition is the condition type reporting the
+// additional networks whose IPAM subnets overlap a machine network
+const AdditionalNetworksInMachineNetworkCondition string = "AdditionalNetworksInMachineNetwork"
+
+// AdditionalNetworkAddressesOverlapCondition is the condition type reporting the
+// additional networks whose whereabouts ranges overlap
+const AdditionalNetworkAddressesOverlapCondition string = "AdditionalNetworkAddressesOverlap"
+
+// DefaultNetworkTypeUnknownCondition is the condition type reporting a default network
+// type the operator does not know, kept by a cluster installed with it
+const DefaultNetworkTypeUnknownCondition string = "DefaultNetworkTypeUnknown"
+
+// AdditionalNetworksCNIConfigInvalidCondition is the condition type reporting the
+// Raw additional networks whose CNI configuration is invalid
+const AdditionalNetworksCNIConfigInvalidCondition string = "AdditionalNetworksCNIConfigInvalid"
+
+// ClusterNetworkNodeSubnetsLowCondition is the condition type reporting that a clusterNetwork
+// entry is running out of per-node subnets
+const ClusterNetworkNodeSubnetsLowCondition string = "ClusterNetworkNodeSubnetsLow"
+
+// ControlPlaneSizingCondition is the condition type reporting the sizing profile of the
+// network control plane
+const ControlPlaneSizingCondition string = "ControlPlaneSizing"
+
+// ControlPlaneSizingProfileAnnotation is set on the operator configuration to the
+// sizing profile last selected for the network control plane
+const ControlPlaneSizingProfileAnnotation = "networkoperator.openshift.io/control-plane-sizing-profile"
+
+// AuxiliaryIPAMCondition is the condition type reporting which additional networks
+// and NetworkAttachmentDefinitions caused the DHCP daemon and the whereabouts
+// reconciler to be rendered
+const AuxiliaryIPAMCondition string = "AuxiliaryIPAMRendered"
+
+// GatewayModeMigrationCondition is the condition type reporting the progress of a node
+// by node migration of the OVN-Kubernetes gateway mode
+const GatewayModeMigrationCondition string = "GatewayModeMigrationProgressing"
+
+// SysctlAllowlistNodesOutOfDateCondition is the condition type reporting the nodes
+// that did not get the current CNI sysctl allowlist
+const SysctlAllowlistNodesOutOfDateCondition string = "SysctlAllowlistNodesOutOfDate"
+
+// WhereaboutsIPPoolsNearlyExhaustedCondition is the condition type reporting the
+// whereabouts IP pools that are running out of addresses
+const WhereaboutsIPPoolsNearlyExhaustedCondition string = "WhereaboutsIPPoolsNearlyExhausted"
+
+// MultiNetworkPoliciesEnforcedCondition is the condition type summarizing the
+// MultiNetworkPolicies that are enforced on each secondary network
+const MultiNetworkPoliciesEnforcedCondition string = "MultiNetworkPoliciesEnforced"
+
+// ProxyProbesFailingCondition is the condition type reporting that the cluster-wide
+// proxy or its readiness endpoints are failing their periodic probes
+const ProxyProbesFailingCondition string = "ProxyProbesFailing"
+
+// ProxyTrustedCAExpiringCondition is the condition type reporting the certificates of
+// the user provided trust bundle of the proxy that are expired or about to expire
+const ProxyTrustedCAExpiringCondition string = "ProxyTrustedCAExpiring"
+
+// SysctlAllowlistHashAnnotation is set on nodes to the hash of the CNI sysctl
+// allowlist that was last copied to them
+const SysctlAllowlistHashAnnotation = "networkoperator.openshift.io/cni-sysctl-allowlist-hash"
+
+// CertificateInventoryConfigMap is the name of the ConfigMap, in APPLIED_NAMESPACE,
+// that holds the certificates issued to the nodes, as last seen by the certificate
+// inventory
+const CertificateInventoryConfigMap = "network-certificate-inventory"
+
+// GatewayModeMigrationConfigMap is the name of the ConfigMap, in APPLIED_NAMESPACE, that
+// holds the state of a node by node migration of the OVN-Kubernetes gateway mode
+const GatewayModeMigrationConfigMap = "gateway-mode-migration"
+
+// GatewayModeMigrationBatchSizeAnnotation can be set on the operator configuration to
+// the number of nodes that are switched to a new OVN-Kubernetes gateway mode at a time
+const GatewayModeMigrationBatchSizeAnnotation = "networkoperator.openshift.io/gateway-mode-migration-batch-size"
+
+// EgressRouterHighAvailabilityAnnotation can be set to "true" on an EgressRouter to
+// run two replicas of the router, of which only the one holding the lease of the
+// router has its addresses
+const EgressRouterHighAvailabilityAnnotation = "network.operator.openshift.io/egress-router-high-availability"
@@ -10,8 +10,11 @@ import (
 	cnitypes "github.com/containernetworking/cni/pkg/types"
 
 	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/names"
 	"github.com/openshift/cluster-network-operator/pkg/render"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 // renderAdditionalNetworksCRD returns the manifests of the NetworkAttachmentDefinition.
@@ -43,25 +46,169 @@ func renderRawCNIConfig(conf *operv1.AdditionalNetworkDefinition, manifestDir st
 	return objs, nil
 }
 
-// validateRaw checks the AdditionalNetwork name and RawCNIConfig.
-func validateRaw(conf *operv1.AdditionalNetworkDefinition) []error {
+// supportedCNIVersions are the CNI specification versions a RawCNIConfig may use
+var supportedCNIVersions = sets.New("0.1.0", "0.2.0", "0.3.0", "0.3.1", "0.4.0", "1.0.0", "1.1.0")
+
+// validateRaw checks the AdditionalNetwork name and that RawCNIConfig is JSON.
+// Its content is checked by ValidateRawCNIConfigs, as configurations accepted
+// before it was checked must not block the reconciliation of the cluster.
+func validateRaw(conf *operv1.AdditionalNetworkDefinition, fldPath *field.Path) []error {
 	out := []error{}
-	var rawConfig map[string]any
-	var err error
 
 	if conf.Name == "" {
-		out = append(out, fmt.Errorf("Additional Network Name cannot be nil")) //nolint:staticcheck // ANN is the name
+		out = append(out, field.Required(fldPath.Child("name"), "Additional Network Name cannot be nil"))
 	}
 
+	var rawConfig map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(conf.RawCNIConfig), &rawConfig); err != nil {
+		out = append(out, field.Invalid(fldPath.Child("rawCNIConfig"), conf.RawCNIConfig, fmt.Sprintf("failed to Unmarshal RawCNIConfig: %v", err)))
+	}
+	return out
+}
+
+// validateRawCNIConfig checks that RawCNIConfig is either a single CNI plugin
+// configuration or a configuration list. It returns nothing if RawCNIConfig is
+// not JSON, which is reported by validateRaw.
+func validateRawCNIConfig(conf *operv1.AdditionalNetworkDefinition, fldPath *field.Path) []error {
+	out := []error{}
+
+	rawPath := fldPath.Child("rawCNIConfig")
 	confBytes := []byte(conf.RawCNIConfig)
-	err = json.Unmarshal(confBytes, &rawConfig)
-	if err != nil {
-		out = append(out, fmt.Errorf("failed to Unmarshal RawCNIConfig: %s", string(confBytes)))
+	var rawConfig map[string]json.RawMessage
+	if err := json.Unmarshal(confBytes, &rawConfig); err != nil {
+		return out
+	}
+
+	if _, ok := rawConfig["plugins"]; !ok {
+		plugin := cnitypes.NetConf{}
+		if err := json.Unmarshal(confBytes, &plugin); err != nil {
+			return append(out, field.Invalid(rawPath, conf.RawCNIConfig, fmt.Sprintf("invalid CNI configuration: %v", err)))
+		}
+		out = append(out, validateCNIVersion(rawPath.Child("cniVersion"), plugin.CNIVersion)...)
+		return append(out, validateCNIPlugin(rawPath, &plugin, rawConfig)...)
+	}
+
+	list := cnitypes.NetConfList{}
+	rawList := struct {
+		Plugins []map[string]json.RawMessage `json:"plugins"`
+	}{}
+	if err := json.Unmarshal(confBytes, &list); err != nil {
+		return append(out, field.Invalid(rawPath, conf.RawCNIConfig, fmt.Sprintf("invalid CNI configuration list: %v", err)))
+	}
+	if err := json.Unmarshal(confBytes, &rawList); err != nil {
+		return append(out, field.Invalid(rawPath, conf.RawCNIConfig, fmt.Sprintf("invalid CNI configuration list: %v", err)))
+	}
+	out = append(out, validateCNIVersion(rawPath.Child("cniVersion"), list.CNIVersion)...)
+	if _, ok := rawConfig["type"]; ok {
+		out = append(out, field.Forbidden(rawPath.Child("type"), "a CNI configuration list sets the type of each of its plugins"))
+	}
+	if len(list.Plugins) == 0 {
+		out = append(out, field.Required(rawPath.Child("plugins"), "a CNI configuration list must have at least one plugin"))
+	}
+	for i, plugin := range list.Plugins {
+		pluginPath := rawPath.Child("plugins").Index(i)
+		if plugin == nil {
+			out = append(out, field.Required(pluginPath, "must be a CNI plugin configuration"))
+			continue
+		}
+		if plugin.CNIVersion != "" && plugin.CNIVersion != list.CNIVersion {
+			out = append(out, field.Invalid(pluginPath.Child("cniVersion"), plugin.CNIVersion,
+				fmt.Sprintf("must match the cniVersion %q of the configuration list", list.CNIVersion)))
+		}
+		out = append(out, validateCNIPlugin(pluginPath, plugin, rawList.Plugins[i])...)
+	}
+
+	return out
+}
+
+func validateCNIVersion(fldPath *field.Path, version string) []error {
+	if version == "" {
+		return []error{field.Required(fldPath, "")}
 	}
+	if !supportedCNIVersions.Has(version) {
+		return []error{field.NotSupported(fldPath, version, sets.List(supportedCNIVersions))}
+	}
+	return nil
+}
+
+// validateCNIPlugin checks a single CNI plugin configuration, raw being the same
+// configuration as parsed JSON to tell unset fields from empty ones
+func validateCNIPlugin(fldPath *field.Path, plugin *cnitypes.NetConf, raw map[string]json.RawMessage) []error {
+	out := []error{}
+	if plugin.Type == "" {
+		out = append(out, field.Required(fldPath.Child("type"), "the CNI plugin to run must be set"))
+	}
+	if _, ok := raw["ipam"]; ok && plugin.IPAM.IsEmpty() {
+		out = append(out, field.Required(fldPath.Child("ipam", "type"), "the IPAM plugin to run must be set"))
+	}
+	return out
+}
+
+// isAppliedRawCNIConfig returns whether the Raw additional network an was already
+// applied, with the same configuration, in prev
+func isAppliedRawCNIConfig(an *operv1.AdditionalNetworkDefinition, prev *operv1.NetworkSpec) bool {
+	if prev == nil {
+		return false
+	}
+	for _, p := range prev.AdditionalNetworks {
+		if p.Type == operv1.NetworkTypeRaw && p.Namespace == an.Namespace && p.Name == an.Name {
+			return p.RawCNIConfig == an.RawCNIConfig
+		}
+	}
+	return false
+}
 
+// rawCNIConfigErrors returns the problems found by validateRawCNIConfig in the Raw
+// additional networks of conf that were applied in prev, if applied is true, or
+// that are new or changed otherwise
+func rawCNIConfigErrors(conf, prev *operv1.NetworkSpec, applied bool) []error {
+	out := []error{}
+	for i := range conf.AdditionalNetworks {
+		an := &conf.AdditionalNetworks[i]
+		if an.Type != operv1.NetworkTypeRaw || isAppliedRawCNIConfig(an, prev) != applied {
+			continue
+		}
+		out = append(out, validateRawCNIConfig(an, field.NewPath("spec", "additionalNetworks").Index(i))...)
+	}
 	return out
 }
 
+// ValidateRawCNIConfigs checks the CNI configuration of the Raw additional
+// networks that are new or changed since prev, the previously applied
+// configuration if any. The ones applied before are only reported by
+// RawCNIConfigCondition.
+func ValidateRawCNIConfigs(conf, prev *operv1.NetworkSpec) error {
+	if errs := rawCNIConfigErrors(conf, prev, false); len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %v", errs)
+	}
+	return nil
+}
+
+// RawCNIConfigCondition returns the informational condition listing the problems
+// found in the CNI configuration of the Raw additional networks applied before
+// it was checked. Multus fails to attach pods to such networks, but they are
+// still rendered.
+func RawCNIConfigCondition(conf, prev *operv1.NetworkSpec) operv1.OperatorCondition {
+	warnings := []string{}
+	for _, err := range rawCNIConfigErrors(conf, prev, true) {
+		warnings = append(warnings, err.Error())
+	}
+
+	if len(warnings) == 0 {
+		return operv1.OperatorCondition{
+			Type:   names.AdditionalNetworksCNIConfigInvalidCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+	return operv1.OperatorCondition{
+		Type:    names.AdditionalNetworksCNIConfigInvalidCondition,
+		Status:  operv1.ConditionTrue,
+		Reason:  "InvalidRawCNIConfig",
+		Message: "The CNI configuration of these additional networks is invalid: " + strings.Join(warnings, "; "),
+	}
+}
+
 // staticIPAMConfig for json generation for static IPAM
 type staticIPAMConfig struct {
 	Type         string              `json:"type"`
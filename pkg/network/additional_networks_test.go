@@ -2,18 +2,20 @@ package network
 
 import (
 	"encoding/json"
+	"fmt"
 	"net"
 	"testing"
 
 	. "github.com/onsi/gomega"
 	operv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 var NetworkAttachmentConfigRaw = operv1.Network{
 	Spec: operv1.NetworkSpec{
 		AdditionalNetworks: []operv1.AdditionalNetworkDefinition{
-			{Type: operv1.NetworkTypeRaw, Namespace: "foobar", Name: "net-attach-1", RawCNIConfig: "{}"},
-			{Type: operv1.NetworkTypeRaw, Name: "net-attach-2", RawCNIConfig: "{}"},
+			{Type: operv1.NetworkTypeRaw, Namespace: "foobar", Name: "net-attach-1", RawCNIConfig: `{"cniVersion":"0.3.1","type":"macvlan","master":"eth0"}`},
+			{Type: operv1.NetworkTypeRaw, Name: "net-attach-2", RawCNIConfig: `{"cniVersion":"0.4.0","name":"chain","plugins":[{"type":"bridge","ipam":{"type":"host-local","subnet":"192.168.10.0/24"}},{"type":"tuning"}]}`},
 		},
 	},
 }
@@ -99,17 +101,19 @@ func TestRenderRawCNIConfig(t *testing.T) {
 
 func TestValidateRaw(t *testing.T) {
 	g := NewGomegaWithT(t)
+	fldPath := field.NewPath("spec", "additionalNetworks").Index(0)
 
 	for _, cfg := range NetworkAttachmentConfigRaw.Spec.AdditionalNetworks {
-		err := validateRaw(&cfg)
+		err := validateRaw(&cfg, fldPath)
 		g.Expect(err).To(BeEmpty())
+		g.Expect(validateRawCNIConfig(&cfg, fldPath)).To(BeEmpty())
 	}
 
 	rawConfig := NetworkAttachmentConfigRaw.Spec.AdditionalNetworks[0]
 
 	errExpect := func(substr string) {
 		t.Helper()
-		g.Expect(validateRaw(&rawConfig)).To(
+		g.Expect(validateRaw(&rawConfig, fldPath)).To(
 			ContainElement(MatchError(
 				ContainSubstring(substr))))
 	}
@@ -119,6 +123,82 @@ func TestValidateRaw(t *testing.T) {
 
 	rawConfig.Name = ""
 	errExpect("Additional Network Name cannot be nil")
+	g.Expect(validateRawCNIConfig(&rawConfig, fldPath)).To(BeEmpty())
+
+	// the content of valid JSON configurations is checked apart
+	rawConfig.Name = "net"
+	rawConfig.RawCNIConfig = `{"type":"macvlan"}`
+	g.Expect(validateRaw(&rawConfig, fldPath)).To(BeEmpty())
+
+	errExpect = func(substr string) {
+		t.Helper()
+		g.Expect(validateRawCNIConfig(&rawConfig, fldPath)).To(
+			ContainElement(MatchError(
+				ContainSubstring(substr))))
+	}
+
+	errExpect("spec.additionalNetworks[0].rawCNIConfig.cniVersion: Required value")
+
+	rawConfig.RawCNIConfig = `{"cniVersion":"0.5.0","type":"macvlan"}`
+	errExpect(`spec.additionalNetworks[0].rawCNIConfig.cniVersion: Unsupported value: "0.5.0"`)
+
+	rawConfig.RawCNIConfig = `{"cniVersion":"0.3.1"}`
+	errExpect("spec.additionalNetworks[0].rawCNIConfig.type: Required value")
+
+	rawConfig.RawCNIConfig = `{"cniVersion":"0.3.1","type":"macvlan","ipam":{}}`
+	errExpect("spec.additionalNetworks[0].rawCNIConfig.ipam.type: Required value")
+
+	rawConfig.RawCNIConfig = `{"cniVersion":"0.3.1","type":"macvlan","ipam":"dhcp"}`
+	errExpect("spec.additionalNetworks[0].rawCNIConfig: Invalid value")
+
+	rawConfig.RawCNIConfig = `{"cniVersion":"1.0.0","name":"chain","plugins":[]}`
+	errExpect("spec.additionalNetworks[0].rawCNIConfig.plugins: Required value")
+
+	rawConfig.RawCNIConfig = `{"cniVersion":"1.0.0","name":"chain","type":"bridge","plugins":[{"type":"bridge"}]}`
+	errExpect("spec.additionalNetworks[0].rawCNIConfig.type: Forbidden")
+
+	rawConfig.RawCNIConfig = `{"cniVersion":"1.0.0","name":"chain","plugins":[{"type":"bridge"},{"cniVersion":"0.4.0"}]}`
+	errExpect("spec.additionalNetworks[0].rawCNIConfig.plugins[1].type: Required value")
+	errExpect("spec.additionalNetworks[0].rawCNIConfig.plugins[1].cniVersion: Invalid value")
+}
+
+func TestValidateRawCNIConfigs(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	config := NetworkAttachmentConfigRaw.Spec.DeepCopy()
+	g.Expect(ValidateRawCNIConfigs(config, nil)).To(Succeed())
+	g.Expect(RawCNIConfigCondition(config, nil).Status).To(Equal(operv1.ConditionFalse))
+
+	config.AdditionalNetworks = append(config.AdditionalNetworks, operv1.AdditionalNetworkDefinition{
+		Type:         operv1.NetworkTypeRaw,
+		Name:         "no-version",
+		Namespace:    "foobar",
+		RawCNIConfig: `{"type":"macvlan","ipam":{}}`,
+	})
+	i := len(config.AdditionalNetworks) - 1
+	g.Expect(validateAdditionalNetworks(config)).To(BeEmpty())
+
+	// a new invalid configuration is rejected
+	err := ValidateRawCNIConfigs(config, nil)
+	g.Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("spec.additionalNetworks[%d].rawCNIConfig.cniVersion: Required value", i))))
+	g.Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("spec.additionalNetworks[%d].rawCNIConfig.ipam.type: Required value", i))))
+	prev := NetworkAttachmentConfigRaw.Spec.DeepCopy()
+	g.Expect(ValidateRawCNIConfigs(config, prev)).NotTo(Succeed())
+	g.Expect(RawCNIConfigCondition(config, prev).Status).To(Equal(operv1.ConditionFalse))
+
+	// one applied before it was checked is only reported
+	prev = config.DeepCopy()
+	g.Expect(ValidateRawCNIConfigs(config, prev)).To(Succeed())
+	cond := RawCNIConfigCondition(config, prev)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal("InvalidRawCNIConfig"))
+	g.Expect(cond.Message).To(ContainSubstring(fmt.Sprintf("spec.additionalNetworks[%d].rawCNIConfig.cniVersion: Required value", i)))
+	g.Expect(cond.Message).To(ContainSubstring(fmt.Sprintf("spec.additionalNetworks[%d].rawCNIConfig.ipam.type: Required value", i)))
+
+	// until it is changed
+	config.AdditionalNetworks[i].RawCNIConfig = `{"type":"macvlan"}`
+	g.Expect(ValidateRawCNIConfigs(config, prev)).To(MatchError(ContainSubstring("cniVersion: Required value")))
+	g.Expect(RawCNIConfigCondition(config, prev).Status).To(Equal(operv1.ConditionFalse))
 }
 
 func TestRenderSimpleMacvlanConfig(t *testing.T) {
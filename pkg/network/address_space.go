@@ -0,0 +1,461 @@
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilnet "k8s.io/utils/net"
+
+	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	iputil "github.com/openshift/cluster-network-operator/pkg/util/ip"
+)
+
+// addressSpace is a model of the addresses used in the cluster: the cluster,
+// service and hybrid overlay networks, the OVN-Kubernetes internal subnets,
+// and the API and ingress VIPs. Each subnet is reserved by the first network
+// that uses it.
+//
+// The machine networks are not part of it: additional networks with addresses on
+// the subnet of the nodes, e.g. macvlan or ipvlan with static or whereabouts
+// addresses, are supported and only reported by MachineNetworkOverlapCondition.
+type addressSpace struct {
+	pool   iputil.IPPool
+	owners map[string]string
+	// vips are kept apart from the subnets: additional networks may share a
+	// subnet with the VIPs as long as they do not assign them
+	vips []addressSpaceVIP
+}
+
+type addressSpaceVIP struct {
+	owner string
+	ip    net.IP
+}
+
+// newAddressSpace returns the address space of the primary network of the
+// cluster. infraStatus may be nil, in which case the VIPs are unknown.
+//
+// The OVN-Kubernetes internal subnets of the IP families of the cluster network
+// are reserved with the values ovn-kubernetes may use, configured or default: a
+// default is only replaced by FillDefaults at install time, and a running
+// cluster keeps using it whatever the additional networks are.
+func newAddressSpace(conf *operv1.NetworkSpec, infraStatus *bootstrap.InfraStatus) *addressSpace {
+	s := &addressSpace{owners: map[string]string{}}
+
+	for _, cn := range conf.ClusterNetwork {
+		s.reserve("clusterNetwork", cn.CIDR)
+	}
+	s.reserve("serviceNetwork", conf.ServiceNetwork...)
+	if infraStatus != nil {
+		api, ingress := platformVIPs(infraStatus.PlatformStatus)
+		for _, vip := range api {
+			s.reserveVIP("API VIP", vip)
+		}
+		for _, vip := range ingress {
+			s.reserveVIP("ingress VIP", vip)
+		}
+	}
+	if conf.DefaultNetwork.Type == operv1.NetworkTypeOVNKubernetes && conf.DefaultNetwork.OVNKubernetesConfig != nil {
+		oc := conf.DefaultNetwork.OVNKubernetesConfig
+		if oc.HybridOverlayConfig != nil {
+			for _, hcn := range oc.HybridOverlayConfig.HybridClusterNetwork {
+				s.reserve("hybridClusterNetwork", hcn.CIDR)
+			}
+		}
+		for _, subnet := range clusterNetworkOVNInternalSubnets(conf) {
+			s.reserve(subnet.name, subnet.effective()...)
+		}
+	}
+	return s
+}
+
+// reserve adds the subnets to the address space. Subnets that overlap a subnet
+// already reserved are skipped: the overlaps between the subnets of the primary
+// network are reported by validateIPPools.
+func (s *addressSpace) reserve(owner string, cidrs ...string) {
+	for _, cidr := range cidrs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		if s.pool.Add(*ipnet) == nil {
+			s.owners[ipnet.String()] = owner
+		}
+	}
+}
+
+func (s *addressSpace) reserveVIP(owner, vip string) {
+	if ip := utilnet.ParseIPSloppy(vip); ip != nil {
+		s.vips = append(s.vips, addressSpaceVIP{owner: owner, ip: ip})
+	}
+}
+
+// conflict describes what subnet cidr overlaps in the address space, or returns
+// "" if it is free
+func (s *addressSpace) conflict(cidr net.IPNet) string {
+	if n := s.pool.Overlapping(cidr); n != nil {
+		return fmt.Sprintf("overlaps %s %s", s.owners[n.String()], n)
+	}
+	return ""
+}
+
+// addressConflict describes what VIPs the addresses of r contain, or returns ""
+// if there are none
+func (s *addressSpace) addressConflict(r addressRange) string {
+	reasons := []string{}
+	for _, vip := range s.vips {
+		if r.contains(vip.ip) {
+			reasons = append(reasons, fmt.Sprintf("contain the %s %s", vip.owner, vip.ip))
+		}
+	}
+	return strings.Join(reasons, " and ")
+}
+
+// addressRange is a range of addresses that an IPAM configuration may assign
+type addressRange struct {
+	first, last net.IP
+}
+
+func (r addressRange) contains(ip net.IP) bool {
+	ip = ip.To16()
+	return bytes.Compare(ip, r.first) >= 0 && bytes.Compare(ip, r.last) <= 0
+}
+
+func (r addressRange) overlaps(other addressRange) bool {
+	return bytes.Compare(r.first, other.last) <= 0 && bytes.Compare(other.first, r.last) <= 0
+}
+
+func (r addressRange) String() string {
+	if r.first.Equal(r.last) {
+		return r.first.String()
+	}
+	return r.first.String() + "-" + r.last.String()
+}
+
+// newAddressRange returns the range of addresses of subnet, which may also be a
+// whereabouts range in the form "<first IP>-<last IP>/<prefix>", restricted to
+// start and end when they are set. ok is false if subnet is invalid.
+func newAddressRange(subnet string, start, end any) (addressRange, bool) {
+	if i := strings.Index(subnet, "-"); i >= 0 {
+		if start == nil {
+			start = subnet[:i]
+		}
+		subnet = subnet[i+1:]
+		if j := strings.Index(subnet, "/"); j >= 0 && end == nil {
+			end = subnet[:j]
+		}
+	}
+	_, cidr, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return addressRange{}, false
+	}
+
+	last := make(net.IP, len(cidr.IP))
+	for i := range cidr.IP {
+		last[i] = cidr.IP[i] | ^cidr.Mask[i]
+	}
+	r := addressRange{first: cidr.IP.To16(), last: last.To16()}
+	if s, ok := start.(string); ok {
+		if ip := utilnet.ParseIPSloppy(s); ip != nil {
+			r.first = ip.To16()
+		}
+	}
+	if s, ok := end.(string); ok {
+		if ip := utilnet.ParseIPSloppy(s); ip != nil {
+			r.last = ip.To16()
+		}
+	}
+	return r, true
+}
+
+// singleAddressRange returns the range of the address of a static IPAM
+// configuration, in CIDR notation
+func singleAddressRange(address string) (addressRange, bool) {
+	ip, _, err := net.ParseCIDR(address)
+	if err != nil {
+		return addressRange{}, false
+	}
+	return addressRange{first: ip.To16(), last: ip.To16()}, true
+}
+
+// ipamAddressRanges returns the ranges of addresses that an IPAM configuration
+// may assign: the host-local subnets and ranges, the whereabouts ranges, and the
+// static addresses. Unparseable values are skipped.
+func ipamAddressRanges(ipam map[string]any) []addressRange {
+	out := []addressRange{}
+	add := func(m map[string]any, subnetKey, startKey, endKey string) {
+		subnet, ok := m[subnetKey].(string)
+		if !ok {
+			return
+		}
+		if r, ok := newAddressRange(subnet, m[startKey], m[endKey]); ok {
+			out = append(out, r)
+		}
+	}
+
+	add(ipam, "subnet", "rangeStart", "rangeEnd")
+	add(ipam, "range", "range_start", "range_end")
+	if ranges, ok := ipam["ranges"].([]any); ok {
+		for _, rangeSet := range ranges {
+			if rangeSet, ok := rangeSet.([]any); ok {
+				for _, r := range rangeSet {
+					if r, ok := r.(map[string]any); ok {
+						add(r, "subnet", "rangeStart", "rangeEnd")
+					}
+				}
+			}
+		}
+	}
+	if ipRanges, ok := ipam["ipRanges"].([]any); ok {
+		for _, r := range ipRanges {
+			if r, ok := r.(map[string]any); ok {
+				add(r, "range", "range_start", "range_end")
+			}
+		}
+	}
+	if addresses, ok := ipam["addresses"].([]any); ok {
+		for _, a := range addresses {
+			if a, ok := a.(map[string]any); ok {
+				if address, ok := a["address"].(string); ok {
+					if r, ok := singleAddressRange(address); ok {
+						out = append(out, r)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// additionalNetworkAddressRanges returns the ranges of addresses that the IPAM
+// configuration of an additional network may assign.
+func additionalNetworkAddressRanges(an *operv1.AdditionalNetworkDefinition) []addressRange {
+	out := []addressRange{}
+	static := func(conf *operv1.StaticIPAMConfig) {
+		for _, addr := range conf.Addresses {
+			if r, ok := singleAddressRange(addr.Address); ok {
+				out = append(out, r)
+			}
+		}
+	}
+
+	switch an.Type {
+	case operv1.NetworkTypeRaw:
+		for _, ipam := range rawIPAMConfigs(an.RawCNIConfig) {
+			out = append(out, ipamAddressRanges(ipam)...)
+		}
+	case operv1.NetworkTypeSimpleMacvlan:
+		if an.SimpleMacvlanConfig != nil && an.SimpleMacvlanConfig.IPAMConfig != nil &&
+			an.SimpleMacvlanConfig.IPAMConfig.StaticIPAMConfig != nil {
+			static(an.SimpleMacvlanConfig.IPAMConfig.StaticIPAMConfig)
+		}
+	case NetworkTypeSimpleIPVLAN, NetworkTypeSimpleBridge, NetworkTypeSimpleHostDevice:
+		ipam := simpleIPAMConfig(an)
+		switch {
+		case ipam == nil:
+		case ipam.Type == IPAMTypeWhereabouts && ipam.WhereaboutsIPAMConfig != nil:
+			if r, ok := newAddressRange(ipam.WhereaboutsIPAMConfig.Range, nil, nil); ok {
+				out = append(out, r)
+			}
+		case ipam.Type == operv1.IPAMTypeStatic && ipam.StaticIPAMConfig != nil:
+			static(ipam.StaticIPAMConfig)
+		}
+	}
+	return out
+}
+
+// whereaboutsPoolRange is a range of addresses assigned by whereabouts from the
+// IP pools of a network_name
+type whereaboutsPoolRange struct {
+	networkName string
+	addressRange
+}
+
+// additionalNetworkWhereaboutsRanges returns the ranges of addresses that the
+// whereabouts IPAM configuration of an additional network may assign
+func additionalNetworkWhereaboutsRanges(an *operv1.AdditionalNetworkDefinition) []whereaboutsPoolRange {
+	out := []whereaboutsPoolRange{}
+	switch an.Type {
+	case operv1.NetworkTypeRaw:
+		for _, ipam := range rawIPAMConfigs(an.RawCNIConfig) {
+			if ipam["type"] != ipamTypeWhereabouts {
+				continue
+			}
+			networkName, _ := ipam["network_name"].(string)
+			for _, r := range ipamAddressRanges(ipam) {
+				out = append(out, whereaboutsPoolRange{networkName: networkName, addressRange: r})
+			}
+		}
+	case NetworkTypeSimpleIPVLAN, NetworkTypeSimpleBridge, NetworkTypeSimpleHostDevice:
+		ipam := simpleIPAMConfig(an)
+		if ipam == nil || ipam.Type != IPAMTypeWhereabouts || ipam.WhereaboutsIPAMConfig == nil {
+			break
+		}
+		if r, ok := newAddressRange(ipam.WhereaboutsIPAMConfig.Range, nil, nil); ok {
+			out = append(out, whereaboutsPoolRange{addressRange: r})
+		}
+	}
+	return out
+}
+
+// platformVIPs returns the API and ingress VIPs of the on-premise platforms
+func platformVIPs(ps *configv1.PlatformStatus) ([]string, []string) {
+	switch {
+	case ps == nil:
+	case ps.BareMetal != nil:
+		return ps.BareMetal.APIServerInternalIPs, ps.BareMetal.IngressIPs
+	case ps.VSphere != nil:
+		return ps.VSphere.APIServerInternalIPs, ps.VSphere.IngressIPs
+	case ps.OpenStack != nil:
+		return ps.OpenStack.APIServerInternalIPs, ps.OpenStack.IngressIPs
+	case ps.Ovirt != nil:
+		return ps.Ovirt.APIServerInternalIPs, ps.Ovirt.IngressIPs
+	case ps.Nutanix != nil:
+		return ps.Nutanix.APIServerInternalIPs, ps.Nutanix.IngressIPs
+	}
+	return nil, nil
+}
+
+// validateAddressSpace checks that the subnets used by the IPAM configuration of
+// the additional networks do not overlap the subnets of the cluster, and that
+// the addresses they may assign are not the API or ingress VIPs. Pods attached
+// to such a network would not be able to reach the overlapping cluster
+// addresses.
+//
+// The additional networks are not checked against each other: they may be
+// isolated from each other, and whereabouts networks may share their IP pools.
+// Their overlaps are reported by AddressOverlapCondition.
+func validateAddressSpace(conf *operv1.NetworkSpec, infraStatus *bootstrap.InfraStatus) []error {
+	s := newAddressSpace(conf, infraStatus)
+
+	out := []error{}
+	for i := range conf.AdditionalNetworks {
+		an := &conf.AdditionalNetworks[i]
+		fldPath := field.NewPath("spec", "additionalNetworks").Index(i)
+		switch an.Type {
+		case operv1.NetworkTypeRaw, NetworkTypeSimpleIPVLAN, NetworkTypeSimpleBridge, NetworkTypeSimpleHostDevice:
+			fldPath = fldPath.Child("rawCNIConfig")
+		case operv1.NetworkTypeSimpleMacvlan:
+			fldPath = fldPath.Child("simpleMacvlanConfig", "ipamConfig", "staticIPAMConfig", "addresses")
+		}
+
+		cidrs := sets.New(additionalNetworkIPAMCIDRs(an)...)
+		for _, cidr := range sets.List(cidrs) {
+			_, ipnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+			if conflict := s.conflict(*ipnet); conflict != "" {
+				out = append(out, field.Invalid(fldPath, cidr, "IPAM subnet "+conflict))
+			}
+		}
+		for _, r := range additionalNetworkAddressRanges(an) {
+			if conflict := s.addressConflict(r); conflict != "" {
+				out = append(out, field.Invalid(fldPath, r.String(), "IPAM addresses "+conflict))
+			}
+		}
+	}
+	return out
+}
+
+// AddressOverlapCondition returns the informational condition listing the
+// additional networks whose whereabouts ranges overlap within the same
+// network_name. Identical ranges share an IP pool, but overlapping ones each have
+// their own and so may assign the same addresses. Ranges of different
+// network_names, and addresses assigned by the other IPAM plugins, may be reused
+// on isolated networks and are not compared.
+func AddressOverlapCondition(conf *operv1.NetworkSpec) operv1.OperatorCondition {
+	type ownedRange struct {
+		owner string
+		whereaboutsPoolRange
+	}
+	seen := []ownedRange{}
+	overlaps := []string{}
+	for i := range conf.AdditionalNetworks {
+		an := &conf.AdditionalNetworks[i]
+		owner := an.Namespace + "/" + an.Name
+		for _, r := range additionalNetworkWhereaboutsRanges(an) {
+			for _, other := range seen {
+				if other.owner == owner || other.networkName != r.networkName ||
+					(other.first.Equal(r.first) && other.last.Equal(r.last)) || !other.overlaps(r.addressRange) {
+					continue
+				}
+				msg := fmt.Sprintf("%s (%s) overlaps %s (%s)", owner, r.addressRange, other.owner, other.addressRange)
+				if r.networkName != "" {
+					msg += fmt.Sprintf(" in network_name %q", r.networkName)
+				}
+				overlaps = append(overlaps, msg)
+			}
+			seen = append(seen, ownedRange{owner: owner, whereaboutsPoolRange: r})
+		}
+	}
+
+	if len(overlaps) == 0 {
+		return operv1.OperatorCondition{
+			Type:   names.AdditionalNetworkAddressesOverlapCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+	return operv1.OperatorCondition{
+		Type:   names.AdditionalNetworkAddressesOverlapCondition,
+		Status: operv1.ConditionTrue,
+		Reason: "OverlappingAddresses",
+		Message: "The whereabouts ranges of these additional networks overlap, the same addresses may be assigned to " +
+			"several pods: " + strings.Join(overlaps, ", "),
+	}
+}
+
+// MachineNetworkOverlapCondition returns the informational condition listing
+// the additional networks whose IPAM subnets overlap a machine network. Such
+// networks are valid, as pods are commonly given addresses on the subnet of the
+// nodes, but the addresses they assign must be kept apart from the ones of the
+// machines.
+func MachineNetworkOverlapCondition(conf *operv1.NetworkSpec, infraStatus *bootstrap.InfraStatus) operv1.OperatorCondition {
+	machineNetworks := []*net.IPNet{}
+	if infraStatus != nil {
+		for _, mn := range infraStatus.MachineNetworks {
+			if _, ipnet, err := net.ParseCIDR(mn); err == nil {
+				machineNetworks = append(machineNetworks, ipnet)
+			}
+		}
+	}
+
+	overlaps := []string{}
+	for i := range conf.AdditionalNetworks {
+		an := &conf.AdditionalNetworks[i]
+		for _, cidr := range sets.List(sets.New(additionalNetworkIPAMCIDRs(an)...)) {
+			_, ipnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+			for _, mn := range machineNetworks {
+				if mn.Contains(ipnet.IP) || ipnet.Contains(mn.IP) {
+					overlaps = append(overlaps, fmt.Sprintf("%s/%s (%s overlaps %s)", an.Namespace, an.Name, cidr, mn))
+					break
+				}
+			}
+		}
+	}
+
+	if len(overlaps) == 0 {
+		return operv1.OperatorCondition{
+			Type:   names.AdditionalNetworksInMachineNetworkCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+	return operv1.OperatorCondition{
+		Type:   names.AdditionalNetworksInMachineNetworkCondition,
+		Status: operv1.ConditionTrue,
+		Reason: "MachineNetworkOverlap",
+		Message: "The IPAM subnets of these additional networks overlap a machine network, make sure that the addresses " +
+			"they assign are not used by the nodes: " + strings.Join(overlaps, ", "),
+	}
+}
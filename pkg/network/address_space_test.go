@@ -0,0 +1,138 @@
+package network
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
+	operv1 "github.com/openshift/api/operator/v1"
+
+	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
+)
+
+func TestValidateAddressSpace(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	raw := func(name, ipam string) operv1.AdditionalNetworkDefinition {
+		return operv1.AdditionalNetworkDefinition{
+			Type:         operv1.NetworkTypeRaw,
+			Name:         name,
+			Namespace:    "foobar",
+			RawCNIConfig: `{"cniVersion":"0.3.1","type":"macvlan","master":"eth0","ipam":` + ipam + `}`,
+		}
+	}
+
+	config := OVNKubernetesConfig.Spec.DeepCopy()
+	fillDefaults(config, nil)
+	// internal subnets left to their default are chosen around the other networks
+	config.DefaultNetwork.OVNKubernetesConfig.IPv4 = &operv1.IPv4OVNKubernetesConfig{InternalJoinSubnet: "100.64.0.0/16"}
+	infraStatus := &bootstrap.InfraStatus{
+		MachineNetworks: []string{"192.168.100.0/24"},
+		PlatformStatus: &configv1.PlatformStatus{
+			BareMetal: &configv1.BareMetalPlatformStatus{
+				APIServerInternalIPs: []string{"192.168.100.5"},
+				IngressIPs:           []string{"192.168.40.5"},
+			},
+		},
+	}
+
+	config.AdditionalNetworks = []operv1.AdditionalNetworkDefinition{
+		raw("dhcp", `{"type":"dhcp"}`),
+		raw("host-local", `{"type":"host-local","ranges":[[{"subnet":"192.168.10.0/24"}]]}`),
+		raw("whereabouts", `{"type":"whereabouts","range":"192.168.20.10-192.168.20.20/24"}`),
+		raw("static", `{"type":"static","addresses":[{"address":"192.168.30.2/24"},{"address":"192.168.30.3/24"}]}`),
+		// addresses on the subnet of the nodes are supported, as long as they are not the VIPs
+		raw("machine-static", `{"type":"static","addresses":[{"address":"192.168.100.10/24"}]}`),
+		raw("machine-whereabouts", `{"type":"whereabouts","range":"192.168.100.0/24","range_start":"192.168.100.100","range_end":"192.168.100.150"}`),
+		raw("machine-host-local", `{"type":"host-local","ranges":[[{"subnet":"192.168.100.0/24","rangeStart":"192.168.100.200","rangeEnd":"192.168.100.250"}]]}`),
+	}
+	g.Expect(validateAddressSpace(config, infraStatus)).To(BeEmpty())
+
+	// but they are reported
+	cond := MachineNetworkOverlapCondition(config, infraStatus)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Message).To(ContainSubstring("foobar/machine-static (192.168.100.0/24 overlaps 192.168.100.0/24), foobar/machine-whereabouts"))
+	g.Expect(cond.Message).NotTo(ContainSubstring("foobar/static"))
+	g.Expect(MachineNetworkOverlapCondition(config, nil).Status).To(Equal(operv1.ConditionFalse))
+
+	config.AdditionalNetworks = []operv1.AdditionalNetworkDefinition{
+		raw("cluster", `{"type":"host-local","subnet":"10.128.10.0/24"}`),
+		raw("service", `{"type":"whereabouts","range":"172.30.0.0/24"}`),
+		raw("machine", `{"type":"static","addresses":[{"address":"192.168.100.5/24"}]}`),
+		raw("join", `{"type":"whereabouts","range":"100.64.0.0/24"}`),
+		raw("first", `{"type":"host-local","subnet":"192.168.10.0/24"}`),
+		raw("second", `{"type":"whereabouts","range":"192.168.10.128/25"}`),
+		raw("ingress", `{"type":"host-local","subnet":"192.168.40.0/24"}`),
+	}
+	g.Expect(validateAddressSpace(config, infraStatus)).To(ConsistOf(
+		MatchError(`spec.additionalNetworks[0].rawCNIConfig: Invalid value: "10.128.10.0/24": IPAM subnet overlaps clusterNetwork 10.128.0.0/15`),
+		MatchError(`spec.additionalNetworks[1].rawCNIConfig: Invalid value: "172.30.0.0/24": IPAM subnet overlaps serviceNetwork 172.30.0.0/16`),
+		MatchError(`spec.additionalNetworks[2].rawCNIConfig: Invalid value: "192.168.100.5": IPAM addresses contain the API VIP 192.168.100.5`),
+		MatchError(`spec.additionalNetworks[3].rawCNIConfig: Invalid value: "100.64.0.0/24": IPAM subnet overlaps v4InternalJoinSubnet 100.64.0.0/16`),
+		MatchError(`spec.additionalNetworks[6].rawCNIConfig: Invalid value: "192.168.40.0-192.168.40.255": IPAM addresses contain the ingress VIP 192.168.40.5`),
+	))
+
+	// without the infrastructure status, the VIPs are unknown
+	g.Expect(validateAddressSpace(config, nil)).To(HaveLen(3))
+
+	// internal subnets left unset are reserved with their defaults
+	config.DefaultNetwork.OVNKubernetesConfig.IPv4 = nil
+	config.AdditionalNetworks = []operv1.AdditionalNetworkDefinition{
+		raw("join", `{"type":"whereabouts","range":"100.64.0.0/24"}`),
+		raw("transit", `{"type":"host-local","subnet":"100.88.1.0/24"}`),
+		raw("masquerade", `{"type":"static","addresses":[{"address":"169.254.0.10/24"}]}`),
+		raw("legacy-masquerade", `{"type":"static","addresses":[{"address":"169.254.169.3/24"}]}`),
+		raw("link-local", `{"type":"static","addresses":[{"address":"169.254.200.3/24"}]}`),
+	}
+	g.Expect(validateAddressSpace(config, nil)).To(ConsistOf(
+		MatchError(`spec.additionalNetworks[0].rawCNIConfig: Invalid value: "100.64.0.0/24": IPAM subnet overlaps v4InternalJoinSubnet 100.64.0.0/16`),
+		MatchError(`spec.additionalNetworks[1].rawCNIConfig: Invalid value: "100.88.1.0/24": IPAM subnet overlaps v4InternalTransitSwitchSubnet 100.88.0.0/16`),
+		MatchError(`spec.additionalNetworks[2].rawCNIConfig: Invalid value: "169.254.0.0/24": IPAM subnet overlaps v4InternalMasqueradeSubnet 169.254.0.0/17`),
+		MatchError(`spec.additionalNetworks[3].rawCNIConfig: Invalid value: "169.254.169.0/24": IPAM subnet overlaps v4InternalMasqueradeSubnet 169.254.169.0/29`),
+	))
+
+	// conflicts are reported by Validate
+	err := Validate(config, infraStatus)
+	g.Expect(err).To(MatchError(ContainSubstring(`IPAM subnet overlaps v4InternalJoinSubnet 100.64.0.0/16`)))
+}
+
+func TestAddressOverlapCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	raw := func(namespace, name, ipam string) operv1.AdditionalNetworkDefinition {
+		return operv1.AdditionalNetworkDefinition{
+			Type:         operv1.NetworkTypeRaw,
+			Name:         name,
+			Namespace:    namespace,
+			RawCNIConfig: `{"cniVersion":"0.3.1","type":"macvlan","master":"eth0","ipam":` + ipam + `}`,
+		}
+	}
+
+	config := OVNKubernetesConfig.Spec.DeepCopy()
+	config.AdditionalNetworks = []operv1.AdditionalNetworkDefinition{
+		// identical whereabouts ranges share an IP pool
+		raw("a", "shared-1", `{"type":"whereabouts","range":"192.168.10.0/24"}`),
+		raw("b", "shared-2", `{"type":"whereabouts","range":"192.168.10.0/24"}`),
+		// so do the ranges of different network_names
+		raw("c", "other-name", `{"type":"whereabouts","range":"192.168.10.128/25","network_name":"other"}`),
+		// static and host-local addresses may be reused on isolated networks
+		raw("a", "static-1", `{"type":"static","addresses":[{"address":"192.168.30.2/24"}]}`),
+		raw("b", "static-2", `{"type":"static","addresses":[{"address":"192.168.30.2/24"}]}`),
+		raw("a", "host-local", `{"type":"host-local","subnet":"192.168.10.0/24"}`),
+	}
+	g.Expect(validateAddressSpace(config, nil)).To(BeEmpty())
+	g.Expect(AddressOverlapCondition(config).Status).To(Equal(operv1.ConditionFalse))
+
+	config.AdditionalNetworks = append(config.AdditionalNetworks,
+		raw("d", "overlapping", `{"type":"whereabouts","range":"192.168.10.128/25"}`),
+		raw("e", "overlapping-name", `{"type":"whereabouts","range":"192.168.10.0/23","network_name":"other"}`),
+	)
+	// overlaps are reported, not rejected
+	g.Expect(validateAddressSpace(config, nil)).To(BeEmpty())
+	cond := AddressOverlapCondition(config)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal("OverlappingAddresses"))
+	g.Expect(cond.Message).To(HaveSuffix("d/overlapping (192.168.10.128-192.168.10.255) overlaps a/shared-1 (192.168.10.0-192.168.10.255), " +
+		"d/overlapping (192.168.10.128-192.168.10.255) overlaps b/shared-2 (192.168.10.0-192.168.10.255), " +
+		`e/overlapping-name (192.168.10.0-192.168.11.255) overlaps c/other-name (192.168.10.128-192.168.10.255) in network_name "other"`))
+}
@@ -24,16 +24,17 @@ func Bootstrap(conf *operv1.Network, client cnoclient.Client) (*bootstrap.Bootst
 	}
 	out.Infra = *infraStatus
 
-	if conf.Spec.DefaultNetwork.Type == operv1.NetworkTypeOVNKubernetes {
-		o, err := bootstrapOVN(conf, client, infraStatus)
-		if err != nil {
-			return nil, err
-		}
-		out.OVN = *o
+	if err := runningDefaultNetworkPlugin(conf.Spec.DefaultNetwork.Type).Bootstrap(conf, client, infraStatus, out); err != nil {
+		return nil, err
 	}
 
 	out.IPTablesAlerter = iptablesAlerterBootstrap(client.ClientFor("").CRClient())
 
+	out.Sizing, err = bootstrapSizing(conf, client)
+	if err != nil {
+		return nil, err
+	}
+
 	out.TLSProfile, err = GetTLSProfile(client, infraStatus.HostedControlPlane)
 	if err != nil {
 		return nil, err
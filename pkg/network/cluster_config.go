@@ -130,39 +130,11 @@ func MergeClusterConfig(operConf *operv1.NetworkSpec, clusterConf configv1.Netwo
 // StatusFromOperatorConfig generates the cluster NetworkStatus from the
 // currently applied operator configuration.
 func StatusFromOperatorConfig(operConf *operv1.NetworkSpec, oldStatus *configv1.NetworkStatus) *configv1.NetworkStatus {
-	knownNetworkType := true
 	status := configv1.NetworkStatus{}
 
-	if operConf.DefaultNetwork.Type != operv1.NetworkTypeOVNKubernetes {
-		knownNetworkType = false
-		// Preserve any status fields set by the unknown network plugin
-		status = *oldStatus
-	}
-
-	if oldStatus.NetworkType == "" || knownNetworkType {
-		status.NetworkType = string(operConf.DefaultNetwork.Type)
-	}
-
 	// TODO: when we support expanding the service cidr or cluster cidr,
 	// don't actually update the status until the changes are rolled out.
-
-	if len(oldStatus.ServiceNetwork) == 0 || knownNetworkType {
-		status.ServiceNetwork = operConf.ServiceNetwork
-	}
-	if len(oldStatus.ClusterNetwork) == 0 || knownNetworkType {
-		for _, cnet := range operConf.ClusterNetwork {
-			status.ClusterNetwork = append(status.ClusterNetwork,
-				configv1.ClusterNetworkEntry{
-					CIDR:       cnet.CIDR,
-					HostPrefix: cnet.HostPrefix,
-				})
-		}
-	}
-
-	// Determine the MTU from the provider
-	if operConf.DefaultNetwork.Type == operv1.NetworkTypeOVNKubernetes {
-		status.ClusterNetworkMTU = int(*operConf.DefaultNetwork.OVNKubernetesConfig.MTU)
-	}
+	runningDefaultNetworkPlugin(operConf.DefaultNetwork.Type).Status(operConf, oldStatus, &status)
 
 	// Set migration in the config status
 	if operConf.Migration != nil {
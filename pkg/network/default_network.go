@@ -0,0 +1,207 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/configobserver/featuregates"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+)
+
+// NetworkTypeNone is the default network type of clusters whose pod network is
+// provided by a third-party network plugin. The operator renders Multus and its
+// other common components, but no default network, for it.
+const NetworkTypeNone operv1.NetworkType = "None"
+
+// DefaultNetworkPlugin implements a default network type
+type DefaultNetworkPlugin interface {
+	// Bootstrap gathers, or creates, whatever the plugin needs to render and
+	// stores it in out
+	Bootstrap(conf *operv1.Network, client cnoclient.Client, infraStatus *bootstrap.InfraStatus, out *bootstrap.BootstrapResult) error
+
+	// Validate checks the default network configuration
+	Validate(conf *operv1.NetworkSpec) []error
+
+	// FillDefaults sets the unset fields of the default network configuration,
+	// using previous, the configuration applied before if any, for the fields
+	// that cannot change
+	FillDefaults(conf, previous *operv1.NetworkSpec, hostMTU int, infraStatus *bootstrap.InfraStatus)
+
+	// IsChangeSafe checks that the default network configuration can change
+	// from prev to next
+	IsChangeSafe(prev, next *operv1.NetworkSpec) []error
+
+	// Render generates the manifests of the default network, and whether the
+	// operator should keep reporting Progressing while they roll out
+	Render(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.BootstrapResult, manifestDir string,
+		client cnoclient.Client, featureGates featuregates.FeatureGate) ([]*uns.Unstructured, bool, error)
+
+	// Status sets the network type, networks and MTU of the cluster network
+	// status. oldStatus is the status reported so far.
+	Status(conf *operv1.NetworkSpec, oldStatus, status *configv1.NetworkStatus)
+}
+
+var defaultNetworkPlugins = map[operv1.NetworkType]DefaultNetworkPlugin{}
+
+func init() {
+	RegisterDefaultNetworkPlugin(operv1.NetworkTypeOVNKubernetes, ovnKubernetesPlugin{})
+	RegisterDefaultNetworkPlugin(NetworkTypeNone, thirdPartyPlugin{})
+}
+
+// RegisterDefaultNetworkPlugin makes plugin the implementation of the given
+// default network type. It panics if the type already has one.
+func RegisterDefaultNetworkPlugin(networkType operv1.NetworkType, plugin DefaultNetworkPlugin) {
+	if _, ok := defaultNetworkPlugins[networkType]; ok {
+		panic(fmt.Sprintf("default network type %s registered twice", networkType))
+	}
+	defaultNetworkPlugins[networkType] = plugin
+}
+
+// defaultNetworkPlugin returns the implementation of the given default network type
+func defaultNetworkPlugin(networkType operv1.NetworkType) (DefaultNetworkPlugin, error) {
+	if plugin, ok := defaultNetworkPlugins[networkType]; ok {
+		return plugin, nil
+	}
+
+	known := []string{}
+	for t := range defaultNetworkPlugins {
+		known = append(known, string(t))
+	}
+	sort.Strings(known)
+	return nil, fmt.Errorf("unknown default network type %q, must be one of %s; use %q if the pod network is provided by a third-party network plugin",
+		networkType, strings.Join(known, ", "), NetworkTypeNone)
+}
+
+// runningDefaultNetworkPlugin returns the implementation of the given default
+// network type. Unknown types, which ValidateDefaultNetworkType only tolerates on
+// the clusters that already run with them, are handled like None.
+func runningDefaultNetworkPlugin(networkType operv1.NetworkType) DefaultNetworkPlugin {
+	if plugin, err := defaultNetworkPlugin(networkType); err == nil {
+		return plugin
+	}
+	return thirdPartyPlugin{}
+}
+
+// ValidateDefaultNetworkType checks that the default network type is known, when
+// the cluster is installed or the type changes. The clusters that were installed
+// with the name of their third-party network plugin as the type keep it: it is
+// merged from the cluster configuration, which cannot be changed to None.
+func ValidateDefaultNetworkType(conf, prev *operv1.NetworkSpec) error {
+	if prev != nil && prev.DefaultNetwork.Type == conf.DefaultNetwork.Type {
+		return nil
+	}
+	_, err := defaultNetworkPlugin(conf.DefaultNetwork.Type)
+	return err
+}
+
+// DefaultNetworkTypeCondition reports a default network type the operator does
+// not know, which is handled like None
+func DefaultNetworkTypeCondition(conf *operv1.NetworkSpec) operv1.OperatorCondition {
+	if _, err := defaultNetworkPlugin(conf.DefaultNetwork.Type); err == nil {
+		return operv1.OperatorCondition{
+			Type:   names.DefaultNetworkTypeUnknownCondition,
+			Status: operv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+	return operv1.OperatorCondition{
+		Type:   names.DefaultNetworkTypeUnknownCondition,
+		Status: operv1.ConditionTrue,
+		Reason: "UnknownNetworkType",
+		Message: fmt.Sprintf("The default network type %q is not known to the operator: the pod network is assumed to be provided by a third-party network plugin, as with %q",
+			conf.DefaultNetwork.Type, NetworkTypeNone),
+	}
+}
+
+// ovnKubernetesPlugin is the OVNKubernetes default network
+type ovnKubernetesPlugin struct{}
+
+func (ovnKubernetesPlugin) Bootstrap(conf *operv1.Network, client cnoclient.Client, infraStatus *bootstrap.InfraStatus, out *bootstrap.BootstrapResult) error {
+	o, err := bootstrapOVN(conf, client, infraStatus)
+	if err != nil {
+		return err
+	}
+	out.OVN = *o
+	return nil
+}
+
+func (ovnKubernetesPlugin) Validate(conf *operv1.NetworkSpec) []error {
+	return validateOVNKubernetes(conf)
+}
+
+func (ovnKubernetesPlugin) FillDefaults(conf, previous *operv1.NetworkSpec, hostMTU int, infraStatus *bootstrap.InfraStatus) {
+	fillOVNKubernetesDefaults(conf, previous, hostMTU, infraStatus)
+}
+
+func (ovnKubernetesPlugin) IsChangeSafe(prev, next *operv1.NetworkSpec) []error {
+	return isOVNKubernetesChangeSafe(prev, next)
+}
+
+func (ovnKubernetesPlugin) Render(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.BootstrapResult, manifestDir string,
+	client cnoclient.Client, featureGates featuregates.FeatureGate) ([]*uns.Unstructured, bool, error) {
+	return renderOVNKubernetes(conf, bootstrapResult, manifestDir, client, featureGates)
+}
+
+func (ovnKubernetesPlugin) Status(conf *operv1.NetworkSpec, oldStatus, status *configv1.NetworkStatus) {
+	status.NetworkType = string(conf.DefaultNetwork.Type)
+	status.ServiceNetwork = conf.ServiceNetwork
+	status.ClusterNetwork = clusterNetworkStatus(conf)
+	status.ClusterNetworkMTU = int(*conf.DefaultNetwork.OVNKubernetesConfig.MTU)
+}
+
+// thirdPartyPlugin is the default network of clusters whose pod network is
+// provided by a third-party network plugin, which the operator does not manage
+type thirdPartyPlugin struct{}
+
+func (thirdPartyPlugin) Bootstrap(conf *operv1.Network, client cnoclient.Client, infraStatus *bootstrap.InfraStatus, out *bootstrap.BootstrapResult) error {
+	return nil
+}
+
+func (thirdPartyPlugin) Validate(conf *operv1.NetworkSpec) []error {
+	return nil
+}
+
+func (thirdPartyPlugin) FillDefaults(conf, previous *operv1.NetworkSpec, hostMTU int, infraStatus *bootstrap.InfraStatus) {
+}
+
+func (thirdPartyPlugin) IsChangeSafe(prev, next *operv1.NetworkSpec) []error {
+	return nil
+}
+
+func (thirdPartyPlugin) Render(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.BootstrapResult, manifestDir string,
+	client cnoclient.Client, featureGates featuregates.FeatureGate) ([]*uns.Unstructured, bool, error) {
+	return nil, false, nil
+}
+
+// Status preserves the fields set by the third-party plugin, and only fills in
+// the ones it did not set
+func (thirdPartyPlugin) Status(conf *operv1.NetworkSpec, oldStatus, status *configv1.NetworkStatus) {
+	*status = *oldStatus
+	if status.NetworkType == "" {
+		status.NetworkType = string(conf.DefaultNetwork.Type)
+	}
+	if len(status.ServiceNetwork) == 0 {
+		status.ServiceNetwork = conf.ServiceNetwork
+	}
+	if len(status.ClusterNetwork) == 0 {
+		status.ClusterNetwork = clusterNetworkStatus(conf)
+	}
+}
+
+func clusterNetworkStatus(conf *operv1.NetworkSpec) []configv1.ClusterNetworkEntry {
+	var entries []configv1.ClusterNetworkEntry
+	for _, cnet := range conf.ClusterNetwork {
+		entries = append(entries, configv1.ClusterNetworkEntry{
+			CIDR:       cnet.CIDR,
+			HostPrefix: cnet.HostPrefix,
+		})
+	}
+	return entries
+}
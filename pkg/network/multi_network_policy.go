@@ -1,16 +1,92 @@
 package network
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	operv1 "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-network-operator/pkg/render"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
+// MultiNetworkPolicyScope restricts the MultiNetworkPolicies that can be written
+// to those in the namespaces matching NamespaceSelector, and to those for the
+// NetworkAttachmentDefinitions in Networks, in any namespace. Neither the
+// multi-networkpolicy daemon nor OVN-Kubernetes can be scoped, so the scope only
+// governs admission. It is read from
+// spec.unsupportedConfigOverrides.multiNetworkPolicyScope, for example:
+//
+//	unsupportedConfigOverrides:
+//	  multiNetworkPolicyScope:
+//	    namespaceSelector:
+//	      matchLabels:
+//	        multi-network-policy: enabled
+//	    networks:
+//	    - shared-networks/storage
+type MultiNetworkPolicyScope struct {
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Networks is the namespace/name of NetworkAttachmentDefinitions
+	Networks []string `json:"networks,omitempty"`
+}
+
+// Namespaces returns the selector of the namespaces in the scope, which
+// matches nothing if no namespace selector is set
+func (s *MultiNetworkPolicyScope) Namespaces() (labels.Selector, error) {
+	if s.NamespaceSelector == nil {
+		return labels.Nothing(), nil
+	}
+	return metav1.LabelSelectorAsSelector(s.NamespaceSelector)
+}
+
+// GetMultiNetworkPolicyScope parses the MultiNetworkPolicy scope from the operator
+// configuration. It returns nil if MultiNetworkPolicies are not scoped.
+func GetMultiNetworkPolicyScope(conf *operv1.NetworkSpec) (*MultiNetworkPolicyScope, error) {
+	if len(conf.UnsupportedConfigOverrides.Raw) == 0 {
+		return nil, nil
+	}
+	overrides := struct {
+		MultiNetworkPolicyScope *MultiNetworkPolicyScope `json:"multiNetworkPolicyScope,omitempty"`
+	}{}
+	if err := json.Unmarshal(conf.UnsupportedConfigOverrides.Raw, &overrides); err != nil {
+		return nil, fmt.Errorf("could not parse unsupportedConfigOverrides: %w", err)
+	}
+	return overrides.MultiNetworkPolicyScope, nil
+}
+
+// validateMultiNetworkPolicyScope checks that the MultiNetworkPolicy scope, if
+// any, has a valid namespace selector and names valid networks
+func validateMultiNetworkPolicyScope(conf *operv1.NetworkSpec) []error {
+	scope, err := GetMultiNetworkPolicyScope(conf)
+	if err != nil {
+		return []error{err}
+	}
+	if scope == nil {
+		return nil
+	}
+	if conf.UseMultiNetworkPolicy == nil || !*conf.UseMultiNetworkPolicy {
+		return []error{fmt.Errorf("multiNetworkPolicyScope cannot be specified without useMultiNetworkPolicy")}
+	}
+
+	errs := []error{}
+	if _, err := scope.Namespaces(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid multiNetworkPolicyScope namespaceSelector: %w", err))
+	}
+	for _, network := range scope.Networks {
+		namespace, name, ok := strings.Cut(network, "/")
+		if !ok || len(validation.IsDNS1123Label(namespace)) > 0 || len(validation.IsDNS1123Subdomain(name)) > 0 {
+			errs = append(errs, fmt.Errorf("invalid multiNetworkPolicyScope network %q: must be the namespace/name of a NetworkAttachmentDefinition", network))
+		}
+	}
+	return errs
+}
+
 // renderMultiNetworkpolicyConfig returns the manifests of MultiNetworkPolicy
 func renderMultiNetworkpolicyConfig(manifestDir string) ([]*uns.Unstructured, error) {
 	objs := []*uns.Unstructured{}
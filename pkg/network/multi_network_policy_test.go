@@ -52,3 +52,24 @@ func TestRenderMultiNetworkPolicy(t *testing.T) {
 	g.Expect(objs).To(ContainElement(HaveKubernetesID("DaemonSet", "openshift-multus", "multus-networkpolicy")))
 	g.Expect(objs).To(ContainElement(HaveKubernetesID("ConfigMap", "openshift-multus", "multi-networkpolicy-custom-rules")))
 }
+
+func TestValidateMultiNetworkPolicyScope(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := MultiNetworkPolicyConfig.DeepCopy()
+	config := &crd.Spec
+	g.Expect(validateMultiNetworkPolicyScope(config)).To(BeEmpty())
+
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multiNetworkPolicyScope": {"networks": ["shared/net1"]}}`)
+	g.Expect(validateMultiNetworkPolicyScope(config)).To(ConsistOf(
+		MatchError("multiNetworkPolicyScope cannot be specified without useMultiNetworkPolicy")))
+
+	enabled := true
+	config.UseMultiNetworkPolicy = &enabled
+	g.Expect(validateMultiNetworkPolicyScope(config)).To(BeEmpty())
+
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multiNetworkPolicyScope": {
+		"namespaceSelector": {"matchExpressions": [{"key": "team", "operator": "In"}]},
+		"networks": ["net1", "Shared/net1"]}}`)
+	g.Expect(validateMultiNetworkPolicyScope(config)).To(HaveLen(3))
+}
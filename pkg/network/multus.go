@@ -36,13 +36,21 @@ func renderMultus(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.Bootstrap
 	}
 	out = append(out, objs...)
 
-	usedhcp, usewhereabouts := detectAuxiliaryIPAM(conf)
+	usedhcp, usewhereabouts := detectAuxiliaryIPAM(conf, &bootstrapResult.AuxiliaryIPAM)
 	h := bootstrapResult.Infra.APIServers[bootstrap.APIServerDefault].Host
 	p := bootstrapResult.Infra.APIServers[bootstrap.APIServerDefault].Port
 	objs, err = renderMultusConfig(manifestDir, string(conf.DefaultNetwork.Type), usedhcp, usewhereabouts, h, p, bootstrapResult)
 	if err != nil {
 		return nil, err
 	}
+	pools, err := getMultusNodePools(conf)
+	if err != nil {
+		return nil, err
+	}
+	objs, err = renderMultusNodePools(pools, objs)
+	if err != nil {
+		return nil, err
+	}
 	out = append(out, objs...)
 
 	objs, err = renderNetworkMetricsDaemon(manifestDir, bootstrapResult)
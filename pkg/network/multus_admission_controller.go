@@ -8,7 +8,8 @@ import (
 	"net"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
+	"strconv"
 
 	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
 	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
@@ -29,12 +30,22 @@ import (
 
 const bytesInMiB = 1024 * 1024
 
-// ignoredNamespaces contains the comma separated namespace list that should be ignored
-// to watch by multus admission controller. This only initialized first invocation.
-var ignoredNamespaces string
+// staticIgnoredNamespaces are the namespaces the multus admission controller always ignores
+var staticIgnoredNamespaces = []string{"openshift-etcd", "openshift-console", "openshift-ingress-canary"}
 
-// getOpenshiftNamespaces collect openshift related namespaces, as comma separate list
-func getOpenshiftNamespaces(client cnoclient.Client) (string, error) {
+// ignoredNamespaces contains the namespaces ignored by the multus admission controller
+// the last time they could be listed, used when listing them fails.
+var ignoredNamespaces []string
+
+// IsMultusAdmissionIgnoredNamespace returns whether ns is an OpenShift component
+// namespace, that the multus admission controller ignores
+func IsMultusAdmissionIgnoredNamespace(ns *corev1.Namespace) bool {
+	return ns.Labels["openshift.io/cluster-monitoring"] == "true" &&
+		ns.Annotations["workload.openshift.io/allowed"] == "management"
+}
+
+// getOpenshiftNamespaces collect openshift related namespaces
+func getOpenshiftNamespaces(client cnoclient.Client) ([]string, error) {
 	namespaces := []string{}
 
 	// get openshift specific namespaces to add them into ignoreNamespace
@@ -42,16 +53,16 @@ func getOpenshiftNamespaces(client cnoclient.Client) (string, error) {
 		LabelSelector: "openshift.io/cluster-monitoring==true",
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get namespaces to render multus admission controller manifests: %w", err)
+		return nil, fmt.Errorf("failed to get namespaces to render multus admission controller manifests: %w", err)
 	}
 
 	for _, ns := range nsList.Items {
 		// add OpenShift components to ignored namespace
-		if metav1.HasAnnotation(ns.ObjectMeta, "workload.openshift.io/allowed") && ns.Annotations["workload.openshift.io/allowed"] == "management" {
+		if IsMultusAdmissionIgnoredNamespace(&ns) {
 			namespaces = append(namespaces, ns.Name)
 		}
 	}
-	return strings.Join(namespaces, ","), nil
+	return namespaces, nil
 }
 
 // renderMultusAdmissonControllerConfig returns the manifests of Multus Admisson Controller
@@ -60,18 +71,21 @@ func renderMultusAdmissonControllerConfig(manifestDir string, externalControlPla
 	var err error
 
 	replicas := getMultusAdmissionControllerReplicas(bootstrapResult, hsc.Enabled)
-	if ignoredNamespaces == "" {
-		ignoredNamespaces, err = getOpenshiftNamespaces(client)
-		if err != nil {
-			klog.Warningf("failed to get openshift namespaces: %+v", err)
-		}
+	// The ignored namespaces are rendered in the namespaceSelector of the webhook
+	// configuration, so they are updated without restarting the admission controller.
+	// The operator reconciles when OpenShift component namespaces come and go.
+	if namespaces, err := getOpenshiftNamespaces(client); err != nil {
+		klog.Warningf("failed to get openshift namespaces: %+v", err)
+	} else {
+		ignoredNamespaces = namespaces
 	}
 
 	// render the manifests on disk
 	data := render.MakeRenderData()
 	data.Data["ReleaseVersion"] = os.Getenv("RELEASE_VERSION")
 	data.Data["MultusAdmissionControllerImage"] = os.Getenv("MULTUS_ADMISSION_CONTROLLER_IMAGE")
-	data.Data["IgnoredNamespace"] = ignoredNamespaces
+	data.Data["IgnoredNamespaces"] = append(slices.Clone(staticIgnoredNamespaces), ignoredNamespaces...)
+	data.Data["MultusAdmissionIgnoreLabel"] = names.MultusAdmissionIgnoreLabel
 	data.Data["MultusValidatingWebhookName"] = names.MULTUS_VALIDATING_WEBHOOK
 	data.Data["KubeRBACProxyImage"] = os.Getenv("KUBE_RBAC_PROXY_IMAGE")
 	data.Data["ExternalControlPlane"] = externalControlPlane
@@ -81,8 +95,8 @@ func renderMultusAdmissonControllerConfig(manifestDir string, externalControlPla
 	data.Data["ManagementClusterName"] = names.ManagementClusterName
 	data.Data["AdmissionControllerNamespace"] = "openshift-multus"
 	data.Data["RHOBSMonitoring"] = os.Getenv("RHOBS_MONITORING")
-	data.Data["ResourceRequestCPU"] = nil
-	data.Data["ResourceRequestMemory"] = nil
+	data.Data["ResourceRequestCPU"] = sizedRequest(bootstrapResult.Sizing.MultusAdmissionController.CPU, "", false)
+	data.Data["ResourceRequestMemory"] = sizedRequest(bootstrapResult.Sizing.MultusAdmissionController.Memory, "", false)
 	data.Data["PriorityClass"] = nil
 
 	if hsc.Enabled {
@@ -129,10 +143,12 @@ func renderMultusAdmissonControllerConfig(manifestDir string, externalControlPla
 				return nil, errors.New("error finding multus container")
 			}
 			if !multusContainer.Resources.Requests.Cpu().IsZero() {
-				data.Data["ResourceRequestCPU"] = multusContainer.Resources.Requests.Cpu().MilliValue()
+				data.Data["ResourceRequestCPU"] = sizedRequest(bootstrapResult.Sizing.MultusAdmissionController.CPU,
+					strconv.FormatInt(multusContainer.Resources.Requests.Cpu().MilliValue(), 10), bootstrapResult.Sizing.Overridden)
 			}
 			if !multusContainer.Resources.Requests.Memory().IsZero() {
-				data.Data["ResourceRequestMemory"] = multusContainer.Resources.Requests.Memory().Value() / bytesInMiB
+				data.Data["ResourceRequestMemory"] = sizedRequest(bootstrapResult.Sizing.MultusAdmissionController.Memory,
+					strconv.FormatInt(multusContainer.Resources.Requests.Memory().Value()/bytesInMiB, 10), bootstrapResult.Sizing.Overridden)
 			}
 		} else {
 			if apierrors.IsNotFound(err) {
@@ -10,6 +10,7 @@ import (
 	cnofake "github.com/openshift/cluster-network-operator/pkg/client/fake"
 	"github.com/openshift/cluster-network-operator/pkg/hypershift"
 	"github.com/openshift/cluster-network-operator/pkg/names"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -241,7 +242,35 @@ func TestRenderMultusAdmissionControllerGetNamespace(t *testing.T) {
 		})
 	namespaces, err := getOpenshiftNamespaces(fakeClient)
 	g.Expect(err).NotTo(HaveOccurred())
-	g.Expect(namespaces).To(Equal("test1-ignored,test3-ignored"))
+	g.Expect(namespaces).To(Equal([]string{"test1-ignored", "test3-ignored"}))
+
+	// The ignored namespaces are rendered in the webhook configuration
+	crd := MultusAdmissionControllerConfig.DeepCopy()
+	config := &crd.Spec
+	enabled := false
+	config.DisableMultiNetwork = &enabled
+	fillDefaults(config, nil)
+	objs, err := renderMultusAdmissionController(config, manifestDir, false, fakeBootstrapResult(), fakeClient, getDefaultFeatureGates())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	webhook := mustFindRenderedObj[*admissionregistrationv1.ValidatingWebhookConfiguration](t, objs, "ValidatingWebhookConfiguration", names.MULTUS_VALIDATING_WEBHOOK)
+	g.Expect(webhook.Webhooks).To(HaveLen(1))
+	g.Expect(webhook.Webhooks[0].NamespaceSelector).To(Equal(&metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      "kubernetes.io/metadata.name",
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   []string{"openshift-etcd", "openshift-console", "openshift-ingress-canary", "test1-ignored", "test3-ignored"},
+			},
+			{
+				Key:      names.MultusAdmissionIgnoreLabel,
+				Operator: metav1.LabelSelectorOpDoesNotExist,
+			},
+		},
+	}))
+
+	// and are not passed to the admission controller, so it is not restarted when they change
+	g.Expect(findMultusWebhookExec(t, objs)).NotTo(ContainSubstring("-ignore-namespaces"))
 }
 
 func mustFindMultusAdmissionDeployment(t *testing.T, objs []*unstructured.Unstructured) *appsv1.Deployment {
@@ -2,82 +2,82 @@ package network
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
 
 	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
+	"github.com/openshift/cluster-network-operator/pkg/names"
 )
 
+// NetworkAttachmentDefinitionGVK is the kind of the Multus network attachments
+var NetworkAttachmentDefinitionGVK = schema.GroupVersionKind{Group: "k8s.cni.cncf.io", Version: "v1", Kind: "NetworkAttachmentDefinition"}
+
 const ipamTypeDHCP = "dhcp"
 const ipamTypeWhereabouts = "whereabouts"
 
 // detectIPAMTypeRaw determines if a target type of IPAM is being used
 // this facilitates using auxiliary features associated with that IPAM (such as DHCP CNI daemon, or ip-reconciler for Whereabouts)
-func detectIPAMTypeRaw(targetType string, addNet *operv1.AdditionalNetworkDefinition) bool {
-	// Parse the RawCNIConfig
-	var rawConfig map[string]any
-	var useipam any
-	var err error
-	foundipam := false
-
-	confBytes := []byte(addNet.RawCNIConfig)
-	err = json.Unmarshal(confBytes, &rawConfig)
+func detectIPAMTypeRaw(targetType string, rawCNIConfig string) bool {
+	ipamType, err := ipamTypeRaw(rawCNIConfig)
 	if err != nil {
-		log.Printf("WARNING: Cannot detect multus network IPAM type, failed to Unmarshal RawCNIConfig: %v", confBytes)
+		log.Printf("WARNING: Cannot detect multus network IPAM type: %v", err)
 		return false
 	}
+	return ipamType == targetType
+}
+
+// ipamTypeRaw returns the type of the IPAM of a raw CNI configuration, or "" if it
+// has none. For a conflist, only the first IPAM found is returned, as it should be
+// the primary one.
+func ipamTypeRaw(rawCNIConfig string) (string, error) {
+	var rawConfig map[string]any
+	if err := json.Unmarshal([]byte(rawCNIConfig), &rawConfig); err != nil {
+		return "", fmt.Errorf("failed to unmarshal the CNI configuration: %w", err)
+	}
 
+	useipam := rawConfig["ipam"]
 	// First we determine if it's a conflist.
 	if rawConfig["plugins"] != nil {
-		// As a limitation, we'll only look for the first instance of ipam (should be the primary case)
-		plugins, okplugincast := rawConfig["plugins"].([]any)
-		if !okplugincast {
-			log.Printf("WARNING: Plugins (conflist) element has data of type %T but wanted []interface{}", rawConfig["plugins"])
-			return false
+		plugins, ok := rawConfig["plugins"].([]any)
+		if !ok {
+			return "", fmt.Errorf("plugins (conflist) element has data of type %T but wanted []interface{}", rawConfig["plugins"])
 		}
-
 		for _, pvalue := range plugins {
-			eachConfig, okeachconfigcast := pvalue.(map[string]any)
-			if !okeachconfigcast {
-				log.Printf("WARNING: Each Plugin element (conflist) has data of type %T but wanted map[string]interface{}", pvalue)
-				return false
+			eachConfig, ok := pvalue.(map[string]any)
+			if !ok {
+				return "", fmt.Errorf("plugin element (conflist) has data of type %T but wanted map[string]interface{}", pvalue)
 			}
 			if eachConfig["ipam"] != nil {
 				useipam = eachConfig["ipam"]
-				foundipam = true
 				break
 			}
 		}
 	}
-
-	// Cycle through the IPAM keys, and determine if the type is dhcp
-	if rawConfig["ipam"] != nil && !foundipam {
-		useipam = rawConfig["ipam"]
-		foundipam = true
+	if useipam == nil {
+		return "", nil
 	}
 
-	if foundipam {
-		ipam, okipamcast := useipam.(map[string]any)
-		if !okipamcast {
-			log.Printf("WARNING: IPAM element has data of type %T but wanted map[string]interface{}", useipam)
-			return false
-		}
-
-		for key, value := range ipam {
-			if key == "type" {
-				typeval, oktypecast := value.(string)
-				if !oktypecast {
-					log.Printf("WARNING: IPAM type element has data of type %T but wanted string", value)
-					return false
-				}
-
-				if typeval == targetType {
-					return true
-				}
-			}
-		}
+	ipam, ok := useipam.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("IPAM element has data of type %T but wanted map[string]interface{}", useipam)
 	}
-
-	return false
+	if ipam["type"] == nil {
+		return "", nil
+	}
+	ipamType, ok := ipam["type"].(string)
+	if !ok {
+		return "", fmt.Errorf("IPAM type element has data of type %T but wanted string", ipam["type"])
+	}
+	return ipamType, nil
 }
 
 // useDHCPSimpleMacvlan determines if the the DHCP CNI plugin running as a daemon should be rendered in case of SimpleMacvlan.
@@ -94,33 +94,305 @@ func useDHCPSimpleMacvlan(conf *operv1.SimpleMacvlanConfig) bool {
 	return false
 }
 
-// detectAuxiliaryIPAM detects if an auxiliary ipam is used.
-func detectAuxiliaryIPAM(conf *operv1.NetworkSpec) (bool, bool) {
-	renderdhcp := false
-	renderwhereabouts := false
+// auxiliaryIPAMUsers returns the additional networks and NetworkAttachmentDefinitions
+// using the dhcp and the whereabouts IPAM plugins, which need the DHCP daemon and
+// the whereabouts reconciler respectively.
+func auxiliaryIPAMUsers(conf *operv1.NetworkSpec, aux *bootstrap.AuxiliaryIPAMBootstrapResult) ([]string, []string) {
+	dhcp := []string{}
+	whereabouts := []string{}
 
 	// This isn't useful without Multinetwork.
 	if *conf.DisableMultiNetwork {
-		return renderdhcp, renderwhereabouts
-	}
-
-	// Look and see if we have an AdditionalNetworks
-	if conf.AdditionalNetworks != nil {
-		for _, addnet := range conf.AdditionalNetworks {
-			switch addnet.Type {
-			case operv1.NetworkTypeRaw:
-				renderdhcp = renderdhcp || detectIPAMTypeRaw(ipamTypeDHCP, &addnet)
-				renderwhereabouts = renderwhereabouts || detectIPAMTypeRaw(ipamTypeWhereabouts, &addnet)
-			case operv1.NetworkTypeSimpleMacvlan:
-				// SimpleMacvlan only supports static and DHCP. So we don't detect whereabouts.
-				renderdhcp = renderdhcp || useDHCPSimpleMacvlan(addnet.SimpleMacvlanConfig)
+		return dhcp, whereabouts
+	}
+
+	for _, addnet := range conf.AdditionalNetworks {
+		name := "additional network " + addnet.Name
+		switch addnet.Type {
+		case operv1.NetworkTypeRaw:
+			if detectIPAMTypeRaw(ipamTypeDHCP, addnet.RawCNIConfig) {
+				dhcp = append(dhcp, name)
+			}
+			if detectIPAMTypeRaw(ipamTypeWhereabouts, addnet.RawCNIConfig) {
+				whereabouts = append(whereabouts, name)
+			}
+		case operv1.NetworkTypeSimpleMacvlan:
+			// SimpleMacvlan only supports static and DHCP. So we don't detect whereabouts.
+			if useDHCPSimpleMacvlan(addnet.SimpleMacvlanConfig) {
+				dhcp = append(dhcp, name)
+			}
+		case NetworkTypeSimpleIPVLAN, NetworkTypeSimpleBridge:
+			// DHCP is the default IPAM
+			ipam := simpleIPAMConfig(&addnet)
+			if ipam == nil || ipam.Type == operv1.IPAMTypeDHCP {
+				dhcp = append(dhcp, name)
+			} else if ipam.Type == IPAMTypeWhereabouts {
+				whereabouts = append(whereabouts, name)
 			}
+		case NetworkTypeSimpleHostDevice:
+			// host-device has no IPAM by default
+			if ipam := simpleIPAMConfig(&addnet); ipam != nil && ipam.Type == operv1.IPAMTypeDHCP {
+				dhcp = append(dhcp, name)
+			} else if ipam != nil && ipam.Type == IPAMTypeWhereabouts {
+				whereabouts = append(whereabouts, name)
+			}
+		}
+	}
 
-			if renderdhcp && renderwhereabouts {
-				break
+	for _, nad := range aux.DHCP {
+		dhcp = append(dhcp, "NetworkAttachmentDefinition "+nad)
+	}
+	for _, nad := range aux.Whereabouts {
+		whereabouts = append(whereabouts, "NetworkAttachmentDefinition "+nad)
+	}
+	return dhcp, whereabouts
+}
+
+// detectAuxiliaryIPAM detects if an auxiliary ipam is used.
+func detectAuxiliaryIPAM(conf *operv1.NetworkSpec, aux *bootstrap.AuxiliaryIPAMBootstrapResult) (bool, bool) {
+	dhcp, whereabouts := auxiliaryIPAMUsers(conf, aux)
+	return len(dhcp) > 0, len(whereabouts) > 0
+}
+
+// unparsableNADs holds the generation of the NetworkAttachmentDefinitions, by
+// namespace/name, whose configuration could not be parsed, so that each
+// generation is only logged once
+var unparsableNADs = struct {
+	sync.Mutex
+	generations map[string]int64
+}{generations: map[string]int64{}}
+
+// UsesAuxiliaryIPAM returns whether a NetworkAttachmentDefinition uses the dhcp
+// and the whereabouts IPAM plugins
+func UsesAuxiliaryIPAM(nad *uns.Unstructured) (bool, bool) {
+	config, _, _ := uns.NestedString(nad.Object, "spec", "config")
+	ipamType, err := ipamTypeRaw(config)
+
+	name := nad.GetNamespace() + "/" + nad.GetName()
+	unparsableNADs.Lock()
+	defer unparsableNADs.Unlock()
+	if err == nil {
+		delete(unparsableNADs.generations, name)
+		return ipamType == ipamTypeDHCP, ipamType == ipamTypeWhereabouts
+	}
+	if generation, ok := unparsableNADs.generations[name]; !ok || generation != nad.GetGeneration() {
+		log.Printf("WARNING: Cannot detect the IPAM type of NetworkAttachmentDefinition %s: %v", name, err)
+		unparsableNADs.generations[name] = nad.GetGeneration()
+	}
+	return false, false
+}
+
+// BootstrapAuxiliaryIPAM finds, among nads, the NetworkAttachmentDefinitions
+// created directly rather than from spec.additionalNetworks that need an
+// auxiliary IPAM component
+func BootstrapAuxiliaryIPAM(conf *operv1.NetworkSpec, nads []*uns.Unstructured) bootstrap.AuxiliaryIPAMBootstrapResult {
+	res := bootstrap.AuxiliaryIPAMBootstrapResult{}
+	if conf.DisableMultiNetwork != nil && *conf.DisableMultiNetwork {
+		return res
+	}
+
+	rendered := additionalNetworkNADNames(conf)
+	for _, nad := range nads {
+		name := nad.GetNamespace() + "/" + nad.GetName()
+		if rendered.Has(name) {
+			continue
+		}
+		dhcp, whereabouts := UsesAuxiliaryIPAM(nad)
+		if dhcp {
+			res.DHCP = append(res.DHCP, name)
+		}
+		if whereabouts {
+			res.Whereabouts = append(res.Whereabouts, name)
+		}
+	}
+	sort.Strings(res.DHCP)
+	sort.Strings(res.Whereabouts)
+	return res
+}
+
+// additionalNetworkNADNames returns the namespace/name of the
+// NetworkAttachmentDefinitions rendered from spec.additionalNetworks
+func additionalNetworkNADNames(conf *operv1.NetworkSpec) sets.Set[string] {
+	rendered := sets.New[string]()
+	for _, an := range conf.AdditionalNetworks {
+		namespace := an.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		rendered.Insert(namespace + "/" + an.Name)
+	}
+	return rendered
+}
+
+// maxAuxiliaryIPAMUsers is the number of additional networks and
+// NetworkAttachmentDefinitions listed for each component by AuxiliaryIPAMCondition
+const maxAuxiliaryIPAMUsers = 10
+
+// AuxiliaryIPAMCondition returns the AuxiliaryIPAMRendered condition, listing why
+// the DHCP daemon and the whereabouts reconciler are rendered
+func AuxiliaryIPAMCondition(conf *operv1.NetworkSpec, aux *bootstrap.AuxiliaryIPAMBootstrapResult) operv1.OperatorCondition {
+	dhcp, whereabouts := auxiliaryIPAMUsers(conf, aux)
+	if len(dhcp) == 0 && len(whereabouts) == 0 {
+		return operv1.OperatorCondition{
+			Type:    names.AuxiliaryIPAMCondition,
+			Status:  operv1.ConditionFalse,
+			Reason:  "NotNeeded",
+			Message: "No additional network uses the dhcp or whereabouts IPAM plugins",
+		}
+	}
+
+	list := func(users []string) string {
+		if len(users) > maxAuxiliaryIPAMUsers {
+			return fmt.Sprintf("%s and %d more", strings.Join(users[:maxAuxiliaryIPAMUsers], ", "), len(users)-maxAuxiliaryIPAMUsers)
+		}
+		return strings.Join(users, ", ")
+	}
+	messages := []string{}
+	if len(dhcp) > 0 {
+		messages = append(messages, "DHCP daemon rendered for "+list(dhcp))
+	}
+	if len(whereabouts) > 0 {
+		messages = append(messages, "whereabouts reconciler rendered for "+list(whereabouts))
+	}
+	return operv1.OperatorCondition{
+		Type:    names.AuxiliaryIPAMCondition,
+		Status:  operv1.ConditionTrue,
+		Reason:  "Rendered",
+		Message: strings.Join(messages, "; "),
+	}
+}
+
+// rawIPAMConfigs returns the ipam sections of a RawCNIConfig, which may be either
+// a single plugin config or a conflist.
+func rawIPAMConfigs(rawCNIConfig string) []map[string]any {
+	var rawConfig map[string]any
+	if err := json.Unmarshal([]byte(rawCNIConfig), &rawConfig); err != nil {
+		return nil
+	}
+
+	pluginConfigs := []any{rawConfig}
+	if plugins, ok := rawConfig["plugins"].([]any); ok {
+		pluginConfigs = plugins
+	}
+
+	out := []map[string]any{}
+	for _, p := range pluginConfigs {
+		pluginConfig, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if ipam, ok := pluginConfig["ipam"].(map[string]any); ok {
+			out = append(out, ipam)
+		}
+	}
+	return out
+}
+
+// ipamCIDRs returns the subnets referenced by an IPAM configuration: host-local
+// subnets and ranges, whereabouts ranges and static addresses. Unparseable values
+// are skipped, validation is left to the IPAM plugin.
+func ipamCIDRs(ipam map[string]any) []string {
+	out := []string{}
+	add := func(v any) {
+		s, ok := v.(string)
+		if !ok {
+			return
+		}
+		if cidr := parseIPAMRange(s); cidr != nil {
+			out = append(out, cidr.String())
+		}
+	}
+	field := func(v any, key string) {
+		if m, ok := v.(map[string]any); ok {
+			add(m[key])
+		}
+	}
+
+	add(ipam["subnet"])
+	add(ipam["range"])
+	if ranges, ok := ipam["ranges"].([]any); ok {
+		for _, rangeSet := range ranges {
+			if rangeSet, ok := rangeSet.([]any); ok {
+				for _, r := range rangeSet {
+					field(r, "subnet")
+				}
+			}
+		}
+	}
+	if ipRanges, ok := ipam["ipRanges"].([]any); ok {
+		for _, r := range ipRanges {
+			field(r, "range")
+		}
+	}
+	if addresses, ok := ipam["addresses"].([]any); ok {
+		for _, a := range addresses {
+			field(a, "address")
+		}
+	}
+	return out
+}
+
+// parseIPAMRange parses a subnet or range of an IPAM configuration, returning nil
+// if it is invalid
+func parseIPAMRange(s string) *net.IPNet {
+	// whereabouts also accepts ranges in the form "<first IP>-<last IP>/<prefix>"
+	if i := strings.Index(s, "-"); i >= 0 {
+		s = s[i+1:]
+	}
+	_, cidr, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil
+	}
+	return cidr
+}
+
+// additionalNetworkCIDRs returns the subnets used by the IPAM configuration of
+// the additional networks.
+func additionalNetworkCIDRs(conf *operv1.NetworkSpec) []string {
+	out := []string{}
+	for i := range conf.AdditionalNetworks {
+		out = append(out, additionalNetworkIPAMCIDRs(&conf.AdditionalNetworks[i])...)
+	}
+	return out
+}
+
+// additionalNetworkIPAMCIDRs returns the subnets used by the IPAM configuration
+// of an additional network.
+func additionalNetworkIPAMCIDRs(an *operv1.AdditionalNetworkDefinition) []string {
+	out := []string{}
+	switch an.Type {
+	case operv1.NetworkTypeRaw:
+		for _, ipam := range rawIPAMConfigs(an.RawCNIConfig) {
+			out = append(out, ipamCIDRs(ipam)...)
+		}
+	case operv1.NetworkTypeSimpleMacvlan:
+		if an.SimpleMacvlanConfig == nil || an.SimpleMacvlanConfig.IPAMConfig == nil ||
+			an.SimpleMacvlanConfig.IPAMConfig.StaticIPAMConfig == nil {
+			return out
+		}
+		out = append(out, staticIPAMCIDRs(an.SimpleMacvlanConfig.IPAMConfig.StaticIPAMConfig)...)
+	case NetworkTypeSimpleIPVLAN, NetworkTypeSimpleBridge, NetworkTypeSimpleHostDevice:
+		ipam := simpleIPAMConfig(an)
+		switch {
+		case ipam == nil:
+		case ipam.Type == IPAMTypeWhereabouts && ipam.WhereaboutsIPAMConfig != nil:
+			if _, cidr, err := net.ParseCIDR(ipam.WhereaboutsIPAMConfig.Range); err == nil {
+				out = append(out, cidr.String())
 			}
+		case ipam.Type == operv1.IPAMTypeStatic && ipam.StaticIPAMConfig != nil:
+			out = append(out, staticIPAMCIDRs(ipam.StaticIPAMConfig)...)
 		}
 	}
+	return out
+}
 
-	return renderdhcp, renderwhereabouts
+// staticIPAMCIDRs returns the subnets of the addresses of a static IPAM configuration
+func staticIPAMCIDRs(conf *operv1.StaticIPAMConfig) []string {
+	out := []string{}
+	for _, addr := range conf.Addresses {
+		if _, cidr, err := net.ParseCIDR(addr.Address); err == nil {
+			out = append(out, cidr.String())
+		}
+	}
+	return out
 }
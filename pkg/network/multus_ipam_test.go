@@ -1,10 +1,15 @@
 package network
 
 import (
+	"fmt"
 	"testing"
 
 	. "github.com/onsi/gomega"
 	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
+	"github.com/openshift/cluster-network-operator/pkg/names"
 )
 
 var NoIPAMConfig = operv1.Network{
@@ -284,3 +289,113 @@ func TestRenderNoDHCPSimpleMacvlan(t *testing.T) {
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(objs).NotTo(ContainElement(HaveKubernetesID("DaemonSet", "openshift-multus", "dhcp-daemon")))
 }
+
+func networkAttachmentDefinition(namespace, name, config string) *uns.Unstructured {
+	nad := &uns.Unstructured{}
+	nad.SetGroupVersionKind(NetworkAttachmentDefinitionGVK)
+	nad.SetNamespace(namespace)
+	nad.SetName(name)
+	if err := uns.SetNestedField(nad.Object, config, "spec", "config"); err != nil {
+		panic(err)
+	}
+	return nad
+}
+
+// TestBootstrapAuxiliaryIPAM tests finding the NetworkAttachmentDefinitions needing an auxiliary IPAM component
+func TestBootstrapAuxiliaryIPAM(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := DHCPConfig.DeepCopy()
+	fillDefaults(&crd.Spec, nil)
+
+	nads := []*uns.Unstructured{
+		// rendered from spec.additionalNetworks
+		networkAttachmentDefinition("default", "net-attach-dhcp", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"dhcp"}}`),
+		networkAttachmentDefinition("team-b", "dhcp", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"dhcp"}}`),
+		networkAttachmentDefinition("team-a", "whereabouts", `{"cniVersion":"0.4.0","name":"wb","plugins":[{"type":"bridge","ipam":{"type":"whereabouts","range":"192.0.2.0/24"}}]}`),
+		networkAttachmentDefinition("team-a", "static", `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"static"}}`),
+		networkAttachmentDefinition("team-c", "invalid", `{"cniVersion":"0.3.1","type":"macvlan","ipam":"dhcp"}`),
+	}
+
+	res := BootstrapAuxiliaryIPAM(&crd.Spec, nads)
+	g.Expect(res.DHCP).To(Equal([]string{"team-b/dhcp"}))
+	g.Expect(res.Whereabouts).To(Equal([]string{"team-a/whereabouts"}))
+
+	*crd.Spec.DisableMultiNetwork = true
+	res = BootstrapAuxiliaryIPAM(&crd.Spec, nads)
+	g.Expect(res.DHCP).To(BeEmpty())
+}
+
+// TestUsesAuxiliaryIPAMUnparsable tests that an unparsable NetworkAttachmentDefinition
+// is only reported once per generation
+func TestUsesAuxiliaryIPAMUnparsable(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	nad := networkAttachmentDefinition("team-c", "invalid", `{"cniVersion":"0.3.1","type":"macvlan","ipam":"dhcp"}`)
+	nad.SetGeneration(1)
+	dhcp, whereabouts := UsesAuxiliaryIPAM(nad)
+	g.Expect(dhcp).To(BeFalse())
+	g.Expect(whereabouts).To(BeFalse())
+	g.Expect(unparsableNADs.generations).To(HaveKeyWithValue("team-c/invalid", int64(1)))
+
+	nad.SetGeneration(2)
+	UsesAuxiliaryIPAM(nad)
+	g.Expect(unparsableNADs.generations).To(HaveKeyWithValue("team-c/invalid", int64(2)))
+
+	// once fixed, it is forgotten
+	g.Expect(uns.SetNestedField(nad.Object, `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"dhcp"}}`, "spec", "config")).To(Succeed())
+	dhcp, _ = UsesAuxiliaryIPAM(nad)
+	g.Expect(dhcp).To(BeTrue())
+	g.Expect(unparsableNADs.generations).NotTo(HaveKey("team-c/invalid"))
+}
+
+// TestRenderWithAuxiliaryIPAMNetworkAttachmentDefinitions tests a rendering with the DHCP
+// daemonset and whereabouts-reconciler only needed by NetworkAttachmentDefinitions
+func TestRenderWithAuxiliaryIPAMNetworkAttachmentDefinitions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := NoIPAMConfig.DeepCopy()
+	config := &crd.Spec
+	fillDefaults(config, nil)
+
+	bootstrapResult := fakeBootstrapResult()
+	bootstrapResult.AuxiliaryIPAM = bootstrap.AuxiliaryIPAMBootstrapResult{
+		DHCP:        []string{"team-b/dhcp"},
+		Whereabouts: []string{"team-a/whereabouts"},
+	}
+	objs, err := renderMultus(config, bootstrapResult, manifestDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(ContainElement(HaveKubernetesID("DaemonSet", "openshift-multus", "dhcp-daemon")))
+	g.Expect(objs).To(ContainElement(HaveKubernetesID("DaemonSet", "openshift-multus", "whereabouts-reconciler")))
+}
+
+func TestAuxiliaryIPAMCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := NoIPAMConfig.DeepCopy()
+	config := &crd.Spec
+	fillDefaults(config, nil)
+
+	cond := AuxiliaryIPAMCondition(config, &bootstrap.AuxiliaryIPAMBootstrapResult{})
+	g.Expect(cond.Type).To(Equal(names.AuxiliaryIPAMCondition))
+	g.Expect(cond.Status).To(Equal(operv1.ConditionFalse))
+
+	crd = DHCPConfig.DeepCopy()
+	config = &crd.Spec
+	fillDefaults(config, nil)
+
+	whereabouts := []string{}
+	for i := 0; i < 12; i++ {
+		whereabouts = append(whereabouts, fmt.Sprintf("team-a/nad-%02d", i))
+	}
+	cond = AuxiliaryIPAMCondition(config, &bootstrap.AuxiliaryIPAMBootstrapResult{
+		DHCP:        []string{"team-b/dhcp"},
+		Whereabouts: whereabouts,
+	})
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Message).To(Equal("DHCP daemon rendered for additional network net-attach-dhcp, NetworkAttachmentDefinition team-b/dhcp; " +
+		"whereabouts reconciler rendered for NetworkAttachmentDefinition team-a/nad-00, NetworkAttachmentDefinition team-a/nad-01, " +
+		"NetworkAttachmentDefinition team-a/nad-02, NetworkAttachmentDefinition team-a/nad-03, NetworkAttachmentDefinition team-a/nad-04, " +
+		"NetworkAttachmentDefinition team-a/nad-05, NetworkAttachmentDefinition team-a/nad-06, NetworkAttachmentDefinition team-a/nad-07, " +
+		"NetworkAttachmentDefinition team-a/nad-08, NetworkAttachmentDefinition team-a/nad-09 and 2 more"))
+}
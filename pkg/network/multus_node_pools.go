@@ -0,0 +1,269 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+)
+
+// multusNodePool overrides the configuration of the multus daemon on the nodes
+// matching nodeSelector, which then run their own multus DaemonSet. It is read
+// from spec.unsupportedConfigOverrides.multusNodePools, for example:
+//
+//	unsupportedConfigOverrides:
+//	  multusNodePools:
+//	  - name: dpu
+//	    nodeSelector:
+//	      node-role.kubernetes.io/dpu-host: ""
+//	    logLevel: debug
+//	    readinessIndicatorFile: ""
+type multusNodePool struct {
+	Name         string            `json:"name"`
+	NodeSelector map[string]string `json:"nodeSelector"`
+	// LogLevel is one of the multus log levels
+	LogLevel string `json:"logLevel,omitempty"`
+	// ReadinessIndicatorFile replaces the file multus waits for before handling
+	// pods; an empty value disables the wait
+	ReadinessIndicatorFile *string `json:"readinessIndicatorFile,omitempty"`
+	// DaemonConfig is merged into the multus daemon-config.json
+	DaemonConfig map[string]json.RawMessage `json:"daemonConfig,omitempty"`
+}
+
+const (
+	multusDaemonSetName       = "multus"
+	multusDaemonConfigMapName = "multus-daemon-config"
+	multusDaemonConfigKey     = "daemon-config.json"
+)
+
+var multusLogLevels = sets.New("panic", "error", "verbose", "debug")
+
+// multusReservedPoolNames would make the objects of a pool collide with other
+// multus objects
+var multusReservedPoolNames = sets.New("daemon-config", "additional-cni-plugins", "admission-controller", "networkpolicy")
+
+// multusProtectedDaemonConfig are the daemon-config.json keys that match the
+// volumes of the multus DaemonSet, and so cannot be overridden
+var multusProtectedDaemonConfig = sets.New("chrootDir", "binDir", "cniConfigDir", "multusAutoconfigDir",
+	"daemonSocketDir", "socketDir", "perNodeCertificate")
+
+// getMultusNodePools parses the multus node pools from the operator configuration
+func getMultusNodePools(conf *operv1.NetworkSpec) ([]multusNodePool, error) {
+	if len(conf.UnsupportedConfigOverrides.Raw) == 0 {
+		return nil, nil
+	}
+	overrides := struct {
+		MultusNodePools []multusNodePool `json:"multusNodePools,omitempty"`
+	}{}
+	if err := json.Unmarshal(conf.UnsupportedConfigOverrides.Raw, &overrides); err != nil {
+		return nil, fmt.Errorf("could not parse unsupportedConfigOverrides: %w", err)
+	}
+	return overrides.MultusNodePools, nil
+}
+
+// validateMultusNodePools checks that the multus node pools have unique names and
+// valid node selectors and daemon configurations
+func validateMultusNodePools(conf *operv1.NetworkSpec) []error {
+	pools, err := getMultusNodePools(conf)
+	if err != nil {
+		return []error{err}
+	}
+	if len(pools) > 0 && conf.DisableMultiNetwork != nil && *conf.DisableMultiNetwork {
+		return []error{fmt.Errorf("multusNodePools cannot be specified without deploying Multus")}
+	}
+
+	errs := []error{}
+	seen := sets.New[string]()
+	for _, pool := range pools {
+		// the name must leave room for the prefix of the DaemonSet
+		if msgs := validation.IsDNS1123Label(multusDaemonSetName + "-" + pool.Name); pool.Name == "" || len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("invalid multusNodePools name %q: must be a DNS label of at most %d characters", pool.Name, validation.DNS1123LabelMaxLength-len(multusDaemonSetName)-1))
+			continue
+		}
+		if multusReservedPoolNames.Has(pool.Name) {
+			errs = append(errs, fmt.Errorf("invalid multusNodePools name %q: the name is reserved", pool.Name))
+			continue
+		}
+		if seen.Has(pool.Name) {
+			errs = append(errs, fmt.Errorf("duplicate multusNodePools name %q", pool.Name))
+		}
+		seen.Insert(pool.Name)
+
+		if len(pool.NodeSelector) == 0 {
+			errs = append(errs, fmt.Errorf("multusNodePools %s must have a nodeSelector", pool.Name))
+		}
+		for k, v := range pool.NodeSelector {
+			for _, msg := range validation.IsQualifiedName(k) {
+				errs = append(errs, fmt.Errorf("multusNodePools %s has an invalid nodeSelector key %q: %s", pool.Name, k, msg))
+			}
+			for _, msg := range validation.IsValidLabelValue(v) {
+				errs = append(errs, fmt.Errorf("multusNodePools %s has an invalid nodeSelector value %q: %s", pool.Name, v, msg))
+			}
+		}
+		if pool.LogLevel != "" && !multusLogLevels.Has(pool.LogLevel) {
+			errs = append(errs, fmt.Errorf("multusNodePools %s has an invalid logLevel %q, must be one of %v", pool.Name, pool.LogLevel, sets.List(multusLogLevels)))
+		}
+		for k := range pool.DaemonConfig {
+			if multusProtectedDaemonConfig.Has(k) {
+				errs = append(errs, fmt.Errorf("multusNodePools %s cannot override %q in daemonConfig", pool.Name, k))
+			}
+		}
+	}
+	return errs
+}
+
+// renderMultusNodePools adds a multus DaemonSet, and its daemon configuration, for
+// each node pool to the rendered multus manifests. They are copies of the default
+// ones, restricted to the nodes of the pool. A node is handled by the first pool
+// it matches, and the default DaemonSet no longer runs on the nodes of any pool.
+func renderMultusNodePools(pools []multusNodePool, objs []*uns.Unstructured) ([]*uns.Unstructured, error) {
+	if len(pools) == 0 {
+		return objs, nil
+	}
+
+	var dsObj, cmObj *uns.Unstructured
+	for _, obj := range objs {
+		switch {
+		case obj.GetKind() == "DaemonSet" && obj.GetName() == multusDaemonSetName:
+			dsObj = obj
+		case obj.GetKind() == "ConfigMap" && obj.GetName() == multusDaemonConfigMapName:
+			cmObj = obj
+		}
+	}
+	if dsObj == nil || cmObj == nil {
+		return nil, fmt.Errorf("failed to find the multus DaemonSet and its configuration")
+	}
+	ds := &appsv1.DaemonSet{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(dsObj.Object, ds); err != nil {
+		return nil, fmt.Errorf("failed to convert the multus DaemonSet: %w", err)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(cmObj.Object, cm); err != nil {
+		return nil, fmt.Errorf("failed to convert the multus daemon configuration: %w", err)
+	}
+
+	out := []*uns.Unstructured{}
+	for i, pool := range pools {
+		name := multusDaemonSetName + "-" + pool.Name
+
+		poolCM := cm.DeepCopy()
+		poolCM.Name = name
+		daemonConfig, err := multusNodePoolDaemonConfig(cm.Data[multusDaemonConfigKey], &pool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render the multus daemon configuration of node pool %s: %w", pool.Name, err)
+		}
+		poolCM.Data[multusDaemonConfigKey] = daemonConfig
+
+		poolDS := ds.DeepCopy()
+		poolDS.Name = name
+		if poolDS.Annotations == nil {
+			poolDS.Annotations = map[string]string{}
+		}
+		poolDS.Annotations["kubernetes.io/description"] = fmt.Sprintf("This daemon set launches the Multus networking component on the nodes of the %s node pool.\n", pool.Name)
+		poolDS.Spec.Selector.MatchLabels["app"] = name
+		poolDS.Spec.Template.Labels["app"] = name
+		poolDS.Spec.Template.Labels[names.MultusNodePoolLabel] = pool.Name
+		for k, v := range pool.NodeSelector {
+			poolDS.Spec.Template.Spec.NodeSelector[k] = v
+		}
+		setNodeAffinityExcluding(&poolDS.Spec.Template.Spec, pools[:i])
+		for j := range poolDS.Spec.Template.Spec.Volumes {
+			v := &poolDS.Spec.Template.Spec.Volumes[j]
+			if v.ConfigMap != nil && v.ConfigMap.Name == multusDaemonConfigMapName {
+				v.ConfigMap.Name = name
+			}
+		}
+
+		for _, obj := range []runtime.Object{poolCM, poolDS} {
+			u, err := toManifest(obj)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, u)
+		}
+	}
+
+	setNodeAffinityExcluding(&ds.Spec.Template.Spec, pools)
+	u, err := toManifest(ds)
+	if err != nil {
+		return nil, err
+	}
+	dsObj.Object = u.Object
+
+	return append(objs, out...), nil
+}
+
+// toManifest converts a typed object back to a manifest, without its empty status
+func toManifest(obj runtime.Object) (*uns.Unstructured, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	delete(u, "status")
+	return &uns.Unstructured{Object: u}, nil
+}
+
+// multusNodePoolDaemonConfig returns the multus daemon-config.json of a node pool
+func multusNodePoolDaemonConfig(defaultConfig string, pool *multusNodePool) (string, error) {
+	config := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(defaultConfig), &config); err != nil {
+		return "", err
+	}
+	for k, v := range pool.DaemonConfig {
+		config[k] = v
+	}
+	if pool.LogLevel != "" {
+		config["logLevel"], _ = json.Marshal(pool.LogLevel)
+	}
+	if pool.ReadinessIndicatorFile != nil {
+		if *pool.ReadinessIndicatorFile == "" {
+			delete(config, "readinessindicatorfile")
+		} else {
+			config["readinessindicatorfile"], _ = json.Marshal(*pool.ReadinessIndicatorFile)
+		}
+	}
+	out, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+// setNodeAffinityExcluding keeps the pod off the nodes matching the selector of any
+// of the given pools. As the terms of a node affinity are ORed, and their
+// expressions ANDed, the negation of the selectors is expanded into one term per
+// combination of a label of each pool.
+func setNodeAffinityExcluding(spec *corev1.PodSpec, pools []multusNodePool) {
+	if len(pools) == 0 {
+		return
+	}
+	terms := []corev1.NodeSelectorTerm{{}}
+	for _, pool := range pools {
+		next := []corev1.NodeSelectorTerm{}
+		for _, term := range terms {
+			for _, k := range sets.List(sets.KeySet(pool.NodeSelector)) {
+				t := term.DeepCopy()
+				t.MatchExpressions = append(t.MatchExpressions, corev1.NodeSelectorRequirement{
+					Key:      k,
+					Operator: corev1.NodeSelectorOpNotIn,
+					Values:   []string{pool.NodeSelector[k]},
+				})
+				next = append(next, *t)
+			}
+		}
+		terms = next
+	}
+	spec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+		},
+	}
+}
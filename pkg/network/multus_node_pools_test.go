@@ -0,0 +1,119 @@
+package network
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+)
+
+func TestValidateMultusNodePools(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	config := MultusConfig.Spec.DeepCopy()
+	fillDefaults(config, nil)
+	g.Expect(validateMultusNodePools(config)).To(BeEmpty())
+
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multusNodePools":[
+		{"name":"dpu","nodeSelector":{"node-role.kubernetes.io/dpu-host":""},"logLevel":"debug"},
+		{"name":"rt","nodeSelector":{"kernel":"rt"},"daemonConfig":{"globalNamespaces":"default"}}]}`)
+	g.Expect(validateMultusNodePools(config)).To(BeEmpty())
+
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multusNodePools":[
+		{"name":"dpu","nodeSelector":{"node-role.kubernetes.io/dpu-host":""},"logLevel":"trace"},
+		{"name":"dpu","nodeSelector":{"kernel":"rt!"}},
+		{"name":"Edge","nodeSelector":{"edge":"true"}},
+		{"name":"daemon-config","nodeSelector":{"edge":"true"}},
+		{"name":"edge","daemonConfig":{"socketDir":"/tmp"}}]}`)
+	g.Expect(validateMultusNodePools(config)).To(ConsistOf(
+		MatchError(`multusNodePools dpu has an invalid logLevel "trace", must be one of [debug error panic verbose]`),
+		MatchError(`duplicate multusNodePools name "dpu"`),
+		MatchError(ContainSubstring(`multusNodePools dpu has an invalid nodeSelector value "rt!"`)),
+		MatchError(`invalid multusNodePools name "Edge": must be a DNS label of at most 56 characters`),
+		MatchError(`invalid multusNodePools name "daemon-config": the name is reserved`),
+		MatchError(`multusNodePools edge must have a nodeSelector`),
+		MatchError(`multusNodePools edge cannot override "socketDir" in daemonConfig`),
+	))
+
+	disabled := true
+	config.DisableMultiNetwork = &disabled
+	g.Expect(validateMultusNodePools(config)).To(ConsistOf(
+		MatchError("multusNodePools cannot be specified without deploying Multus"),
+	))
+}
+
+func TestRenderMultusNodePools(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	config := MultusConfig.Spec.DeepCopy()
+	enabled := false
+	config.DisableMultiNetwork = &enabled
+	fillDefaults(config, nil)
+	config.UnsupportedConfigOverrides.Raw = []byte(`{"multusNodePools":[
+		{"name":"dpu","nodeSelector":{"node-role.kubernetes.io/dpu-host":""},"logLevel":"debug","readinessIndicatorFile":""},
+		{"name":"rt","nodeSelector":{"kernel":"rt","zone":"edge"},"daemonConfig":{"globalNamespaces":"default"}}]}`)
+
+	objs, err := renderMultus(config, fakeBootstrapResult(), manifestDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(38))
+
+	daemonSets := map[string]*appsv1.DaemonSet{}
+	configMaps := map[string]*corev1.ConfigMap{}
+	for _, obj := range objs {
+		switch obj.GetKind() {
+		case "DaemonSet":
+			ds := &appsv1.DaemonSet{}
+			g.Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ds)).To(Succeed())
+			daemonSets[ds.Name] = ds
+		case "ConfigMap":
+			cm := &corev1.ConfigMap{}
+			g.Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, cm)).To(Succeed())
+			configMaps[cm.Name] = cm
+		}
+	}
+	g.Expect(daemonSets).To(HaveKey("multus-dpu"))
+	g.Expect(daemonSets).To(HaveKey("multus-rt"))
+	g.Expect(configMaps).To(HaveKey("multus-dpu"))
+	g.Expect(configMaps).To(HaveKey("multus-rt"))
+
+	notIn := func(k, v string) corev1.NodeSelectorRequirement {
+		return corev1.NodeSelectorRequirement{Key: k, Operator: corev1.NodeSelectorOpNotIn, Values: []string{v}}
+	}
+
+	// the default DaemonSet runs on the nodes of no pool
+	def := daemonSets["multus"]
+	g.Expect(def.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).To(ConsistOf(
+		corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{notIn("node-role.kubernetes.io/dpu-host", ""), notIn("kernel", "rt")}},
+		corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{notIn("node-role.kubernetes.io/dpu-host", ""), notIn("zone", "edge")}},
+	))
+
+	// the first pool runs on its nodes, with its own configuration
+	dpu := daemonSets["multus-dpu"]
+	g.Expect(dpu.Spec.Selector.MatchLabels).To(Equal(map[string]string{"app": "multus-dpu"}))
+	g.Expect(dpu.Spec.Template.Labels).To(HaveKeyWithValue("app", "multus-dpu"))
+	g.Expect(dpu.Spec.Template.Labels).To(HaveKeyWithValue(names.MultusNodePoolLabel, "dpu"))
+	g.Expect(dpu.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"kubernetes.io/os": "linux", "node-role.kubernetes.io/dpu-host": ""}))
+	g.Expect(dpu.Spec.Template.Spec.Affinity).To(BeNil())
+	g.Expect(dpu.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("VolumeSource.ConfigMap.LocalObjectReference.Name", "multus-dpu")))
+
+	daemonConfig := map[string]any{}
+	g.Expect(json.Unmarshal([]byte(configMaps["multus-dpu"].Data[multusDaemonConfigKey]), &daemonConfig)).To(Succeed())
+	g.Expect(daemonConfig).To(HaveKeyWithValue("logLevel", "debug"))
+	g.Expect(daemonConfig).NotTo(HaveKey("readinessindicatorfile"))
+	g.Expect(daemonConfig).To(HaveKeyWithValue("socketDir", "/host/run/multus/socket"))
+
+	// the second pool leaves the nodes of the first one alone
+	rt := daemonSets["multus-rt"]
+	g.Expect(rt.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).To(ConsistOf(
+		corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{notIn("node-role.kubernetes.io/dpu-host", "")}},
+	))
+	daemonConfig = map[string]any{}
+	g.Expect(json.Unmarshal([]byte(configMaps["multus-rt"].Data[multusDaemonConfigKey]), &daemonConfig)).To(Succeed())
+	g.Expect(daemonConfig).To(HaveKeyWithValue("globalNamespaces", "default"))
+	g.Expect(daemonConfig).To(HaveKeyWithValue("readinessindicatorfile", "/host/run/multus/cni/net.d/10-ovn-kubernetes.conf"))
+}
@@ -7,6 +7,7 @@ import (
 	operv1 "github.com/openshift/api/operator/v1"
 
 	. "github.com/onsi/gomega"
+	"github.com/openshift/cluster-network-operator/pkg/names"
 	"github.com/openshift/cluster-network-operator/pkg/render"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
@@ -125,6 +126,7 @@ func TestAllowlistDaemonSetServiceAccount(t *testing.T) {
 	data.Data["MultusImage"] = "test-multus-image:latest"
 	data.Data["CniSysctlAllowlist"] = "cni-sysctl-allowlist"
 	data.Data["ReleaseVersion"] = "test-version"
+	data.Data["AllowlistHash"] = "0123456789abcdef"
 
 	objs, err := render.RenderDir(filepath.Join(manifestDir, "allowlist/daemonset"), &data)
 	g.Expect(err).NotTo(HaveOccurred())
@@ -139,4 +141,9 @@ func TestAllowlistDaemonSetServiceAccount(t *testing.T) {
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(found).To(BeTrue(), "serviceAccountName should be set in the daemonset")
 	g.Expect(serviceAccount).To(Equal("multus-ancillary-tools"), "daemonset should use multus-ancillary-tools service account")
+
+	// The pods record the allowlist they copy, for the nodes to be annotated with it
+	hash, _, err := uns.NestedString(daemonSet.Object, "spec", "template", "metadata", "annotations", names.SysctlAllowlistHashAnnotation)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hash).To(Equal("0123456789abcdef"))
 }
@@ -59,6 +59,8 @@ func renderNetworkNodeIdentity(conf *operv1.NetworkSpec, bootstrapResult *bootst
 		data.Data["OVNHybridOverlayEnable"] = conf.DefaultNetwork.OVNKubernetesConfig.HybridOverlayConfig != nil
 	}
 	data.Data["NetworkNodeIdentityPort"] = NetworkNodeIdentityWebhookPort
+	data.Data["ResourceRequestCPU"] = sizedRequest(bootstrapResult.Sizing.NetworkNodeIdentity.CPU, "", false)
+	data.Data["ResourceRequestMemory"] = sizedRequest(bootstrapResult.Sizing.NetworkNodeIdentity.Memory, "", false)
 
 	addTLSInfoToRenderData(data.Data, bootstrapResult, true)
 
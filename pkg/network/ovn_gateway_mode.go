@@ -0,0 +1,132 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	"github.com/openshift/cluster-network-operator/pkg/util"
+)
+
+// GatewayModeMigrationKey is the key of the names.GatewayModeMigrationConfigMap
+// ConfigMap holding the JSON encoded GatewayModeMigration
+const GatewayModeMigrationKey = "migration"
+
+// GatewayModeDefaultKey is the key of the ovnkube-gateway-mode ConfigMap holding the
+// gateway mode of the nodes that have no entry of their own
+const GatewayModeDefaultKey = "default"
+
+// GatewayModeMigration is the state of a node by node migration of the OVN-Kubernetes
+// gateway mode. It is kept up to date by the gateway mode controller and used when
+// rendering the ovnkube-gateway-mode ConfigMap, which ovnkube-node reads its gateway
+// mode from when it starts.
+type GatewayModeMigration struct {
+	// From and To are the gateway modes the nodes are migrated from and to
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Migrated are the nodes that were switched to To and passed their checks
+	Migrated []string `json:"migrated,omitempty"`
+	// Batch are the nodes being switched to To since BatchStarted
+	Batch        []string    `json:"batch,omitempty"`
+	BatchStarted metav1.Time `json:"batchStarted,omitempty"`
+	// Stuck are the nodes of past batches that did not report any gateway mode in
+	// time, for example because ovnkube-node does not run on them. They are left
+	// switched to To and the migration carries on without them.
+	Stuck []string `json:"stuck,omitempty"`
+	// Failed are the nodes of a batch that was rolled back to From. The migration
+	// does not progress any further once a batch failed.
+	Failed []string `json:"failed,omitempty"`
+	// Message describes why the batch failed
+	Message string `json:"message,omitempty"`
+	// Completed is set once all nodes were switched to To
+	Completed bool `json:"completed,omitempty"`
+}
+
+// GetGatewayModeMigration returns the state of the ongoing, or last, gateway mode
+// migration, or nil if there was none
+func GetGatewayModeMigration(ctx context.Context, client crclient.Reader) (*GatewayModeMigration, error) {
+	cm := &corev1.ConfigMap{}
+	err := client.Get(ctx, types.NamespacedName{Namespace: names.APPLIED_NAMESPACE, Name: names.GatewayModeMigrationConfigMap}, cm)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get gateway mode migration state: %w", err)
+	}
+
+	m := &GatewayModeMigration{}
+	if err := json.Unmarshal([]byte(cm.Data[GatewayModeMigrationKey]), m); err != nil {
+		return nil, fmt.Errorf("failed to parse gateway mode migration state: %w", err)
+	}
+	return m, nil
+}
+
+// GatewayModes returns the contents of the ovnkube-gateway-mode ConfigMap: the gateway
+// mode of the nodes under GatewayModeDefaultKey, plus the mode of each node that does
+// not use the default one. current is the default mode rendered before, if any.
+//
+// While a migration to desired is in progress the nodes keep the mode they are
+// migrated from, except for the ones switched so far. When desired changes and no
+// migration is in progress yet, the current mode is kept until the gateway mode
+// controller starts one. When desired changes back in the middle of a migration, the
+// migrated nodes are switched back and the others were never switched.
+func GatewayModes(desired, current string, m *GatewayModeMigration) map[string]string {
+	modes := map[string]string{GatewayModeDefaultKey: desired}
+	switch {
+	case m != nil && m.To == desired && !m.Completed:
+		modes[GatewayModeDefaultKey] = m.From
+		for _, node := range m.Migrated {
+			modes[node] = m.To
+		}
+		for _, node := range m.Batch {
+			modes[node] = m.To
+		}
+		for _, node := range m.Stuck {
+			modes[node] = m.To
+		}
+	case m != nil && m.To == desired:
+		// the migration to desired completed
+	case current != "" && (m == nil || m.Completed):
+		modes[GatewayModeDefaultKey] = current
+	}
+	return modes
+}
+
+// DesiredGatewayMode returns the gateway mode set in the operator configuration
+func DesiredGatewayMode(conf *operv1.NetworkSpec) string {
+	if conf.DefaultNetwork.OVNKubernetesConfig != nil && conf.DefaultNetwork.OVNKubernetesConfig.GatewayConfig != nil &&
+		conf.DefaultNetwork.OVNKubernetesConfig.GatewayConfig.RoutingViaHost {
+		return OVN_LOCAL_GW_MODE
+	}
+	return OVN_SHARED_GW_MODE
+}
+
+// bootstrapOVNGatewayModes returns the per-node gateway modes to render, from the
+// ovnkube-gateway-mode ConfigMap rendered before and the state of any gateway mode
+// migration
+func bootstrapOVNGatewayModes(conf *operv1.Network, kubeClient cnoclient.Client) (map[string]string, error) {
+	client := kubeClient.ClientFor("").CRClient()
+
+	current := ""
+	cm := &corev1.ConfigMap{}
+	err := client.Get(context.TODO(), types.NamespacedName{Namespace: util.OVN_NAMESPACE, Name: util.OVN_GATEWAY_MODE_CM_NAME}, cm)
+	if err == nil {
+		current = cm.Data[GatewayModeDefaultKey]
+	} else if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get %s ConfigMap: %w", util.OVN_GATEWAY_MODE_CM_NAME, err)
+	}
+
+	m, err := GetGatewayModeMigration(context.TODO(), client)
+	if err != nil {
+		return nil, err
+	}
+	return GatewayModes(DesiredGatewayMode(&conf.Spec), current, m), nil
+}
@@ -0,0 +1,154 @@
+package network
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
+	cnofake "github.com/openshift/cluster-network-operator/pkg/client/fake"
+)
+
+func TestGatewayModes(t *testing.T) {
+	testcases := []struct {
+		name      string
+		desired   string
+		current   string
+		migration *GatewayModeMigration
+		expected  map[string]string
+	}{
+		{
+			name:     "new cluster",
+			desired:  "local",
+			expected: map[string]string{"default": "local"},
+		},
+		{
+			name:     "mode changed, migration not started yet",
+			desired:  "local",
+			current:  "shared",
+			expected: map[string]string{"default": "shared"},
+		},
+		{
+			name:    "migration in progress",
+			desired: "local",
+			current: "shared",
+			migration: &GatewayModeMigration{
+				From:     "shared",
+				To:       "local",
+				Migrated: []string{"node-a"},
+				Batch:    []string{"node-b"},
+				Stuck:    []string{"node-d"},
+				Failed:   []string{"node-c"},
+			},
+			expected: map[string]string{"default": "shared", "node-a": "local", "node-b": "local", "node-d": "local"},
+		},
+		{
+			name:      "migration completed",
+			desired:   "local",
+			current:   "shared",
+			migration: &GatewayModeMigration{From: "shared", To: "local", Migrated: []string{"node-a"}, Completed: true},
+			expected:  map[string]string{"default": "local"},
+		},
+		{
+			name:      "mode changed again after a completed migration",
+			desired:   "shared",
+			current:   "local",
+			migration: &GatewayModeMigration{From: "shared", To: "local", Completed: true},
+			expected:  map[string]string{"default": "local"},
+		},
+		{
+			name:      "migration reverted",
+			desired:   "shared",
+			current:   "shared",
+			migration: &GatewayModeMigration{From: "shared", To: "local", Migrated: []string{"node-a"}},
+			expected:  map[string]string{"default": "shared"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(GatewayModes(tc.desired, tc.current, tc.migration)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestRenderOVNKubernetesGatewayModes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	crd := OVNKubernetesConfig.DeepCopy()
+	config := &crd.Spec
+	fillDefaults(config, nil)
+
+	render := func(routingViaHost bool, modes map[string]string) []*uns.Unstructured {
+		config.DefaultNetwork.OVNKubernetesConfig.GatewayConfig = &operv1.GatewayConfig{RoutingViaHost: routingViaHost}
+		bootstrapResult := fakeBootstrapResult()
+		bootstrapResult.OVN = bootstrap.OVNBootstrapResult{
+			ControlPlaneReplicaCount: 3,
+			OVNKubernetesConfig: &bootstrap.OVNConfigBoostrapResult{
+				DpuHostModeLabel:          OVN_NODE_SELECTOR_DEFAULT_DPU_HOST,
+				DpuModeLabel:              OVN_NODE_SELECTOR_DEFAULT_DPU,
+				SmartNicModeLabel:         OVN_NODE_SELECTOR_DEFAULT_SMART_NIC,
+				DpuNodeLeaseRenewInterval: DPU_NODE_LEASE_RENEW_INTERVAL_DEFAULT,
+				DpuNodeLeaseDuration:      DPU_NODE_LEASE_DURATION_DEFAULT,
+				HyperShiftConfig: &bootstrap.OVNHyperShiftBootstrapResult{
+					Enabled: false,
+				},
+			},
+			GatewayModes: modes,
+		}
+		objs, _, err := renderOVNKubernetes(config, bootstrapResult, manifestDirOvn, cnofake.NewFakeClient(), getDefaultFeatureGates())
+		g.Expect(err).NotTo(HaveOccurred())
+		return objs
+	}
+	gatewayModes := func(objs []*uns.Unstructured) map[string]string {
+		cm := findInObjs("", "ConfigMap", "ovnkube-gateway-mode", "openshift-ovn-kubernetes", objs)
+		g.Expect(cm).NotTo(BeNil())
+		data, _, err := uns.NestedStringMap(cm.Object, "data")
+		g.Expect(err).NotTo(HaveOccurred())
+		return data
+	}
+	scriptLibHash := func(objs []*uns.Unstructured) string {
+		ds := findInObjs("apps", "DaemonSet", "ovnkube-node", "openshift-ovn-kubernetes", objs)
+		g.Expect(ds).NotTo(BeNil())
+		hash, _, err := uns.NestedString(ds.Object, "spec", "template", "metadata", "annotations", "network.operator.openshift.io/ovnkube-script-lib-hash")
+		g.Expect(err).NotTo(HaveOccurred())
+		return hash
+	}
+
+	// Without bootstrap data, all nodes use the configured mode
+	shared := render(false, nil)
+	g.Expect(gatewayModes(shared)).To(Equal(map[string]string{"default": "shared"}))
+	g.Expect(extractOVNScriptLib(g, shared)).To(ContainSubstring(`gateway_mode=$(ovn-gateway-mode)`))
+
+	// Switching a node to another mode does not restart all ovnkube-node pods
+	migrating := render(true, map[string]string{"default": "shared", "node-a": "local"})
+	g.Expect(gatewayModes(migrating)).To(Equal(map[string]string{"default": "shared", "node-a": "local"}))
+	g.Expect(scriptLibHash(migrating)).To(Equal(scriptLibHash(shared)))
+
+	// The control plane and the shared configuration keep the mode of the nodes
+	// that were not migrated yet
+	controlPlaneScript := func(objs []*uns.Unstructured) string {
+		d := findInObjs("apps", "Deployment", "ovnkube-control-plane", "openshift-ovn-kubernetes", objs)
+		g.Expect(d).NotTo(BeNil())
+		containers, _, err := uns.NestedSlice(d.Object, "spec", "template", "spec", "containers")
+		g.Expect(err).NotTo(HaveOccurred())
+		for _, c := range containers {
+			c := c.(map[string]any)
+			if c["name"] == "ovnkube-cluster-manager" {
+				command, _, _ := uns.NestedStringSlice(c, "command")
+				return strings.Join(command, " ")
+			}
+		}
+		return ""
+	}
+	g.Expect(extractOVNKubeConfig(g, migrating)).To(ContainSubstring("mode=shared"))
+	g.Expect(controlPlaneScript(migrating)).To(ContainSubstring(`if [ "shared" == "shared" ]`))
+
+	migrated := render(true, map[string]string{"default": "local"})
+	g.Expect(extractOVNKubeConfig(g, migrated)).To(ContainSubstring("mode=local"))
+	g.Expect(controlPlaneScript(migrated)).To(ContainSubstring(`if [ "local" == "shared" ]`))
+}
@@ -272,8 +272,10 @@ func renderOVNKubernetes(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.Bo
 	data.Data["PriorityClass"] = bootstrapResult.OVN.OVNKubernetesConfig.HyperShiftConfig.PriorityClass
 	data.Data["TokenMinterResourceRequestCPU"] = bootstrapResult.OVN.OVNKubernetesConfig.HyperShiftConfig.TokenMinterResourceRequestCPU
 	data.Data["TokenMinterResourceRequestMemory"] = bootstrapResult.OVN.OVNKubernetesConfig.HyperShiftConfig.TokenMinterResourceRequestMemory
-	data.Data["OVNControlPlaneResourceRequestCPU"] = bootstrapResult.OVN.OVNKubernetesConfig.HyperShiftConfig.OVNControlPlaneResourceRequestCPU
-	data.Data["OVNControlPlaneResourceRequestMemory"] = bootstrapResult.OVN.OVNKubernetesConfig.HyperShiftConfig.OVNControlPlaneResourceRequestMemory
+	data.Data["OVNControlPlaneResourceRequestCPU"] = sizedRequest(bootstrapResult.Sizing.OVNControlPlane.CPU,
+		bootstrapResult.OVN.OVNKubernetesConfig.HyperShiftConfig.OVNControlPlaneResourceRequestCPU, bootstrapResult.Sizing.Overridden)
+	data.Data["OVNControlPlaneResourceRequestMemory"] = sizedRequest(bootstrapResult.Sizing.OVNControlPlane.Memory,
+		bootstrapResult.OVN.OVNKubernetesConfig.HyperShiftConfig.OVNControlPlaneResourceRequestMemory, bootstrapResult.Sizing.Overridden)
 	data.Data["Socks5ProxyResourceRequestCPU"] = bootstrapResult.OVN.OVNKubernetesConfig.HyperShiftConfig.Socks5ProxyResourceRequestCPU
 	data.Data["Socks5ProxyResourceRequestMemory"] = bootstrapResult.OVN.OVNKubernetesConfig.HyperShiftConfig.Socks5ProxyResourceRequestMemory
 	data.Data["OVN_NB_INACTIVITY_PROBE"] = nb_inactivity_probe
@@ -342,11 +344,16 @@ func renderOVNKubernetes(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.Bo
 
 	klog.V(5).Infof("IPsec: is MachineConfig enabled: %v, is East-West DaemonSet enabled: %v", data.Data["IPsecMachineConfigEnable"], data.Data["OVNIPsecDaemonsetEnable"])
 
-	if c.GatewayConfig != nil && c.GatewayConfig.RoutingViaHost {
-		data.Data["OVN_GATEWAY_MODE"] = OVN_LOCAL_GW_MODE
-	} else {
-		data.Data["OVN_GATEWAY_MODE"] = OVN_SHARED_GW_MODE
+	// ovnkube-node reads its gateway mode from a ConfigMap that is not part of the
+	// config hash, so that the mode can be switched node by node
+	gatewayModes := bootstrapResult.OVN.GatewayModes
+	if len(gatewayModes) == 0 {
+		gatewayModes = map[string]string{GatewayModeDefaultKey: DesiredGatewayMode(conf)}
 	}
+	data.Data["OVNGatewayModes"] = gatewayModes
+	// the control plane and the shared configuration follow the mode of the nodes
+	// that were not migrated yet, and only switch once the migration completed
+	data.Data["OVN_GATEWAY_MODE"] = gatewayModes[GatewayModeDefaultKey]
 
 	// We accept 3 valid inputs:
 	// c.GatewayConfig.IPForwarding not set --> defaults to "".
@@ -1323,7 +1330,7 @@ func isOVNKubernetesChangeSafe(prev, next *operv1.NetworkSpec) []error {
 	return errs
 }
 
-func fillOVNKubernetesDefaults(conf, previous *operv1.NetworkSpec, hostMTU int) {
+func fillOVNKubernetesDefaults(conf, previous *operv1.NetworkSpec, hostMTU int, infraStatus *bootstrap.InfraStatus) {
 
 	if conf.DefaultNetwork.OVNKubernetesConfig == nil {
 		conf.DefaultNetwork.OVNKubernetesConfig = &operv1.OVNKubernetesConfig{}
@@ -1383,6 +1390,15 @@ func fillOVNKubernetesDefaults(conf, previous *operv1.NetworkSpec, hostMTU int)
 		sc.PolicyAuditConfig.SyslogFacility = "local0"
 	}
 
+	// The internal subnets can only be chosen at install time; moving them on a
+	// running cluster is disruptive and has to be requested explicitly.
+	if previous == nil {
+		var machineNetworks []string
+		if infraStatus != nil {
+			machineNetworks = infraStatus.MachineNetworks
+		}
+		planOVNKubernetesSubnets(conf, machineNetworks)
+	}
 }
 
 type replicaCountDecoder struct {
@@ -1552,6 +1568,11 @@ func bootstrapOVN(conf *operv1.Network, kubeClient cnoclient.Client, infraStatus
 		FlowsConfig:              bootstrapFlowsConfig(kubeClient.ClientFor("").CRClient()),
 	}
 
+	res.GatewayModes, err = bootstrapOVNGatewayModes(conf, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
 	// preserve any default masquerade subnet values that might have been set previously
 	if masqueradeCIDRs, ok := nodeDaemonSet.GetAnnotations()[names.MasqueradeCIDRsAnnotation]; ok {
 		for masqueradeCIDR := range strings.SplitSeq(masqueradeCIDRs, ",") {
@@ -0,0 +1,371 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	iputil "github.com/openshift/cluster-network-operator/pkg/util/ip"
+)
+
+// The subnets ovn-kubernetes uses when none are configured.
+const (
+	defaultV4JoinSubnet          = "100.64.0.0/16"
+	defaultV6JoinSubnet          = "fd98::/64"
+	defaultV4TransitSwitchSubnet = "100.88.0.0/16"
+	defaultV6TransitSwitchSubnet = "fd97::/64"
+
+	// The masquerade subnets of the clusters installed before the current
+	// defaults, which keep using them across upgrades.
+	legacyV4MasqueradeSubnet = "169.254.169.0/29"
+	legacyV6MasqueradeSubnet = "fd69::/125"
+)
+
+var (
+	// v4InternalSubnetRanges are searched, in order, for a replacement join or
+	// transit switch subnet: the shared address space and the benchmarking range,
+	// neither of which is routable on the internet.
+	v4InternalSubnetRanges = []string{"100.64.0.0/10", "198.18.0.0/15"}
+	// v4MasqueradeSubnetRanges are searched for a replacement masquerade subnet.
+	v4MasqueradeSubnetRanges = []string{"169.254.0.0/16"}
+	// v6InternalSubnetRanges are searched for any replacement IPv6 subnet.
+	v6InternalSubnetRanges = []string{"fd00::/8"}
+)
+
+// ovnInternalSubnet describes one of the subnets ovn-kubernetes uses internally.
+type ovnInternalSubnet struct {
+	// name is the name of the field in the operator configuration
+	name string
+	// configured is the value of the field, if any
+	configured string
+	// def is the subnet ovn-kubernetes uses if the field is unset
+	def string
+	// legacyDef is the subnet older clusters keep using if the field is unset
+	legacyDef string
+	// perNode is true if the subnet must hold an address for every node
+	perNode bool
+	// ranges are searched for a replacement if def cannot be used
+	ranges []string
+	// set writes the field
+	set func(oc *operv1.OVNKubernetesConfig, subnet string)
+}
+
+// ovnInternalSubnets returns the internal subnets of both IP families along with
+// their configured values in oc.
+func ovnInternalSubnets(oc *operv1.OVNKubernetesConfig) []ovnInternalSubnet {
+	var v4Join, v6Join, v4Transit, v6Transit, v4Masquerade, v6Masquerade string
+	// Note: V4InternalSubnet and V6InternalSubnet are deprecated aliases for the join subnets
+	v4Join, v6Join = oc.V4InternalSubnet, oc.V6InternalSubnet
+	if oc.IPv4 != nil {
+		if oc.IPv4.InternalJoinSubnet != "" {
+			v4Join = oc.IPv4.InternalJoinSubnet
+		}
+		v4Transit = oc.IPv4.InternalTransitSwitchSubnet
+	}
+	if oc.IPv6 != nil {
+		if oc.IPv6.InternalJoinSubnet != "" {
+			v6Join = oc.IPv6.InternalJoinSubnet
+		}
+		v6Transit = oc.IPv6.InternalTransitSwitchSubnet
+	}
+	if oc.GatewayConfig != nil {
+		v4Masquerade = oc.GatewayConfig.IPv4.InternalMasqueradeSubnet
+		v6Masquerade = oc.GatewayConfig.IPv6.InternalMasqueradeSubnet
+	}
+
+	return []ovnInternalSubnet{
+		{
+			name:       "v4InternalJoinSubnet",
+			configured: v4Join,
+			def:        defaultV4JoinSubnet,
+			perNode:    true,
+			ranges:     v4InternalSubnetRanges,
+			set: func(oc *operv1.OVNKubernetesConfig, subnet string) {
+				if oc.IPv4 == nil {
+					oc.IPv4 = &operv1.IPv4OVNKubernetesConfig{}
+				}
+				oc.IPv4.InternalJoinSubnet = subnet
+			},
+		},
+		{
+			name:       "v4InternalTransitSwitchSubnet",
+			configured: v4Transit,
+			def:        defaultV4TransitSwitchSubnet,
+			perNode:    true,
+			ranges:     v4InternalSubnetRanges,
+			set: func(oc *operv1.OVNKubernetesConfig, subnet string) {
+				if oc.IPv4 == nil {
+					oc.IPv4 = &operv1.IPv4OVNKubernetesConfig{}
+				}
+				oc.IPv4.InternalTransitSwitchSubnet = subnet
+			},
+		},
+		{
+			name:       "v4InternalMasqueradeSubnet",
+			configured: v4Masquerade,
+			def:        defaultV4MasqueradeSubnet,
+			legacyDef:  legacyV4MasqueradeSubnet,
+			ranges:     v4MasqueradeSubnetRanges,
+			set: func(oc *operv1.OVNKubernetesConfig, subnet string) {
+				if oc.GatewayConfig == nil {
+					oc.GatewayConfig = &operv1.GatewayConfig{}
+				}
+				oc.GatewayConfig.IPv4.InternalMasqueradeSubnet = subnet
+			},
+		},
+		{
+			name:       "v6InternalJoinSubnet",
+			configured: v6Join,
+			def:        defaultV6JoinSubnet,
+			perNode:    true,
+			ranges:     v6InternalSubnetRanges,
+			set: func(oc *operv1.OVNKubernetesConfig, subnet string) {
+				if oc.IPv6 == nil {
+					oc.IPv6 = &operv1.IPv6OVNKubernetesConfig{}
+				}
+				oc.IPv6.InternalJoinSubnet = subnet
+			},
+		},
+		{
+			name:       "v6InternalTransitSwitchSubnet",
+			configured: v6Transit,
+			def:        defaultV6TransitSwitchSubnet,
+			perNode:    true,
+			ranges:     v6InternalSubnetRanges,
+			set: func(oc *operv1.OVNKubernetesConfig, subnet string) {
+				if oc.IPv6 == nil {
+					oc.IPv6 = &operv1.IPv6OVNKubernetesConfig{}
+				}
+				oc.IPv6.InternalTransitSwitchSubnet = subnet
+			},
+		},
+		{
+			name:       "v6InternalMasqueradeSubnet",
+			configured: v6Masquerade,
+			def:        defaultV6MasqueradeSubnet,
+			legacyDef:  legacyV6MasqueradeSubnet,
+			ranges:     v6InternalSubnetRanges,
+			set: func(oc *operv1.OVNKubernetesConfig, subnet string) {
+				if oc.GatewayConfig == nil {
+					oc.GatewayConfig = &operv1.GatewayConfig{}
+				}
+				oc.GatewayConfig.IPv6.InternalMasqueradeSubnet = subnet
+			},
+		},
+	}
+}
+
+// effective returns the subnets ovn-kubernetes may use: the configured one, which
+// includes any chosen by planOVNKubernetesSubnets at install time, or else the
+// default and, for the clusters installed before it, the legacy default.
+func (s ovnInternalSubnet) effective() []string {
+	if s.configured != "" {
+		return []string{s.configured}
+	}
+	if s.legacyDef != "" {
+		return []string{s.def, s.legacyDef}
+	}
+	return []string{s.def}
+}
+
+// clusterNetworkOVNInternalSubnets returns the internal subnets of the IP families
+// of the cluster network, with their defaults if the configuration is unset.
+func clusterNetworkOVNInternalSubnets(conf *operv1.NetworkSpec) []ovnInternalSubnet {
+	var cnHasIPv4, cnHasIPv6 bool
+	for _, cn := range conf.ClusterNetwork {
+		if utilnet.IsIPv6CIDRString(cn.CIDR) {
+			cnHasIPv6 = true
+		} else {
+			cnHasIPv4 = true
+		}
+	}
+
+	oc := conf.DefaultNetwork.OVNKubernetesConfig
+	if oc == nil {
+		oc = &operv1.OVNKubernetesConfig{}
+	}
+	subnets := []ovnInternalSubnet{}
+	for _, s := range ovnInternalSubnets(oc) {
+		if utilnet.IsIPv6CIDRString(s.def) && cnHasIPv6 || !utilnet.IsIPv6CIDRString(s.def) && cnHasIPv4 {
+			subnets = append(subnets, s)
+		}
+	}
+	return subnets
+}
+
+// clusterNetworkPeers returns the networks, besides the cluster network and the
+// OVN-Kubernetes internal subnets, that the cluster network and the internal
+// subnets must not overlap: the service, machine and hybrid overlay networks, and
+// the subnets of the additional networks.
+func clusterNetworkPeers(conf *operv1.NetworkSpec, machineNetworks []string) []string {
+	out := []string{}
+	out = append(out, conf.ServiceNetwork...)
+	out = append(out, machineNetworks...)
+	if oc := conf.DefaultNetwork.OVNKubernetesConfig; oc != nil && oc.HybridOverlayConfig != nil {
+		for _, hcn := range oc.HybridOverlayConfig.HybridClusterNetwork {
+			out = append(out, hcn.CIDR)
+		}
+	}
+	return append(out, additionalNetworkCIDRs(conf)...)
+}
+
+// ClusterNetworkReservedSubnets returns the networks a new clusterNetwork entry
+// must not overlap, besides the existing entries: those of clusterNetworkPeers
+// and, with OVN-Kubernetes, the internal subnets it uses.
+func ClusterNetworkReservedSubnets(conf *operv1.NetworkSpec, machineNetworks []string) []string {
+	out := clusterNetworkPeers(conf, machineNetworks)
+	if conf.DefaultNetwork.Type == operv1.NetworkTypeOVNKubernetes {
+		for _, s := range clusterNetworkOVNInternalSubnets(conf) {
+			out = append(out, s.effective()...)
+		}
+	}
+	return out
+}
+
+// planOVNKubernetesSubnets replaces the default join, transit switch and masquerade
+// subnets with free ones when the defaults overlap a network already in use by the
+// cluster: the cluster, service, machine and hybrid overlay networks, and the
+// subnets of the additional networks. Subnets set by the administrator are never
+// changed. The chosen subnets are written to the configuration, so that they are
+// carried forward as if they had been configured at install time.
+func planOVNKubernetesSubnets(conf *operv1.NetworkSpec, machineNetworks []string) {
+	oc := conf.DefaultNetwork.OVNKubernetesConfig
+	planner := iputil.SubnetPlanner{}
+	reserve := func(cidrs ...string) {
+		for _, cidr := range cidrs {
+			if err := planner.ReserveString(cidr); err != nil {
+				klog.Warningf("Ignoring network while planning OVN-Kubernetes internal subnets: %v", err)
+			}
+		}
+	}
+
+	for _, cn := range conf.ClusterNetwork {
+		reserve(cn.CIDR)
+	}
+	reserve(clusterNetworkPeers(conf, machineNetworks)...)
+
+	subnets := clusterNetworkOVNInternalSubnets(conf)
+
+	// Reserve everything that is kept as is first, so that no replacement
+	// collides with another internal subnet.
+	for _, s := range subnets {
+		if s.configured != "" {
+			reserve(s.configured)
+		}
+	}
+	conflicting := []ovnInternalSubnet{}
+	for _, s := range subnets {
+		if s.configured != "" {
+			continue
+		}
+		_, def, _ := net.ParseCIDR(s.def)
+		if planner.Available(*def) {
+			planner.Reserve(*def)
+			continue
+		}
+		conflicting = append(conflicting, s)
+	}
+
+	for _, s := range conflicting {
+		_, def, _ := net.ParseCIDR(s.def)
+		prefixLen, _ := def.Mask.Size()
+		if s.perNode {
+			prefixLen = perNodeSubnetPrefix(conf.ClusterNetwork, s.def)
+		}
+		ranges := []net.IPNet{}
+		for _, r := range s.ranges {
+			_, cidr, _ := net.ParseCIDR(r)
+			ranges = append(ranges, *cidr)
+		}
+
+		subnet, err := planner.Allocate(prefixLen, ranges...)
+		if err != nil {
+			klog.Warningf("The default %s %s overlaps a network in use, and no replacement could be found: %v", s.name, s.def, err)
+			continue
+		}
+		klog.Infof("The default %s %s overlaps a network in use, using %s instead", s.name, s.def, subnet)
+		s.set(oc, subnet.String())
+	}
+}
+
+// perNodeSubnetPrefix returns the prefix length of the smallest subnet, no smaller
+// than def, that can hold an address for every node the cluster network allows.
+func perNodeSubnetPrefix(cn []operv1.ClusterNetworkEntry, def string) int {
+	_, cidr, _ := net.ParseCIDR(def)
+	prefixLen, bits := cidr.Mask.Size()
+	for ; prefixLen > 1; prefixLen-- {
+		subnet := fmt.Sprintf("%s/%d", cidr.IP, prefixLen)
+		if bits == 128 && isV6NodeSubnetLargeEnough(cn, subnet) ||
+			bits == 32 && isV4NodeSubnetLargeEnough(cn, subnet) {
+			break
+		}
+	}
+	return prefixLen
+}
+
+// SelectedOVNKubernetesSubnets returns a "name=subnet" entry for every OVN-Kubernetes
+// internal subnet that is set in filled but not in orig, that is, every subnet that
+// was chosen by FillDefaults.
+func SelectedOVNKubernetesSubnets(orig, filled *operv1.NetworkSpec) []string {
+	if filled.DefaultNetwork.OVNKubernetesConfig == nil {
+		return nil
+	}
+	before := map[string]string{}
+	if orig.DefaultNetwork.OVNKubernetesConfig != nil {
+		for _, s := range ovnInternalSubnets(orig.DefaultNetwork.OVNKubernetesConfig) {
+			before[s.name] = s.configured
+		}
+	}
+
+	out := []string{}
+	for _, s := range ovnInternalSubnets(filled.DefaultNetwork.OVNKubernetesConfig) {
+		if s.configured != "" && s.configured != before[s.name] {
+			out = append(out, fmt.Sprintf("%s=%s", s.name, s.configured))
+		}
+	}
+	return out
+}
+
+// SetSelectedOVNKubernetesSubnetsAnnotation records the names of the subnets in
+// selected, as returned by SelectedOVNKubernetesSubnets, on the operator
+// configuration. Once written to the configuration, the chosen subnets cannot be
+// told apart from the ones set by the administrator anymore.
+func SetSelectedOVNKubernetesSubnetsAnnotation(conf *operv1.Network, selected []string) {
+	if len(selected) == 0 {
+		return
+	}
+	subnetNames := []string{}
+	for _, s := range selected {
+		name, _, _ := strings.Cut(s, "=")
+		subnetNames = append(subnetNames, name)
+	}
+	if conf.Annotations == nil {
+		conf.Annotations = map[string]string{}
+	}
+	conf.Annotations[names.OVNKubernetesSelectedSubnetsAnnotation] = strings.Join(subnetNames, ",")
+}
+
+// ReportedOVNKubernetesSubnets returns a "name=subnet" entry for every internal
+// subnet recorded by SetSelectedOVNKubernetesSubnetsAnnotation that still differs
+// from its default.
+func ReportedOVNKubernetesSubnets(conf *operv1.Network) []string {
+	recorded, ok := conf.Annotations[names.OVNKubernetesSelectedSubnetsAnnotation]
+	if !ok || conf.Spec.DefaultNetwork.OVNKubernetesConfig == nil {
+		return nil
+	}
+	selected := sets.New(strings.Split(recorded, ",")...)
+
+	out := []string{}
+	for _, s := range ovnInternalSubnets(conf.Spec.DefaultNetwork.OVNKubernetesConfig) {
+		if selected.Has(s.name) && s.configured != "" && s.configured != s.def {
+			out = append(out, fmt.Sprintf("%s=%s", s.name, s.configured))
+		}
+	}
+	return out
+}
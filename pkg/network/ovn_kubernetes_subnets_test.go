@@ -0,0 +1,172 @@
+package network
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	operv1 "github.com/openshift/api/operator/v1"
+)
+
+func TestPlanOVNKubernetesSubnets(t *testing.T) {
+	testcases := []struct {
+		name            string
+		clusterNetwork  []operv1.ClusterNetworkEntry
+		serviceNetwork  []string
+		machineNetworks []string
+		configure       func(conf *operv1.NetworkSpec)
+		expected        []string
+	}{
+		{
+			name: "defaults do not collide",
+			clusterNetwork: []operv1.ClusterNetworkEntry{
+				{CIDR: "10.128.0.0/14", HostPrefix: 23},
+			},
+			serviceNetwork:  []string{"172.30.0.0/16"},
+			machineNetworks: []string{"10.0.0.0/16"},
+			expected:        []string{},
+		},
+		{
+			name: "machine network collides with the join subnet",
+			clusterNetwork: []operv1.ClusterNetworkEntry{
+				{CIDR: "10.128.0.0/14", HostPrefix: 23},
+			},
+			serviceNetwork:  []string{"172.30.0.0/16"},
+			machineNetworks: []string{"100.64.0.0/16"},
+			expected:        []string{"v4InternalJoinSubnet=100.65.0.0/16"},
+		},
+		{
+			name: "cluster network collides with join and transit switch subnets",
+			clusterNetwork: []operv1.ClusterNetworkEntry{
+				{CIDR: "100.64.0.0/11", HostPrefix: 23},
+			},
+			serviceNetwork: []string{"172.30.0.0/16"},
+			expected: []string{
+				"v4InternalJoinSubnet=100.96.0.0/16",
+				"v4InternalTransitSwitchSubnet=100.97.0.0/16",
+			},
+		},
+		{
+			name: "replacement avoids the administrator's subnets",
+			clusterNetwork: []operv1.ClusterNetworkEntry{
+				{CIDR: "10.128.0.0/14", HostPrefix: 23},
+			},
+			serviceNetwork: []string{"100.64.0.0/16"},
+			configure: func(conf *operv1.NetworkSpec) {
+				conf.DefaultNetwork.OVNKubernetesConfig.IPv4 = &operv1.IPv4OVNKubernetesConfig{
+					InternalTransitSwitchSubnet: "100.65.0.0/16",
+				}
+			},
+			expected: []string{"v4InternalJoinSubnet=100.66.0.0/16"},
+		},
+		{
+			name: "replacement is large enough for every node",
+			clusterNetwork: []operv1.ClusterNetworkEntry{
+				{CIDR: "10.0.0.0/8", HostPrefix: 26},
+			},
+			serviceNetwork:  []string{"172.30.0.0/16"},
+			machineNetworks: []string{"100.64.0.0/24"},
+			expected:        []string{"v4InternalJoinSubnet=100.72.0.0/13"},
+		},
+		{
+			name: "hybrid overlay and additional networks collide",
+			clusterNetwork: []operv1.ClusterNetworkEntry{
+				{CIDR: "10.128.0.0/14", HostPrefix: 23},
+			},
+			serviceNetwork: []string{"172.30.0.0/16"},
+			configure: func(conf *operv1.NetworkSpec) {
+				conf.DefaultNetwork.OVNKubernetesConfig.HybridOverlayConfig = &operv1.HybridOverlayConfig{
+					HybridClusterNetwork: []operv1.ClusterNetworkEntry{{CIDR: "100.64.0.0/16", HostPrefix: 24}},
+				}
+				conf.AdditionalNetworks = []operv1.AdditionalNetworkDefinition{
+					{
+						Type:         operv1.NetworkTypeRaw,
+						Name:         "net1",
+						RawCNIConfig: `{"cniVersion":"0.3.1","type":"macvlan","ipam":{"type":"whereabouts","range":"100.65.0.10-100.65.0.20/24"}}`,
+					},
+					{
+						Type: operv1.NetworkTypeSimpleMacvlan,
+						Name: "net2",
+						SimpleMacvlanConfig: &operv1.SimpleMacvlanConfig{
+							IPAMConfig: &operv1.IPAMConfig{
+								Type: operv1.IPAMTypeStatic,
+								StaticIPAMConfig: &operv1.StaticIPAMConfig{
+									Addresses: []operv1.StaticIPAMAddresses{{Address: "100.66.0.5/24"}},
+								},
+							},
+						},
+					},
+				}
+			},
+			expected: []string{"v4InternalJoinSubnet=100.67.0.0/16"},
+		},
+		{
+			name: "dual stack",
+			clusterNetwork: []operv1.ClusterNetworkEntry{
+				{CIDR: "10.128.0.0/14", HostPrefix: 23},
+				{CIDR: "fd98::/48", HostPrefix: 64},
+			},
+			serviceNetwork:  []string{"172.30.0.0/16", "fd02::/112"},
+			machineNetworks: []string{"169.254.0.0/24", "fd69::/64"},
+			expected: []string{
+				"v4InternalMasqueradeSubnet=169.254.128.0/17",
+				"v6InternalJoinSubnet=fd00::/64",
+				"v6InternalMasqueradeSubnet=fd00:0:0:1::/112",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			conf := &operv1.NetworkSpec{
+				ClusterNetwork: tc.clusterNetwork,
+				ServiceNetwork: tc.serviceNetwork,
+				DefaultNetwork: operv1.DefaultNetworkDefinition{
+					Type:                operv1.NetworkTypeOVNKubernetes,
+					OVNKubernetesConfig: &operv1.OVNKubernetesConfig{},
+				},
+			}
+			if tc.configure != nil {
+				tc.configure(conf)
+			}
+			orig := conf.DeepCopy()
+
+			planOVNKubernetesSubnets(conf, tc.machineNetworks)
+			g.Expect(SelectedOVNKubernetesSubnets(orig, conf)).To(Equal(tc.expected))
+			g.Expect(validateOVNKubernetesSubnets(conf)).To(Succeed())
+		})
+	}
+}
+
+func TestReportedOVNKubernetesSubnets(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	conf := &operv1.Network{
+		Spec: operv1.NetworkSpec{
+			ClusterNetwork: []operv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/14", HostPrefix: 23}},
+			ServiceNetwork: []string{"100.64.0.0/16"},
+			DefaultNetwork: operv1.DefaultNetworkDefinition{
+				Type: operv1.NetworkTypeOVNKubernetes,
+				OVNKubernetesConfig: &operv1.OVNKubernetesConfig{
+					IPv4: &operv1.IPv4OVNKubernetesConfig{InternalTransitSwitchSubnet: "100.65.0.0/16"},
+				},
+			},
+		},
+	}
+	g.Expect(ReportedOVNKubernetesSubnets(conf)).To(BeEmpty())
+
+	orig := conf.Spec.DeepCopy()
+	planOVNKubernetesSubnets(&conf.Spec, nil)
+	SetSelectedOVNKubernetesSubnetsAnnotation(conf, SelectedOVNKubernetesSubnets(orig, &conf.Spec))
+	g.Expect(conf.Annotations).To(HaveKeyWithValue("networkoperator.openshift.io/selected-internal-subnets", "v4InternalJoinSubnet"))
+
+	// the selection is still reported once it is part of the configuration, but
+	// not the subnets set by the administrator
+	g.Expect(ReportedOVNKubernetesSubnets(conf)).To(Equal([]string{"v4InternalJoinSubnet=100.66.0.0/16"}))
+
+	// until the subnet is set back to its default
+	conf.Spec.DefaultNetwork.OVNKubernetesConfig.IPv4.InternalJoinSubnet = defaultV4JoinSubnet
+	g.Expect(ReportedOVNKubernetesSubnets(conf)).To(BeEmpty())
+}
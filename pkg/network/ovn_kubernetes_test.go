@@ -1228,7 +1228,7 @@ func TestFillOVNKubernetesDefaults(t *testing.T) {
 		},
 	}
 
-	fillOVNKubernetesDefaults(conf, nil, 9000)
+	fillOVNKubernetesDefaults(conf, nil, 9000, nil)
 
 	g.Expect(conf).To(Equal(&expected))
 
@@ -1270,7 +1270,7 @@ func TestFillOVNKubernetesDefaultsIPsec(t *testing.T) {
 		},
 	}
 
-	fillOVNKubernetesDefaults(conf, conf, 9000)
+	fillOVNKubernetesDefaults(conf, conf, 9000, nil)
 
 	g.Expect(conf).To(Equal(&expected))
 
@@ -4432,7 +4432,7 @@ func Test_renderOVNKubernetes(t *testing.T) {
 				client:          cnofake.NewFakeClient(),
 				featureGates:    noFeatureGates,
 			},
-			expectNumObjs: 52,
+			expectNumObjs: 53,
 		},
 		{
 			name: "render routeadvertisements",
@@ -4447,7 +4447,7 @@ func Test_renderOVNKubernetes(t *testing.T) {
 				client:          cnofake.NewFakeClient(),
 				featureGates:    noFeatureGates,
 			},
-			expectNumObjs: 53,
+			expectNumObjs: 54,
 		},
 		{
 			name: "render with UDN",
@@ -4458,7 +4458,7 @@ func Test_renderOVNKubernetes(t *testing.T) {
 				client:          cnofake.NewFakeClient(),
 				featureGates:    udnFeatureGate,
 			},
-			expectNumObjs: 52,
+			expectNumObjs: 53,
 		},
 		{
 			name: "render with PreconfiguredUDNAddresses, UDN, persistent-IP, and RA",
@@ -4472,7 +4472,7 @@ func Test_renderOVNKubernetes(t *testing.T) {
 				client:       cnofake.NewFakeClient(),
 				featureGates: preDefUDNFeatureGates,
 			},
-			expectNumObjs: 53,
+			expectNumObjs: 54,
 		},
 	}
 	for _, tt := range tests {
@@ -5113,7 +5113,7 @@ func TestFillOVNKubernetesDefaultsMTUNoOverlay(t *testing.T) {
 		conf.DefaultNetwork.OVNKubernetesConfig.MTU = nil // not set
 
 		hostMTU := 9000
-		fillOVNKubernetesDefaults(conf, nil, hostMTU)
+		fillOVNKubernetesDefaults(conf, nil, hostMTU, nil)
 
 		g.Expect(conf.DefaultNetwork.OVNKubernetesConfig.MTU).NotTo(BeNil())
 		g.Expect(*conf.DefaultNetwork.OVNKubernetesConfig.MTU).To(Equal(uint32(hostMTU)))
@@ -5126,7 +5126,7 @@ func TestFillOVNKubernetesDefaultsMTUNoOverlay(t *testing.T) {
 		conf.DefaultNetwork.OVNKubernetesConfig.MTU = nil // not set
 
 		hostMTU := 9000
-		fillOVNKubernetesDefaults(conf, nil, hostMTU)
+		fillOVNKubernetesDefaults(conf, nil, hostMTU, nil)
 
 		g.Expect(conf.DefaultNetwork.OVNKubernetesConfig.MTU).NotTo(BeNil())
 		// Geneve overhead is 100 bytes
@@ -5140,7 +5140,7 @@ func TestFillOVNKubernetesDefaultsMTUNoOverlay(t *testing.T) {
 		conf.DefaultNetwork.OVNKubernetesConfig.MTU = nil      // not set
 
 		hostMTU := 9000
-		fillOVNKubernetesDefaults(conf, nil, hostMTU)
+		fillOVNKubernetesDefaults(conf, nil, hostMTU, nil)
 
 		g.Expect(conf.DefaultNetwork.OVNKubernetesConfig.MTU).NotTo(BeNil())
 		// Empty defaults to Geneve, so overhead is 100 bytes
@@ -5158,7 +5158,7 @@ func TestFillOVNKubernetesDefaultsMTUNoOverlay(t *testing.T) {
 		prev.Spec.DefaultNetwork.OVNKubernetesConfig.MTU = &prevMTU
 
 		hostMTU := 9000
-		fillOVNKubernetesDefaults(conf, &prev.Spec, hostMTU)
+		fillOVNKubernetesDefaults(conf, &prev.Spec, hostMTU, nil)
 
 		g.Expect(conf.DefaultNetwork.OVNKubernetesConfig.MTU).NotTo(BeNil())
 		g.Expect(*conf.DefaultNetwork.OVNKubernetesConfig.MTU).To(Equal(prevMTU))
@@ -49,7 +49,7 @@ func TestPreviousVersionsSafe(t *testing.T) {
 			fillDefaults(applied, applied)
 
 			// This is the exact config transformation flow in the operator
-			g.Expect(Validate(input)).NotTo(HaveOccurred())
+			g.Expect(Validate(input, nil)).NotTo(HaveOccurred())
 			fillDefaults(input, applied)
 			g.Expect(IsChangeSafe(applied, input, &fakeBootstrapResult().Infra)).NotTo(HaveOccurred())
 		})
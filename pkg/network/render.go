@@ -21,6 +21,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/util/retry"
 	utilnet "k8s.io/utils/net"
 
@@ -220,15 +221,20 @@ func DeprecatedCanonicalize(conf *operv1.NetworkSpec) {
 }
 
 // Validate checks that the supplied configuration is reasonable.
-// This should be called after Canonicalize
-func Validate(conf *operv1.NetworkSpec) error {
+// This should be called after Canonicalize. infraStatus may be nil, in which case
+// additional networks are not checked against the machine networks and the VIPs.
+func Validate(conf *operv1.NetworkSpec, infraStatus *bootstrap.InfraStatus) error {
 	errs := []error{}
 
 	errs = append(errs, validateIPPools(conf)...)
+	errs = append(errs, validateAddressSpace(conf, infraStatus)...)
 	errs = append(errs, validateDefaultNetwork(conf)...)
 	errs = append(errs, validateMultus(conf)...)
+	errs = append(errs, validateMultusNodePools(conf)...)
+	errs = append(errs, validateMultiNetworkPolicyScope(conf)...)
 	errs = append(errs, validateKubeProxy(conf)...)
 	errs = append(errs, validateMigration(conf)...)
+	errs = append(errs, validateSizingOverrides(conf)...)
 
 	if len(errs) > 0 {
 		return fmt.Errorf("invalid configuration: %v", errs)
@@ -243,8 +249,9 @@ func Validate(conf *operv1.NetworkSpec) error {
 // can change defaults as we move forward, but won't disrupt existing clusters.
 //
 // We may need to know the MTU of nodes in the cluster, so we can compute the correct
-// underlay MTU for OVN-K.
-func FillDefaults(conf, previous *operv1.NetworkSpec, hostMTU int) {
+// underlay MTU for OVN-K, and the machine networks from infraStatus, so that default
+// internal subnets do not collide with them. infraStatus may be nil.
+func FillDefaults(conf, previous *operv1.NetworkSpec, hostMTU int, infraStatus *bootstrap.InfraStatus) {
 	// DisableMultiNetwork defaults to false
 	if conf.DisableMultiNetwork == nil {
 		disable := false
@@ -261,7 +268,7 @@ func FillDefaults(conf, previous *operv1.NetworkSpec, hostMTU int) {
 		conf.LogLevel = "Normal"
 	}
 
-	fillDefaultNetworkDefaults(conf, previous, hostMTU)
+	fillDefaultNetworkDefaults(conf, previous, hostMTU, infraStatus)
 	fillKubeProxyDefaults(conf, previous)
 }
 
@@ -547,10 +554,7 @@ func validateMultus(conf *operv1.NetworkSpec) []error {
 // validateDefaultNetwork validates whichever network is specified
 // as the default network.
 func validateDefaultNetwork(conf *operv1.NetworkSpec) []error {
-	if conf.DefaultNetwork.Type == operv1.NetworkTypeOVNKubernetes {
-		return validateOVNKubernetes(conf)
-	}
-	return nil
+	return runningDefaultNetworkPlugin(conf.DefaultNetwork.Type).Validate(conf)
 }
 
 // validateMigration validates if migration path is possible
@@ -572,50 +576,53 @@ func validateMigration(conf *operv1.NetworkSpec) []error {
 // default network
 func renderDefaultNetwork(conf *operv1.NetworkSpec, bootstrapResult *bootstrap.BootstrapResult, manifestDir string,
 	client cnoclient.Client, featureGates featuregates.FeatureGate) ([]*uns.Unstructured, bool, error) {
-	dn := conf.DefaultNetwork
 	if errs := validateDefaultNetwork(conf); len(errs) > 0 {
 		return nil, false, fmt.Errorf("invalid Default Network configuration: %v", errs)
 	}
 
-	if dn.Type == operv1.NetworkTypeOVNKubernetes {
-		return renderOVNKubernetes(conf, bootstrapResult, manifestDir, client, featureGates)
-	}
-
-	log.Printf("NOTICE: Unknown network type %s, ignoring", dn.Type)
-	return nil, false, nil
+	return runningDefaultNetworkPlugin(conf.DefaultNetwork.Type).Render(conf, bootstrapResult, manifestDir, client, featureGates)
 }
 
-func fillDefaultNetworkDefaults(conf, previous *operv1.NetworkSpec, hostMTU int) {
-	if conf.DefaultNetwork.Type == operv1.NetworkTypeOVNKubernetes {
-		fillOVNKubernetesDefaults(conf, previous, hostMTU)
-	}
+func fillDefaultNetworkDefaults(conf, previous *operv1.NetworkSpec, hostMTU int, infraStatus *bootstrap.InfraStatus) {
+	runningDefaultNetworkPlugin(conf.DefaultNetwork.Type).FillDefaults(conf, previous, hostMTU, infraStatus)
 }
 
 func isDefaultNetworkChangeSafe(prev, next *operv1.NetworkSpec) []error {
+	prevPlugin, err := defaultNetworkPlugin(prev.DefaultNetwork.Type)
 	if prev.DefaultNetwork.Type != next.DefaultNetwork.Type {
+		// Clusters that used to name their third-party plugin as the default
+		// network type may switch to the explicit "None" type
+		if err != nil && next.DefaultNetwork.Type == NetworkTypeNone {
+			return nil
+		}
 		return []error{fmt.Errorf("cannot change default network type")}
 	}
-
-	if prev.DefaultNetwork.Type == operv1.NetworkTypeOVNKubernetes {
-		return isOVNKubernetesChangeSafe(prev, next)
+	if err != nil {
+		return nil
 	}
-	return nil
+	return prevPlugin.IsChangeSafe(prev, next)
 }
 
 // ValidateAdditionalNetworks validates additional networks configs
 func validateAdditionalNetworks(conf *operv1.NetworkSpec) []error {
 	out := []error{}
 	ans := conf.AdditionalNetworks
-	for _, an := range ans {
+	for i, an := range ans {
 		switch an.Type {
 		case operv1.NetworkTypeRaw:
-			if errs := validateRaw(&an); len(errs) > 0 {
+			if errs := validateRaw(&an, field.NewPath("spec", "additionalNetworks").Index(i)); len(errs) > 0 {
 				out = append(out, errs...)
 			}
 		case operv1.NetworkTypeSimpleMacvlan:
 			if errs := validateSimpleMacvlanConfig(&an); len(errs) > 0 {
 				out = append(out, errs...)
 			}
+		case NetworkTypeSimpleIPVLAN:
+			out = append(out, validateSimpleIPVLANConfig(&an)...)
+		case NetworkTypeSimpleBridge:
+			out = append(out, validateSimpleBridgeConfig(&an)...)
+		case NetworkTypeSimpleHostDevice:
+			out = append(out, validateSimpleHostDeviceConfig(&an)...)
 		default:
 			out = append(out, fmt.Errorf("unknown or unsupported NetworkType: %s", an.Type))
 		}
@@ -652,6 +659,24 @@ func renderAdditionalNetworks(conf *operv1.NetworkSpec, manifestDir string) ([]*
 				return nil, err
 			}
 			out = append(out, objs...)
+		case NetworkTypeSimpleIPVLAN:
+			objs, err := renderSimpleIPVLANConfig(&an, manifestDir)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, objs...)
+		case NetworkTypeSimpleBridge:
+			objs, err := renderSimpleBridgeConfig(&an, manifestDir)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, objs...)
+		case NetworkTypeSimpleHostDevice:
+			objs, err := renderSimpleHostDeviceConfig(&an, manifestDir)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, objs...)
 		default:
 			return nil, fmt.Errorf("unknown or unsupported NetworkType: %s", an.Type)
 		}
@@ -63,13 +63,13 @@ func TestNoErrorOnIdenticalConfigs(t *testing.T) {
 func TestClusterNetworkNoMigration(t *testing.T) {
 	g := NewGomegaWithT(t)
 	config := OVNKubernetesConfig.DeepCopy()
-	err := Validate(&config.Spec)
+	err := Validate(&config.Spec, nil)
 	g.Expect(err).NotTo(HaveOccurred())
 
 	config.Spec.Migration = &operv1.NetworkMigration{
 		NetworkType: "Whatever",
 	}
-	err = Validate(&config.Spec)
+	err = Validate(&config.Spec, nil)
 	g.Expect(err).To(MatchError(ContainSubstring("network type migration is not supported")))
 }
 
@@ -304,7 +304,7 @@ func getDefaultFeatureGatesWithDualStack() featuregates.FeatureGate {
 	)
 }
 
-func TestRenderUnknownNetwork(t *testing.T) {
+func TestRenderThirdPartyNetwork(t *testing.T) {
 	g := NewGomegaWithT(t)
 
 	config := operv1.Network{
@@ -321,7 +321,7 @@ func TestRenderUnknownNetwork(t *testing.T) {
 				},
 			},
 			DefaultNetwork: operv1.DefaultNetworkDefinition{
-				Type: "MyAwesomeThirdPartyPlugin",
+				Type: NetworkTypeNone,
 			},
 		},
 	}
@@ -344,7 +344,7 @@ func TestRenderUnknownNetwork(t *testing.T) {
 	err := createProxy(client)
 	g.Expect(err).NotTo(HaveOccurred())
 
-	err = Validate(&config.Spec)
+	err = Validate(&config.Spec, nil)
 	g.Expect(err).NotTo(HaveOccurred())
 
 	prev := config.Spec.DeepCopy()
@@ -355,9 +355,22 @@ func TestRenderUnknownNetwork(t *testing.T) {
 	err = IsChangeSafe(prev, next, &fakeBootstrapResult().Infra)
 	g.Expect(err).NotTo(HaveOccurred())
 
+	// Clusters that named their third-party plugin may switch to None
+	unknown := config.Spec.DeepCopy()
+	unknown.DefaultNetwork.Type = "MyAwesomeThirdPartyPlugin"
+	fillDefaults(unknown, nil)
+	err = IsChangeSafe(unknown, next, &fakeBootstrapResult().Infra)
+	g.Expect(err).NotTo(HaveOccurred())
+
 	bootstrapResult, err := Bootstrap(&config, client)
 	g.Expect(err).NotTo(HaveOccurred())
 
+	// an unknown type kept by a running cluster is bootstrapped like None
+	unknownConfig := config.DeepCopy()
+	unknownConfig.Spec.DefaultNetwork.Type = "MyAwesomeThirdPartyPlugin"
+	_, err = Bootstrap(unknownConfig, client)
+	g.Expect(err).NotTo(HaveOccurred())
+
 	featureGatesCNO := getDefaultFeatureGatesWithDualStack()
 
 	objs, _, err := Render(prev, &configv1.NetworkSpec{}, manifestDir, client, featureGatesCNO, bootstrapResult)
@@ -376,6 +389,44 @@ func TestRenderUnknownNetwork(t *testing.T) {
 	// TODO(cdc) validate that kube-proxy is rendered
 }
 
+func TestValidateUnknownNetwork(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	config := OVNKubernetesConfig.Spec.DeepCopy()
+	config.DefaultNetwork.Type = "MyAwesomeThirdPartyPlugin"
+	config.DefaultNetwork.OVNKubernetesConfig = nil
+
+	// unknown types are rejected at install time
+	err := ValidateDefaultNetworkType(config, nil)
+	g.Expect(err).To(MatchError(ContainSubstring(`unknown default network type "MyAwesomeThirdPartyPlugin", must be one of None, OVNKubernetes; use "None"`)))
+
+	// and when the type changes
+	prev := config.DeepCopy()
+	prev.DefaultNetwork.Type = operv1.NetworkTypeOVNKubernetes
+	g.Expect(ValidateDefaultNetworkType(config, prev)).To(HaveOccurred())
+	g.Expect(isDefaultNetworkChangeSafe(prev, config)).To(HaveLen(1))
+
+	// but clusters installed with them keep them, handled like None
+	prev.DefaultNetwork.Type = config.DefaultNetwork.Type
+	g.Expect(ValidateDefaultNetworkType(config, prev)).To(Succeed())
+	g.Expect(Validate(config, nil)).To(Succeed())
+	objs, _, err := renderDefaultNetwork(config, fakeBootstrapResult(), manifestDir, nil, getDefaultFeatureGates())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(BeEmpty())
+
+	cond := DefaultNetworkTypeCondition(config)
+	g.Expect(cond.Status).To(Equal(operv1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal("UnknownNetworkType"))
+	g.Expect(cond.Message).To(ContainSubstring(`"MyAwesomeThirdPartyPlugin"`))
+	g.Expect(DefaultNetworkTypeCondition(&OVNKubernetesConfig.Spec).Status).To(Equal(operv1.ConditionFalse))
+
+	// they may switch to None
+	next := config.DeepCopy()
+	next.DefaultNetwork.Type = NetworkTypeNone
+	g.Expect(ValidateDefaultNetworkType(next, prev)).To(Succeed())
+	g.Expect(isDefaultNetworkChangeSafe(prev, next)).To(BeEmpty())
+}
+
 func Test_getMultusAdmissionControllerReplicas(t *testing.T) {
 	type args struct {
 		bootstrapResult   *bootstrap.BootstrapResult
@@ -499,7 +550,7 @@ func Test_getMultusAdmissionControllerReplicas(t *testing.T) {
 }
 
 func fillDefaults(conf, previous *operv1.NetworkSpec) {
-	FillDefaults(conf, previous, 1400)
+	FillDefaults(conf, previous, 1400, nil)
 }
 
 func setupTestInfraAndBasicRenderConfigs(t *testing.T, prevType, nextType operv1.Network) (
@@ -0,0 +1,337 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/openshift/cluster-network-operator/pkg/render"
+)
+
+// The operator.openshift.io API only has typed configuration for SimpleMacvlan
+// additional networks. The following types of additional networks take their typed
+// configuration, the Simple*Config types below, as JSON in rawCNIConfig.
+const (
+	// NetworkTypeSimpleIPVLAN is an ipvlan additional network, configured by SimpleIPVLANConfig
+	NetworkTypeSimpleIPVLAN operv1.NetworkType = "SimpleIPVLAN"
+	// NetworkTypeSimpleBridge is a bridge additional network, configured by SimpleBridgeConfig
+	NetworkTypeSimpleBridge operv1.NetworkType = "SimpleBridge"
+	// NetworkTypeSimpleHostDevice is a host-device additional network, configured by SimpleHostDeviceConfig
+	NetworkTypeSimpleHostDevice operv1.NetworkType = "SimpleHostDevice"
+)
+
+// IPAMTypeWhereabouts uses whereabouts for IP management, configured by WhereaboutsIPAMConfig
+const IPAMTypeWhereabouts operv1.IPAMType = "Whereabouts"
+
+// SimpleIPAMConfig is the IPAM configuration of the SimpleIPVLAN, SimpleBridge and
+// SimpleHostDevice additional networks. It adds whereabouts to the DHCP and static
+// IPAM types of SimpleMacvlan.
+type SimpleIPAMConfig struct {
+	operv1.IPAMConfig
+
+	// whereaboutsIPAMConfig configures whereabouts in case of type:Whereabouts
+	WhereaboutsIPAMConfig *WhereaboutsIPAMConfig `json:"whereaboutsIPAMConfig,omitempty"`
+}
+
+// WhereaboutsIPAMConfig configures whereabouts IPAM
+type WhereaboutsIPAMConfig struct {
+	// range is the CIDR to assign addresses from
+	Range string `json:"range"`
+	// exclude are the CIDRs of range not to assign
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// IPVLANMode is the mode of an ipvlan interface
+type IPVLANMode string
+
+const (
+	IPVLANModeL2  IPVLANMode = "L2"
+	IPVLANModeL3  IPVLANMode = "L3"
+	IPVLANModeL3S IPVLANMode = "L3S"
+)
+
+// SimpleIPVLANConfig configures the ipvlan interface in case of type:SimpleIPVLAN
+type SimpleIPVLANConfig struct {
+	// master is the host interface to create the ipvlan interface from.
+	// If not specified, it will be default route interface
+	Master string `json:"master,omitempty"`
+	// mode is the ipvlan mode: L2, L3 or L3S. The default is L2
+	Mode IPVLANMode `json:"mode,omitempty"`
+	// mtu is the mtu to use for the ipvlan interface
+	MTU uint32 `json:"mtu,omitempty"`
+	// ipamConfig configures IPAM. The default is DHCP
+	IPAMConfig *SimpleIPAMConfig `json:"ipamConfig,omitempty"`
+}
+
+// SimpleBridgeConfig configures the bridge in case of type:SimpleBridge
+type SimpleBridgeConfig struct {
+	// bridge is the name of the host bridge to use, created if it does not exist.
+	// The default is cni0
+	Bridge string `json:"bridge,omitempty"`
+	// vlan is the VLAN tag of the pod interfaces on the bridge, 0 for none
+	VLAN uint32 `json:"vlan,omitempty"`
+	// isGateway assigns the gateway address of the network to the bridge
+	IsGateway bool `json:"isGateway,omitempty"`
+	// ipMasq masquerades the traffic leaving the network through the host
+	IPMasq bool `json:"ipMasq,omitempty"`
+	// mtu is the mtu to use for the pod interfaces
+	MTU uint32 `json:"mtu,omitempty"`
+	// ipamConfig configures IPAM. The default is DHCP
+	IPAMConfig *SimpleIPAMConfig `json:"ipamConfig,omitempty"`
+}
+
+// SimpleHostDeviceConfig configures the moved host device in case of type:SimpleHostDevice
+type SimpleHostDeviceConfig struct {
+	// device is the name of the host interface to move into the pod
+	Device string `json:"device,omitempty"`
+	// pciAddress is the PCI address of the host device to move into the pod
+	PCIAddress string `json:"pciAddress,omitempty"`
+	// ipamConfig configures IPAM. By default the device keeps no address
+	IPAMConfig *SimpleIPAMConfig `json:"ipamConfig,omitempty"`
+}
+
+// simpleNetworkConfig parses the typed configuration of a SimpleIPVLAN,
+// SimpleBridge or SimpleHostDevice additional network into conf
+func simpleNetworkConfig(an *operv1.AdditionalNetworkDefinition, conf any) error {
+	if strings.TrimSpace(an.RawCNIConfig) == "" {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(an.RawCNIConfig)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(conf); err != nil {
+		return fmt.Errorf("failed to parse %s configuration of additional network %s: %w", an.Type, an.Name, err)
+	}
+	return nil
+}
+
+// simpleIPAMConfig returns the IPAM configuration of a SimpleIPVLAN, SimpleBridge or
+// SimpleHostDevice additional network, or nil if it is invalid or has none
+func simpleIPAMConfig(an *operv1.AdditionalNetworkDefinition) *SimpleIPAMConfig {
+	switch an.Type {
+	case NetworkTypeSimpleIPVLAN:
+		conf := SimpleIPVLANConfig{}
+		if err := simpleNetworkConfig(an, &conf); err == nil {
+			return conf.IPAMConfig
+		}
+	case NetworkTypeSimpleBridge:
+		conf := SimpleBridgeConfig{}
+		if err := simpleNetworkConfig(an, &conf); err == nil {
+			return conf.IPAMConfig
+		}
+	case NetworkTypeSimpleHostDevice:
+		conf := SimpleHostDeviceConfig{}
+		if err := simpleNetworkConfig(an, &conf); err == nil {
+			return conf.IPAMConfig
+		}
+	}
+	return nil
+}
+
+// whereaboutsIPAMConfig for json generation for whereabouts IPAM
+type whereaboutsIPAMConfig struct {
+	Type    string   `json:"type"`
+	Range   string   `json:"range"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// getSimpleIPAMConfigJSON generates IPAM CNI json config
+func getSimpleIPAMConfigJSON(conf *SimpleIPAMConfig) (string, error) {
+	if conf == nil {
+		return getIPAMConfigJSON(nil)
+	}
+	if conf.Type != IPAMTypeWhereabouts {
+		return getIPAMConfigJSON(&conf.IPAMConfig)
+	}
+
+	jsonByte, err := json.Marshal(whereaboutsIPAMConfig{
+		Type:    "whereabouts",
+		Range:   conf.WhereaboutsIPAMConfig.Range,
+		Exclude: conf.WhereaboutsIPAMConfig.Exclude,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create whereabouts ipam config: %w", err)
+	}
+	return string(jsonByte), nil
+}
+
+// validateSimpleIPAMConfig checks its SimpleIPAMConfig.
+func validateSimpleIPAMConfig(conf *SimpleIPAMConfig) []error {
+	if conf.Type != IPAMTypeWhereabouts {
+		return validateIPAMConfig(&conf.IPAMConfig)
+	}
+
+	out := []error{}
+	if conf.WhereaboutsIPAMConfig == nil {
+		return append(out, fmt.Errorf("whereaboutsIPAMConfig is required for whereabouts IPAM"))
+	}
+	if _, _, err := net.ParseCIDR(conf.WhereaboutsIPAMConfig.Range); err != nil {
+		out = append(out, fmt.Errorf("invalid whereabouts range: %w", err))
+	}
+	for _, exclude := range conf.WhereaboutsIPAMConfig.Exclude {
+		if _, _, err := net.ParseCIDR(exclude); err != nil {
+			out = append(out, fmt.Errorf("invalid whereabouts exclude: %w", err))
+		}
+	}
+	return out
+}
+
+// validateSimpleIPVLANConfig checks its name and SimpleIPVLANConfig.
+func validateSimpleIPVLANConfig(an *operv1.AdditionalNetworkDefinition) []error {
+	out := []error{}
+	if an.Name == "" {
+		out = append(out, fmt.Errorf("Additional Network Name cannot be nil")) //nolint:staticcheck // ANN is the name
+	}
+
+	conf := SimpleIPVLANConfig{}
+	if err := simpleNetworkConfig(an, &conf); err != nil {
+		return append(out, err)
+	}
+	if conf.IPAMConfig != nil {
+		out = append(out, validateSimpleIPAMConfig(conf.IPAMConfig)...)
+	}
+	switch conf.Mode {
+	case "", IPVLANModeL2, IPVLANModeL3, IPVLANModeL3S:
+	default:
+		out = append(out, fmt.Errorf("invalid IPVLAN mode: %s", conf.Mode))
+	}
+	return out
+}
+
+// validateSimpleBridgeConfig checks its name and SimpleBridgeConfig.
+func validateSimpleBridgeConfig(an *operv1.AdditionalNetworkDefinition) []error {
+	out := []error{}
+	if an.Name == "" {
+		out = append(out, fmt.Errorf("Additional Network Name cannot be nil")) //nolint:staticcheck // ANN is the name
+	}
+
+	conf := SimpleBridgeConfig{}
+	if err := simpleNetworkConfig(an, &conf); err != nil {
+		return append(out, err)
+	}
+	if conf.IPAMConfig != nil {
+		out = append(out, validateSimpleIPAMConfig(conf.IPAMConfig)...)
+	}
+	// Linux interface names are at most 15 characters long
+	if len(conf.Bridge) > 15 || strings.ContainsAny(conf.Bridge, "/ ") {
+		out = append(out, fmt.Errorf("invalid bridge name: %s", conf.Bridge))
+	}
+	if conf.VLAN > 4094 {
+		out = append(out, fmt.Errorf("invalid VLAN: %d", conf.VLAN))
+	}
+	return out
+}
+
+// validateSimpleHostDeviceConfig checks its name and SimpleHostDeviceConfig.
+func validateSimpleHostDeviceConfig(an *operv1.AdditionalNetworkDefinition) []error {
+	out := []error{}
+	if an.Name == "" {
+		out = append(out, fmt.Errorf("Additional Network Name cannot be nil")) //nolint:staticcheck // ANN is the name
+	}
+
+	conf := SimpleHostDeviceConfig{}
+	if err := simpleNetworkConfig(an, &conf); err != nil {
+		return append(out, err)
+	}
+	if conf.IPAMConfig != nil {
+		out = append(out, validateSimpleIPAMConfig(conf.IPAMConfig)...)
+	}
+	if (conf.Device == "") == (conf.PCIAddress == "") {
+		out = append(out, fmt.Errorf("exactly one of device and pciAddress must be set"))
+	}
+	return out
+}
+
+// renderSimpleIPVLANConfig returns the SimpleIPVLAN manifests
+func renderSimpleIPVLANConfig(an *operv1.AdditionalNetworkDefinition, manifestDir string) ([]*uns.Unstructured, error) {
+	conf := SimpleIPVLANConfig{}
+	if err := simpleNetworkConfig(an, &conf); err != nil {
+		return nil, err
+	}
+
+	var err error
+	data := render.MakeRenderData()
+	data.Data["AdditionalNetworkName"] = an.Name
+	data.Data["AdditionalNetworkNamespace"] = an.Namespace
+	data.Data["Master"] = conf.Master
+	// ipvlan CNI only accepts mode in lowercase
+	data.Data["Mode"] = strings.ToLower(string(conf.Mode))
+	if conf.MTU != 0 {
+		data.Data["MTU"] = conf.MTU
+	}
+	data.Data["IPAMConfig"], err = getSimpleIPAMConfigJSON(conf.IPAMConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render ipam config: %w", err)
+	}
+
+	objs, err := render.RenderDir(filepath.Join(manifestDir, "network/additional-networks/simpleipvlan"), &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render simpleipvlan additional network: %w", err)
+	}
+	return objs, nil
+}
+
+// renderSimpleBridgeConfig returns the SimpleBridge manifests
+func renderSimpleBridgeConfig(an *operv1.AdditionalNetworkDefinition, manifestDir string) ([]*uns.Unstructured, error) {
+	conf := SimpleBridgeConfig{}
+	if err := simpleNetworkConfig(an, &conf); err != nil {
+		return nil, err
+	}
+
+	var err error
+	data := render.MakeRenderData()
+	data.Data["AdditionalNetworkName"] = an.Name
+	data.Data["AdditionalNetworkNamespace"] = an.Namespace
+	data.Data["Bridge"] = conf.Bridge
+	if conf.VLAN != 0 {
+		data.Data["VLAN"] = conf.VLAN
+	}
+	data.Data["IsGateway"] = conf.IsGateway
+	data.Data["IPMasq"] = conf.IPMasq
+	if conf.MTU != 0 {
+		data.Data["MTU"] = conf.MTU
+	}
+	data.Data["IPAMConfig"], err = getSimpleIPAMConfigJSON(conf.IPAMConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render ipam config: %w", err)
+	}
+
+	objs, err := render.RenderDir(filepath.Join(manifestDir, "network/additional-networks/simplebridge"), &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render simplebridge additional network: %w", err)
+	}
+	return objs, nil
+}
+
+// renderSimpleHostDeviceConfig returns the SimpleHostDevice manifests
+func renderSimpleHostDeviceConfig(an *operv1.AdditionalNetworkDefinition, manifestDir string) ([]*uns.Unstructured, error) {
+	conf := SimpleHostDeviceConfig{}
+	if err := simpleNetworkConfig(an, &conf); err != nil {
+		return nil, err
+	}
+
+	var err error
+	data := render.MakeRenderData()
+	data.Data["AdditionalNetworkName"] = an.Name
+	data.Data["AdditionalNetworkNamespace"] = an.Namespace
+	data.Data["Device"] = conf.Device
+	data.Data["PCIAddress"] = conf.PCIAddress
+	data.Data["IPAMConfig"] = ""
+	if conf.IPAMConfig != nil {
+		data.Data["IPAMConfig"], err = getSimpleIPAMConfigJSON(conf.IPAMConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render ipam config: %w", err)
+		}
+	}
+
+	objs, err := render.RenderDir(filepath.Join(manifestDir, "network/additional-networks/simplehostdevice"), &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render simplehostdevice additional network: %w", err)
+	}
+	return objs, nil
+}
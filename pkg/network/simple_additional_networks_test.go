@@ -0,0 +1,184 @@
+package network
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
+)
+
+// renderedCNIConfig renders an additional network and returns the CNI
+// configuration of its NetworkAttachmentDefinition, checking it is valid
+func renderedCNIConfig(g *WithT, an *operv1.AdditionalNetworkDefinition) map[string]any {
+	objs, err := renderAdditionalNetworks(&operv1.NetworkSpec{AdditionalNetworks: []operv1.AdditionalNetworkDefinition{*an}}, manifestDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objs).To(HaveLen(1))
+	g.Expect(objs).To(ContainElement(HaveKubernetesID("NetworkAttachmentDefinition", "foobar", an.Name)))
+
+	config, _, err := uns.NestedString(objs[0].Object, "spec", "config")
+	g.Expect(err).NotTo(HaveOccurred())
+	raw := operv1.AdditionalNetworkDefinition{Name: an.Name, RawCNIConfig: config}
+	g.Expect(validateRaw(&raw, field.NewPath("spec"))).To(BeEmpty())
+	g.Expect(validateRawCNIConfig(&raw, field.NewPath("spec"))).To(BeEmpty())
+
+	out := map[string]any{}
+	g.Expect(json.Unmarshal([]byte(config), &out)).To(Succeed())
+	return out
+}
+
+func TestRenderSimpleIPVLANConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	an := operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleIPVLAN, Name: "ipvlan", Namespace: "foobar"}
+	g.Expect(renderedCNIConfig(g, &an)).To(Equal(map[string]any{
+		"cniVersion": "0.3.1",
+		"type":       "ipvlan",
+		"ipam":       map[string]any{"type": "dhcp"},
+	}))
+
+	an.RawCNIConfig = `{"master":"eth1","mode":"L3S","mtu":1400,"ipamConfig":{"type":"Whereabouts","whereaboutsIPAMConfig":{"range":"192.0.2.0/24","exclude":["192.0.2.0/28"]}}}`
+	g.Expect(renderedCNIConfig(g, &an)).To(Equal(map[string]any{
+		"cniVersion": "0.3.1",
+		"type":       "ipvlan",
+		"master":     "eth1",
+		"mode":       "l3s",
+		"mtu":        float64(1400),
+		"ipam":       map[string]any{"type": "whereabouts", "range": "192.0.2.0/24", "exclude": []any{"192.0.2.0/28"}},
+	}))
+}
+
+func TestRenderSimpleBridgeConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	an := operv1.AdditionalNetworkDefinition{
+		Type:         NetworkTypeSimpleBridge,
+		Name:         "bridge",
+		Namespace:    "foobar",
+		RawCNIConfig: `{"bridge":"br-pods","vlan":100,"isGateway":true,"ipMasq":true,"ipamConfig":{"type":"Static","staticIPAMConfig":{"addresses":[{"address":"192.0.2.10/24"}]}}}`,
+	}
+	g.Expect(renderedCNIConfig(g, &an)).To(Equal(map[string]any{
+		"cniVersion": "0.3.1",
+		"type":       "bridge",
+		"bridge":     "br-pods",
+		"vlan":       float64(100),
+		"isGateway":  true,
+		"ipMasq":     true,
+		"ipam":       map[string]any{"type": "static", "addresses": []any{map[string]any{"address": "192.0.2.10/24"}}},
+	}))
+}
+
+func TestRenderSimpleHostDeviceConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	an := operv1.AdditionalNetworkDefinition{
+		Type:         NetworkTypeSimpleHostDevice,
+		Name:         "hostdev",
+		Namespace:    "foobar",
+		RawCNIConfig: `{"pciAddress":"0000:3b:00.1"}`,
+	}
+	g.Expect(renderedCNIConfig(g, &an)).To(Equal(map[string]any{
+		"cniVersion": "0.3.1",
+		"type":       "host-device",
+		"pciBusID":   "0000:3b:00.1",
+	}))
+
+	an.RawCNIConfig = `{"device":"ens5f1","ipamConfig":{"type":"DHCP"}}`
+	g.Expect(renderedCNIConfig(g, &an)).To(Equal(map[string]any{
+		"cniVersion": "0.3.1",
+		"type":       "host-device",
+		"device":     "ens5f1",
+		"ipam":       map[string]any{"type": "dhcp"},
+	}))
+}
+
+func TestValidateSimpleAdditionalNetworks(t *testing.T) {
+	testcases := []struct {
+		name     string
+		an       operv1.AdditionalNetworkDefinition
+		expected []string
+	}{
+		{
+			name: "valid ipvlan",
+			an:   operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleIPVLAN, Name: "a", RawCNIConfig: `{"mode":"L2"}`},
+		},
+		{
+			name:     "unknown field",
+			an:       operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleIPVLAN, Name: "a", RawCNIConfig: `{"masterr":"eth0"}`},
+			expected: []string{`failed to parse SimpleIPVLAN configuration of additional network a: json: unknown field "masterr"`},
+		},
+		{
+			name:     "invalid ipvlan mode",
+			an:       operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleIPVLAN, Name: "a", RawCNIConfig: `{"mode":"bridge"}`},
+			expected: []string{"invalid IPVLAN mode: bridge"},
+		},
+		{
+			name:     "invalid whereabouts",
+			an:       operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleIPVLAN, Name: "a", RawCNIConfig: `{"ipamConfig":{"type":"Whereabouts","whereaboutsIPAMConfig":{"range":"192.0.2.0","exclude":["x"]}}}`},
+			expected: []string{"invalid whereabouts range: invalid CIDR address: 192.0.2.0", "invalid whereabouts exclude: invalid CIDR address: x"},
+		},
+		{
+			name:     "missing whereabouts",
+			an:       operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleBridge, Name: "a", RawCNIConfig: `{"ipamConfig":{"type":"Whereabouts"}}`},
+			expected: []string{"whereaboutsIPAMConfig is required for whereabouts IPAM"},
+		},
+		{
+			name:     "invalid static",
+			an:       operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleBridge, Name: "a", RawCNIConfig: `{"ipamConfig":{"type":"Static","staticIPAMConfig":{"addresses":[{"address":"192.0.2.1"}]}}}`},
+			expected: []string{"invalid static address: invalid CIDR address: 192.0.2.1"},
+		},
+		{
+			name:     "invalid bridge",
+			an:       operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleBridge, RawCNIConfig: `{"bridge":"a-very-long-bridge-name","vlan":4095}`},
+			expected: []string{"Additional Network Name cannot be nil", "invalid bridge name: a-very-long-bridge-name", "invalid VLAN: 4095"},
+		},
+		{
+			name:     "host device without device",
+			an:       operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleHostDevice, Name: "a"},
+			expected: []string{"exactly one of device and pciAddress must be set"},
+		},
+		{
+			name:     "host device with both",
+			an:       operv1.AdditionalNetworkDefinition{Type: NetworkTypeSimpleHostDevice, Name: "a", RawCNIConfig: `{"device":"eth1","pciAddress":"0000:3b:00.1"}`},
+			expected: []string{"exactly one of device and pciAddress must be set"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			errs := validateAdditionalNetworks(&operv1.NetworkSpec{AdditionalNetworks: []operv1.AdditionalNetworkDefinition{tc.an}})
+			messages := []string{}
+			for _, err := range errs {
+				messages = append(messages, err.Error())
+			}
+			if len(tc.expected) == 0 {
+				g.Expect(messages).To(BeEmpty())
+			} else {
+				g.Expect(messages).To(Equal(tc.expected))
+			}
+		})
+	}
+}
+
+func TestSimpleAdditionalNetworksAuxiliaryIPAM(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	disabled := false
+	conf := &operv1.NetworkSpec{
+		DisableMultiNetwork: &disabled,
+		AdditionalNetworks: []operv1.AdditionalNetworkDefinition{
+			{Type: NetworkTypeSimpleIPVLAN, Name: "ipvlan"},
+			{Type: NetworkTypeSimpleBridge, Name: "bridge", RawCNIConfig: `{"ipamConfig":{"type":"Whereabouts","whereaboutsIPAMConfig":{"range":"192.0.2.0/24"}}}`},
+			{Type: NetworkTypeSimpleHostDevice, Name: "hostdev", RawCNIConfig: `{"device":"eth1"}`},
+		},
+	}
+	dhcp, whereabouts := auxiliaryIPAMUsers(conf, &bootstrap.AuxiliaryIPAMBootstrapResult{})
+	g.Expect(dhcp).To(Equal([]string{"additional network ipvlan"}))
+	g.Expect(whereabouts).To(Equal([]string{"additional network bridge"}))
+	g.Expect(additionalNetworkCIDRs(conf)).To(Equal([]string{"192.0.2.0/24"}))
+}
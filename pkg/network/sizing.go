@@ -0,0 +1,381 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
+	cnoclient "github.com/openshift/cluster-network-operator/pkg/client"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sizingProfile holds the resource requests of the network control plane components
+// for clusters up to a given size.
+type sizingProfile struct {
+	name string
+	// maxNodes, maxPods, maxServices and maxNetworkPolicies are the largest object
+	// counts the profile is meant for; 0 means unlimited
+	maxNodes           int64
+	maxPods            int64
+	maxServices        int64
+	maxNetworkPolicies int64
+
+	ovnControlPlane           bootstrap.ResourceRequests
+	multusAdmissionController bootstrap.ResourceRequests
+	networkNodeIdentity       bootstrap.ResourceRequests
+}
+
+// sizingProfiles are ordered from the smallest to the largest. The smallest profile
+// sets no requests, leaving those the manifests have always had, which are not the
+// same in the self-hosted and managed ovnkube-control-plane manifests.
+var sizingProfiles = []sizingProfile{
+	{
+		name:               "Small",
+		maxNodes:           25,
+		maxPods:            2500,
+		maxServices:        500,
+		maxNetworkPolicies: 500,
+	},
+	{
+		name:                      "Medium",
+		maxNodes:                  120,
+		maxPods:                   15000,
+		maxServices:               3000,
+		maxNetworkPolicies:        3000,
+		ovnControlPlane:           bootstrap.ResourceRequests{CPU: 50, Memory: 600},
+		multusAdmissionController: bootstrap.ResourceRequests{CPU: 20, Memory: 100},
+		networkNodeIdentity:       bootstrap.ResourceRequests{CPU: 20, Memory: 100},
+	},
+	{
+		name:                      "Large",
+		maxNodes:                  250,
+		maxPods:                   50000,
+		maxServices:               10000,
+		maxNetworkPolicies:        10000,
+		ovnControlPlane:           bootstrap.ResourceRequests{CPU: 100, Memory: 1500},
+		multusAdmissionController: bootstrap.ResourceRequests{CPU: 50, Memory: 200},
+		networkNodeIdentity:       bootstrap.ResourceRequests{CPU: 50, Memory: 200},
+	},
+	{
+		name:                      "XLarge",
+		ovnControlPlane:           bootstrap.ResourceRequests{CPU: 200, Memory: 3000},
+		multusAdmissionController: bootstrap.ResourceRequests{CPU: 100, Memory: 400},
+		networkNodeIdentity:       bootstrap.ResourceRequests{CPU: 100, Memory: 400},
+	},
+}
+
+// sizingDownscaleFactor is applied to the limits of a profile before moving a
+// cluster to a smaller one, so that a cluster whose size hovers around a limit does
+// not keep redeploying its control plane.
+const sizingDownscaleFactor = 0.8
+
+// sizingOverrides is read from spec.unsupportedConfigOverrides.controlPlaneSizing,
+// for example:
+//
+//	unsupportedConfigOverrides:
+//	  controlPlaneSizing:
+//	    profile: Large
+//	    resources:
+//	      ovnkube-control-plane:
+//	        memory: 2Gi
+type sizingOverrides struct {
+	Profile   string                            `json:"profile,omitempty"`
+	Resources map[string]sizingOverrideRequests `json:"resources,omitempty"`
+}
+
+type sizingOverrideRequests struct {
+	CPU    *resource.Quantity `json:"cpu,omitempty"`
+	Memory *resource.Quantity `json:"memory,omitempty"`
+}
+
+// The components whose resource requests can be overridden
+const (
+	sizingComponentOVNControlPlane           = "ovnkube-control-plane"
+	sizingComponentMultusAdmissionController = "multus-admission-controller"
+	sizingComponentNetworkNodeIdentity       = "network-node-identity"
+)
+
+// getSizingOverrides parses the sizing overrides from the operator configuration. It
+// returns nil if there are none.
+func getSizingOverrides(conf *operv1.NetworkSpec) (*sizingOverrides, error) {
+	if len(conf.UnsupportedConfigOverrides.Raw) == 0 {
+		return nil, nil
+	}
+	overrides := struct {
+		ControlPlaneSizing *sizingOverrides `json:"controlPlaneSizing,omitempty"`
+	}{}
+	if err := json.Unmarshal(conf.UnsupportedConfigOverrides.Raw, &overrides); err != nil {
+		return nil, fmt.Errorf("could not parse unsupportedConfigOverrides: %w", err)
+	}
+	return overrides.ControlPlaneSizing, nil
+}
+
+// validateSizingOverrides checks that the sizing overrides, if any, name a known
+// profile and known components.
+func validateSizingOverrides(conf *operv1.NetworkSpec) []error {
+	overrides, err := getSizingOverrides(conf)
+	if err != nil {
+		return []error{err}
+	}
+	if overrides == nil {
+		return nil
+	}
+
+	errs := []error{}
+	if overrides.Profile != "" && findSizingProfile(overrides.Profile) == nil {
+		known := []string{}
+		for _, p := range sizingProfiles {
+			known = append(known, p.name)
+		}
+		errs = append(errs, fmt.Errorf("unknown controlPlaneSizing profile %q, must be one of %s", overrides.Profile, strings.Join(known, ", ")))
+	}
+	for component, requests := range overrides.Resources {
+		switch component {
+		case sizingComponentOVNControlPlane, sizingComponentMultusAdmissionController, sizingComponentNetworkNodeIdentity:
+		default:
+			errs = append(errs, fmt.Errorf("unknown controlPlaneSizing component %q", component))
+		}
+		if requests.CPU != nil && requests.CPU.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("controlPlaneSizing cpu request for %s must be positive", component))
+		}
+		if requests.Memory != nil && requests.Memory.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("controlPlaneSizing memory request for %s must be positive", component))
+		}
+	}
+	return errs
+}
+
+// findSizingProfile returns the profile with the given name, ignoring case
+func findSizingProfile(name string) *sizingProfile {
+	for i := range sizingProfiles {
+		if strings.EqualFold(sizingProfiles[i].name, name) {
+			return &sizingProfiles[i]
+		}
+	}
+	return nil
+}
+
+// fits returns true if the object counts, scaled by factor, fit within the limits
+// of the profile
+func (p *sizingProfile) fits(s *bootstrap.SizingBootstrapResult, factor float64) bool {
+	within := func(count, max int64) bool {
+		return max == 0 || float64(count) <= factor*float64(max)
+	}
+	return within(s.Nodes, p.maxNodes) && within(s.Pods, p.maxPods) &&
+		within(s.Services, p.maxServices) && within(s.NetworkPolicies, p.maxNetworkPolicies)
+}
+
+// selectSizingProfile returns the smallest profile the cluster fits in. The cluster
+// only moves to a smaller profile than previous once it fits well within it.
+func selectSizingProfile(s *bootstrap.SizingBootstrapResult, previous string) *sizingProfile {
+	selected := len(sizingProfiles) - 1
+	for i := range sizingProfiles {
+		if sizingProfiles[i].fits(s, 1) {
+			selected = i
+			break
+		}
+	}
+
+	for i := range sizingProfiles {
+		if !strings.EqualFold(sizingProfiles[i].name, previous) || i <= selected {
+			continue
+		}
+		// Downscaling: pick the smallest profile the cluster fits well within
+		for j := selected; j < i; j++ {
+			if sizingProfiles[j].fits(s, sizingDownscaleFactor) {
+				return &sizingProfiles[j]
+			}
+		}
+		return &sizingProfiles[i]
+	}
+	return &sizingProfiles[selected]
+}
+
+// sizingCountInterval is how often the objects of the cluster are counted. The
+// profiles are far apart, so there is no need to count them on every reconcile.
+const sizingCountInterval = 30 * time.Minute
+
+// objectCounts are the last counts of the objects of the cluster
+type objectCounts struct {
+	sync.Mutex
+	counted                                time.Time
+	nodes, pods, services, networkPolicies int64
+}
+
+var clusterObjectCounts = &objectCounts{}
+
+// countObjects returns the number of objects of a kind without retrieving all of
+// them: it lists the metadata of a single object, and adds the remaining item
+// count of the list.
+func countObjects(ctx context.Context, c crclient.Client, gvk schema.GroupVersionKind) (int64, error) {
+	l := &metav1.PartialObjectMetadataList{}
+	l.SetGroupVersionKind(gvk)
+	if err := c.List(ctx, l, crclient.Limit(1)); err != nil {
+		return 0, err
+	}
+	count := int64(len(l.Items))
+	if remaining := l.GetRemainingItemCount(); remaining != nil {
+		count += *remaining
+	}
+	return count, nil
+}
+
+// count refreshes the counts of the objects of the cluster, if they are older than
+// sizingCountInterval, and copies them to out
+func (o *objectCounts) count(c crclient.Client, out *bootstrap.SizingBootstrapResult) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.counted.IsZero() || time.Since(o.counted) >= sizingCountInterval {
+		ctx := context.TODO()
+		counts := []struct {
+			kind  schema.GroupVersionKind
+			count *int64
+		}{
+			{corev1.SchemeGroupVersion.WithKind("NodeList"), &o.nodes},
+			{corev1.SchemeGroupVersion.WithKind("PodList"), &o.pods},
+			{corev1.SchemeGroupVersion.WithKind("ServiceList"), &o.services},
+			{networkingv1.SchemeGroupVersion.WithKind("NetworkPolicyList"), &o.networkPolicies},
+		}
+		for _, list := range counts {
+			n, err := countObjects(ctx, c, list.kind)
+			if err != nil {
+				return fmt.Errorf("failed to count %s objects: %w", strings.TrimSuffix(list.kind.Kind, "List"), err)
+			}
+			*list.count = n
+		}
+		o.counted = time.Now()
+	}
+
+	out.Nodes, out.Pods, out.Services, out.NetworkPolicies = o.nodes, o.pods, o.services, o.networkPolicies
+	return nil
+}
+
+// bootstrapSizing counts the nodes, pods, services and network policies of the
+// cluster, at most every sizingCountInterval, and selects the sizing profile of the network control plane, applying the
+// overrides from the operator configuration.
+func bootstrapSizing(conf *operv1.Network, client cnoclient.Client) (bootstrap.SizingBootstrapResult, error) {
+	out := bootstrap.SizingBootstrapResult{}
+	if err := clusterObjectCounts.count(client.Default().CRClient(), &out); err != nil {
+		return out, err
+	}
+
+	profile := selectSizingProfile(&out, conf.Annotations[names.ControlPlaneSizingProfileAnnotation])
+	out.Selected = profile.name
+
+	// Invalid overrides are reported by Validate
+	overrides, err := getSizingOverrides(&conf.Spec)
+	if err != nil {
+		klog.Warningf("Ignoring control plane sizing overrides: %v", err)
+		overrides = nil
+	}
+	if overrides != nil && overrides.Profile != "" {
+		if p := findSizingProfile(overrides.Profile); p != nil {
+			profile = p
+			out.Overridden = true
+		}
+	}
+
+	out.Profile = profile.name
+	out.OVNControlPlane = profile.ovnControlPlane
+	out.MultusAdmissionController = profile.multusAdmissionController
+	out.NetworkNodeIdentity = profile.networkNodeIdentity
+	if overrides != nil {
+		for component, requests := range overrides.Resources {
+			var r *bootstrap.ResourceRequests
+			switch component {
+			case sizingComponentOVNControlPlane:
+				r = &out.OVNControlPlane
+			case sizingComponentMultusAdmissionController:
+				r = &out.MultusAdmissionController
+			case sizingComponentNetworkNodeIdentity:
+				r = &out.NetworkNodeIdentity
+			default:
+				continue
+			}
+			if requests.CPU != nil && requests.CPU.Sign() > 0 {
+				r.CPU = requests.CPU.MilliValue()
+				out.Overridden = true
+			}
+			if requests.Memory != nil && requests.Memory.Sign() > 0 {
+				r.Memory = requests.Memory.Value() / bytesInMiB
+				out.Overridden = true
+			}
+		}
+	}
+
+	klog.Infof("Selected control plane sizing profile %s for %d nodes, %d pods, %d services and %d network policies (overridden: %v)",
+		out.Profile, out.Nodes, out.Pods, out.Services, out.NetworkPolicies, out.Overridden)
+	return out, nil
+}
+
+// sizedRequest returns the request to render for a component. On HyperShift the
+// requests of the existing deployment may have been raised by an external source,
+// in which case they are kept unless the administrator overrode the sizing. An empty
+// result leaves the default of the manifest in place.
+func sizedRequest(sized int64, preserved string, overridden bool) string {
+	if sized == 0 {
+		return preserved
+	}
+	if !overridden && preserved != "" {
+		if existing, err := strconv.ParseInt(preserved, 10, 64); err == nil && existing > sized {
+			return preserved
+		}
+	}
+	return strconv.FormatInt(sized, 10)
+}
+
+// SizingCondition returns the operator condition reporting the sizing profile of the
+// network control plane. It only changes with the profile, not with the object
+// counts it was selected from.
+func SizingCondition(s *bootstrap.SizingBootstrapResult) operv1.OperatorCondition {
+	cond := operv1.OperatorCondition{
+		Type:   names.ControlPlaneSizingCondition,
+		Status: operv1.ConditionTrue,
+		Reason: "ProfileSelected",
+	}
+	if s.Overridden {
+		cond.Reason = "Overridden"
+	}
+
+	profile := findSizingProfile(s.Profile)
+	if profile == nil {
+		return cond
+	}
+	if profile.maxNodes == 0 {
+		cond.Message = fmt.Sprintf("Sizing profile %s, for clusters larger than the other profiles", profile.name)
+	} else {
+		cond.Message = fmt.Sprintf("Sizing profile %s, for clusters of up to %d nodes, %d pods, %d services and %d network policies",
+			profile.name, profile.maxNodes, profile.maxPods, profile.maxServices, profile.maxNetworkPolicies)
+	}
+	if s.Overridden {
+		cond.Message += ", overridden by spec.unsupportedConfigOverrides.controlPlaneSizing"
+	}
+	return cond
+}
+
+// SetSizingProfileAnnotation records the profile selected from the object counts
+// on the operator configuration, so that the next selection only moves to a
+// smaller profile once the cluster fits well within it
+func SetSizingProfileAnnotation(conf *operv1.Network, s *bootstrap.SizingBootstrapResult) {
+	if s.Selected == "" || conf.Annotations[names.ControlPlaneSizingProfileAnnotation] == s.Selected {
+		return
+	}
+	if conf.Annotations == nil {
+		conf.Annotations = map[string]string{}
+	}
+	conf.Annotations[names.ControlPlaneSizingProfileAnnotation] = s.Selected
+}
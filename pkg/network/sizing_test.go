@@ -0,0 +1,192 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	operv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
+	cnofake "github.com/openshift/cluster-network-operator/pkg/client/fake"
+	"github.com/openshift/cluster-network-operator/pkg/names"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	crclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSelectSizingProfile(t *testing.T) {
+	testcases := []struct {
+		name     string
+		counts   bootstrap.SizingBootstrapResult
+		previous string
+		expected string
+	}{
+		{
+			name:     "empty cluster",
+			expected: "Small",
+		},
+		{
+			name:     "nodes exceed small",
+			counts:   bootstrap.SizingBootstrapResult{Nodes: 26, Pods: 100},
+			expected: "Medium",
+		},
+		{
+			name:     "network policies exceed large",
+			counts:   bootstrap.SizingBootstrapResult{Nodes: 3, NetworkPolicies: 20000},
+			expected: "XLarge",
+		},
+		{
+			name:     "grows past previous",
+			counts:   bootstrap.SizingBootstrapResult{Nodes: 130},
+			previous: "Medium",
+			expected: "Large",
+		},
+		{
+			name:     "stays while close to the limit",
+			counts:   bootstrap.SizingBootstrapResult{Nodes: 24},
+			previous: "Medium",
+			expected: "Medium",
+		},
+		{
+			name:     "shrinks once well within the limit",
+			counts:   bootstrap.SizingBootstrapResult{Nodes: 20},
+			previous: "Medium",
+			expected: "Small",
+		},
+		{
+			name:     "shrinks by one profile only as far as it fits well",
+			counts:   bootstrap.SizingBootstrapResult{Nodes: 100},
+			previous: "XLarge",
+			expected: "Large",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(selectSizingProfile(&tc.counts, tc.previous).name).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestValidateSizingOverrides(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	conf := &operv1.NetworkSpec{}
+	g.Expect(validateSizingOverrides(conf)).To(BeEmpty())
+
+	conf.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(`{"controlPlaneSizing":{"profile":"large","resources":{"ovnkube-control-plane":{"memory":"2Gi"}}}}`)}
+	g.Expect(validateSizingOverrides(conf)).To(BeEmpty())
+
+	conf.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(`{"controlPlaneSizing":{"profile":"huge","resources":{"ovnkube-node":{"cpu":"-1"}}}}`)}
+	errs := validateSizingOverrides(conf)
+	g.Expect(errs).To(HaveLen(3))
+	g.Expect(errs[0]).To(MatchError(ContainSubstring(`unknown controlPlaneSizing profile "huge"`)))
+
+	conf.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(`{"controlPlaneSizing":`)}
+	g.Expect(validateSizingOverrides(conf)).To(HaveLen(1))
+}
+
+func TestBootstrapSizing(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	objs := []crclient.Object{}
+	for i := 0; i < 30; i++ {
+		objs = append(objs, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node%d", i)}})
+	}
+	objs = append(objs, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"}})
+	client := cnofake.NewFakeClient(objs...)
+	clusterObjectCounts = &objectCounts{}
+
+	conf := &operv1.Network{}
+	sizing, err := bootstrapSizing(conf, client)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sizing.Nodes).To(BeEquivalentTo(30))
+	g.Expect(sizing.Pods).To(BeEquivalentTo(1))
+	g.Expect(sizing.Profile).To(Equal("Medium"))
+	g.Expect(sizing.Selected).To(Equal("Medium"))
+	g.Expect(sizing.Overridden).To(BeFalse())
+	g.Expect(sizing.OVNControlPlane).To(Equal(bootstrap.ResourceRequests{CPU: 50, Memory: 600}))
+
+	cond := SizingCondition(&sizing)
+	g.Expect(cond.Type).To(Equal(names.ControlPlaneSizingCondition))
+	g.Expect(cond.Reason).To(Equal("ProfileSelected"))
+	g.Expect(cond.Message).To(Equal("Sizing profile Medium, for clusters of up to 120 nodes, 15000 pods, 3000 services and 3000 network policies"))
+
+	// The selected profile is kept on the operator configuration
+	SetSizingProfileAnnotation(conf, &sizing)
+	g.Expect(conf.Annotations).To(HaveKeyWithValue(names.ControlPlaneSizingProfileAnnotation, "Medium"))
+
+	// The counts are not refreshed before sizingCountInterval
+	g.Expect(client.Default().CRClient().Delete(context.TODO(), objs[0])).To(Succeed())
+	sizing, err = bootstrapSizing(conf, client)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sizing.Nodes).To(BeEquivalentTo(30))
+
+	// The administrator can pick the profile and override single requests
+	conf.Spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(`{"controlPlaneSizing":{"profile":"XLarge","resources":{"network-node-identity":{"cpu":"1","memory":"1Gi"}}}}`)}
+	sizing, err = bootstrapSizing(conf, client)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sizing.Profile).To(Equal("XLarge"))
+	g.Expect(sizing.Selected).To(Equal("Medium"))
+	g.Expect(sizing.Overridden).To(BeTrue())
+	g.Expect(SizingCondition(&sizing).Reason).To(Equal("Overridden"))
+	g.Expect(sizing.OVNControlPlane).To(Equal(bootstrap.ResourceRequests{CPU: 200, Memory: 3000}))
+	g.Expect(sizing.NetworkNodeIdentity).To(Equal(bootstrap.ResourceRequests{CPU: 1000, Memory: 1024}))
+}
+
+// limitingClient honors the limit of List calls, like the API server, which the
+// fake client ignores
+type limitingClient struct {
+	crclient.Client
+}
+
+func (c *limitingClient) List(ctx context.Context, list crclient.ObjectList, opts ...crclient.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	o := &crclient.ListOptions{}
+	o.ApplyOptions(opts)
+	l := list.(*metav1.PartialObjectMetadataList)
+	if o.Limit > 0 && int64(len(l.Items)) > o.Limit {
+		remaining := int64(len(l.Items)) - o.Limit
+		l.Items = l.Items[:o.Limit]
+		l.SetContinue("continue")
+		l.SetRemainingItemCount(&remaining)
+	}
+	return nil
+}
+
+func TestCountObjects(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	objs := []crclient.Object{}
+	for i := 0; i < 30; i++ {
+		objs = append(objs, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node%d", i)}})
+	}
+	c := &limitingClient{Client: cnofake.NewFakeClient(objs...).Default().CRClient()}
+	nodes := corev1.SchemeGroupVersion.WithKind("NodeList")
+
+	// A single node is listed, the others are counted from the remaining item count
+	n, err := countObjects(context.TODO(), c, nodes)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(BeEquivalentTo(30))
+
+	n, err = countObjects(context.TODO(), c, corev1.SchemeGroupVersion.WithKind("PodList"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(BeEquivalentTo(0))
+}
+
+func TestSizedRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(sizedRequest(0, "", false)).To(Equal(""))
+	g.Expect(sizedRequest(50, "", false)).To(Equal("50"))
+	// Requests raised externally on HyperShift are kept...
+	g.Expect(sizedRequest(50, "80", false)).To(Equal("80"))
+	g.Expect(sizedRequest(50, "20", false)).To(Equal("50"))
+	// ...unless the administrator overrode the sizing
+	g.Expect(sizedRequest(50, "80", true)).To(Equal("50"))
+}
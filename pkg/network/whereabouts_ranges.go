@@ -0,0 +1,235 @@
+package network
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+
+	operv1 "github.com/openshift/api/operator/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WhereaboutsRange is an address range allocated by the whereabouts IPAM plugin
+type WhereaboutsRange struct {
+	// Owner is the additional network or the NetworkAttachmentDefinition using the range
+	Owner string
+	// NetworkName is the whereabouts network_name, which scopes the IP pools
+	NetworkName string
+	// CIDR is the subnet of the range, which names its IP pool
+	CIDR *net.IPNet
+
+	// spans are the addresses of CIDR that whereabouts assigns: those between
+	// range_start and range_end, but the excluded ones
+	spans []addressRange
+	// narrowed is true when range_start, range_end or exclude are set
+	narrowed bool
+}
+
+// newWhereaboutsRange returns the whereabouts range rng, which may also be in the
+// form "<first IP>-<last IP>/<prefix>", restricted to start and end when they are
+// set and without the exclude subnets. ok is false if rng is invalid.
+func newWhereaboutsRange(owner, networkName, rng string, start, end any, exclude []string) (WhereaboutsRange, bool) {
+	cidr := parseIPAMRange(rng)
+	if cidr == nil {
+		return WhereaboutsRange{}, false
+	}
+	full, _ := newAddressRange(cidr.String(), nil, nil)
+	r, ok := newAddressRange(rng, start, end)
+	if !ok {
+		return WhereaboutsRange{}, false
+	}
+	out := WhereaboutsRange{
+		Owner:       owner,
+		NetworkName: networkName,
+		CIDR:        cidr,
+		narrowed:    !r.first.Equal(full.first) || !r.last.Equal(full.last),
+	}
+
+	// whereabouts never assigns the network and broadcast addresses of IPv4 subnets
+	if ones, bits := cidr.Mask.Size(); bits == 32 && bits-ones >= 2 {
+		if first := nextIP(full.first, 1); bytes.Compare(r.first, first) < 0 {
+			r.first = first
+		}
+		if last := nextIP(full.last, -1); bytes.Compare(r.last, last) > 0 {
+			r.last = last
+		}
+	}
+	if bytes.Compare(r.first, r.last) <= 0 {
+		out.spans = []addressRange{r}
+	}
+	for _, e := range exclude {
+		excluded, ok := newAddressRange(e, nil, nil)
+		if !ok || strings.Contains(e, "-") {
+			continue
+		}
+		out.narrowed = true
+		spans := []addressRange{}
+		for _, s := range out.spans {
+			if !s.overlaps(excluded) {
+				spans = append(spans, s)
+				continue
+			}
+			if bytes.Compare(s.first, excluded.first) < 0 {
+				spans = append(spans, addressRange{first: s.first, last: nextIP(excluded.first, -1)})
+			}
+			if bytes.Compare(s.last, excluded.last) > 0 {
+				spans = append(spans, addressRange{first: nextIP(excluded.last, 1), last: s.last})
+			}
+		}
+		out.spans = spans
+	}
+	return out, true
+}
+
+// Overlaps returns true if r and other may assign the same addresses
+func (r *WhereaboutsRange) Overlaps(other *WhereaboutsRange) bool {
+	for _, a := range r.spans {
+		for _, b := range other.spans {
+			if a.overlaps(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String returns the subnet of the range, or the addresses it assigns when they
+// are restricted
+func (r *WhereaboutsRange) String() string {
+	if !r.narrowed {
+		return r.CIDR.String()
+	}
+	spans := []string{}
+	for _, s := range r.spans {
+		spans = append(spans, s.String())
+	}
+	return r.CIDR.String() + " [" + strings.Join(spans, ",") + "]"
+}
+
+// WhereaboutsPoolSize returns the number of addresses that the ranges of the
+// whereabouts IP pool name, of subnet cidr, assign. ok is false if none of ranges
+// uses the pool.
+func WhereaboutsPoolSize(ranges []WhereaboutsRange, name string, cidr *net.IPNet) (size float64, ok bool) {
+	spans := []addressRange{}
+	for i := range ranges {
+		if ranges[i].CIDR.String() == cidr.String() && whereaboutsPoolName(ranges[i].NetworkName, cidr) == name {
+			spans = append(spans, ranges[i].spans...)
+			ok = true
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+
+	// ranges of the same pool may assign the same addresses
+	sort.Slice(spans, func(i, j int) bool { return bytes.Compare(spans[i].first, spans[j].first) < 0 })
+	total := new(big.Int)
+	for i := 0; i < len(spans); {
+		first, last := spans[i].first, spans[i].last
+		for i++; i < len(spans) && bytes.Compare(spans[i].first, nextIP(last, 1)) <= 0; i++ {
+			if bytes.Compare(spans[i].last, last) > 0 {
+				last = spans[i].last
+			}
+		}
+		total.Add(total, new(big.Int).Sub(new(big.Int).SetBytes(last), new(big.Int).SetBytes(first)))
+		total.Add(total, big.NewInt(1))
+	}
+	size, _ = new(big.Float).SetInt(total).Float64()
+	return size, true
+}
+
+// whereaboutsPoolName returns the name whereabouts gives to the IP pool of the
+// subnet cidr in networkName
+func whereaboutsPoolName(networkName string, cidr *net.IPNet) string {
+	name := strings.NewReplacer(":", "-", "/", "-").Replace(cidr.String())
+	if networkName != "" {
+		name = networkName + "-" + name
+	}
+	return name
+}
+
+// nextIP returns the address delta, 1 or -1, after ip
+func nextIP(ip net.IP, delta int) net.IP {
+	out := make(net.IP, len(ip))
+	copy(out, ip)
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] += byte(delta)
+		if (delta > 0 && out[i] != 0) || (delta < 0 && out[i] != 0xff) {
+			break
+		}
+	}
+	return out
+}
+
+// WhereaboutsRanges returns the whereabouts ranges of the additional networks and
+// of the NetworkAttachmentDefinitions that were not rendered from them
+func WhereaboutsRanges(conf *operv1.NetworkSpec, nads []uns.Unstructured) []WhereaboutsRange {
+	out := []WhereaboutsRange{}
+	for i := range conf.AdditionalNetworks {
+		an := &conf.AdditionalNetworks[i]
+		owner := "additional network " + an.Name
+		switch an.Type {
+		case operv1.NetworkTypeRaw:
+			out = append(out, rawWhereaboutsRanges(owner, an.RawCNIConfig)...)
+		case NetworkTypeSimpleIPVLAN, NetworkTypeSimpleBridge, NetworkTypeSimpleHostDevice:
+			ipam := simpleIPAMConfig(an)
+			if ipam == nil || ipam.Type != IPAMTypeWhereabouts || ipam.WhereaboutsIPAMConfig == nil {
+				continue
+			}
+			if r, ok := newWhereaboutsRange(owner, "", ipam.WhereaboutsIPAMConfig.Range, nil, nil, ipam.WhereaboutsIPAMConfig.Exclude); ok {
+				out = append(out, r)
+			}
+		}
+	}
+
+	rendered := additionalNetworkNADNames(conf)
+	for i := range nads {
+		name := nads[i].GetNamespace() + "/" + nads[i].GetName()
+		if rendered.Has(name) {
+			continue
+		}
+		config, _, _ := uns.NestedString(nads[i].Object, "spec", "config")
+		out = append(out, rawWhereaboutsRanges("NetworkAttachmentDefinition "+name, config)...)
+	}
+	return out
+}
+
+// rawWhereaboutsRanges returns the whereabouts ranges of a CNI configuration
+func rawWhereaboutsRanges(owner, rawCNIConfig string) []WhereaboutsRange {
+	out := []WhereaboutsRange{}
+	for _, ipam := range rawIPAMConfigs(rawCNIConfig) {
+		if ipam["type"] != ipamTypeWhereabouts {
+			continue
+		}
+		networkName, _ := ipam["network_name"].(string)
+		add := func(m map[string]any) {
+			s, ok := m["range"].(string)
+			if !ok {
+				return
+			}
+			exclude := []string{}
+			if e, ok := m["exclude"].([]any); ok {
+				for _, v := range e {
+					if v, ok := v.(string); ok {
+						exclude = append(exclude, v)
+					}
+				}
+			}
+			if r, ok := newWhereaboutsRange(owner, networkName, s, m["range_start"], m["range_end"], exclude); ok {
+				out = append(out, r)
+			}
+		}
+
+		add(ipam)
+		if ipRanges, ok := ipam["ipRanges"].([]any); ok {
+			for _, r := range ipRanges {
+				if m, ok := r.(map[string]any); ok {
+					add(m)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/ghodss/yaml"
 	configv1 "github.com/openshift/api/config/v1"
 	mcfgv1 "github.com/openshift/api/machineconfiguration/v1"
 	"github.com/openshift/cluster-network-operator/pkg/bootstrap"
@@ -30,6 +31,11 @@ var cloudProviderConfig = types.NamespacedName{
 	Name:      "kube-cloud-config",
 }
 
+var clusterConfig = types.NamespacedName{
+	Namespace: "kube-system",
+	Name:      "cluster-config-v1",
+}
+
 // isNetworkNodeIdentityEnabled determines if network node identity should be enabled.
 // It checks the `enabled` key in the network-node-identity/openshift-network-operator configmap.
 // If the configmap doesn't exist, it returns true (the feature is enabled by default).
@@ -134,6 +140,11 @@ func InfraStatus(client cnoclient.Client) (*bootstrap.InfraStatus, error) {
 		return nil, err
 	}
 
+	res.MachineNetworks, err = machineNetworks(client, infraConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	// Skip retrieving IPsec MachineConfig and MachineConfigPool if it's a hypershift cluster because
 	// those object kinds are not supported there.
 	if res.HostedControlPlane != nil {
@@ -263,3 +274,57 @@ func consolePluginCRDExists(cl cnoclient.Client) (bool, error) {
 	}
 	return true, nil
 }
+
+// machineNetworks returns the machine network CIDRs of the cluster. They are taken from
+// the install-config, which is not available on HyperShift, and from the platform status
+// of the on-prem platforms that record them.
+func machineNetworks(cl cnoclient.Client, infraConfig *configv1.Infrastructure) ([]string, error) {
+	type installConfig struct {
+		Networking struct {
+			MachineCIDR    string `json:"machineCIDR"`
+			MachineNetwork []struct {
+				CIDR string `json:"cidr"`
+			} `json:"machineNetwork,omitempty"`
+		} `json:"networking"`
+	}
+
+	cidrs := sets.New[string]()
+
+	cm := &corev1.ConfigMap{}
+	if err := cl.Default().CRClient().Get(context.TODO(), clusterConfig, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to retrieve ConfigMap %s: %w", clusterConfig, err)
+		}
+	} else if data, ok := cm.Data["install-config"]; ok {
+		ic := installConfig{}
+		if err := yaml.Unmarshal([]byte(data), &ic); err != nil {
+			klog.Warningf("Ignoring machine networks from invalid install-config: %v", err)
+		} else {
+			if ic.Networking.MachineCIDR != "" {
+				cidrs.Insert(ic.Networking.MachineCIDR)
+			}
+			for _, mn := range ic.Networking.MachineNetwork {
+				cidrs.Insert(mn.CIDR)
+			}
+		}
+	}
+
+	var platformMachineNetworks []configv1.CIDR
+	if ps := infraConfig.Status.PlatformStatus; ps != nil {
+		switch {
+		case ps.BareMetal != nil:
+			platformMachineNetworks = ps.BareMetal.MachineNetworks
+		case ps.VSphere != nil:
+			platformMachineNetworks = ps.VSphere.MachineNetworks
+		case ps.OpenStack != nil:
+			platformMachineNetworks = ps.OpenStack.MachineNetworks
+		}
+	}
+	for _, mn := range platformMachineNetworks {
+		cidrs.Insert(string(mn))
+	}
+
+	// 0.0.0.0/0 is what the installer uses when the machine network is unknown
+	cidrs.Delete("0.0.0.0/0", "::/0", "")
+	return sets.List(cidrs), nil
+}
@@ -12,17 +12,26 @@ type IPPool struct {
 }
 
 func (p *IPPool) Add(cidr net.IPNet) error {
-	for _, n := range p.cidrs {
-		if NetsOverlap(n, cidr) {
-			return fmt.Errorf("CIDRs %s and %s overlap",
-				n.String(),
-				cidr.String())
-		}
+	if n := p.Overlapping(cidr); n != nil {
+		return fmt.Errorf("CIDRs %s and %s overlap",
+			n.String(),
+			cidr.String())
 	}
 	p.cidrs = append(p.cidrs, cidr)
 	return nil
 }
 
+// Overlapping returns the first CIDR of the pool that overlaps cidr, or nil
+// if there is none
+func (p *IPPool) Overlapping(cidr net.IPNet) *net.IPNet {
+	for i := range p.cidrs {
+		if NetsOverlap(p.cidrs[i], cidr) {
+			return &p.cidrs[i]
+		}
+	}
+	return nil
+}
+
 // NetsOverlap return true if two nets overlap
 func NetsOverlap(a, b net.IPNet) bool {
 	// ignore different families
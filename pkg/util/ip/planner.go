@@ -0,0 +1,87 @@
+package ip
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// SubnetPlanner hands out subnets that do not overlap any CIDR that has
+// already been reserved. It is built on top of IPPool, but unlike IPPool.Add
+// the reserved CIDRs are allowed to overlap each other: a planner only cares
+// about what is free, not about whether the existing configuration is sane.
+type SubnetPlanner struct {
+	reserved IPPool
+}
+
+// Reserve marks cidr as in use.
+func (p *SubnetPlanner) Reserve(cidr net.IPNet) {
+	p.reserved.cidrs = append(p.reserved.cidrs, cidr)
+}
+
+// ReserveString parses and reserves each of the given CIDRs.
+func (p *SubnetPlanner) ReserveString(cidrs ...string) error {
+	for _, c := range cidrs {
+		_, cidr, err := net.ParseCIDR(c)
+		if err != nil {
+			return fmt.Errorf("could not parse CIDR %s: %w", c, err)
+		}
+		p.Reserve(*cidr)
+	}
+	return nil
+}
+
+// Available returns true if cidr does not overlap any reserved CIDR.
+func (p *SubnetPlanner) Available(cidr net.IPNet) bool {
+	return p.reserved.Overlapping(cidr) == nil
+}
+
+// Allocate returns the lowest subnet with the given prefix length, inside the
+// first of the candidate ranges that has room for it, which does not overlap
+// any reserved CIDR. The returned subnet is reserved, so successive calls
+// never return overlapping subnets.
+func (p *SubnetPlanner) Allocate(prefixLen int, candidates ...net.IPNet) (*net.IPNet, error) {
+	for _, within := range candidates {
+		ones, bits := within.Mask.Size()
+		if prefixLen < ones || prefixLen > bits {
+			continue
+		}
+		mask := net.CIDRMask(prefixLen, bits)
+		step := new(big.Int).Lsh(big.NewInt(1), uint(bits-prefixLen))
+		end := ipToInt(lastIP(within))
+
+		for cur := ipToInt(within.IP); cur.Cmp(end) <= 0; {
+			candidate := net.IPNet{IP: intToIP(cur, len(within.IP)), Mask: mask}
+			conflict := p.reserved.Overlapping(candidate)
+			if conflict == nil {
+				p.Reserve(candidate)
+				return &candidate, nil
+			}
+
+			// Skip past whichever ends last, the conflicting reservation
+			// or the candidate itself, and round up to the next subnet
+			// boundary.
+			next := ipToInt(lastIP(candidate))
+			if c := ipToInt(lastIP(*conflict)); c.Cmp(next) > 0 {
+				next = c
+			}
+			next.Add(next, big.NewInt(1))
+			next.Add(next, new(big.Int).Sub(step, big.NewInt(1)))
+			next.Div(next, step)
+			cur = next.Mul(next, step)
+		}
+	}
+
+	return nil, fmt.Errorf("no free /%d subnet left in %v", prefixLen, candidates)
+}
+
+// ipToInt converts an IP address to an integer
+func ipToInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip)
+}
+
+// intToIP converts an integer to an IP address of the given length in bytes
+func intToIP(i *big.Int, length int) net.IP {
+	ip := make(net.IP, length)
+	return i.FillBytes(ip)
+}
@@ -0,0 +1,111 @@
+package ip
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func mustParseCIDR(g *WithT, cidr string) net.IPNet {
+	_, n, err := net.ParseCIDR(cidr)
+	g.Expect(err).NotTo(HaveOccurred())
+	return *n
+}
+
+func TestSubnetPlannerAllocate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	testcases := []struct {
+		name       string
+		reserved   []string
+		prefixLen  int
+		candidates []string
+		expected   []string
+		fail       bool
+	}{
+		{
+			name:       "nothing reserved",
+			prefixLen:  16,
+			candidates: []string{"100.64.0.0/10"},
+			expected:   []string{"100.64.0.0/16", "100.65.0.0/16"},
+		},
+		{
+			name:       "skips a smaller reservation",
+			reserved:   []string{"100.64.3.0/24"},
+			prefixLen:  16,
+			candidates: []string{"100.64.0.0/10"},
+			expected:   []string{"100.65.0.0/16"},
+		},
+		{
+			name:       "skips a larger reservation",
+			reserved:   []string{"100.64.0.0/12", "10.0.0.0/8"},
+			prefixLen:  16,
+			candidates: []string{"100.64.0.0/10"},
+			expected:   []string{"100.80.0.0/16", "100.81.0.0/16"},
+		},
+		{
+			name:       "overlapping reservations",
+			reserved:   []string{"100.64.0.0/14", "100.65.0.0/16", "100.67.0.0/16"},
+			prefixLen:  16,
+			candidates: []string{"100.64.0.0/10"},
+			expected:   []string{"100.68.0.0/16"},
+		},
+		{
+			name:       "falls through to the next candidate range",
+			reserved:   []string{"169.254.0.0/16"},
+			prefixLen:  17,
+			candidates: []string{"169.254.0.0/16", "198.18.0.0/15"},
+			expected:   []string{"198.18.0.0/17"},
+		},
+		{
+			name:       "candidate smaller than the prefix is ignored",
+			prefixLen:  16,
+			candidates: []string{"100.64.0.0/24", "198.18.0.0/15"},
+			expected:   []string{"198.18.0.0/16"},
+		},
+		{
+			name:       "ipv6",
+			reserved:   []string{"fd00::/16", "fd01::/64", "10.0.0.0/8"},
+			prefixLen:  64,
+			candidates: []string{"fd00::/8"},
+			expected:   []string{"fd01:0:0:1::/64"},
+		},
+		{
+			name:       "exhausted",
+			reserved:   []string{"169.254.0.0/17"},
+			prefixLen:  16,
+			candidates: []string{"169.254.0.0/16"},
+			fail:       true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			p := SubnetPlanner{}
+			g.Expect(p.ReserveString(tc.reserved...)).To(Succeed())
+			candidates := []net.IPNet{}
+			for _, c := range tc.candidates {
+				candidates = append(candidates, mustParseCIDR(g, c))
+			}
+
+			if tc.fail {
+				_, err := p.Allocate(tc.prefixLen, candidates...)
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			for _, expected := range tc.expected {
+				n, err := p.Allocate(tc.prefixLen, candidates...)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(n.String()).To(Equal(expected))
+			}
+		})
+	}
+
+	p := SubnetPlanner{}
+	g.Expect(p.ReserveString("10.0.0.0/16")).To(Succeed())
+	g.Expect(p.Available(mustParseCIDR(g, "10.0.1.0/24"))).To(BeFalse())
+	g.Expect(p.Available(mustParseCIDR(g, "10.1.0.0/24"))).To(BeTrue())
+	g.Expect(p.ReserveString("not-a-cidr")).NotTo(Succeed())
+}
@@ -19,6 +19,7 @@ const OVN_CONTROLLER = "ovnkube-controller"
 const MTU_CM_NAMESPACE = "openshift-network-operator"
 const MTU_CM_NAME = "mtu"
 const OVN_NBDB = "nbdb"
+const OVN_GATEWAY_MODE_CM_NAME = "ovnkube-gateway-mode"
 
 func ReadMTUConfigMap(ctx context.Context, client cnoclient.Client) (int, error) {
 	klog.V(4).Infof("Looking for ConfigMap %s/%s", MTU_CM_NAMESPACE, MTU_CM_NAME)
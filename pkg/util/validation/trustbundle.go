@@ -1,8 +1,11 @@
 package validation
 
 import (
+	"bytes"
 	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"time"
 
 	"github.com/openshift/cluster-network-operator/pkg/names"
 	"github.com/openshift/library-go/pkg/crypto"
@@ -51,3 +54,91 @@ func MergeCertificateData(addlData, systemData []byte) ([]*x509.Certificate, err
 
 	return mergedCerts, nil
 }
+
+// TrustBundleCertificateStatus is the status of a certificate of a user
+// provided trust bundle
+type TrustBundleCertificateStatus string
+
+const (
+	// TrustBundleCertificateValid certificates are merged with the system trust bundle
+	TrustBundleCertificateValid TrustBundleCertificateStatus = "Valid"
+	// TrustBundleCertificateExpiringSoon certificates are merged with the system
+	// trust bundle, but expire within TrustBundleExpiryWarningPeriod
+	TrustBundleCertificateExpiringSoon TrustBundleCertificateStatus = "ExpiringSoon"
+	// TrustBundleCertificateExpired certificates are left out of the merged bundle
+	TrustBundleCertificateExpired TrustBundleCertificateStatus = "Expired"
+	// TrustBundleCertificateDuplicate certificates are already in the system
+	// trust bundle, so they are left out of the merged bundle
+	TrustBundleCertificateDuplicate TrustBundleCertificateStatus = "Duplicate"
+)
+
+// TrustBundleExpiryWarningPeriod is how long before their expiry the
+// certificates of a user provided trust bundle are reported as expiring soon.
+const TrustBundleExpiryWarningPeriod = 30 * 24 * time.Hour
+
+// TrustBundleCertificate is the summary of a certificate of a user provided
+// trust bundle.
+type TrustBundleCertificate struct {
+	Subject      string                       `json:"subject"`
+	Issuer       string                       `json:"issuer"`
+	SerialNumber string                       `json:"serialNumber"`
+	NotAfter     time.Time                    `json:"notAfter"`
+	Status       TrustBundleCertificateStatus `json:"status"`
+}
+
+// InspectTrustBundle checks each certificate of addlData, a user provided trust
+// bundle, against systemData and now. It returns the PEM data of the
+// certificates that should be merged with the system trust bundle, leaving out
+// the expired ones and the ones already in systemData, and the summary of every
+// certificate of addlData.
+func InspectTrustBundle(addlData, systemData []byte, now time.Time) ([]byte, []TrustBundleCertificate, error) {
+	addlCerts, err := crypto.CertsFromPEM(addlData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate data: %v", err)
+	}
+	systemCerts := []*x509.Certificate{}
+	if len(systemData) > 0 {
+		systemCerts, err = crypto.CertsFromPEM(systemData)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse system certificate data: %v", err)
+		}
+	}
+
+	var merged bytes.Buffer
+	summary := make([]TrustBundleCertificate, 0, len(addlCerts))
+	for _, cert := range addlCerts {
+		status := trustBundleCertificateStatus(cert, systemCerts, now)
+		summary = append(summary, TrustBundleCertificate{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: cert.SerialNumber.Text(16),
+			NotAfter:     cert.NotAfter.UTC(),
+			Status:       status,
+		})
+		if status == TrustBundleCertificateExpired || status == TrustBundleCertificateDuplicate {
+			continue
+		}
+		if err := pem.Encode(&merged, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode certificate %q: %v", cert.Subject, err)
+		}
+	}
+
+	return merged.Bytes(), summary, nil
+}
+
+// trustBundleCertificateStatus returns the status of cert, a certificate of a
+// user provided trust bundle, given the certificates of the system trust bundle.
+func trustBundleCertificateStatus(cert *x509.Certificate, systemCerts []*x509.Certificate, now time.Time) TrustBundleCertificateStatus {
+	if now.After(cert.NotAfter) {
+		return TrustBundleCertificateExpired
+	}
+	for _, systemCert := range systemCerts {
+		if cert.Equal(systemCert) {
+			return TrustBundleCertificateDuplicate
+		}
+	}
+	if now.Add(TrustBundleExpiryWarningPeriod).After(cert.NotAfter) {
+		return TrustBundleCertificateExpiringSoon
+	}
+	return TrustBundleCertificateValid
+}
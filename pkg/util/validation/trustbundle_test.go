@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/openshift/library-go/pkg/crypto"
+)
+
+func makeCAPEM(t *testing.T, name string, validity time.Duration) []byte {
+	t.Helper()
+	config, err := crypto.MakeSelfSignedCAConfigForDuration(name, validity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, _, err := config.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certPEM
+}
+
+func TestInspectTrustBundle(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	shortLived := makeCAPEM(t, "short-lived", 10*24*time.Hour)
+	longLived := makeCAPEM(t, "long-lived", 365*24*time.Hour)
+	system := makeCAPEM(t, "system", 365*24*time.Hour)
+	addlData := append(append(append([]byte{}, shortLived...), longLived...), system...)
+	now := time.Now()
+
+	merged, summary, err := InspectTrustBundle(addlData, system, now)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(summary).To(HaveLen(3))
+	g.Expect(summary[0].Subject).To(Equal("CN=short-lived"))
+	g.Expect(summary[0].Issuer).To(Equal("CN=short-lived"))
+	g.Expect(summary[0].Status).To(Equal(TrustBundleCertificateExpiringSoon))
+	g.Expect(summary[1].Status).To(Equal(TrustBundleCertificateValid))
+	// the certificates of the system bundle are not merged again
+	g.Expect(summary[2].Status).To(Equal(TrustBundleCertificateDuplicate))
+	g.Expect(string(merged)).To(Equal(string(shortLived) + string(longLived)))
+
+	// expired certificates are left out
+	merged, summary, err = InspectTrustBundle(addlData, system, now.Add(20*24*time.Hour))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(summary[0].Status).To(Equal(TrustBundleCertificateExpired))
+	g.Expect(string(merged)).To(Equal(string(longLived)))
+
+	_, _, err = InspectTrustBundle([]byte("garbage"), system, now)
+	g.Expect(err).To(HaveOccurred())
+}